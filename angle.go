@@ -0,0 +1,68 @@
+package go_mpls
+
+import (
+	"fmt"
+)
+
+// NumberOfAngles returns the largest angle count of the play items, or 1
+// when the playlist has no multi-angle play item.
+func (mpls *MPLS) NumberOfAngles() int {
+	numberOfAngles := 1
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		if playItem.IsMultiAngle {
+			numberOfAngles = max(numberOfAngles, playItem.NumberOfAngles)
+		}
+	}
+	return numberOfAngles
+}
+
+// AngleClip returns the clip presented by the play item for a 1-based angle
+// ID. Play items with fewer angles present their own clip.
+func (item *PlayItem) AngleClip(angleID int) *Angle {
+	if item.IsMultiAngle && angleID >= 2 && angleID-2 < len(item.AnglesList) {
+		return item.AnglesList[angleID-2]
+	}
+	return &Angle{
+		ClipInformationFileName: item.ClipInformationFileName,
+		ClipCodecIdentifier:     item.ClipCodecIdentifier,
+		RefToSTCID:              item.RefToSTCID,
+	}
+}
+
+// AnglePlayList returns a virtual playlist presenting a single angle. Its
+// play items are copies with the angle clip in place of their own clip and
+// no angle list, so it can be handled like any linear playlist. Stream
+// tables are shared with the source playlist.
+func (mpls *MPLS) AnglePlayList(angleID int) (*MPLS, error) {
+	if angleID < 1 || angleID > mpls.NumberOfAngles() {
+		return nil, fmt.Errorf("angle %d is not defined", angleID)
+	}
+
+	playList := *mpls.PlayList
+	playList.PlayItemList = nil
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		angle := playItem.AngleClip(angleID)
+		anglePlayItem := *playItem
+		anglePlayItem.ClipInformationFileName = angle.ClipInformationFileName
+		anglePlayItem.ClipCodecIdentifier = angle.ClipCodecIdentifier
+		anglePlayItem.RefToSTCID = angle.RefToSTCID
+		anglePlayItem.IsMultiAngle = false
+		anglePlayItem.NumberOfAngles = 0
+		anglePlayItem.AnglesList = nil
+		playList.PlayItemList = append(playList.PlayItemList, &anglePlayItem)
+	}
+
+	anglePlayList := *mpls
+	anglePlayList.PlayList = &playList
+	return &anglePlayList, nil
+}
+
+// ExpandAngles returns one virtual playlist per angle, angle 1 first.
+func (mpls *MPLS) ExpandAngles() []*MPLS {
+	var playListsList []*MPLS = nil
+	for angleID := 1; angleID <= mpls.NumberOfAngles(); angleID++ {
+		anglePlayList, _ := mpls.AnglePlayList(angleID)
+		playListsList = append(playListsList, anglePlayList)
+	}
+	return playListsList
+}
@@ -0,0 +1,66 @@
+package go_mpls
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMultiAnglePlayItem(t *testing.T) {
+	rawData := make([]byte, 34)
+	copy(rawData[2:11], "00001M2TS")
+	rawData[12] = 0x10
+	rawData = append(rawData, 0x02, 0x01)
+	rawData = append(rawData, []byte("00002M2TS")...)
+	rawData = append(rawData, 0x00)
+	stnTable := make([]byte, 16)
+	stnTable[1] = 14
+	rawData = append(rawData, stnTable...)
+
+	playItem := parsePlayItem(&byteCursor{data: rawData})
+	if len(playItem.AnglesList) != 1 || playItem.AnglesList[0].ClipInformationFileName != "00002" || !playItem.IsSeamlessAngleChange {
+		t.Fatalf("unexpected angles %#v", playItem.AnglesList)
+	}
+	if playItem.STNTable.Length != 14 {
+		t.Fatalf("STN table read from the wrong offset %#v", playItem.STNTable)
+	}
+}
+
+func TestExpandAngles(t *testing.T) {
+	mpls := &MPLS{PlayList: &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00001", INTimeTicks: 0, OUTTimeTicks: 45000},
+		{ClipInformationFileName: "00002", INTimeTicks: 0, OUTTimeTicks: 90000, IsMultiAngle: true, NumberOfAngles: 3,
+			AnglesList: []*Angle{{ClipInformationFileName: "00003"}, {ClipInformationFileName: "00004"}},
+			STNTable: &STNTable{PrimaryVideoStreamsList: []*Stream{{
+				StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: 0x1011},
+				StreamAttributes: &StreamAttributes{StreamCodingType: HEVCVideo},
+			}}}},
+	}}}
+
+	anglePlayLists := mpls.ExpandAngles()
+	if len(anglePlayLists) != 3 {
+		t.Fatalf("unexpected number of angles %d", len(anglePlayLists))
+	}
+	var clipsList []string = nil
+	for _, playItem := range anglePlayLists[2].PlayList.PlayItemList {
+		clipsList = append(clipsList, playItem.ClipInformationFileName)
+	}
+	if !slices.Equal(clipsList, []string{"00001", "00004"}) || anglePlayLists[2].DurationTicks() != 135000 {
+		t.Fatalf("unexpected angle 3 %v", clipsList)
+	}
+	if mpls.PlayList.PlayItemList[1].ClipInformationFileName != "00002" {
+		t.Fatal("expanding angles changed the source playlist")
+	}
+	if _, err := mpls.AnglePlayList(4); err == nil {
+		t.Fatal("angle 4 should not exist")
+	}
+
+	info := mpls.Info()
+	if len(info.AnglesList) != 3 || info.AnglesList[1].ClipsList[1] != "00003" || info.Duration != 3 {
+		t.Fatalf("unexpected info %#v", info)
+	}
+	angleStreams := info.AnglesList[1].StreamsList
+	if len(angleStreams) != 2 || len(angleStreams[0].VideoStreamsList) != 0 ||
+		len(angleStreams[1].VideoStreamsList) != 1 || angleStreams[1].VideoStreamsList[0].PID != 0x1011 {
+		t.Fatalf("unexpected angle streams %#v", angleStreams)
+	}
+}
@@ -0,0 +1,738 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+type AudioStreamInfo struct {
+	PID               int
+	StreamCodingType  StreamCodingType
+	Codec             string
+	NumberOfChannels  int
+	NumberOfLFE       int
+	SampleRate        int
+	BitDepth          int
+	BitRate           int
+	MaximumBitRate    int
+	IsVariableBitRate bool
+	HasAtmos          bool
+	HasDTSX           bool
+	CoreInfo          *AudioStreamInfo
+}
+
+type AudioStreamAnalysis struct {
+	PlayItem        *PlayItem
+	Stream          *Stream
+	AudioStreamInfo *AudioStreamInfo
+}
+
+func (info *AudioStreamInfo) ChannelLayout() string {
+	if info.NumberOfChannels == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", info.NumberOfChannels-info.NumberOfLFE, info.NumberOfLFE)
+}
+
+var ac3SampleRates = []int{48000, 44100, 32000}
+var eac3ReducedSampleRates = []int{24000, 22050, 16000}
+var ac3Channels = []int{2, 1, 2, 3, 3, 4, 4, 5}
+var ac3BitRates = []int{32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 448, 512, 576, 640}
+var eac3Blocks = []int{1, 2, 3, 6}
+
+// channel counts of the E-AC-3 chanmap locations, most significant bit first
+var eac3ChannelMapChannels = []int{1, 1, 1, 1, 1, 2, 2, 1, 1, 2, 2, 2, 1, 2, 1, 1}
+
+type ac3Frame struct {
+	IsEAC3               bool
+	StreamType           int
+	SubstreamID          int
+	FrameSize            int
+	SampleRate           int
+	NumberOfBlocks       int
+	NumberOfChannels     int
+	NumberOfLFE          int
+	ChannelMap           int
+	BitRate              int
+	ExtensionTypeA       bool
+	ComplexityIndexTypeA int
+}
+
+func parseAC3Frame(rawData []byte) *ac3Frame {
+	if len(rawData) < 8 || rawData[0] != 0x0b || rawData[1] != 0x77 {
+		return nil
+	}
+
+	bsid := int(rawData[5] >> 3)
+	if bsid <= 10 {
+		fscod := int(rawData[4] >> 6)
+		frmsizecod := int(rawData[4] & 0b00111111)
+		if fscod == 3 || frmsizecod >= 38 {
+			return nil
+		}
+		bitRate := ac3BitRates[frmsizecod>>1] * 1000
+		sampleRate := ac3SampleRates[fscod]
+
+		frameSize := 0
+		switch fscod {
+		case 0:
+			frameSize = 4 * ac3BitRates[frmsizecod>>1]
+		case 1:
+			frameSize = 2 * (320*ac3BitRates[frmsizecod>>1]/147 + frmsizecod&1)
+		case 2:
+			frameSize = 6 * ac3BitRates[frmsizecod>>1]
+		}
+
+		reader := newBitReader(rawData[6:])
+		acmod := reader.readInt(3)
+		if acmod&1 != 0 && acmod != 1 {
+			reader.skipBits(2)
+		}
+		if acmod&4 != 0 {
+			reader.skipBits(2)
+		}
+		if acmod == 2 {
+			reader.skipBits(2)
+		}
+		lfe := reader.readInt(1)
+
+		return &ac3Frame{
+			FrameSize:        frameSize,
+			SampleRate:       sampleRate,
+			NumberOfBlocks:   6,
+			NumberOfChannels: ac3Channels[acmod] + lfe,
+			NumberOfLFE:      lfe,
+			ChannelMap:       -1,
+			BitRate:          bitRate,
+		}
+	}
+	if bsid > 16 {
+		return nil
+	}
+
+	reader := newBitReader(rawData[2:])
+	frame := &ac3Frame{IsEAC3: true, ChannelMap: -1}
+	frame.StreamType = reader.readInt(2)
+	frame.SubstreamID = reader.readInt(3)
+	frame.FrameSize = (reader.readInt(11) + 1) * 2
+	fscod := reader.readInt(2)
+	numblkscod := 3
+	if fscod == 3 {
+		fscod2 := reader.readInt(2)
+		if fscod2 == 3 {
+			return nil
+		}
+		frame.SampleRate = eac3ReducedSampleRates[fscod2]
+	} else {
+		numblkscod = reader.readInt(2)
+		frame.SampleRate = ac3SampleRates[fscod]
+	}
+	frame.NumberOfBlocks = eac3Blocks[numblkscod]
+	acmod := reader.readInt(3)
+	lfeon := reader.readFlag()
+	frame.NumberOfChannels = ac3Channels[acmod]
+	if lfeon {
+		frame.NumberOfChannels++
+		frame.NumberOfLFE = 1
+	}
+	frame.BitRate = frame.FrameSize * 8 * frame.SampleRate / (frame.NumberOfBlocks * 256)
+	reader.skipBits(5)
+
+	// dual mono (acmod 0) repeats the per-program fields
+	programs := 1
+	if acmod == 0 {
+		programs = 2
+	}
+	for i := 0; i < programs; i++ {
+		reader.skipBits(5)
+		if reader.readFlag() {
+			reader.skipBits(8)
+		}
+	}
+	if frame.StreamType == 1 && reader.readFlag() {
+		frame.ChannelMap = reader.readInt(16)
+	}
+
+	if reader.readFlag() {
+		if acmod > 2 {
+			reader.skipBits(2)
+		}
+		if acmod&1 != 0 && acmod > 2 {
+			reader.skipBits(6)
+		}
+		if acmod&4 != 0 {
+			reader.skipBits(6)
+		}
+		if lfeon && reader.readFlag() {
+			reader.skipBits(5)
+		}
+		if frame.StreamType == 0 {
+			if reader.readFlag() {
+				reader.skipBits(6)
+			}
+			if acmod == 0 && reader.readFlag() {
+				reader.skipBits(6)
+			}
+			if reader.readFlag() {
+				reader.skipBits(6)
+			}
+			switch reader.readInt(2) {
+			case 1:
+				reader.skipBits(5)
+			case 2:
+				reader.skipBits(12)
+			case 3:
+				reader.skipBits((reader.readInt(5) + 2) * 8)
+			}
+			if acmod < 2 {
+				for i := 0; i < programs; i++ {
+					if reader.readFlag() {
+						reader.skipBits(14)
+					}
+				}
+			}
+			if reader.readFlag() {
+				if numblkscod == 0 {
+					reader.skipBits(5)
+				} else {
+					for i := 0; i < frame.NumberOfBlocks; i++ {
+						if reader.readFlag() {
+							reader.skipBits(5)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if reader.readFlag() {
+		reader.skipBits(5)
+		if acmod == 2 {
+			reader.skipBits(4)
+		}
+		if acmod >= 6 {
+			reader.skipBits(2)
+		}
+		for i := 0; i < programs; i++ {
+			if reader.readFlag() {
+				reader.skipBits(8)
+			}
+		}
+		if fscod < 3 {
+			reader.skipBits(1)
+		}
+	}
+	if frame.StreamType == 0 && numblkscod != 3 {
+		reader.skipBits(1)
+	}
+	if frame.StreamType == 2 && (numblkscod == 3 || reader.readFlag()) {
+		reader.skipBits(6)
+	}
+
+	// the least significant bit of the first additional bsi byte is
+	// flag_ec3_extension_type_a, which signals Dolby Atmos (JOC) content
+	if reader.readFlag() {
+		addbsil := reader.readInt(6) + 1
+		if addbsil >= 1 {
+			reader.skipBits(7)
+			frame.ExtensionTypeA = reader.readFlag()
+			if frame.ExtensionTypeA && addbsil >= 2 {
+				frame.ComplexityIndexTypeA = reader.readInt(8)
+			}
+		}
+	}
+
+	if reader.overrun() {
+		return nil
+	}
+	return frame
+}
+
+func analyzeAC3(info *AudioStreamInfo, payload []byte) bool {
+	var independent *ac3Frame
+	for offset := 0; offset+8 <= len(payload); {
+		frame := parseAC3Frame(payload[offset:])
+		if frame == nil || frame.FrameSize == 0 {
+			offset++
+			continue
+		}
+		offset += frame.FrameSize
+
+		if !frame.IsEAC3 {
+			if info.CoreInfo == nil && info.StreamCodingType == DolbyDigitalTureHDAudio {
+				info.CoreInfo = &AudioStreamInfo{
+					PID:              info.PID,
+					StreamCodingType: info.StreamCodingType,
+					Codec:            "AC-3",
+					NumberOfChannels: frame.NumberOfChannels,
+					NumberOfLFE:      frame.NumberOfLFE,
+					SampleRate:       frame.SampleRate,
+					BitRate:          frame.BitRate,
+				}
+				continue
+			}
+			if info.StreamCodingType == DolbyDigitalTureHDAudio {
+				continue
+			}
+			info.Codec = "AC-3"
+			info.NumberOfChannels = frame.NumberOfChannels
+			info.NumberOfLFE = frame.NumberOfLFE
+			info.SampleRate = frame.SampleRate
+			info.BitRate = frame.BitRate
+			return true
+		}
+
+		if frame.StreamType != 1 {
+			if independent != nil {
+				return true
+			}
+			independent = frame
+			info.Codec = "E-AC-3"
+			info.NumberOfChannels = frame.NumberOfChannels
+			info.NumberOfLFE = frame.NumberOfLFE
+			info.SampleRate = frame.SampleRate
+			info.BitRate = frame.BitRate
+			info.HasAtmos = frame.ExtensionTypeA
+			continue
+		}
+		if independent == nil {
+			continue
+		}
+
+		info.BitRate += frame.BitRate
+		info.HasAtmos = info.HasAtmos || frame.ExtensionTypeA
+		if frame.ChannelMap >= 0 {
+			// dependent channels either replace L/C/R/Ls/Rs/LFE of the
+			// independent substream or extend the layout
+			for bit := 0; bit < 16; bit++ {
+				if frame.ChannelMap&(1<<(15-bit)) == 0 || bit < 5 || bit == 15 {
+					continue
+				}
+				info.NumberOfChannels += eac3ChannelMapChannels[bit]
+				if bit == 14 {
+					info.NumberOfLFE++
+				}
+			}
+		} else {
+			info.NumberOfChannels += frame.NumberOfChannels
+			info.NumberOfLFE += frame.NumberOfLFE
+		}
+	}
+	return independent != nil
+}
+
+var trueHDSampleRates = map[int]int{0: 48000, 1: 96000, 2: 192000, 8: 44100, 9: 88200, 10: 176400}
+
+// channel counts of the 13-bit TrueHD channel arrangement, least significant bit first
+var trueHDArrangementChannels = []int{2, 1, 1, 2, 2, 2, 2, 1, 1, 2, 2, 1, 1}
+
+func analyzeTrueHD(info *AudioStreamInfo, payload []byte) bool {
+	offset := bytes.Index(payload, []byte{0xf8, 0x72, 0x6f, 0xba})
+	if offset < 0 || offset+18 > len(payload) {
+		return false
+	}
+
+	major := payload[offset:]
+	sampleRate, ok := trueHDSampleRates[int(major[4]>>4)]
+	if !ok {
+		return false
+	}
+
+	reader := newBitReader(major[5:])
+	reader.skipBits(4)
+	arrangement1 := reader.readInt(5)
+	reader.skipBits(2)
+	arrangement2 := reader.readInt(13)
+	reader.skipBits(48)
+	isVariableBitRate := reader.readFlag()
+	peakBitRate := reader.readInt(15)
+	numberOfSubstreams := reader.readInt(4)
+	reader.skipBits(4)
+	substreamInfo := reader.readInt(8)
+
+	arrangement := arrangement2
+	if arrangement == 0 {
+		arrangement = arrangement1
+	}
+	channels := 0
+	lfe := 0
+	for bit := 0; bit < 13; bit++ {
+		if arrangement&(1<<bit) != 0 {
+			channels += trueHDArrangementChannels[bit]
+			if bit == 2 || bit == 12 {
+				lfe++
+			}
+		}
+	}
+
+	info.Codec = "TrueHD"
+	info.NumberOfChannels = channels
+	info.NumberOfLFE = lfe
+	info.SampleRate = sampleRate
+	info.BitDepth = 24
+	info.IsVariableBitRate = isVariableBitRate
+	info.MaximumBitRate = (peakBitRate*sampleRate + 8) >> 4
+	// a fourth substream carries the 16-channel presentation used by Atmos
+	info.HasAtmos = numberOfSubstreams == 4 || substreamInfo&0x80 != 0
+	return true
+}
+
+var dtsChannels = []int{1, 2, 2, 2, 2, 3, 3, 4, 4, 5, 6, 6, 6, 7, 8, 8}
+var dtsSampleRates = []int{0, 8000, 16000, 32000, 0, 0, 11025, 22050, 44100, 0, 0, 12000, 24000, 48000, 0, 0}
+var dtsBitRates = []int{32000, 56000, 64000, 96000, 112000, 128000, 192000, 224000, 256000, 320000, 384000, 448000,
+	512000, 576000, 640000, 768000, 896000, 1024000, 1152000, 1280000, 1344000, 1408000, 1411200, 1472000,
+	1536000, 0, 0, 0, 0, 0, 0, 0}
+var dtsBitDepths = []int{16, 16, 20, 20, 0, 24, 24, 0}
+var dtsExtSSSampleRates = []int{8000, 16000, 32000, 64000, 128000, 22050, 44100, 88200, 176400, 352800,
+	12000, 24000, 48000, 96000, 192000, 384000}
+
+const (
+	dtsCoreSync  = 0x7ffe8001
+	dtsExtSSSync = 0x64582025
+	dtsXLLSync   = 0x41a29547
+	dtsXBRSync   = 0x655e315e
+	dtsX96Sync   = 0x1d95f262
+	dtsLBRSync   = 0x0a801921
+	// MediaInfo reports DTS:X when this sync word follows the lossless extension
+	dtsXSync = 0x02000850
+)
+
+func parseDTSCore(rawData []byte) (*AudioStreamInfo, int) {
+	if len(rawData) < 16 || binary.BigEndian.Uint32(rawData[:4]) != dtsCoreSync {
+		return nil, 0
+	}
+
+	reader := newBitReader(rawData[4:])
+	reader.skipBits(1 + 5)
+	crcPresent := reader.readFlag()
+	reader.skipBits(7)
+	frameSize := reader.readInt(14) + 1
+	amode := reader.readInt(6)
+	sampleRate := dtsSampleRates[reader.readInt(4)]
+	bitRate := dtsBitRates[reader.readInt(5)]
+	reader.skipBits(1 + 1 + 1 + 1 + 1 + 3 + 1 + 1)
+	lfe := 0
+	if reader.readInt(2) != 0 {
+		lfe = 1
+	}
+	reader.skipBits(1)
+	if crcPresent {
+		reader.skipBits(16)
+	}
+	reader.skipBits(1 + 4 + 2)
+	bitDepth := dtsBitDepths[reader.readInt(3)]
+
+	if amode >= len(dtsChannels) || sampleRate == 0 {
+		return nil, 0
+	}
+	return &AudioStreamInfo{
+		Codec:            "DTS",
+		NumberOfChannels: dtsChannels[amode] + lfe,
+		NumberOfLFE:      lfe,
+		SampleRate:       sampleRate,
+		BitDepth:         bitDepth,
+		BitRate:          bitRate,
+	}, frameSize
+}
+
+func parseDTSExtSS(rawData []byte, info *AudioStreamInfo) int {
+	if len(rawData) < 16 || binary.BigEndian.Uint32(rawData[:4]) != dtsExtSSSync {
+		return 0
+	}
+
+	reader := newBitReader(rawData[4:])
+	reader.skipBits(8)
+	extSSIndex := reader.readInt(2)
+	headerSizeType := reader.readInt(1)
+	headerSizeBits := []int{8, 12}[headerSizeType]
+	frameSizeBits := []int{16, 20}[headerSizeType]
+	reader.skipBits(headerSizeBits)
+	frameSize := reader.readInt(frameSizeBits) + 1
+
+	staticFieldsPresent := reader.readFlag()
+	numberOfAssets := 1
+	if staticFieldsPresent {
+		reader.skipBits(2 + 3)
+		if reader.readFlag() {
+			reader.skipBits(36)
+		}
+		numberOfPresentations := reader.readInt(3) + 1
+		numberOfAssets = reader.readInt(3) + 1
+		activeMasks := make([]int, numberOfPresentations)
+		for i := range activeMasks {
+			activeMasks[i] = reader.readInt(extSSIndex + 1)
+		}
+		for i := range activeMasks {
+			for j := 0; j <= extSSIndex; j++ {
+				if (activeMasks[i]>>j)&1 != 0 {
+					reader.skipBits(8)
+				}
+			}
+		}
+		if reader.readFlag() {
+			reader.skipBits(2)
+			mixOutMaskBits := (reader.readInt(2) + 1) << 2
+			numberOfMixOutConfigs := reader.readInt(2) + 1
+			reader.skipBits(mixOutMaskBits * numberOfMixOutConfigs)
+		}
+	}
+	for i := 0; i < numberOfAssets; i++ {
+		reader.skipBits(frameSizeBits)
+	}
+
+	if staticFieldsPresent {
+		reader.skipBits(9 + 3)
+		if reader.readFlag() {
+			reader.skipBits(4)
+		}
+		if reader.readFlag() {
+			reader.skipBits(24)
+		}
+		if reader.readFlag() {
+			reader.skipBits((reader.readInt(10) + 1) * 8)
+		}
+		bitDepth := reader.readInt(5) + 1
+		sampleRate := dtsExtSSSampleRates[reader.readInt(4)]
+		channels := reader.readInt(8) + 1
+		lfe := 0
+		if reader.readFlag() {
+			if channels > 2 {
+				reader.skipBits(1)
+			}
+			if channels > 6 {
+				reader.skipBits(1)
+			}
+			if reader.readFlag() {
+				speakerMask := reader.readInt((reader.readInt(2) + 1) << 2)
+				channels = 0
+				for bit := 0; bit < 16; bit++ {
+					if speakerMask&(1<<bit) == 0 {
+						continue
+					}
+					switch bit {
+					case 0, 3, 4, 7, 8, 12, 14:
+						channels++
+					default:
+						channels += 2
+					}
+					if bit == 3 || bit == 12 {
+						lfe++
+					}
+				}
+			}
+		}
+		if !reader.overrun() {
+			info.NumberOfChannels = channels
+			info.NumberOfLFE = lfe
+			info.SampleRate = sampleRate
+			info.BitDepth = bitDepth
+		}
+	}
+
+	extension := rawData[:min(frameSize, len(rawData))]
+	hasSync := func(sync uint32) bool {
+		var pattern [4]byte
+		binary.BigEndian.PutUint32(pattern[:], sync)
+		return bytes.Contains(extension, pattern[:])
+	}
+	switch {
+	case hasSync(dtsXLLSync):
+		info.Codec = "DTS-HD Master Audio"
+		info.IsVariableBitRate = true
+		info.HasDTSX = hasSync(dtsXSync)
+	case hasSync(dtsXBRSync) || hasSync(dtsX96Sync):
+		info.Codec = "DTS-HD High Resolution Audio"
+	case hasSync(dtsLBRSync):
+		info.Codec = "DTS Express"
+	default:
+		info.Codec = "DTS-HD"
+	}
+	return frameSize
+}
+
+func analyzeDTS(info *AudioStreamInfo, payload []byte) bool {
+	found := false
+	for offset := 0; offset+16 <= len(payload); {
+		sync := binary.BigEndian.Uint32(payload[offset : offset+4])
+		if sync == dtsCoreSync {
+			core, frameSize := parseDTSCore(payload[offset:])
+			if core == nil {
+				offset++
+				continue
+			}
+			core.PID = info.PID
+			core.StreamCodingType = info.StreamCodingType
+			info.CoreInfo = core
+			found = true
+			offset += frameSize
+			continue
+		}
+		if sync == dtsExtSSSync {
+			if frameSize := parseDTSExtSS(payload[offset:], info); frameSize > 0 {
+				if info.CoreInfo != nil && info.NumberOfChannels == 0 {
+					info.NumberOfChannels = info.CoreInfo.NumberOfChannels
+					info.NumberOfLFE = info.CoreInfo.NumberOfLFE
+					info.SampleRate = info.CoreInfo.SampleRate
+					info.BitDepth = info.CoreInfo.BitDepth
+				}
+				return true
+			}
+		}
+		offset++
+	}
+
+	if found && info.Codec == "" && info.StreamCodingType == DTSAudio {
+		*info = AudioStreamInfo{
+			PID:              info.PID,
+			StreamCodingType: info.StreamCodingType,
+			Codec:            info.CoreInfo.Codec,
+			NumberOfChannels: info.CoreInfo.NumberOfChannels,
+			NumberOfLFE:      info.CoreInfo.NumberOfLFE,
+			SampleRate:       info.CoreInfo.SampleRate,
+			BitDepth:         info.CoreInfo.BitDepth,
+			BitRate:          info.CoreInfo.BitRate,
+		}
+		return true
+	}
+	return false
+}
+
+var lpcmChannels = []int{0, 1, 0, 2, 3, 3, 4, 4, 5, 6, 7, 8, 0, 0, 0, 0}
+var lpcmSampleRates = map[int]int{1: 48000, 4: 96000, 5: 192000}
+var lpcmBitDepths = []int{0, 16, 20, 24}
+
+func analyzeLPCM(info *AudioStreamInfo, payload []byte) bool {
+	if len(payload) < 4 {
+		return false
+	}
+
+	channelAssignment := int(payload[2] >> 4)
+	sampleRate, ok := lpcmSampleRates[int(payload[2]&0b00001111)]
+	bitDepth := lpcmBitDepths[payload[3]>>6]
+	channels := lpcmChannels[channelAssignment]
+	if !ok || bitDepth == 0 || channels == 0 {
+		return false
+	}
+
+	info.Codec = "LPCM"
+	info.NumberOfChannels = channels
+	if channelAssignment == 9 || channelAssignment == 11 {
+		info.NumberOfLFE = 1
+	}
+	info.SampleRate = sampleRate
+	info.BitDepth = bitDepth
+	// odd channel counts are padded to an even number in the stream
+	info.BitRate = (channels + channels%2) * bitDepth * sampleRate
+	return true
+}
+
+// AnalyzeAudioPayload feeds one PES payload to the analyzer and reports whether
+// the stream has been fully described.
+func AnalyzeAudioPayload(info *AudioStreamInfo, payload []byte) bool {
+	switch info.StreamCodingType {
+	case LPCMAudio:
+		return analyzeLPCM(info, payload)
+	case DolbyDigitalAudio, DolbyDigitalPlusAudioPri, DolbyDigitalPlusAudioSec:
+		return analyzeAC3(info, payload)
+	case DolbyDigitalTureHDAudio:
+		analyzeAC3(info, payload)
+		return analyzeTrueHD(info, payload) && info.CoreInfo != nil
+	case DTSAudio, DTSHDHighResolutionAudio, DTSHDMasterAudio, DTSHDAudio:
+		return analyzeDTS(info, payload)
+	}
+	info.Codec = "MPEG Audio"
+	return true
+}
+
+func isAudioStreamCodingType(streamCodingType StreamCodingType) bool {
+	switch streamCodingType {
+	case MPEG1Audio, MPEG2Audio, LPCMAudio, DolbyDigitalAudio, DTSAudio, DolbyDigitalTureHDAudio,
+		DolbyDigitalPlusAudioPri, DTSHDHighResolutionAudio, DTSHDMasterAudio, DolbyDigitalPlusAudioSec, DTSHDAudio:
+		return true
+	}
+	return false
+}
+
+// AnalyzeAudioReader demuxes at most maxPackets packets (all of them if
+// maxPackets is not positive) and analyzes every audio stream of the clip.
+func AnalyzeAudioReader(r io.Reader, maxPackets int) ([]*AudioStreamInfo, error) {
+	demuxer := NewDemuxer(r)
+	infos := map[int]*AudioStreamInfo{}
+	done := map[int]bool{}
+	var audioStreamInfosList []*AudioStreamInfo = nil
+
+	for packets := 0; maxPackets <= 0 || packets < maxPackets; packets++ {
+		_, err := demuxer.ReadPacket()
+		if err == io.EOF {
+			demuxer.Flush()
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, pesPacket := range demuxer.PendingPES() {
+			streamCodingType, _ := demuxer.StreamCodingType(pesPacket.PID)
+			if !isAudioStreamCodingType(streamCodingType) || done[pesPacket.PID] {
+				continue
+			}
+			info, ok := infos[pesPacket.PID]
+			if !ok {
+				info = &AudioStreamInfo{PID: pesPacket.PID, StreamCodingType: streamCodingType}
+				infos[pesPacket.PID] = info
+				audioStreamInfosList = append(audioStreamInfosList, info)
+			}
+			done[pesPacket.PID] = AnalyzeAudioPayload(info, pesPacket.Payload)
+		}
+
+		if err == io.EOF || (len(demuxer.PMTsList) > 0 && audioAnalysisDone(demuxer, done)) {
+			break
+		}
+	}
+
+	return audioStreamInfosList, nil
+}
+
+func audioAnalysisDone(demuxer *Demuxer, done map[int]bool) bool {
+	for pid, streamCodingType := range demuxer.esPIDs {
+		if isAudioStreamCodingType(streamCodingType) && !done[pid] {
+			return false
+		}
+	}
+	return true
+}
+
+func AnalyzeAudio(path string, maxPackets int) ([]*AudioStreamInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return AnalyzeAudioReader(file, maxPackets)
+}
+
+// AnalyzeAudioStreams analyzes the clip of every play item and pairs the
+// audio streams of its STN table with what was found in the clip.
+func (mpls *MPLS) AnalyzeAudioStreams(bdmvRoot string, maxPackets int) ([]*AudioStreamAnalysis, error) {
+	var audioStreamAnalysesList []*AudioStreamAnalysis = nil
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		infos, err := AnalyzeAudio(playItem.ClipStreamPath(bdmvRoot), maxPackets)
+		if err != nil {
+			return nil, err
+		}
+
+		streams := append(append([]*Stream(nil), playItem.STNTable.PrimaryAudioStreamsList...), playItem.STNTable.SecondaryAudioStreamsList...)
+		for _, stream := range streams {
+			analysis := &AudioStreamAnalysis{PlayItem: playItem, Stream: stream}
+			for _, info := range infos {
+				if stream.StreamEntry.StreamType != 0x02 && info.PID == stream.StreamEntry.RefToStreamPID {
+					analysis.AudioStreamInfo = info
+				}
+			}
+			audioStreamAnalysesList = append(audioStreamAnalysesList, analysis)
+		}
+	}
+	return audioStreamAnalysesList, nil
+}
@@ -0,0 +1,262 @@
+package go_mpls
+
+import (
+	"bytes"
+	"testing"
+)
+
+type bitWriter struct {
+	data     []byte
+	position int
+}
+
+func (w *bitWriter) writeBits(value uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.position%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		if (value>>i)&1 != 0 {
+			w.data[len(w.data)-1] |= 1 << (7 - w.position%8)
+		}
+		w.position++
+	}
+}
+
+func buildEAC3Frame(atmos bool) []byte {
+	w := &bitWriter{}
+	w.writeBits(0x0b77, 16)
+	w.writeBits(0, 2)
+	w.writeBits(0, 3)
+	w.writeBits(767, 11)
+	w.writeBits(0, 2)
+	w.writeBits(3, 2)
+	w.writeBits(7, 3)
+	w.writeBits(1, 1)
+	w.writeBits(16, 5)
+	w.writeBits(0, 5)
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	w.writeBits(1, 6)
+	if atmos {
+		w.writeBits(1, 8)
+	} else {
+		w.writeBits(0, 8)
+	}
+	w.writeBits(16, 8)
+	return append(w.data, make([]byte, 1536-len(w.data))...)
+}
+
+func TestAnalyzeEAC3(t *testing.T) {
+	info := &AudioStreamInfo{StreamCodingType: DolbyDigitalPlusAudioPri}
+	payload := append(buildEAC3Frame(true), buildEAC3Frame(true)...)
+	if !AnalyzeAudioPayload(info, payload) {
+		t.Fatal("analysis incomplete")
+	}
+	if info.Codec != "E-AC-3" || info.ChannelLayout() != "5.1" || info.SampleRate != 48000 || info.BitRate != 384000 || !info.HasAtmos {
+		t.Fatalf("unexpected info %#v", info)
+	}
+
+	info = &AudioStreamInfo{StreamCodingType: DolbyDigitalPlusAudioPri}
+	AnalyzeAudioPayload(info, buildEAC3Frame(false))
+	if info.HasAtmos {
+		t.Fatal("unexpected Atmos flag")
+	}
+}
+
+// buildAC3Frame returns a 448 kbps stereo AC-3 frame.
+func buildAC3Frame() []byte {
+	w := &bitWriter{}
+	w.writeBits(0x0b77, 16)
+	w.writeBits(0, 16)
+	w.writeBits(0, 2)
+	w.writeBits(0x1e, 6)
+	w.writeBits(8, 5)
+	w.writeBits(0, 3)
+	w.writeBits(2, 3)
+	w.writeBits(0, 2)
+	w.writeBits(0, 1)
+	return append(w.data, make([]byte, 1792-len(w.data))...)
+}
+
+func TestAnalyzeAC3(t *testing.T) {
+	info := &AudioStreamInfo{StreamCodingType: DolbyDigitalAudio}
+	if !AnalyzeAudioPayload(info, buildAC3Frame()) {
+		t.Fatal("analysis incomplete")
+	}
+	if info.Codec != "AC-3" || info.ChannelLayout() != "2.0" || info.BitRate != 448000 {
+		t.Fatalf("unexpected info %#v", info)
+	}
+}
+
+// buildTrueHDMajorSync returns a 7.1 TrueHD access unit header at 48 kHz
+// with a peak rate of 9 Mbps.
+func buildTrueHDMajorSync(numberOfSubstreams, substreamInfo int) []byte {
+	w := &bitWriter{}
+	w.writeBits(0xf8726fba, 32)
+	w.writeBits(0, 4)
+	w.writeBits(0, 4)
+	w.writeBits(0, 4)
+	w.writeBits(0x0f, 5)
+	w.writeBits(0, 2)
+	w.writeBits(0x1f, 13)
+	w.writeBits(0xb752, 16)
+	w.writeBits(0, 32)
+	w.writeBits(1, 1)
+	w.writeBits(3000, 15)
+	w.writeBits(uint64(numberOfSubstreams), 4)
+	w.writeBits(0, 4)
+	w.writeBits(uint64(substreamInfo), 8)
+	return append(w.data, make([]byte, 16)...)
+}
+
+func TestAnalyzeTrueHD(t *testing.T) {
+	for _, test := range []struct {
+		numberOfSubstreams, substreamInfo int
+		hasAtmos                          bool
+	}{
+		{4, 0, true},
+		{3, 0x80, true},
+		{3, 0, false},
+	} {
+		info := &AudioStreamInfo{StreamCodingType: DolbyDigitalTureHDAudio}
+		if !AnalyzeAudioPayload(info, append(buildAC3Frame(), buildTrueHDMajorSync(test.numberOfSubstreams, test.substreamInfo)...)) {
+			t.Fatal("analysis incomplete")
+		}
+		if info.Codec != "TrueHD" || info.ChannelLayout() != "7.1" || info.SampleRate != 48000 || !info.IsVariableBitRate || info.MaximumBitRate != 9000000 {
+			t.Fatalf("unexpected info %#v", info)
+		}
+		if info.CoreInfo == nil || info.CoreInfo.Codec != "AC-3" || info.CoreInfo.BitRate != 448000 {
+			t.Fatalf("unexpected core %#v", info.CoreInfo)
+		}
+		if info.HasAtmos != test.hasAtmos {
+			t.Fatalf("unexpected Atmos flag with %d substreams and info 0x%02x", test.numberOfSubstreams, test.substreamInfo)
+		}
+	}
+
+	// the AC-3 core alone does not describe the stream
+	info := &AudioStreamInfo{StreamCodingType: DolbyDigitalTureHDAudio}
+	if AnalyzeAudioPayload(info, buildAC3Frame()) {
+		t.Fatal("analysis complete without major sync")
+	}
+}
+
+// buildDTSCoreFrame returns a 5.1 DTS core frame at 48 kHz and 1536 kbps.
+func buildDTSCoreFrame() []byte {
+	w := &bitWriter{}
+	w.writeBits(dtsCoreSync, 32)
+	w.writeBits(1, 1)
+	w.writeBits(31, 5)
+	w.writeBits(0, 1)
+	w.writeBits(15, 7)
+	w.writeBits(2011, 14)
+	w.writeBits(9, 6)
+	w.writeBits(13, 4)
+	w.writeBits(24, 5)
+	w.writeBits(0, 10)
+	w.writeBits(1, 2)
+	w.writeBits(0, 1)
+	w.writeBits(0, 7)
+	w.writeBits(6, 3)
+	return append(w.data, make([]byte, 2012-len(w.data))...)
+}
+
+// buildDTSExtSS returns a 64 byte 7.1 extension substream at 48 kHz and 24
+// bits carrying the given extension sync words.
+func buildDTSExtSS(syncs ...uint64) []byte {
+	w := &bitWriter{}
+	w.writeBits(dtsExtSSSync, 32)
+	w.writeBits(0, 8)
+	w.writeBits(0, 2)
+	w.writeBits(0, 1)
+	w.writeBits(0, 8)
+	w.writeBits(63, 16)
+	w.writeBits(1, 1)
+	w.writeBits(0, 2+3+1)
+	w.writeBits(0, 3)
+	w.writeBits(0, 3)
+	w.writeBits(1, 1)
+	w.writeBits(0, 8)
+	w.writeBits(0, 1)
+	w.writeBits(0, 16)
+	w.writeBits(0, 9+3+1+1+1)
+	w.writeBits(23, 5)
+	w.writeBits(12, 4)
+	w.writeBits(7, 8)
+	w.writeBits(1, 1)
+	w.writeBits(0, 2)
+	w.writeBits(1, 1)
+	w.writeBits(3, 2)
+	w.writeBits(0b0000000001001111, 16)
+	for len(w.data) < 32 {
+		w.writeBits(0, 8)
+	}
+	for _, sync := range syncs {
+		w.writeBits(sync, 32)
+		w.writeBits(0, 32)
+	}
+	return append(w.data, make([]byte, 64-len(w.data))...)
+}
+
+func TestAnalyzeDTSHD(t *testing.T) {
+	for _, test := range []struct {
+		syncs   []uint64
+		codec   string
+		hasDTSX bool
+	}{
+		{[]uint64{dtsXLLSync, dtsXSync}, "DTS-HD Master Audio", true},
+		{[]uint64{dtsXLLSync}, "DTS-HD Master Audio", false},
+		{[]uint64{dtsXBRSync}, "DTS-HD High Resolution Audio", false},
+		{[]uint64{dtsXBRSync, dtsXSync}, "DTS-HD High Resolution Audio", false},
+		{[]uint64{dtsLBRSync}, "DTS Express", false},
+	} {
+		info := &AudioStreamInfo{StreamCodingType: DTSHDMasterAudio}
+		if !AnalyzeAudioPayload(info, append(buildDTSCoreFrame(), buildDTSExtSS(test.syncs...)...)) {
+			t.Fatal("analysis incomplete")
+		}
+		if info.Codec != test.codec || info.HasDTSX != test.hasDTSX {
+			t.Fatalf("unexpected codec %q and DTS:X flag %v for syncs %x", info.Codec, info.HasDTSX, test.syncs)
+		}
+		if info.ChannelLayout() != "7.1" || info.SampleRate != 48000 || info.BitDepth != 24 {
+			t.Fatalf("unexpected info %#v", info)
+		}
+		if info.CoreInfo == nil || info.CoreInfo.ChannelLayout() != "5.1" || info.CoreInfo.BitRate != 1536000 {
+			t.Fatalf("unexpected core %#v", info.CoreInfo)
+		}
+	}
+}
+
+func TestAnalyzeDTS(t *testing.T) {
+	info := &AudioStreamInfo{StreamCodingType: DTSAudio}
+	if !AnalyzeAudioPayload(info, buildDTSCoreFrame()) {
+		t.Fatal("analysis incomplete")
+	}
+	if info.Codec != "DTS" || info.ChannelLayout() != "5.1" || info.SampleRate != 48000 || info.BitRate != 1536000 || info.BitDepth != 24 {
+		t.Fatalf("unexpected info %#v", info)
+	}
+}
+
+func TestAnalyzeLPCM(t *testing.T) {
+	info := &AudioStreamInfo{StreamCodingType: LPCMAudio}
+	if !AnalyzeAudioPayload(info, []byte{0x03, 0xc0, 0x91, 0xc0}) {
+		t.Fatal("analysis incomplete")
+	}
+	if info.ChannelLayout() != "5.1" || info.SampleRate != 48000 || info.BitDepth != 24 || info.BitRate != 6912000 {
+		t.Fatalf("unexpected info %#v", info)
+	}
+}
+
+func TestAnalyzeAudioReader(t *testing.T) {
+	clip := buildTestClip(
+		map[int]StreamCodingType{0x1100: LPCMAudio},
+		map[int][]byte{0x1100: append([]byte{0x00, 0x04, 0x31, 0x40}, bytes.Repeat([]byte{0}, 4)...)},
+	)
+	infos, err := AnalyzeAudioReader(bytes.NewReader(clip), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].PID != 0x1100 || infos[0].ChannelLayout() != "2.0" {
+		t.Fatalf("unexpected infos %#v", infos)
+	}
+}
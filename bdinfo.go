@@ -0,0 +1,399 @@
+package go_mpls
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// BDInfoClip is a clip of a BDInfo report with the share of the packets
+// of every PID found while scanning it. Size counts the bytes played by
+// the play item, between the entry points of the primary video at IN and
+// OUT, or the whole file when the clip information has none.
+type BDInfoClip struct {
+	PlayItem       *PlayItem
+	Size           int64
+	ClipStreamInfo *ClipStreamInfo
+}
+
+// BDInfoReport gathers what the BDInfo playlist report shows. Stream
+// bitrates are estimated from the share of packets of each PID in the
+// scanned part of the clips, so they are exact only when the clips are
+// scanned whole.
+type BDInfoReport struct {
+	DiscTitle         string
+	PlayList          *MPLS
+	ClipsList         []*BDInfoClip
+	VideoAnalysesList []*VideoStreamAnalysis
+	AudioAnalysesList []*AudioStreamAnalysis
+}
+
+// BDInfoReport scans the clips of the playlist, reading at most maxPackets
+// packets of each (all of them if maxPackets is not positive).
+func (mpls *MPLS) BDInfoReport(bdmvRoot string, maxPackets int) (*BDInfoReport, error) {
+	report := &BDInfoReport{
+		DiscTitle: filepath.Base(filepath.Dir(filepath.Clean(bdmvRoot))),
+		PlayList:  mpls,
+	}
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		info, err := os.Stat(playItem.ClipStreamPath(bdmvRoot))
+		if err != nil {
+			return nil, err
+		}
+		clpi, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", playItem.ClipInformationFileName+".clpi"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		clipStreamInfo, err := ScanClip(playItem.ClipStreamPath(bdmvRoot), maxPackets)
+		if err != nil {
+			return nil, err
+		}
+		report.ClipsList = append(report.ClipsList, &BDInfoClip{PlayItem: playItem, Size: playedSize(clpi, playItem, info.Size()), ClipStreamInfo: clipStreamInfo})
+	}
+
+	var err error
+	if report.VideoAnalysesList, err = mpls.AnalyzeVideoStreams(bdmvRoot, maxPackets); err != nil {
+		return nil, err
+	}
+	if report.AudioAnalysesList, err = mpls.AnalyzeAudioStreams(bdmvRoot, maxPackets); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// playedSize returns the bytes of the source packets of the clip played by
+// playItem, or fileSize without entry points for its primary video.
+func playedSize(clpi *CLPI, playItem *PlayItem, fileSize int64) int64 {
+	if clpi == nil || playItem.STNTable == nil || len(playItem.STNTable.PrimaryVideoStreamsList) == 0 {
+		return fileSize
+	}
+	pid := playItem.STNTable.PrimaryVideoStreamsList[0].StreamEntry.RefToStreamPID
+	startSPN, endSPN, ok := clpi.sourcePacketRange(pid, playItem.INTimeTicks, playItem.OUTTimeTicks)
+	if !ok {
+		return fileSize
+	}
+	return int64(endSPN-startSPN) * M2TSPacketSize
+}
+
+// formatBDInfoTime formats 45 kHz ticks as h:mm:ss.fff.
+func formatBDInfoTime(ticks int) string {
+	milliseconds := (int64(ticks) + 22) / 45
+	return fmt.Sprintf("%d:%02d:%02d.%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+// formatThousands formats n with comma separated thousands.
+func formatThousands(n int64) string {
+	digits := fmt.Sprint(n)
+	var builder strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteRune(digit)
+	}
+	return builder.String()
+}
+
+func languageName(code string) string {
+	base, err := language.ParseBase(code)
+	if err != nil {
+		return code
+	}
+	if name := display.English.Languages().Name(base); name != "" {
+		return name
+	}
+	return code
+}
+
+func (report *BDInfoReport) size() int64 {
+	size := int64(0)
+	for _, clip := range report.ClipsList {
+		size += clip.Size
+	}
+	return size
+}
+
+// bitRate returns the total bitrate of the playlist in bits per second.
+func (report *BDInfoReport) bitRate() float64 {
+	duration := report.PlayList.DurationTicks()
+	if duration == 0 {
+		return 0
+	}
+	return float64(report.size()) * 8 * 45000 / float64(duration)
+}
+
+// streamBitRate estimates the bitrate of a PID in bits per second, weighting
+// every clip by its size.
+func (report *BDInfoReport) streamBitRate(pid int) float64 {
+	bits := 0.0
+	for _, clip := range report.ClipsList {
+		if stream := clip.ClipStreamInfo.Stream(pid); stream != nil && clip.ClipStreamInfo.NumberOfPackets > 0 {
+			bits += float64(clip.Size) * 8 * float64(stream.NumberOfPackets) / float64(clip.ClipStreamInfo.NumberOfPackets)
+		}
+	}
+	duration := report.PlayList.DurationTicks()
+	if duration == 0 {
+		return 0
+	}
+	return bits * 45000 / float64(duration)
+}
+
+func videoCodecName(codingType StreamCodingType) string {
+	switch codingType {
+	case MPEG1Video:
+		return "MPEG-1 Video"
+	case MPEG2Video:
+		return "MPEG-2 Video"
+	case MPEG4AVCVideo:
+		return "MPEG-4 AVC Video"
+	case MPEG4MVCVideo:
+		return "MPEG-4 MVC Video"
+	case SMTPEVC1Video:
+		return "VC-1 Video"
+	case HEVCVideo:
+		return "MPEG-H HEVC Video"
+	}
+	return codingType.String()
+}
+
+func audioCodecName(codingType StreamCodingType, info *AudioStreamInfo) string {
+	switch codingType {
+	case LPCMAudio:
+		return "LPCM Audio"
+	case DolbyDigitalAudio:
+		return "Dolby Digital Audio"
+	case DTSAudio:
+		return "DTS Audio"
+	case DolbyDigitalTureHDAudio:
+		if info != nil && info.HasAtmos {
+			return "Dolby TrueHD/Atmos Audio"
+		}
+		return "Dolby TrueHD Audio"
+	case DolbyDigitalPlusAudioPri, DolbyDigitalPlusAudioSec:
+		if info != nil && info.HasAtmos {
+			return "Dolby Digital Plus/Atmos Audio"
+		}
+		return "Dolby Digital Plus Audio"
+	case DTSHDHighResolutionAudio:
+		return "DTS-HD High-Res Audio"
+	case DTSHDMasterAudio:
+		if info != nil && info.HasDTSX {
+			return "DTS:X Master Audio"
+		}
+		return "DTS-HD Master Audio"
+	case DTSHDAudio:
+		return "DTS Express"
+	}
+	return codingType.String()
+}
+
+func (report *BDInfoReport) videoInfo(pid int) *VideoStreamInfo {
+	for _, analysis := range report.VideoAnalysesList {
+		if analysis.VideoStreamInfo != nil && analysis.VideoStreamInfo.PID == pid {
+			return analysis.VideoStreamInfo
+		}
+	}
+	return nil
+}
+
+func (report *BDInfoReport) audioInfo(pid int) *AudioStreamInfo {
+	for _, analysis := range report.AudioAnalysesList {
+		if analysis.AudioStreamInfo != nil && analysis.AudioStreamInfo.PID == pid {
+			return analysis.AudioStreamInfo
+		}
+	}
+	return nil
+}
+
+func frameRateName(frameRate FrameRate) string {
+	switch frameRate {
+	case FR23D98FPS:
+		return "23.976"
+	case FR24FPS:
+		return "24"
+	case FR25FPS:
+		return "25"
+	case FR29D97FPS:
+		return "29.970"
+	case FR50FPS:
+		return "50"
+	case FR59D94FPS:
+		return "59.940"
+	}
+	return ""
+}
+
+// videoDescription follows BDInfo: resolution, frame rate, aspect ratio,
+// then profile, level, bit depth and dynamic range when analyzed.
+func (report *BDInfoReport) videoDescription(stream *Stream) string {
+	partsList := []string{stream.StreamAttributes.VideoFormat.String()}
+	info := report.videoInfo(stream.StreamEntry.RefToStreamPID)
+	if frameRate := frameRateName(stream.StreamAttributes.FrameRate); frameRate != "" {
+		partsList = append(partsList, frameRate+" fps")
+	}
+	if info != nil && info.Height > 0 {
+		if float64(info.Width)/float64(info.Height) < 1.5 {
+			partsList = append(partsList, "4:3")
+		} else {
+			partsList = append(partsList, "16:9")
+		}
+	}
+	if info == nil || info.Profile == "" {
+		return strings.Join(partsList, " / ")
+	}
+	if info.StreamCodingType == HEVCVideo {
+		partsList = append(partsList, fmt.Sprintf("%s @ Level %s @ %s", info.Profile, info.Level, info.Tier))
+	} else {
+		partsList = append(partsList, fmt.Sprintf("%s Profile %s", info.Profile, info.Level))
+	}
+	if info.BitDepth > 8 {
+		partsList = append(partsList, fmt.Sprintf("%d bits", info.BitDepth))
+	}
+	switch {
+	case info.DolbyVision != nil:
+		partsList = append(partsList, "Dolby Vision")
+	case info.HasHDR10Plus:
+		partsList = append(partsList, "HDR10+")
+	case info.DynamicRangeType() == HDR10:
+		partsList = append(partsList, "HDR10")
+	}
+	if info.ColourPrimaries == primaries2020 {
+		partsList = append(partsList, "BT.2020")
+	}
+	return strings.Join(partsList, " / ")
+}
+
+func describeAudioInfo(info *AudioStreamInfo, kbps int) string {
+	partsList := []string{info.ChannelLayout(), fmt.Sprintf("%d kHz", info.SampleRate/1000), fmt.Sprintf("%d kbps", kbps)}
+	if info.BitDepth > 0 {
+		partsList = append(partsList, fmt.Sprintf("%d-bit", info.BitDepth))
+	}
+	return strings.Join(partsList, " / ")
+}
+
+func (report *BDInfoReport) audioDescription(stream *Stream, kbps int) string {
+	info := report.audioInfo(stream.StreamEntry.RefToStreamPID)
+	if info == nil || info.NumberOfChannels == 0 {
+		return fmt.Sprintf("%s / %d kbps", stream.StreamAttributes.AudioFormat, kbps)
+	}
+	description := describeAudioInfo(info, kbps)
+	if core := info.CoreInfo; core != nil && core.NumberOfChannels > 0 {
+		name := "DTS Core"
+		if core.Codec == "AC-3" {
+			name = "AC3 Embedded"
+		}
+		description += fmt.Sprintf(" (%s: %s)", name, describeAudioInfo(core, core.BitRate/1000))
+	}
+	return description
+}
+
+func (report *BDInfoReport) streams() *STNTable {
+	if len(report.PlayList.PlayList.PlayItemList) == 0 || report.PlayList.PlayList.PlayItemList[0].STNTable == nil {
+		return &STNTable{}
+	}
+	return report.PlayList.PlayList.PlayItemList[0].STNTable
+}
+
+func (report *BDInfoReport) name() string {
+	return strings.ToUpper(filepath.Base(report.PlayList.FilePath))
+}
+
+// WriteQuickSummary writes the report in the BDInfo quick summary format.
+func (report *BDInfoReport) WriteQuickSummary(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "Disc Title: %s\n", report.DiscTitle)
+	fmt.Fprintf(writer, "Playlist: %s\n", report.name())
+	fmt.Fprintf(writer, "Size: %s bytes\n", formatThousands(report.size()))
+	fmt.Fprintf(writer, "Length: %s\n", formatBDInfoTime(report.PlayList.DurationTicks()))
+	fmt.Fprintf(writer, "Total Bitrate: %.2f Mbps\n", report.bitRate()/1000000)
+
+	stn := report.streams()
+	for _, stream := range stn.PrimaryVideoStreamsList {
+		fmt.Fprintf(writer, "Video: %s / %d kbps / %s\n", videoCodecName(stream.StreamAttributes.StreamCodingType),
+			int(report.streamBitRate(stream.StreamEntry.RefToStreamPID)/1000), report.videoDescription(stream))
+	}
+	for _, stream := range stn.PrimaryAudioStreamsList {
+		info := report.audioInfo(stream.StreamEntry.RefToStreamPID)
+		fmt.Fprintf(writer, "Audio: %s / %s / %s\n", languageName(stream.StreamAttributes.LanguageCode),
+			audioCodecName(stream.StreamAttributes.StreamCodingType, info),
+			report.audioDescription(stream, int(report.streamBitRate(stream.StreamEntry.RefToStreamPID)/1000)))
+	}
+	for _, stream := range stn.PrimaryPGStreamsList {
+		fmt.Fprintf(writer, "Subtitle: %s / %.3f kbps\n", languageName(stream.StreamAttributes.LanguageCode),
+			report.streamBitRate(stream.StreamEntry.RefToStreamPID)/1000)
+	}
+	return writer.Flush()
+}
+
+// WriteFull writes the report in the BDInfo full playlist report format,
+// followed by the quick summary. The chapter table lists times only, as
+// the per-chapter video rates of BDInfo need frame level analysis.
+func (report *BDInfoReport) WriteFull(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "DISC INFO:\n\nDisc Title:     %s\n\n", report.DiscTitle)
+	fmt.Fprintf(writer, "PLAYLIST REPORT:\n\n")
+	fmt.Fprintf(writer, "%-24s%s\n", "Name:", report.name())
+	fmt.Fprintf(writer, "%-24s%s (h:m:s.ms)\n", "Length:", formatBDInfoTime(report.PlayList.DurationTicks()))
+	fmt.Fprintf(writer, "%-24s%s bytes\n", "Size:", formatThousands(report.size()))
+	fmt.Fprintf(writer, "%-24s%.2f Mbps\n", "Total Bitrate:", report.bitRate()/1000000)
+
+	stn := report.streams()
+	fmt.Fprintf(writer, "\nVIDEO:\n\n%-24s%-20s%-16s\n%-24s%-20s%-16s\n", "Codec", "Bitrate", "Description", "-----", "-------", "-----------")
+	for _, stream := range stn.PrimaryVideoStreamsList {
+		fmt.Fprintf(writer, "%-24s%-20s%s\n", videoCodecName(stream.StreamAttributes.StreamCodingType),
+			fmt.Sprintf("%d kbps", int(report.streamBitRate(stream.StreamEntry.RefToStreamPID)/1000)), report.videoDescription(stream))
+	}
+
+	fmt.Fprintf(writer, "\nAUDIO:\n\n%-32s%-16s%-16s%-16s\n%-32s%-16s%-16s%-16s\n", "Codec", "Language", "Bitrate", "Description",
+		"-----", "--------", "-------", "-----------")
+	for _, stream := range stn.PrimaryAudioStreamsList {
+		kbps := int(report.streamBitRate(stream.StreamEntry.RefToStreamPID) / 1000)
+		fmt.Fprintf(writer, "%-32s%-16s%-16s%s\n", audioCodecName(stream.StreamAttributes.StreamCodingType, report.audioInfo(stream.StreamEntry.RefToStreamPID)),
+			languageName(stream.StreamAttributes.LanguageCode), fmt.Sprintf("%d kbps", kbps), report.audioDescription(stream, kbps))
+	}
+
+	fmt.Fprintf(writer, "\nSUBTITLES:\n\n%-32s%-16s%-16s%-16s\n%-32s%-16s%-16s%-16s\n", "Codec", "Language", "Bitrate", "Description",
+		"-----", "--------", "-------", "-----------")
+	for _, stream := range stn.PrimaryPGStreamsList {
+		fmt.Fprintf(writer, "%-32s%-16s%s\n", "Presentation Graphics", languageName(stream.StreamAttributes.LanguageCode),
+			fmt.Sprintf("%.3f kbps", report.streamBitRate(stream.StreamEntry.RefToStreamPID)/1000))
+	}
+
+	fmt.Fprintf(writer, "\nFILES:\n\n%-16s%-16s%-16s%-16s%-16s\n%-16s%-16s%-16s%-16s%-16s\n", "Name", "Time In", "Length", "Size", "Total Bitrate",
+		"----", "-------", "------", "----", "-------------")
+	timeIn := 0
+	for _, clip := range report.ClipsList {
+		duration := clip.PlayItem.DurationTicks()
+		kbps := int64(0)
+		if duration > 0 {
+			kbps = clip.Size * 8 * 45 / int64(duration)
+		}
+		fmt.Fprintf(writer, "%-16s%-16s%-16s%-16s%s\n", clip.PlayItem.ClipInformationFileName+".M2TS", formatBDInfoTime(timeIn),
+			formatBDInfoTime(duration), formatThousands(clip.Size), formatThousands(kbps))
+		timeIn += duration
+	}
+
+	fmt.Fprintf(writer, "\nCHAPTERS:\n\n%-16s%-16s%-16s\n%-16s%-16s%-16s\n", "Number", "Time In", "Length", "------", "-------", "------")
+	chapters := report.PlayList.Chapters()
+	for k, chapter := range chapters {
+		end := report.PlayList.DurationTicks()
+		if k+1 < len(chapters) {
+			end = chapters[k+1].TimeTicks
+		}
+		fmt.Fprintf(writer, "%-16d%-16s%s\n", chapter.Number, formatBDInfoTime(chapter.TimeTicks), formatBDInfoTime(end-chapter.TimeTicks))
+	}
+
+	writer.WriteString("\nQUICK SUMMARY:\n\n")
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return report.WriteQuickSummary(w)
+}
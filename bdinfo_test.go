@@ -0,0 +1,113 @@
+package go_mpls
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBDInfoReport(t *testing.T) {
+	video := newTestStream(0x1011, MPEG4AVCVideo, "")
+	video.StreamAttributes.VideoFormat = VF1080P
+	video.StreamAttributes.FrameRate = FR23D98FPS
+	stnTable := &STNTable{
+		PrimaryVideoStreamsList: []*Stream{video},
+		PrimaryAudioStreamsList: []*Stream{newTestStream(0x1100, DTSHDMasterAudio, "eng")},
+		PrimaryPGStreamsList:    []*Stream{newTestStream(0x1200, PresentationGraphics, "fra")},
+	}
+	playItem := &PlayItem{ClipInformationFileName: "00001", OUTTimeTicks: 45000 * 100, STNTable: stnTable}
+	mpls := &MPLS{
+		FilePath: "/disc/BDMV/PLAYLIST/00800.mpls",
+		PlayList: &PlayList{PlayItemList: []*PlayItem{playItem}},
+		PlayListMark: &PlayListMark{PlayListMarksList: []*PlayListMarkItem{
+			{MarkType: EntryMark, MarkTimeTicks: 0},
+			{MarkType: EntryMark, MarkTimeTicks: 45000 * 60},
+		}},
+	}
+	report := &BDInfoReport{
+		DiscTitle: "disc",
+		PlayList:  mpls,
+		ClipsList: []*BDInfoClip{{PlayItem: playItem, Size: 250000000, ClipStreamInfo: &ClipStreamInfo{
+			NumberOfPackets: 1000,
+			StreamsList: []*ClipStream{
+				{PID: 0x1011, NumberOfPackets: 800},
+				{PID: 0x1100, NumberOfPackets: 150},
+				{PID: 0x1200, NumberOfPackets: 10},
+			},
+		}}},
+		VideoAnalysesList: []*VideoStreamAnalysis{{VideoStreamInfo: &VideoStreamInfo{
+			PID: 0x1011, StreamCodingType: MPEG4AVCVideo, Profile: "High", Level: "4.1", Width: 1920, Height: 1080, BitDepth: 8,
+		}}},
+		AudioAnalysesList: []*AudioStreamAnalysis{{AudioStreamInfo: &AudioStreamInfo{
+			PID: 0x1100, NumberOfChannels: 6, NumberOfLFE: 1, SampleRate: 48000, BitDepth: 24,
+			CoreInfo: &AudioStreamInfo{Codec: "DTS", NumberOfChannels: 6, NumberOfLFE: 1, SampleRate: 48000, BitDepth: 24, BitRate: 1509000},
+		}}},
+	}
+
+	var quick bytes.Buffer
+	if err := report.WriteQuickSummary(&quick); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Disc Title: disc
+Playlist: 00800.MPLS
+Size: 250,000,000 bytes
+Length: 0:01:40.000
+Total Bitrate: 20.00 Mbps
+Video: MPEG-4 AVC Video / 16000 kbps / 1080p / 23.976 fps / 16:9 / High Profile 4.1
+Audio: English / DTS-HD Master Audio / 5.1 / 48 kHz / 3000 kbps / 24-bit (DTS Core: 5.1 / 48 kHz / 1509 kbps / 24-bit)
+Subtitle: French / 200.000 kbps
+`
+	if quick.String() != expected {
+		t.Fatalf("unexpected quick summary:\n%s", quick.String())
+	}
+
+	var full bytes.Buffer
+	if err := report.WriteFull(&full); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Name:                   00800.MPLS",
+		"00001.M2TS      0:00:00.000     0:01:40.000     250,000,000     20,000",
+		"1               0:00:00.000     0:01:00.000",
+		"2               0:01:00.000     0:00:40.000",
+		"Presentation Graphics           French          200.000 kbps",
+	} {
+		if !strings.Contains(full.String(), line) {
+			t.Fatalf("missing %q in report:\n%s", line, full.String())
+		}
+	}
+	if !strings.HasSuffix(full.String(), "QUICK SUMMARY:\n\n"+expected) {
+		t.Fatalf("unexpected report ending:\n%s", full.String())
+	}
+}
+
+func TestBDInfoPlayedSize(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	writeCLPI(t, bdmvRoot, "00001", buildCLPI(0, 900000))
+	clpi, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", "00001.clpi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stnTable := &STNTable{PrimaryVideoStreamsList: []*Stream{newTestStream(0x1011, HEVCVideo, "")}}
+
+	// the entry points of the clip are at 524288 and 526848 ticks, packets
+	// 0 and 64 of 1000
+	for _, test := range []struct {
+		inTimeTicks, outTimeTicks int
+		size                      int64
+	}{
+		{0, 900000, 1000 * M2TSPacketSize},
+		{524288, 526848, 64 * M2TSPacketSize},
+		{524288, 526000, 64 * M2TSPacketSize},
+		{600000, 900000, 936 * M2TSPacketSize},
+	} {
+		playItem := &PlayItem{INTimeTicks: test.inTimeTicks, OUTTimeTicks: test.outTimeTicks, STNTable: stnTable}
+		if size := playedSize(clpi, playItem, 5000000); size != test.size {
+			t.Fatalf("unexpected size %d from %d to %d", size, test.inTimeTicks, test.outTimeTicks)
+		}
+	}
+	if size := playedSize(nil, &PlayItem{STNTable: stnTable}, 5000000); size != 5000000 {
+		t.Fatalf("unexpected size %d without clip information", size)
+	}
+}
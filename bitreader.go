@@ -0,0 +1,66 @@
+package go_mpls
+
+type bitReader struct {
+	data     []byte
+	position int
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+func (r *bitReader) remaining() int {
+	return len(r.data)*8 - r.position
+}
+
+func (r *bitReader) readBits(n int) uint64 {
+	var value uint64
+	for i := 0; i < n; i++ {
+		value <<= 1
+		if r.position < len(r.data)*8 {
+			value |= uint64((r.data[r.position/8] >> (7 - r.position%8)) & 1)
+		}
+		r.position++
+	}
+	return value
+}
+
+func (r *bitReader) readInt(n int) int {
+	return int(r.readBits(n))
+}
+
+func (r *bitReader) readFlag() bool {
+	return r.readBits(1) != 0
+}
+
+func (r *bitReader) skipBits(n int) {
+	r.position += n
+}
+
+func (r *bitReader) byteAlign() {
+	r.position = (r.position + 7) / 8 * 8
+}
+
+// readUE reads an unsigned Exp-Golomb code as used by H.264 and HEVC.
+func (r *bitReader) readUE() int {
+	leadingZeros := 0
+	for !r.readFlag() {
+		leadingZeros++
+		if leadingZeros > 31 || r.remaining() <= 0 {
+			return 0
+		}
+	}
+	return (1 << leadingZeros) - 1 + r.readInt(leadingZeros)
+}
+
+func (r *bitReader) readSE() int {
+	value := r.readUE()
+	if value%2 == 1 {
+		return (value + 1) / 2
+	}
+	return -value / 2
+}
+
+func (r *bitReader) overrun() bool {
+	return r.position > len(r.data)*8
+}
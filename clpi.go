@@ -0,0 +1,402 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var errTruncatedCLPI = errors.New("clip information is truncated")
+
+type ClipInfo struct {
+	Length                int
+	ClipStreamType        int
+	ApplicationType       int
+	IsATCDelta            bool
+	TSRecordingRate       int
+	NumberOfSourcePackets int
+}
+
+// STCSequence times are 45 kHz ticks, like play item IN and OUT times.
+type STCSequence struct {
+	STCID                 int
+	PCRPID                int
+	SPNSTCStart           int
+	PresentationStartTime int
+	PresentationEndTime   int
+}
+
+type ATCSequence struct {
+	SPNATCStart          int
+	NumberOfSTCSequences int
+	OffsetSTCID          int
+	STCSequencesList     []*STCSequence
+}
+
+type SequenceInfo struct {
+	Length               int
+	NumberOfATCSequences int
+	ATCSequencesList     []*ATCSequence
+}
+
+type ProgramStream struct {
+	PID              int
+	StreamAttributes *StreamAttributes
+}
+
+type ProgramSequence struct {
+	SPNProgramSequenceStart int
+	ProgramMapPID           int
+	NumberOfStreams         int
+	NumberOfGroups          int
+	StreamsList             []*ProgramStream
+}
+
+type ProgramInfo struct {
+	Length               int
+	NumberOfPrograms     int
+	ProgramSequencesList []*ProgramSequence
+}
+
+// EPMapEntry is an entry point of the clip. PTS is in 90 kHz ticks and SPN
+// counts 192 byte source packets from the start of the clip.
+type EPMapEntry struct {
+	PTS                int64
+	SPN                int
+	IsAngleChangePoint bool
+	IEndPositionOffset int
+}
+
+type EPMapStream struct {
+	PID                     int
+	EPStreamType            int
+	NumberOfEPCoarseEntries int
+	NumberOfEPFineEntries   int
+	EntriesList             []*EPMapEntry
+}
+
+type CPI struct {
+	Length           int
+	CPIType          int
+	EPMapStreamsList []*EPMapStream
+}
+
+type CLPI struct {
+	FilePath                  string
+	RawData                   []byte
+	VersionNumber             int
+	SequenceInfoStartAddress  int
+	ProgramInfoStartAddress   int
+	CPIStartAddress           int
+	ClipMarkStartAddress      int
+	ExtensionDataStartAddress int
+	ClipInfo                  *ClipInfo
+	SequenceInfo              *SequenceInfo
+	ProgramInfo               *ProgramInfo
+	CPI                       *CPI
+}
+
+func parseClipInfo(rawData []byte) (*ClipInfo, error) {
+	if len(rawData) < 20 {
+		return nil, errTruncatedCLPI
+	}
+	return &ClipInfo{
+		Length:                int(binary.BigEndian.Uint32(rawData[0:4])),
+		ClipStreamType:        int(rawData[6]),
+		ApplicationType:       int(rawData[7]),
+		IsATCDelta:            (rawData[11] & 1) != 0,
+		TSRecordingRate:       int(binary.BigEndian.Uint32(rawData[12:16])),
+		NumberOfSourcePackets: int(binary.BigEndian.Uint32(rawData[16:20])),
+	}, nil
+}
+
+func parseSequenceInfo(rawData []byte) (*SequenceInfo, error) {
+	if len(rawData) < 6 {
+		return nil, errTruncatedCLPI
+	}
+	sequenceInfo := &SequenceInfo{
+		Length:               int(binary.BigEndian.Uint32(rawData[0:4])),
+		NumberOfATCSequences: int(rawData[5]),
+	}
+
+	offset := 6
+	for i := 0; i < sequenceInfo.NumberOfATCSequences; i++ {
+		if offset+6 > len(rawData) {
+			return nil, errTruncatedCLPI
+		}
+		atcSequence := &ATCSequence{
+			SPNATCStart:          int(binary.BigEndian.Uint32(rawData[offset : offset+4])),
+			NumberOfSTCSequences: int(rawData[offset+4]),
+			OffsetSTCID:          int(rawData[offset+5]),
+		}
+		offset += 6
+		for j := 0; j < atcSequence.NumberOfSTCSequences; j++ {
+			if offset+14 > len(rawData) {
+				return nil, errTruncatedCLPI
+			}
+			atcSequence.STCSequencesList = append(atcSequence.STCSequencesList, &STCSequence{
+				STCID:                 atcSequence.OffsetSTCID + j,
+				PCRPID:                int(binary.BigEndian.Uint16(rawData[offset : offset+2])),
+				SPNSTCStart:           int(binary.BigEndian.Uint32(rawData[offset+2 : offset+6])),
+				PresentationStartTime: int(binary.BigEndian.Uint32(rawData[offset+6 : offset+10])),
+				PresentationEndTime:   int(binary.BigEndian.Uint32(rawData[offset+10 : offset+14])),
+			})
+			offset += 14
+		}
+		sequenceInfo.ATCSequencesList = append(sequenceInfo.ATCSequencesList, atcSequence)
+	}
+	return sequenceInfo, nil
+}
+
+func parseProgramInfo(rawData []byte) (*ProgramInfo, error) {
+	if len(rawData) < 6 {
+		return nil, errTruncatedCLPI
+	}
+	programInfo := &ProgramInfo{
+		Length:           int(binary.BigEndian.Uint32(rawData[0:4])),
+		NumberOfPrograms: int(rawData[5]),
+	}
+
+	offset := 6
+	for i := 0; i < programInfo.NumberOfPrograms; i++ {
+		if offset+8 > len(rawData) {
+			return nil, errTruncatedCLPI
+		}
+		programSequence := &ProgramSequence{
+			SPNProgramSequenceStart: int(binary.BigEndian.Uint32(rawData[offset : offset+4])),
+			ProgramMapPID:           int(binary.BigEndian.Uint16(rawData[offset+4 : offset+6])),
+			NumberOfStreams:         int(rawData[offset+6]),
+			NumberOfGroups:          int(rawData[offset+7]),
+		}
+		offset += 8
+		for j := 0; j < programSequence.NumberOfStreams; j++ {
+			if offset+3 > len(rawData) {
+				return nil, errTruncatedCLPI
+			}
+			pid := int(binary.BigEndian.Uint16(rawData[offset : offset+2]))
+			length := int(rawData[offset+2])
+			if length < 5 || offset+3+length > len(rawData) {
+				return nil, errTruncatedCLPI
+			}
+			streamAttributes := &StreamAttributes{}
+			parseStreamAttributes(&byteCursor{data: rawData[offset+2 : offset+3+length]}, streamAttributes)
+			programSequence.StreamsList = append(programSequence.StreamsList, &ProgramStream{
+				PID:              pid,
+				StreamAttributes: streamAttributes,
+			})
+			offset += 3 + length
+		}
+		programInfo.ProgramSequencesList = append(programInfo.ProgramSequencesList, programSequence)
+	}
+	return programInfo, nil
+}
+
+// parseEPMapStream joins the coarse and fine entries of one stream. rawData
+// starts at the stream's EP map data.
+func parseEPMapStream(rawData []byte, epMapStream *EPMapStream) error {
+	coarseSize := 8 * epMapStream.NumberOfEPCoarseEntries
+	if len(rawData) < 4+coarseSize {
+		return errTruncatedCLPI
+	}
+	if epMapStream.NumberOfEPFineEntries > 0 && epMapStream.NumberOfEPCoarseEntries == 0 {
+		return errTruncatedCLPI
+	}
+	fineStart := int(binary.BigEndian.Uint32(rawData[0:4]))
+	if fineStart+4*epMapStream.NumberOfEPFineEntries > len(rawData) {
+		return errTruncatedCLPI
+	}
+
+	coarse := 0
+	for i := 0; i < epMapStream.NumberOfEPFineEntries; i++ {
+		for coarse+1 < epMapStream.NumberOfEPCoarseEntries &&
+			int(binary.BigEndian.Uint32(rawData[4+8*(coarse+1):8+8*(coarse+1)])>>14) <= i {
+			coarse++
+		}
+		coarseEntry := rawData[4+8*coarse : 12+8*coarse]
+		ptsCoarse := int64(binary.BigEndian.Uint32(coarseEntry[0:4]) & 0x3fff)
+		spnCoarse := int(binary.BigEndian.Uint32(coarseEntry[4:8]))
+
+		fineEntry := binary.BigEndian.Uint32(rawData[fineStart+4*i : fineStart+4*i+4])
+		epMapStream.EntriesList = append(epMapStream.EntriesList, &EPMapEntry{
+			PTS:                (ptsCoarse&^1)<<19 | int64((fineEntry>>17)&0x7ff)<<9,
+			SPN:                spnCoarse&^0x1ffff | int(fineEntry&0x1ffff),
+			IsAngleChangePoint: fineEntry&(1<<31) != 0,
+			IEndPositionOffset: int((fineEntry >> 28) & 0x07),
+		})
+	}
+	return nil
+}
+
+func parseCPI(rawData []byte) (*CPI, error) {
+	if len(rawData) < 4 {
+		return nil, errTruncatedCLPI
+	}
+	cpi := &CPI{Length: int(binary.BigEndian.Uint32(rawData[0:4]))}
+	if cpi.Length == 0 {
+		return cpi, nil
+	}
+	if len(rawData) < 8 {
+		return nil, errTruncatedCLPI
+	}
+	cpi.CPIType = int(rawData[5] & 0x0f)
+
+	epMap := rawData[6:]
+	numberOfStreams := int(epMap[1])
+	for i := 0; i < numberOfStreams; i++ {
+		if len(epMap) < 14+12*i {
+			return nil, errTruncatedCLPI
+		}
+		entry := epMap[2+12*i:]
+		epMapStream := &EPMapStream{
+			PID:                     int(binary.BigEndian.Uint16(entry[0:2])),
+			EPStreamType:            int((entry[3] >> 2) & 0x0f),
+			NumberOfEPCoarseEntries: int(entry[3]&0x03)<<14 | int(entry[4])<<6 | int(entry[5]>>2),
+			NumberOfEPFineEntries:   int(entry[5]&0x03)<<16 | int(entry[6])<<8 | int(entry[7]),
+		}
+		streamStart := int(binary.BigEndian.Uint32(entry[8:12]))
+		if streamStart > len(epMap) {
+			return nil, errTruncatedCLPI
+		}
+		if err := parseEPMapStream(epMap[streamStart:], epMapStream); err != nil {
+			return nil, err
+		}
+		cpi.EPMapStreamsList = append(cpi.EPMapStreamsList, epMapStream)
+	}
+	return cpi, nil
+}
+
+func ParseCLPI(path string) (*CLPI, error) {
+	rawData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(rawData) < 40 || !bytes.Equal(rawData[:4], []byte("HDMV")) {
+		return nil, errors.New("invalid file")
+	}
+	versionNumber, err := strconv.Atoi(string(rawData[0x04:0x08]))
+	if err != nil {
+		return nil, err
+	}
+
+	clpi := &CLPI{
+		FilePath:                  path,
+		RawData:                   rawData,
+		VersionNumber:             versionNumber,
+		SequenceInfoStartAddress:  int(binary.BigEndian.Uint32(rawData[0x08:0x0c])),
+		ProgramInfoStartAddress:   int(binary.BigEndian.Uint32(rawData[0x0c:0x10])),
+		CPIStartAddress:           int(binary.BigEndian.Uint32(rawData[0x10:0x14])),
+		ClipMarkStartAddress:      int(binary.BigEndian.Uint32(rawData[0x14:0x18])),
+		ExtensionDataStartAddress: int(binary.BigEndian.Uint32(rawData[0x18:0x1c])),
+	}
+	for _, address := range []int{clpi.SequenceInfoStartAddress, clpi.ProgramInfoStartAddress, clpi.CPIStartAddress} {
+		if address > len(rawData) {
+			return nil, errTruncatedCLPI
+		}
+	}
+
+	if clpi.ClipInfo, err = parseClipInfo(rawData[0x28:]); err != nil {
+		return nil, err
+	}
+	if clpi.SequenceInfo, err = parseSequenceInfo(rawData[clpi.SequenceInfoStartAddress:]); err != nil {
+		return nil, err
+	}
+	if clpi.ProgramInfo, err = parseProgramInfo(rawData[clpi.ProgramInfoStartAddress:]); err != nil {
+		return nil, err
+	}
+	if clpi.CPI, err = parseCPI(rawData[clpi.CPIStartAddress:]); err != nil {
+		return nil, err
+	}
+	return clpi, nil
+}
+
+func (clpi *CLPI) STCSequence(stcID int) *STCSequence {
+	for _, atcSequence := range clpi.SequenceInfo.ATCSequencesList {
+		for _, stcSequence := range atcSequence.STCSequencesList {
+			if stcSequence.STCID == stcID {
+				return stcSequence
+			}
+		}
+	}
+	return nil
+}
+
+// EPMap returns the entry points of a PID, or nil when it has none.
+func (clpi *CLPI) EPMap(pid int) *EPMapStream {
+	if clpi.CPI == nil {
+		return nil
+	}
+	for _, epMapStream := range clpi.CPI.EPMapStreamsList {
+		if epMapStream.PID == pid {
+			return epMapStream
+		}
+	}
+	return nil
+}
+
+// sourcePacketRange returns the source packets played from inTicks to
+// outTicks: from the entry point of pid at or before inTicks up to the
+// first one at or after outTicks, or the end of the clip. ok is false when
+// pid has no entry points.
+func (clpi *CLPI) sourcePacketRange(pid, inTicks, outTicks int) (startSPN, endSPN int, ok bool) {
+	epMap := clpi.EPMap(pid)
+	if epMap == nil || len(epMap.EntriesList) == 0 || clpi.ClipInfo == nil {
+		return 0, 0, false
+	}
+	inPTS, outPTS := int64(inTicks)*2, int64(outTicks)*2
+	startSPN, endSPN = epMap.EntriesList[0].SPN, clpi.ClipInfo.NumberOfSourcePackets
+	for _, entry := range epMap.EntriesList {
+		if entry.PTS <= inPTS {
+			startSPN = entry.SPN
+		}
+		if entry.PTS >= outPTS {
+			endSPN = entry.SPN
+			break
+		}
+	}
+	return startSPN, max(startSPN, endSPN), true
+}
+
+// ClipNames lists every clip the playlist refers to through its play items,
+// angles and sub play items, each once and in order of appearance.
+func (mpls *MPLS) ClipNames() []string {
+	var namesList []string = nil
+	seen := map[string]bool{}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			namesList = append(namesList, name)
+		}
+	}
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		add(playItem.ClipInformationFileName)
+		for _, angle := range playItem.AnglesList {
+			add(angle.ClipInformationFileName)
+		}
+	}
+	for _, subPath := range mpls.PlayList.SubPathsList {
+		for _, subPlayItem := range subPath.SubPlayItemsList {
+			add(subPlayItem.ClipInformationFileName)
+			for _, multiClipEntry := range subPlayItem.MultiClipEntriesList {
+				add(multiClipEntry.ClipInformationFileName)
+			}
+		}
+	}
+	return namesList
+}
+
+// LoadClipInformation parses the CLPI file of every clip of the playlist,
+// keyed by clip name.
+func (mpls *MPLS) LoadClipInformation(bdmvRoot string) (map[string]*CLPI, error) {
+	clips := map[string]*CLPI{}
+	for _, name := range mpls.ClipNames() {
+		clpi, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", name+".clpi"))
+		if err != nil {
+			return nil, err
+		}
+		clips[name] = clpi
+	}
+	return clips, nil
+}
@@ -0,0 +1,147 @@
+package go_mpls
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildCLPI builds a clip with one STC sequence per [start, end) pair, an
+// HEVC and an AC-3 stream and two entry points for the video PID.
+func buildCLPI(stcTimes ...int) []byte {
+	clipInfo := make([]byte, 20)
+	binary.BigEndian.PutUint32(clipInfo[0:4], 16)
+	clipInfo[6] = 1
+	clipInfo[7] = 1
+	binary.BigEndian.PutUint32(clipInfo[12:16], 6000000)
+	binary.BigEndian.PutUint32(clipInfo[16:20], 1000)
+
+	sequenceInfo := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, byte(len(stcTimes) / 2), 0}
+	for i := 0; i+1 < len(stcTimes); i += 2 {
+		stc := make([]byte, 14)
+		binary.BigEndian.PutUint16(stc[0:2], 0x1001)
+		binary.BigEndian.PutUint32(stc[6:10], uint32(stcTimes[i]))
+		binary.BigEndian.PutUint32(stc[10:14], uint32(stcTimes[i+1]))
+		sequenceInfo = append(sequenceInfo, stc...)
+	}
+	binary.BigEndian.PutUint32(sequenceInfo[0:4], uint32(len(sequenceInfo)-4))
+
+	programInfo := []byte{0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0x01, 0x00, 2, 0}
+	programInfo = append(programInfo, 0x10, 0x11, 5, byte(HEVCVideo), 0x81, 0x12, 0x00, 0x00)
+	programInfo = append(programInfo, 0x11, 0x00, 5, byte(DolbyDigitalAudio), 0x61, 'e', 'n', 'g')
+	binary.BigEndian.PutUint32(programInfo[0:4], uint32(len(programInfo)-4))
+
+	epMap := []byte{0, 1, 0x10, 0x11, 0x00, 0x04, 0x00, 0x04, 0x00, 0x02, 0, 0, 0, 14}
+	streamData := []byte{0, 0, 0, 12}
+	streamData = append(streamData, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00)
+	streamData = append(streamData, 0x00, 0x00, 0x00, 0x00, 0x80, 0x14, 0x00, 0x40)
+	epMap = append(epMap, streamData...)
+	cpi := append([]byte{0, 0, 0, 0, 0x00, 0x01}, epMap...)
+	binary.BigEndian.PutUint32(cpi[0:4], uint32(len(cpi)-4))
+
+	rawData := make([]byte, 40)
+	copy(rawData, "HDMV0200")
+	rawData = append(rawData, clipInfo...)
+	binary.BigEndian.PutUint32(rawData[8:12], uint32(len(rawData)))
+	rawData = append(rawData, sequenceInfo...)
+	binary.BigEndian.PutUint32(rawData[12:16], uint32(len(rawData)))
+	rawData = append(rawData, programInfo...)
+	binary.BigEndian.PutUint32(rawData[16:20], uint32(len(rawData)))
+	return append(rawData, cpi...)
+}
+
+func writeCLPI(t *testing.T, bdmvRoot, name string, rawData []byte) {
+	if err := os.MkdirAll(filepath.Join(bdmvRoot, "CLIPINF"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bdmvRoot, "CLIPINF", name+".clpi"), rawData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseCLPI(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	writeCLPI(t, bdmvRoot, "00001", buildCLPI(0, 90000))
+
+	clpi, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", "00001.clpi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clpi.VersionNumber != 200 || clpi.ClipInfo.NumberOfSourcePackets != 1000 || clpi.ClipInfo.TSRecordingRate != 6000000 {
+		t.Fatalf("unexpected clip info %#v", clpi.ClipInfo)
+	}
+	if stc := clpi.STCSequence(0); stc == nil || stc.PCRPID != 0x1001 || stc.PresentationEndTime != 90000 {
+		t.Fatalf("unexpected STC sequence %#v", stc)
+	}
+	streams := clpi.ProgramInfo.ProgramSequencesList[0].StreamsList
+	if len(streams) != 2 || streams[0].StreamAttributes.VideoFormat != VF2160P || streams[1].StreamAttributes.LanguageCode != "eng" {
+		t.Fatalf("unexpected program info %#v", streams)
+	}
+
+	epMap := clpi.EPMap(0x1011)
+	if epMap == nil || len(epMap.EntriesList) != 2 {
+		t.Fatalf("unexpected EP map %#v", epMap)
+	}
+	if entry := epMap.EntriesList[1]; entry.PTS != 1<<20|10<<9 || entry.SPN != 64 || !entry.IsAngleChangePoint {
+		t.Fatalf("unexpected entry point %#v", entry)
+	}
+}
+
+func TestParseCLPITruncated(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	rawData := buildCLPI(0, 90000)
+	for length := 0; length < len(rawData); length++ {
+		writeCLPI(t, bdmvRoot, "00001", rawData[:length])
+		if _, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", "00001.clpi")); err == nil {
+			t.Fatalf("no error for %d of %d bytes", length, len(rawData))
+		}
+	}
+
+	for _, epMapStream := range []*EPMapStream{
+		{NumberOfEPCoarseEntries: 0, NumberOfEPFineEntries: 1},
+		{NumberOfEPCoarseEntries: 1, NumberOfEPFineEntries: 1},
+		{NumberOfEPCoarseEntries: 0, NumberOfEPFineEntries: 3},
+	} {
+		if err := parseEPMapStream([]byte{0, 0, 0, 4, 0, 0, 0, 0}, epMapStream); err != errTruncatedCLPI {
+			t.Fatalf("unexpected error %v for %#v", err, epMapStream)
+		}
+	}
+}
+
+func TestValidateConnections(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	writeCLPI(t, bdmvRoot, "00001", buildCLPI(0, 90000))
+	writeCLPI(t, bdmvRoot, "00002", buildCLPI(90000, 180000))
+	writeCLPI(t, bdmvRoot, "00003", buildCLPI(45000, 180000))
+
+	stnTable := &STNTable{PrimaryVideoStreamsList: []*Stream{{
+		StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: 0x1011},
+		StreamAttributes: &StreamAttributes{StreamCodingType: HEVCVideo},
+	}}}
+	mpls := &MPLS{PlayList: &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00001", ConnectionCondition: 1, INTimeTicks: 0, OUTTimeTicks: 90000, STNTable: stnTable},
+		{ClipInformationFileName: "00002", ConnectionCondition: 6, INTimeTicks: 90000, OUTTimeTicks: 180000, STNTable: stnTable},
+		{ClipInformationFileName: "00003", ConnectionCondition: 6, INTimeTicks: 45000, OUTTimeTicks: 180000, STNTable: &STNTable{}},
+		{ClipInformationFileName: "00003", ConnectionCondition: 5, RefToSTCID: 1, INTimeTicks: 45000, OUTTimeTicks: 90000, STNTable: &STNTable{}},
+	}}}
+
+	violations, err := mpls.ValidateDiscConnections(bdmvRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reasonsList []string = nil
+	for _, violation := range violations {
+		if violation.PlayItemID < 2 {
+			t.Fatalf("unexpected violation %#v", violation)
+		}
+		reasonsList = append(reasonsList, violation.Reason)
+	}
+	reasons := strings.Join(reasonsList, "\n")
+	if len(violations) != 3 || !strings.Contains(reasons, "RefToSTCID 1 does not exist") ||
+		!strings.Contains(reasons, "number of primary video streams changes from 1 to 0") ||
+		!strings.Contains(reasons, "STC is not continuous") {
+		t.Fatalf("unexpected violations\n%s", reasons)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// item is a row of a view. Rows of side by side views set left and right
+// instead of text.
+type item struct {
+	text        string
+	left, right string
+	isDifferent bool
+	open        func() *view
+}
+
+// view is a scrollable list of rows. keys handles keys other than the
+// navigation ones; it returns a view to open or a message to show.
+type view struct {
+	title     string
+	help      string
+	itemsList []*item
+	cursor    int
+	top       int
+	keys      func(v *view, key string) (*view, string)
+}
+
+type browser struct {
+	out       io.Writer
+	viewsList []*view
+	message   string
+}
+
+func (b *browser) push(v *view) {
+	b.viewsList = append(b.viewsList, v)
+}
+
+// readKey decodes the bytes of one key press, naming the keys used for
+// navigation.
+func readKey(rawData []byte) string {
+	switch string(rawData) {
+	case "\x1b[A", "\x1bOA", "k":
+		return "up"
+	case "\x1b[B", "\x1bOB", "j":
+		return "down"
+	case "\x1b[5~", "\x02":
+		return "pgup"
+	case "\x1b[6~", "\x06":
+		return "pgdn"
+	case "\x1b[H", "\x1bOH", "\x1b[1~", "g":
+		return "home"
+	case "\x1b[F", "\x1bOF", "\x1b[4~", "G":
+		return "end"
+	case "\r", "\n", "\x1b[C", "\x1bOC", "l":
+		return "enter"
+	case "\x7f", "\x08", "\x1b", "\x1b[D", "\x1bOD", "h":
+		return "back"
+	case "q", "\x03":
+		return "quit"
+	}
+	return string(rawData)
+}
+
+func (b *browser) run(in io.Reader) error {
+	buffer := make([]byte, 16)
+	for {
+		if err := b.draw(); err != nil {
+			return err
+		}
+		n, err := in.Read(buffer)
+		if err != nil {
+			return err
+		}
+		if !b.handle(readKey(buffer[:n])) {
+			return nil
+		}
+	}
+}
+
+func (b *browser) handle(key string) bool {
+	v := b.viewsList[len(b.viewsList)-1]
+	b.message = ""
+	switch key {
+	case "up":
+		v.cursor--
+	case "down":
+		v.cursor++
+	case "pgup":
+		v.cursor -= b.rows()
+	case "pgdn":
+		v.cursor += b.rows()
+	case "home":
+		v.cursor = 0
+	case "end":
+		v.cursor = len(v.itemsList) - 1
+	case "enter":
+		if v.cursor < len(v.itemsList) && v.itemsList[v.cursor].open != nil {
+			b.push(v.itemsList[v.cursor].open())
+		}
+	case "back":
+		if len(b.viewsList) == 1 {
+			return false
+		}
+		b.viewsList = b.viewsList[:len(b.viewsList)-1]
+	case "quit":
+		return false
+	default:
+		if v.keys != nil {
+			opened, message := v.keys(v, key)
+			if opened != nil {
+				b.push(opened)
+			}
+			b.message = message
+		}
+	}
+	v.cursor = max(0, min(v.cursor, len(v.itemsList)-1))
+	return true
+}
+
+func size() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 80, 24
+	}
+	return width, height
+}
+
+// rows is the number of rows of a view shown between the title and the
+// status line.
+func (b *browser) rows() int {
+	_, height := size()
+	return max(1, height-2)
+}
+
+// fit truncates or pads s to width columns.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if count := utf8.RuneCountInString(s); count <= width {
+		return s + strings.Repeat(" ", width-count)
+	}
+	return string([]rune(s)[:width-1]) + "…"
+}
+
+func (b *browser) draw() error {
+	width, _ := size()
+	rows := b.rows()
+	v := b.viewsList[len(b.viewsList)-1]
+	if v.cursor < v.top {
+		v.top = v.cursor
+	}
+	if v.cursor >= v.top+rows {
+		v.top = v.cursor - rows + 1
+	}
+
+	var screen strings.Builder
+	titlesList := make([]string, 0, len(b.viewsList))
+	for _, opened := range b.viewsList {
+		titlesList = append(titlesList, opened.title)
+	}
+	screen.WriteString("\x1b[H\x1b[1;7m" + fit(strings.Join(titlesList, " › "), width) + "\x1b[0m\r\n")
+	for row := 0; row < rows; row++ {
+		k := v.top + row
+		if k >= len(v.itemsList) {
+			screen.WriteString("\x1b[K\r\n")
+			continue
+		}
+		current := v.itemsList[k]
+		marker := "  "
+		if current.open != nil {
+			marker = "▸ "
+		}
+		line := fit(marker+current.text, width)
+		if current.text == "" && (current.left != "" || current.right != "") {
+			half := (width - 5) / 2
+			line = fit(marker+fit(current.left, half)+" │ "+current.right, width)
+		}
+		switch {
+		case k == v.cursor:
+			screen.WriteString("\x1b[7m" + line + "\x1b[0m")
+		case current.isDifferent:
+			screen.WriteString("\x1b[33m" + line + "\x1b[0m")
+		default:
+			screen.WriteString(line)
+		}
+		screen.WriteString("\x1b[K\r\n")
+	}
+	status := "↑↓ move  enter open  backspace back  q quit"
+	if v.help != "" {
+		status = v.help + "  " + status
+	}
+	if b.message != "" {
+		status = b.message
+	}
+	screen.WriteString("\x1b[2m" + fit(status, width) + "\x1b[0m\x1b[J")
+	_, err := io.WriteString(b.out, screen.String())
+	return err
+}
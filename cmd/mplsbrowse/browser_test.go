@@ -0,0 +1,183 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/syxxzzr/go-mpls"
+)
+
+func TestReadKey(t *testing.T) {
+	for rawData, key := range map[string]string{
+		"\x1b[A": "up", "k": "up", "\x1bOB": "down", "j": "down",
+		"\x1b[5~": "pgup", "\x1b[6~": "pgdn", "g": "home", "\x1b[F": "end",
+		"\r": "enter", "\x1b[C": "enter", "\x7f": "back", "\x1b": "back",
+		"q": "quit", "\x03": "quit", "s": "s", " ": " ",
+	} {
+		if readKey([]byte(rawData)) != key {
+			t.Fatalf("%q read as %q, want %q", rawData, readKey([]byte(rawData)), key)
+		}
+	}
+}
+
+func newTestBrowser(t *testing.T) *browser {
+	fsys, bdmvRoot, closer, err := openDisc(writeTestImage(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	playListsList, err := loadPlayLists(fsys, bdmvRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &browser{out: io.Discard}
+	b.push(playListsView(playListsList))
+	return b
+}
+
+func (b *browser) current() *view {
+	return b.viewsList[len(b.viewsList)-1]
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	b := newTestBrowser(t)
+	playLists := b.current()
+	if len(playLists.itemsList) != 3 || !strings.Contains(playLists.itemsList[2].text, "error") {
+		t.Fatalf("unexpected playlists %#v", playLists.itemsList)
+	}
+
+	for _, test := range []struct {
+		key    string
+		cursor int
+	}{
+		{"up", 0}, {"down", 1}, {"end", 2}, {"down", 2}, {"home", 0}, {"pgdn", 2}, {"pgup", 0},
+	} {
+		if !b.handle(test.key) || playLists.cursor != test.cursor {
+			t.Fatalf("%s moved the cursor to %d, want %d", test.key, playLists.cursor, test.cursor)
+		}
+	}
+
+	// the corrupt playlist does not open
+	b.handle("end")
+	b.handle("enter")
+	if len(b.viewsList) != 1 {
+		t.Fatal("corrupt playlist opened")
+	}
+
+	b.handle("home")
+	b.handle("enter")
+	if b.current().title != "00800" {
+		t.Fatalf("opened %q", b.current().title)
+	}
+	for b.current().itemsList[b.current().cursor].open == nil {
+		b.handle("down")
+	}
+	b.handle("enter")
+	if b.current().title != "Play items" || len(b.current().itemsList) != 1 {
+		t.Fatalf("opened %q", b.current().title)
+	}
+	b.handle("enter")
+	if b.current().title != "Play item 0" || len(b.viewsList) != 4 {
+		t.Fatalf("opened %q", b.current().title)
+	}
+	if err := b.draw(); err != nil {
+		t.Fatal(err)
+	}
+
+	for len(b.viewsList) > 1 {
+		if !b.handle("back") {
+			t.Fatal("back left the browser")
+		}
+	}
+	if b.current() != playLists || playLists.cursor != 0 {
+		t.Fatal("back did not return to the playlists")
+	}
+	if b.handle("back") {
+		t.Fatal("back on the playlists did not leave the browser")
+	}
+	if b.handle("quit") {
+		t.Fatal("quit did not leave the browser")
+	}
+}
+
+func TestBrowserPlayListKeys(t *testing.T) {
+	b := newTestBrowser(t)
+	playLists := b.current()
+
+	b.handle("s")
+	if b.message != "sorted by duration" || playLists.cursor != 0 || !strings.Contains(playLists.itemsList[0].text, "00800") {
+		t.Fatalf("unexpected order after sorting by duration: %q", b.message)
+	}
+	b.handle("down")
+	b.handle("s")
+	if b.message != "sorted by name" || playLists.cursor != 1 || !strings.Contains(playLists.itemsList[1].text, "00801") {
+		t.Fatalf("selection lost sorting by name: %q", b.message)
+	}
+
+	b.handle("d")
+	if !strings.HasPrefix(b.message, "mark two playlists") || len(b.viewsList) != 1 {
+		t.Fatalf("compared without marks: %q", b.message)
+	}
+	b.handle("home")
+	b.handle(" ")
+	if playLists.cursor != 1 || !strings.HasPrefix(playLists.itemsList[0].text, "*") {
+		t.Fatal("playlist not marked")
+	}
+	b.handle("d")
+	if b.current().title != "00800 ↔ 00801" {
+		t.Fatalf("opened %q", b.current().title)
+	}
+	b.handle("enter")
+	if b.current().title != "Changes" || len(b.current().itemsList) == 0 {
+		t.Fatalf("opened %q", b.current().title)
+	}
+}
+
+// keysReader returns one key press per read.
+type keysReader []string
+
+func (r *keysReader) Read(buffer []byte) (int, error) {
+	if len(*r) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(buffer, (*r)[0])
+	*r = (*r)[1:]
+	return n, nil
+}
+
+func TestBrowserRun(t *testing.T) {
+	b := newTestBrowser(t)
+	var screen strings.Builder
+	b.out = &screen
+	if err := b.run(&keysReader{"\x1b[B", "\r", "q"}); err != nil {
+		t.Fatal(err)
+	}
+	if b.current().title != "00801" || !strings.Contains(screen.String(), "Playlists › 00801") {
+		t.Fatalf("unexpected screen %q", screen.String())
+	}
+	if err := b.run(&keysReader{"j"}); err != io.EOF {
+		t.Fatalf("run ended with %v", err)
+	}
+}
+
+func TestUOMasksView(t *testing.T) {
+	mpls := &go_mpls.MPLS{
+		ApplicationInfoPlaylist: &go_mpls.AppInfoPlayList{UOMaskTable: &go_mpls.UOMaskTable{Stop: true}},
+		PlayList: &go_mpls.PlayList{PlayItemList: []*go_mpls.PlayItem{
+			{UserOperationMaskTable: &go_mpls.UOMaskTable{StillOff: true}},
+			{},
+		}},
+	}
+	v := uoMasksView(&playListEntry{name: "00800", mpls: mpls})
+	lines := map[string]string{}
+	for _, item := range v.itemsList {
+		if fields := strings.Fields(item.text); len(fields) == 4 {
+			lines[fields[0]] = strings.Join(fields[1:], " ")
+		}
+	}
+	if len(lines) != len(go_mpls.UserOperationsList) || lines["Stop"] != "x . ." ||
+		lines["StillOff"] != ". x ." || lines["MenuCall"] != ". . ." {
+		t.Fatalf("unexpected masks %v", lines)
+	}
+}
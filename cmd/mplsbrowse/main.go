@@ -0,0 +1,133 @@
+// Command mplsbrowse browses the playlists of a disc in the terminal.
+//
+//	mplsbrowse DISC
+//
+// DISC is a disc directory, its BDMV directory or an ISO image. The playlist
+// list is sorted by name or duration with s; enter opens the play items,
+// streams, sub paths, marks and UO masks of a playlist, and backspace goes
+// back. Two playlists marked with space are compared side by side with d.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/syxxzzr/go-mpls"
+	"golang.org/x/term"
+)
+
+type playListEntry struct {
+	name string
+	mpls *go_mpls.MPLS
+	err  error
+}
+
+// openDisc returns the file system of a disc directory or image and the
+// path of its BDMV directory in it.
+func openDisc(discPath string) (fs.FS, string, io.Closer, error) {
+	info, err := os.Stat(discPath)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	var fsys fs.FS
+	var closer io.Closer = io.NopCloser(nil)
+	if info.IsDir() {
+		fsys = os.DirFS(discPath)
+	} else {
+		file, err := os.Open(discPath)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		udf, err := go_mpls.NewUDF(file)
+		if err != nil {
+			file.Close()
+			return nil, "", nil, fmt.Errorf("%s: %w", discPath, err)
+		}
+		fsys, closer = udf, file
+	}
+	for _, bdmvRoot := range []string{"BDMV", "."} {
+		if info, err := fs.Stat(fsys, path.Join(bdmvRoot, "PLAYLIST")); err == nil && info.IsDir() {
+			return fsys, bdmvRoot, closer, nil
+		}
+	}
+	closer.Close()
+	return nil, "", nil, fmt.Errorf("%s: no BDMV/PLAYLIST directory", discPath)
+}
+
+func loadPlayLists(fsys fs.FS, bdmvRoot string) ([]*playListEntry, error) {
+	entries, err := fs.ReadDir(fsys, path.Join(bdmvRoot, "PLAYLIST"))
+	if err != nil {
+		return nil, err
+	}
+	var playListsList []*playListEntry = nil
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(path.Ext(entry.Name()), ".mpls") {
+			continue
+		}
+		playList := &playListEntry{name: strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))}
+		filePath := path.Join(bdmvRoot, "PLAYLIST", entry.Name())
+		rawData, err := fs.ReadFile(fsys, filePath)
+		if err == nil {
+			playList.mpls, err = go_mpls.ParseBytes(rawData)
+		}
+		if err != nil {
+			playList.err = err
+		} else {
+			playList.mpls.FilePath = filePath
+		}
+		playListsList = append(playListsList, playList)
+	}
+	if len(playListsList) == 0 {
+		return nil, errors.New("no playlists")
+	}
+	return playListsList, nil
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: mplsbrowse DISC|BDMV|image.iso")
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fsys, bdmvRoot, closer, err := openDisc(flag.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	defer closer.Close()
+	playListsList, err := loadPlayLists(fsys, bdmvRoot)
+	if err != nil {
+		fail(err)
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fail(errors.New("mplsbrowse needs a terminal"))
+	}
+	state, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		fail(err)
+	}
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	browser := &browser{out: os.Stdout}
+	browser.push(playListsView(playListsList))
+	err = browser.run(os.Stdin)
+	os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+	term.Restore(int(os.Stdin.Fd()), state)
+	if err != nil {
+		fail(err)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syxxzzr/go-mpls/internal/fixture"
+)
+
+// testPlayLists are the playlists of the test discs: 00800 is the longer
+// one, 00801 the shorter one and 00802 does not parse.
+func testPlayLists() map[string][]byte {
+	return map[string][]byte{
+		"00800.mpls": fixture.MovieMPLS(45000*60, 0, 45000*30),
+		"00801.mpls": fixture.MovieMPLS(45000*20, 0),
+		"00802.mpls": []byte("MPLSxxxx"),
+	}
+}
+
+func writeDisc(t *testing.T, dir string) {
+	if err := os.MkdirAll(filepath.Join(dir, "BDMV", "PLAYLIST"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, rawData := range testPlayLists() {
+		if err := os.WriteFile(filepath.Join(dir, "BDMV", "PLAYLIST", name), rawData, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeTestImage(t *testing.T) string {
+	image := filepath.Join(t.TempDir(), "disc.iso")
+	if err := os.WriteFile(image, fixture.UDF(testPlayLists()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+func TestOpenDisc(t *testing.T) {
+	dir := t.TempDir()
+	writeDisc(t, filepath.Join(dir, "DISC"))
+	image := writeTestImage(t)
+
+	for _, test := range []struct {
+		discPath, bdmvRoot string
+	}{
+		{filepath.Join(dir, "DISC"), "BDMV"},
+		{filepath.Join(dir, "DISC", "BDMV"), "."},
+		{image, "BDMV"},
+	} {
+		fsys, bdmvRoot, closer, err := openDisc(test.discPath)
+		if err != nil {
+			t.Fatalf("%s: %v", test.discPath, err)
+		}
+		if bdmvRoot != test.bdmvRoot {
+			t.Fatalf("%s: BDMV directory %q", test.discPath, bdmvRoot)
+		}
+		playListsList, err := loadPlayLists(fsys, bdmvRoot)
+		closer.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", test.discPath, err)
+		}
+		if len(playListsList) != 3 {
+			t.Fatalf("%s: %d playlists", test.discPath, len(playListsList))
+		}
+		for i, name := range []string{"00800", "00801", "00802"} {
+			if playListsList[i].name != name {
+				t.Fatalf("%s: playlist %d is %s", test.discPath, i, playListsList[i].name)
+			}
+		}
+		if playListsList[0].err != nil || playListsList[0].mpls.DurationTicks() != 45000*60 ||
+			len(playListsList[0].mpls.Chapters()) != 2 {
+			t.Fatalf("%s: unexpected playlist %#v", test.discPath, playListsList[0])
+		}
+		if playListsList[2].err == nil || playListsList[2].mpls != nil {
+			t.Fatalf("%s: corrupt playlist parsed", test.discPath)
+		}
+	}
+}
+
+func TestOpenDiscErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "empty.iso"), make([]byte, 300*fixture.UDFSectorSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, discPath := range []string{filepath.Join(dir, "missing"), dir, filepath.Join(dir, "empty.iso")} {
+		if _, _, _, err := openDisc(discPath); err == nil {
+			t.Fatalf("%s opened", discPath)
+		}
+	}
+
+	image := filepath.Join(dir, "noplaylists.iso")
+	if err := os.WriteFile(image, fixture.UDF(map[string][]byte{"index.bdmv": []byte("INDX0300")}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys, bdmvRoot, closer, err := openDisc(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	if _, err := loadPlayLists(fsys, bdmvRoot); err == nil {
+		t.Fatal("disc without playlists loaded")
+	}
+}
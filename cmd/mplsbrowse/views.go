@@ -0,0 +1,384 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/syxxzzr/go-mpls"
+)
+
+var subPathTypeNames = map[go_mpls.SubPathType]string{
+	go_mpls.PrimaryAudio:               "primary audio",
+	go_mpls.InteractiveGraphicsMenu:    "IG menu",
+	go_mpls.TextSubtitlePath:           "text subtitle",
+	go_mpls.OutMuxAndSyncTypeOfStreams: "out-of-mux synchronous",
+	go_mpls.OutMuxAndAsyncTypeOfPIP:    "out-of-mux asynchronous PiP",
+	go_mpls.InMuxAndSyncTypeOfPIP:      "in-mux synchronous PiP",
+	go_mpls.StereoscopicVideo:          "stereoscopic video",
+	go_mpls.StereoscopicIGMenu:         "stereoscopic IG menu",
+	go_mpls.DolbyVisionEnhancement:     "Dolby Vision enhancement layer",
+}
+
+func formatTicks(ticks int) string {
+	milliseconds := (int64(ticks) + 22) / 45
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+func textItems(lines ...string) []*item {
+	itemsList := make([]*item, 0, len(lines))
+	for _, line := range lines {
+		itemsList = append(itemsList, &item{text: line})
+	}
+	return itemsList
+}
+
+func playListsView(playListsList []*playListEntry) *view {
+	order := slices.Clone(playListsList)
+	marked := map[*playListEntry]bool{}
+	sortByDuration := false
+
+	v := &view{title: "Playlists", help: "s sort  space mark  d diff"}
+	refresh := func() {
+		slices.SortStableFunc(order, func(a, b *playListEntry) int {
+			if sortByDuration && a.mpls != nil && b.mpls != nil {
+				if c := cmp.Compare(b.mpls.DurationTicks(), a.mpls.DurationTicks()); c != 0 {
+					return c
+				}
+			}
+			return cmp.Compare(a.name, b.name)
+		})
+		v.itemsList = nil
+		for _, playList := range order {
+			mark := " "
+			if marked[playList] {
+				mark = "*"
+			}
+			if playList.err != nil {
+				v.itemsList = append(v.itemsList, &item{text: fmt.Sprintf("%s %s  error: %v", mark, playList.name, playList.err)})
+				continue
+			}
+			mpls := playList.mpls
+			v.itemsList = append(v.itemsList, &item{
+				text: fmt.Sprintf("%s %s  %s  %3d play items  %3d chapters  %d angles", mark, playList.name,
+					formatTicks(mpls.DurationTicks()), len(mpls.PlayList.PlayItemList), len(mpls.Chapters()), mpls.NumberOfAngles()),
+				open: func() *view { return playListView(playList) },
+			})
+		}
+	}
+	v.keys = func(v *view, key string) (*view, string) {
+		selected := order[v.cursor]
+		switch key {
+		case "s":
+			sortByDuration = !sortByDuration
+			refresh()
+			v.cursor = slices.Index(order, selected)
+			if sortByDuration {
+				return nil, "sorted by duration"
+			}
+			return nil, "sorted by name"
+		case " ":
+			if selected.mpls != nil {
+				marked[selected] = !marked[selected]
+				refresh()
+				v.cursor = min(v.cursor+1, len(order)-1)
+			}
+		case "d":
+			var pairList []*playListEntry = nil
+			for _, playList := range order {
+				if marked[playList] {
+					pairList = append(pairList, playList)
+				}
+			}
+			if len(pairList) == 1 && pairList[0] != selected && selected.mpls != nil {
+				pairList = append(pairList, selected)
+			}
+			if len(pairList) != 2 {
+				return nil, "mark two playlists with space to compare them"
+			}
+			return diffView(pairList[0], pairList[1]), ""
+		}
+		return nil, ""
+	}
+	refresh()
+	return v
+}
+
+func playListView(playList *playListEntry) *view {
+	mpls := playList.mpls
+	v := &view{title: playList.name}
+	v.itemsList = textItems(
+		fmt.Sprintf("Version         %04d", mpls.VersionNumber),
+		fmt.Sprintf("Duration        %s", formatTicks(mpls.DurationTicks())),
+		fmt.Sprintf("Angles          %d", mpls.NumberOfAngles()),
+		fmt.Sprintf("Fingerprint     %s", mpls.PlayList.Fingerprint()),
+	)
+	if appInfo := mpls.ApplicationInfoPlaylist; appInfo != nil {
+		v.itemsList = append(v.itemsList, textItems(
+			fmt.Sprintf("Playback type   %d (count %d)", appInfo.PlaybackType, appInfo.PlaybackCount),
+			fmt.Sprintf("Random access   %t", appInfo.RandomAccessFlag),
+		)...)
+	}
+	v.itemsList = append(v.itemsList,
+		&item{text: fmt.Sprintf("Play items (%d)", len(mpls.PlayList.PlayItemList)), open: func() *view { return playItemsView(playList) }},
+		&item{text: fmt.Sprintf("Sub paths (%d)", len(mpls.PlayList.SubPathsList)), open: func() *view { return subPathsView(playList) }},
+		&item{text: fmt.Sprintf("Marks (%d)", marksCount(mpls)), open: func() *view { return marksView(playList) }},
+		&item{text: "UO masks", open: func() *view { return uoMasksView(playList) }},
+	)
+	return v
+}
+
+func marksCount(mpls *go_mpls.MPLS) int {
+	if mpls.PlayListMark == nil {
+		return 0
+	}
+	return len(mpls.PlayListMark.PlayListMarksList)
+}
+
+func playItemsView(playList *playListEntry) *view {
+	v := &view{title: "Play items"}
+	offset := 0
+	for i, playItem := range playList.mpls.PlayList.PlayItemList {
+		start := offset
+		v.itemsList = append(v.itemsList, &item{
+			text: fmt.Sprintf("%3d  %s.m2ts  %s - %s  at %s  CC %d", i, playItem.ClipInformationFileName,
+				formatTicks(playItem.INTimeTicks), formatTicks(playItem.OUTTimeTicks), formatTicks(start), playItem.ConnectionCondition),
+			open: func() *view { return playItemView(i, playItem) },
+		})
+		offset += playItem.DurationTicks()
+	}
+	return v
+}
+
+func streamLines(stn *go_mpls.STNTable) []string {
+	if stn == nil {
+		return nil
+	}
+	groupsList := []struct {
+		name    string
+		streams []*go_mpls.Stream
+	}{
+		{"Primary video", stn.PrimaryVideoStreamsList},
+		{"Primary audio", stn.PrimaryAudioStreamsList},
+		{"Presentation graphics", stn.PrimaryPGStreamsList},
+		{"Interactive graphics", stn.PrimaryIGStreamsList},
+		{"Secondary audio", stn.SecondaryAudioStreamsList},
+		{"Secondary video", stn.SecondaryVideoStreamsList},
+		{"Secondary PG", stn.SecondaryPGStreamsList},
+		{"Dolby Vision", stn.DVStreamsList},
+	}
+	var linesList []string = nil
+	for _, group := range groupsList {
+		if len(group.streams) == 0 {
+			continue
+		}
+		linesList = append(linesList, group.name+":")
+		for _, stream := range group.streams {
+			attributes := stream.StreamAttributes
+			line := fmt.Sprintf("  0x%04x  %s", stream.StreamEntry.RefToStreamPID, attributes.StreamCodingType)
+			switch attributes.StreamCodingType {
+			case go_mpls.MPEG1Video, go_mpls.MPEG2Video, go_mpls.MPEG4AVCVideo, go_mpls.MPEG4MVCVideo, go_mpls.SMTPEVC1Video, go_mpls.HEVCVideo:
+				line += fmt.Sprintf("  %v %v", attributes.VideoFormat, attributes.DynamicRangeType)
+			case go_mpls.PresentationGraphics, go_mpls.InteractiveGraphics, go_mpls.TextSubtitle:
+			default:
+				line += fmt.Sprintf("  %v", attributes.AudioFormat)
+			}
+			if attributes.LanguageCode != "" {
+				line += "  " + attributes.LanguageCode
+			}
+			if stream.StreamEntry.StreamType == 0x02 || stream.StreamEntry.StreamType == 0x04 {
+				line += fmt.Sprintf("  (sub path %d)", stream.StreamEntry.RefToSubPathID)
+			}
+			linesList = append(linesList, line)
+		}
+	}
+	return linesList
+}
+
+func playItemView(i int, playItem *go_mpls.PlayItem) *view {
+	v := &view{title: fmt.Sprintf("Play item %d", i)}
+	v.itemsList = textItems(
+		fmt.Sprintf("Clip            %s.m2ts (%s)", playItem.ClipInformationFileName, playItem.ClipCodecIdentifier),
+		fmt.Sprintf("STC sequence    %d", playItem.RefToSTCID),
+		fmt.Sprintf("IN - OUT        %s - %s (%s)", formatTicks(playItem.INTimeTicks), formatTicks(playItem.OUTTimeTicks), formatTicks(playItem.DurationTicks())),
+		fmt.Sprintf("Connection      %d", playItem.ConnectionCondition),
+		fmt.Sprintf("Still mode      %d (%v s)", playItem.StillMode, playItem.StillTime),
+		fmt.Sprintf("Random access   %t", playItem.PlayItemRandomAccessFlag),
+	)
+	for k, angle := range playItem.AnglesList {
+		v.itemsList = append(v.itemsList, &item{text: fmt.Sprintf("Angle %d         %s.m2ts", k+2, angle.ClipInformationFileName)})
+	}
+	if playItem.STNTable != nil {
+		v.itemsList = append(v.itemsList, textItems(streamLines(playItem.STNTable)...)...)
+	}
+	return v
+}
+
+func subPathsView(playList *playListEntry) *view {
+	v := &view{title: "Sub paths"}
+	for i, subPath := range playList.mpls.PlayList.SubPathsList {
+		name := subPathTypeNames[subPath.SubPathType]
+		if name == "" {
+			name = fmt.Sprintf("type %d", subPath.SubPathType)
+		}
+		v.itemsList = append(v.itemsList, &item{
+			text: fmt.Sprintf("%3d  %s  repeat %t  %d sub play items", i, name, subPath.IsRepeatSubPath, len(subPath.SubPlayItemsList)),
+			open: func() *view {
+				sub := &view{title: fmt.Sprintf("Sub path %d", i)}
+				for k, subPlayItem := range subPath.SubPlayItemsList {
+					sub.itemsList = append(sub.itemsList, &item{text: fmt.Sprintf("%3d  %s.m2ts  %s - %s  sync play item %d at %s  CC %d",
+						k, subPlayItem.ClipInformationFileName, formatTicks(subPlayItem.INTimeTicks), formatTicks(subPlayItem.OUTTimeTicks),
+						subPlayItem.SyncPlayItemID, formatTicks(subPlayItem.SyncStartPTS), subPlayItem.ConnectionCondition)})
+				}
+				return sub
+			},
+		})
+	}
+	return v
+}
+
+func marksView(playList *playListEntry) *view {
+	mpls := playList.mpls
+	v := &view{title: "Marks"}
+	if mpls.PlayListMark == nil {
+		return v
+	}
+	for i, mark := range mpls.PlayListMark.PlayListMarksList {
+		kind := "entry"
+		if mark.MarkType != go_mpls.EntryMark {
+			kind = fmt.Sprintf("type %d", mark.MarkType)
+		}
+		line := fmt.Sprintf("%3d  %-7s  play item %d  %s", i, kind, mark.RefToPlayItemID, formatTicks(mark.MarkTimeTicks))
+		if mark.RefToPlayItemID < len(mpls.PlayList.PlayItemList) {
+			offset := 0
+			for _, playItem := range mpls.PlayList.PlayItemList[:mark.RefToPlayItemID] {
+				offset += playItem.DurationTicks()
+			}
+			line += "  at " + formatTicks(offset+mark.MarkTimeTicks-mpls.PlayList.PlayItemList[mark.RefToPlayItemID].INTimeTicks)
+		}
+		v.itemsList = append(v.itemsList, &item{text: line})
+	}
+	return v
+}
+
+// uoMasksView shows which user operations the playlist and each play item
+// mask, one column each.
+func uoMasksView(playList *playListEntry) *view {
+	var masksList []*go_mpls.UOMaskTable = nil
+	header := fmt.Sprintf("%-34s %s", "", "PL")
+	if playList.mpls.ApplicationInfoPlaylist != nil {
+		masksList = append(masksList, playList.mpls.ApplicationInfoPlaylist.UOMaskTable)
+	} else {
+		masksList = append(masksList, nil)
+	}
+	for i, playItem := range playList.mpls.PlayList.PlayItemList {
+		masksList = append(masksList, playItem.UserOperationMaskTable)
+		header += fmt.Sprintf(" %3d", i)
+	}
+
+	v := &view{title: "UO masks"}
+	v.itemsList = textItems(header)
+	for _, operation := range go_mpls.UserOperationsList {
+		var line strings.Builder
+		fmt.Fprintf(&line, "%-34s", operation)
+		for i, mask := range masksList {
+			cell := "."
+			if mask.IsMasked(operation) {
+				cell = "x"
+			}
+			if i == 0 {
+				fmt.Fprintf(&line, " %2s", cell)
+			} else {
+				fmt.Fprintf(&line, " %3s", cell)
+			}
+		}
+		v.itemsList = append(v.itemsList, &item{text: line.String()})
+	}
+	v.itemsList = append(v.itemsList, textItems("", "x: operation masked   PL: playlist   n: play item n")...)
+	return v
+}
+
+// describe lists the parts of a playlist compared by diffView.
+func describe(mpls *go_mpls.MPLS) []string {
+	linesList := []string{fmt.Sprintf("Duration %s", formatTicks(mpls.DurationTicks()))}
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		linesList = append(linesList, fmt.Sprintf("Play item %s.m2ts %s - %s", playItem.ClipInformationFileName,
+			formatTicks(playItem.INTimeTicks), formatTicks(playItem.OUTTimeTicks)))
+		if playItem.STNTable != nil && (i == 0 || !slices.Equal(streamLines(playItem.STNTable), streamLines(mpls.PlayList.PlayItemList[i-1].STNTable))) {
+			linesList = append(linesList, streamLines(playItem.STNTable)...)
+		}
+	}
+	for i, subPath := range mpls.PlayList.SubPathsList {
+		linesList = append(linesList, fmt.Sprintf("Sub path %d type %d, %d sub play items", i, subPath.SubPathType, len(subPath.SubPlayItemsList)))
+	}
+	for _, chapter := range mpls.Chapters() {
+		linesList = append(linesList, fmt.Sprintf("%s %s", chapter.Name(), formatTicks(chapter.TimeTicks)))
+	}
+	return linesList
+}
+
+// diffView aligns the descriptions of two playlists on their longest
+// common subsequence and shows them side by side, highlighting the rows
+// that differ.
+func diffView(oldPlayList, newPlayList *playListEntry) *view {
+	oldLines, newLines := describe(oldPlayList.mpls), describe(newPlayList.mpls)
+	m, n := len(oldLines), len(newLines)
+	weights := make([][]int, m+1)
+	for i := range weights {
+		weights[i] = make([]int, n+1)
+	}
+	for i := m - 1; i >= 0; i-- {
+		for j := n - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				weights[i][j] = weights[i+1][j+1] + 1
+			} else {
+				weights[i][j] = max(weights[i+1][j], weights[i][j+1])
+			}
+		}
+	}
+
+	changes := go_mpls.Diff(oldPlayList.mpls, newPlayList.mpls)
+	v := &view{title: fmt.Sprintf("%s ↔ %s", oldPlayList.name, newPlayList.name)}
+	v.itemsList = append(v.itemsList, &item{
+		text: fmt.Sprintf("%d changes", len(changes)),
+		open: func() *view {
+			var builder strings.Builder
+			go_mpls.WriteDiffText(&builder, changes)
+			return &view{title: "Changes", itemsList: textItems(strings.Split(strings.TrimSuffix(builder.String(), "\n"), "\n")...)}
+		},
+	})
+	v.itemsList = append(v.itemsList, &item{left: oldPlayList.name, right: newPlayList.name})
+	// Lines only in one playlist are paired with the next lines only in the
+	// other one, so that replaced lines share a row.
+	var removedList, addedList []string
+	flush := func() {
+		for k := range max(len(removedList), len(addedList)) {
+			row := &item{isDifferent: true}
+			if k < len(removedList) {
+				row.left = removedList[k]
+			}
+			if k < len(addedList) {
+				row.right = addedList[k]
+			}
+			v.itemsList = append(v.itemsList, row)
+		}
+		removedList, addedList = nil, nil
+	}
+	for i, j := 0, 0; i < m || j < n; {
+		switch {
+		case i < m && j < n && oldLines[i] == newLines[j]:
+			flush()
+			v.itemsList = append(v.itemsList, &item{left: oldLines[i], right: newLines[j]})
+			i, j = i+1, j+1
+		case j == n || (i < m && weights[i+1][j] >= weights[i][j+1]):
+			removedList = append(removedList, oldLines[i])
+			i++
+		default:
+			addedList = append(addedList, newLines[j])
+			j++
+		}
+	}
+	flush()
+	return v
+}
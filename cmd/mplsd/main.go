@@ -0,0 +1,45 @@
+// Command mplsd serves the playlists of the discs under a directory as a JSON
+// API over HTTP.
+//
+//	mplsd [-addr :8080] -root DIR
+//
+// Every subdirectory of the root holding a BDMV/PLAYLIST directory is a
+// disc, named after the subdirectory:
+//
+//	GET /discs
+//	GET /discs/{disc}/playlists
+//	GET /discs/{disc}/playlists/{playlist}
+//	GET /discs/{disc}/playlists/{playlist}/chapters?format=json|ogm|ffmetadata
+//	GET /discs/{disc}/playlists/{playlist}/lint?format=json|sarif&disable=IDs
+//
+// Playlists are named without extension, like 00800. Responses carry an ETag
+// built from the size and modification time of the files they are read from,
+// and If-None-Match requests for unchanged files are answered with 304.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	root := flag.String("root", "", "directory holding one directory per disc")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: mplsd [-addr :8080] -root DIR")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *root == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fmt.Fprintf(os.Stderr, "serving %s on http://%s/discs\n", *root, *addr)
+	if err := http.ListenAndServe(*addr, newHandler(*root)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
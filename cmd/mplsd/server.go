@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/syxxzzr/go-mpls"
+)
+
+type server struct {
+	root string
+}
+
+type discSummary struct {
+	Name              string `json:"name"`
+	NumberOfPlayLists int    `json:"playlists"`
+}
+
+// playListSummary is a playlist entry of a disc. Playlists that fail to
+// parse are listed with the error only.
+type playListSummary struct {
+	Name              string  `json:"name"`
+	Duration          float64 `json:"duration,omitempty"`
+	NumberOfPlayItems int     `json:"playItems,omitempty"`
+	NumberOfChapters  int     `json:"chapters,omitempty"`
+	NumberOfAngles    int     `json:"angles,omitempty"`
+	Fingerprint       string  `json:"fingerprint,omitempty"`
+	Signature         string  `json:"durationSignature,omitempty"`
+	Error             string  `json:"error,omitempty"`
+}
+
+type chapterInfo struct {
+	Number     int     `json:"number"`
+	Name       string  `json:"name"`
+	PlayItemID int     `json:"playItem"`
+	Time       float64 `json:"time"`
+}
+
+func newHandler(root string) http.Handler {
+	s := &server{root: root}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /discs", s.handleDiscs)
+	mux.HandleFunc("GET /discs/{disc}/playlists", s.handlePlayLists)
+	mux.HandleFunc("GET /discs/{disc}/playlists/{playlist}", s.handlePlayList)
+	mux.HandleFunc("GET /discs/{disc}/playlists/{playlist}/chapters", s.handleChapters)
+	mux.HandleFunc("GET /discs/{disc}/playlists/{playlist}/lint", s.handleLint)
+	return mux
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// entityTag hashes the name, size and modification time of every file a
+// response is read from, along with the request URI selecting the
+// representation.
+func entityTag(uri string, infos ...os.FileInfo) string {
+	hash := fnv.New64a()
+	fmt.Fprintln(hash, uri)
+	for _, info := range infos {
+		fmt.Fprintf(hash, "%s %d %d\n", info.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return fmt.Sprintf(`"%016x"`, hash.Sum64())
+}
+
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// serve answers with 304 when the client holds the current version of the
+// files described by infos, and with the output of render otherwise.
+func serve(w http.ResponseWriter, r *http.Request, contentType string, infos []os.FileInfo, render func(w io.Writer) error) {
+	etag := entityTag(r.URL.RequestURI(), infos...)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if header := r.Header.Get("If-None-Match"); header != "" && matchesETag(header, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	var buffer bytes.Buffer
+	if err := render(&buffer); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(buffer.Bytes())
+}
+
+// discs returns the disc directories under the root by name. Only names
+// found here are ever joined to the root, so request paths cannot leave it.
+func (s *server) discs() ([]string, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+	var namesList []string = nil
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(s.root, entry.Name(), "BDMV", "PLAYLIST")); err == nil && info.IsDir() {
+			namesList = append(namesList, entry.Name())
+		}
+	}
+	return namesList, nil
+}
+
+func (s *server) bdmvRoot(w http.ResponseWriter, r *http.Request) (string, bool) {
+	discs, err := s.discs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return "", false
+	}
+	for _, name := range discs {
+		if name == r.PathValue("disc") {
+			return filepath.Join(s.root, name, "BDMV"), true
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no disc %q", r.PathValue("disc")))
+	return "", false
+}
+
+func playListBaseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// playList parses the playlist named in the request and returns it with the
+// file information of the MPLS file.
+func (s *server) playList(w http.ResponseWriter, r *http.Request) (*go_mpls.MPLS, os.FileInfo, bool) {
+	bdmvRoot, ok := s.bdmvRoot(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	paths, err := go_mpls.PlayListPaths(bdmvRoot)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+	for _, path := range paths {
+		if !strings.EqualFold(playListBaseName(path), r.PathValue("playlist")) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return nil, nil, false
+		}
+		if r.Header.Get("If-None-Match") != "" && matchesETag(r.Header.Get("If-None-Match"), entityTag(r.URL.RequestURI(), info)) {
+			// The client holds this version, so serve answers 304 without
+			// the playlist.
+			return nil, info, true
+		}
+		mpls, err := go_mpls.Parse(path)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return nil, nil, false
+		}
+		return mpls, info, true
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("no playlist %q", r.PathValue("playlist")))
+	return nil, nil, false
+}
+
+func (s *server) handleDiscs(w http.ResponseWriter, r *http.Request) {
+	discs, err := s.discs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rootInfo, err := os.Stat(s.root)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	summariesList := []*discSummary{}
+	infosList := []os.FileInfo{rootInfo}
+	for _, name := range discs {
+		paths, err := go_mpls.PlayListPaths(filepath.Join(s.root, name, "BDMV"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		info, err := os.Stat(filepath.Join(s.root, name, "BDMV", "PLAYLIST"))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		infosList = append(infosList, info)
+		summariesList = append(summariesList, &discSummary{Name: name, NumberOfPlayLists: len(paths)})
+	}
+	serve(w, r, "application/json", infosList, func(w io.Writer) error {
+		return writeJSON(w, summariesList)
+	})
+}
+
+func (s *server) handlePlayLists(w http.ResponseWriter, r *http.Request) {
+	bdmvRoot, ok := s.bdmvRoot(w, r)
+	if !ok {
+		return
+	}
+	paths, err := go_mpls.PlayListPaths(bdmvRoot)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var infosList []os.FileInfo = nil
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		infosList = append(infosList, info)
+	}
+	serve(w, r, "application/json", infosList, func(w io.Writer) error {
+		summariesList := []*playListSummary{}
+		for _, path := range paths {
+			summary := &playListSummary{Name: playListBaseName(path)}
+			if mpls, err := go_mpls.Parse(path); err != nil {
+				summary.Error = err.Error()
+			} else {
+				info := mpls.Info()
+				summary.Duration = info.Duration
+				summary.NumberOfPlayItems = len(info.PlayItemsList)
+				summary.NumberOfChapters = len(info.ChaptersList)
+				summary.NumberOfAngles = info.NumberOfAngles
+				summary.Fingerprint = info.Fingerprint
+				summary.Signature = info.Signature
+			}
+			summariesList = append(summariesList, summary)
+		}
+		return writeJSON(w, summariesList)
+	})
+}
+
+func (s *server) handlePlayList(w http.ResponseWriter, r *http.Request) {
+	mpls, info, ok := s.playList(w, r)
+	if !ok {
+		return
+	}
+	serve(w, r, "application/json", []os.FileInfo{info}, func(w io.Writer) error {
+		playListInfo := mpls.Info()
+		playListInfo.Path = playListBaseName(mpls.FilePath)
+		return writeJSON(w, playListInfo)
+	})
+}
+
+func (s *server) handleChapters(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	contentType := map[string]string{"": "application/json", "json": "application/json",
+		"ogm": "text/plain; charset=utf-8", "ffmetadata": "text/plain; charset=utf-8"}[format]
+	if contentType == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q", format))
+		return
+	}
+	mpls, info, ok := s.playList(w, r)
+	if !ok {
+		return
+	}
+	serve(w, r, contentType, []os.FileInfo{info}, func(w io.Writer) error {
+		switch format {
+		case "ogm":
+			return mpls.WriteOGMChapters(w)
+		case "ffmetadata":
+			return mpls.WriteFFMetadataChapters(w)
+		}
+		chaptersList := []*chapterInfo{}
+		for _, chapter := range mpls.Chapters() {
+			chaptersList = append(chaptersList, &chapterInfo{
+				Number:     chapter.Number,
+				Name:       chapter.Name(),
+				PlayItemID: chapter.PlayItemID,
+				Time:       chapter.Seconds(),
+			})
+		}
+		return writeJSON(w, chaptersList)
+	})
+}
+
+func (s *server) handleLint(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "sarif" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q", format))
+		return
+	}
+	config := &go_mpls.LintConfig{}
+	if disabled := r.URL.Query().Get("disable"); disabled != "" {
+		config.DisabledRulesList = strings.Split(disabled, ",")
+	}
+	mpls, info, ok := s.playList(w, r)
+	if !ok {
+		return
+	}
+	serve(w, r, "application/json", []os.FileInfo{info}, func(w io.Writer) error {
+		findings := mpls.Lint(config)
+		if format == "sarif" {
+			return go_mpls.WriteLintSARIF(w, playListBaseName(mpls.FilePath)+".mpls", findings)
+		}
+		return go_mpls.WriteLintJSON(w, findings)
+	})
+}
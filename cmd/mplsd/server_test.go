@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syxxzzr/go-mpls/internal/fixture"
+)
+
+// newTestServer serves a root holding disc DISC with a valid playlist 00000,
+// a playlist 00001 with a mark past its end and a corrupt playlist 00002,
+// next to a directory that is not a disc.
+func newTestServer(t *testing.T) (*httptest.Server, string) {
+	root := t.TempDir()
+	playListDir := filepath.Join(root, "DISC", "BDMV", "PLAYLIST")
+	if err := os.MkdirAll(playListDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "OTHER"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, rawData := range map[string][]byte{
+		"00000.mpls": fixture.MovieMPLS(45000*60, 0, 45000*30),
+		"00001.mpls": fixture.MovieMPLS(45000*60, 0, 45000*90),
+		"00002.mpls": []byte("MPLSxxxx"),
+	} {
+		if err := os.WriteFile(filepath.Join(playListDir, name), rawData, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(newHandler(root))
+	t.Cleanup(server.Close)
+	return server, playListDir
+}
+
+func get(t *testing.T, url string, header http.Header) *http.Response {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { response.Body.Close() })
+	return response
+}
+
+func getJSON(t *testing.T, url string, status int, v any) *http.Response {
+	response := get(t, url, nil)
+	if response.StatusCode != status {
+		t.Fatalf("GET %s: status %d, want %d", url, response.StatusCode, status)
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	return response
+}
+
+func TestServerDiscs(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	var discs []*discSummary
+	getJSON(t, server.URL+"/discs", http.StatusOK, &discs)
+	if len(discs) != 1 || discs[0].Name != "DISC" || discs[0].NumberOfPlayLists != 3 {
+		t.Fatalf("unexpected discs %#v", discs)
+	}
+
+	var playLists []*playListSummary
+	getJSON(t, server.URL+"/discs/DISC/playlists", http.StatusOK, &playLists)
+	if len(playLists) != 3 {
+		t.Fatalf("unexpected playlists %#v", playLists)
+	}
+	if summary := playLists[0]; summary.Name != "00000" || summary.Duration != 60 || summary.NumberOfPlayItems != 1 ||
+		summary.NumberOfChapters != 2 || summary.Fingerprint == "" || summary.Error != "" {
+		t.Fatalf("unexpected summary %#v", summary)
+	}
+	if summary := playLists[2]; summary.Name != "00002" || summary.Error == "" || summary.Duration != 0 {
+		t.Fatalf("corrupt playlist listed as %#v", summary)
+	}
+}
+
+func TestServerPlayList(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	var info struct {
+		Path     string    `json:"path"`
+		Duration float64   `json:"duration"`
+		Chapters []float64 `json:"chapters"`
+	}
+	getJSON(t, server.URL+"/discs/DISC/playlists/00000", http.StatusOK, &info)
+	if info.Path != "00000" || info.Duration != 60 || len(info.Chapters) != 2 || info.Chapters[1] != 30 {
+		t.Fatalf("unexpected playlist %#v", info)
+	}
+
+	var chapters []*chapterInfo
+	getJSON(t, server.URL+"/discs/DISC/playlists/00000/chapters", http.StatusOK, &chapters)
+	if len(chapters) != 2 || chapters[0].Number != 1 || chapters[1].Time != 30 || chapters[1].PlayItemID != 0 {
+		t.Fatalf("unexpected chapters %#v", chapters)
+	}
+
+	response := get(t, server.URL+"/discs/DISC/playlists/00000/chapters?format=ogm", nil)
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || !strings.HasPrefix(response.Header.Get("Content-Type"), "text/plain") ||
+		!strings.Contains(string(body), "CHAPTER02=00:00:30.000") {
+		t.Fatalf("unexpected OGM chapters %d %q", response.StatusCode, body)
+	}
+}
+
+func TestServerLint(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	var findings []struct {
+		RuleID string `json:"ruleId"`
+	}
+	getJSON(t, server.URL+"/discs/DISC/playlists/00000/lint", http.StatusOK, &findings)
+	if len(findings) != 0 {
+		t.Fatalf("unexpected findings %#v", findings)
+	}
+	getJSON(t, server.URL+"/discs/DISC/playlists/00001/lint", http.StatusOK, &findings)
+	if len(findings) != 1 || findings[0].RuleID != "MPLS004" {
+		t.Fatalf("unexpected findings %#v", findings)
+	}
+	getJSON(t, server.URL+"/discs/DISC/playlists/00001/lint?disable=MPLS004", http.StatusOK, &findings)
+	if len(findings) != 0 {
+		t.Fatalf("disabled rule reported %#v", findings)
+	}
+
+	var sarif struct {
+		Version string `json:"version"`
+	}
+	getJSON(t, server.URL+"/discs/DISC/playlists/00001/lint?format=sarif", http.StatusOK, &sarif)
+	if sarif.Version == "" {
+		t.Fatal("SARIF log without version")
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	for _, test := range []struct {
+		path   string
+		status int
+	}{
+		{"/discs/NODISC/playlists", http.StatusNotFound},
+		{"/discs/OTHER/playlists", http.StatusNotFound},
+		{"/discs/..%2F..%2Fetc/playlists", http.StatusNotFound},
+		{"/discs/DISC/playlists/00009", http.StatusNotFound},
+		{"/discs/DISC/playlists/00009/chapters", http.StatusNotFound},
+		{"/discs/DISC/playlists/00009/lint", http.StatusNotFound},
+		{"/discs/DISC/playlists/00002", http.StatusUnprocessableEntity},
+		{"/discs/DISC/playlists/00000/chapters?format=srt", http.StatusBadRequest},
+		{"/discs/DISC/playlists/00000/lint?format=xml", http.StatusBadRequest},
+	} {
+		var body map[string]string
+		getJSON(t, server.URL+test.path, test.status, &body)
+		if body["error"] == "" {
+			t.Fatalf("GET %s: no error message", test.path)
+		}
+	}
+}
+
+func TestServerETag(t *testing.T) {
+	server, playListDir := newTestServer(t)
+
+	for _, path := range []string{"/discs", "/discs/DISC/playlists", "/discs/DISC/playlists/00000",
+		"/discs/DISC/playlists/00000/chapters", "/discs/DISC/playlists/00000/lint"} {
+		response := get(t, server.URL+path, nil)
+		etag := response.Header.Get("ETag")
+		if response.StatusCode != http.StatusOK || etag == "" {
+			t.Fatalf("GET %s: status %d, ETag %q", path, response.StatusCode, etag)
+		}
+		for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+			response = get(t, server.URL+path, http.Header{"If-None-Match": {header}})
+			if response.StatusCode != http.StatusNotModified || response.Header.Get("ETag") != etag {
+				t.Fatalf("GET %s with If-None-Match %s: status %d", path, header, response.StatusCode)
+			}
+		}
+		if response = get(t, server.URL+path, http.Header{"If-None-Match": {`"other"`}}); response.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s with a stale ETag: status %d", path, response.StatusCode)
+		}
+	}
+
+	// the representations differ, and so do their tags
+	chapters := get(t, server.URL+"/discs/DISC/playlists/00000/chapters", nil).Header.Get("ETag")
+	ogm := get(t, server.URL+"/discs/DISC/playlists/00000/chapters?format=ogm", nil).Header.Get("ETag")
+	if chapters == ogm {
+		t.Fatal("chapter formats share an ETag")
+	}
+
+	// rewriting the playlist invalidates the tag
+	url := server.URL + "/discs/DISC/playlists/00000"
+	etag := get(t, url, nil).Header.Get("ETag")
+	path := filepath.Join(playListDir, "00000.mpls")
+	if err := os.WriteFile(path, fixture.MovieMPLS(45000*60, 0, 45000*20, 45000*40), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	response := get(t, url, http.Header{"If-None-Match": {etag}})
+	if response.StatusCode != http.StatusOK || response.Header.Get("ETag") == etag {
+		t.Fatalf("modified playlist served with status %d and ETag %s", response.StatusCode, response.Header.Get("ETag"))
+	}
+}
@@ -0,0 +1,499 @@
+// Command mplsinfo prints the content of an MPLS playlist as text or JSON.
+//
+//	mplsinfo [-json] [-angle n] 00800.mpls
+//	mplsinfo lint [-format text|json|sarif] 00800.mpls
+//	mplsinfo check [-json] BDMV
+//	mplsinfo diff [-json] 00800.mpls 00801.mpls
+//	mplsinfo graph [-format dot|json] BDMV
+//	mplsinfo export [-format edl|ffconcat|m3u] [-bdmv BDMV] 00800.mpls
+//	mplsinfo hls [-addr :8080] [-segment 6] [-bdmv BDMV] 00800.mpls
+//	mplsinfo bdinfo [-quick] [-packets n] [-bdmv BDMV] 00800.mpls
+//	mplsinfo nfo [-title name] 00800.mpls
+//	mplsinfo scan [-workers n] [-progress] DIR
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/syxxzzr/go-mpls"
+)
+
+func formatTime(seconds float64) string {
+	milliseconds := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+func printStreams(w io.Writer, kind string, streams []*go_mpls.StreamInfo) {
+	for _, stream := range streams {
+		fmt.Fprintf(w, "    %-5s 0x%04x  %s", kind, stream.PID, stream.CodingType)
+		if stream.Format != "" {
+			fmt.Fprintf(w, "  %s", stream.Format)
+		}
+		if stream.Language != "" {
+			fmt.Fprintf(w, "  %s", stream.Language)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func printSTNTable(w io.Writer, streams *go_mpls.STNTableInfo) {
+	printStreams(w, "video", streams.VideoStreamsList)
+	printStreams(w, "audio", streams.AudioStreamsList)
+	printStreams(w, "pg", streams.PGStreamsList)
+	printStreams(w, "ig", streams.IGStreamsList)
+}
+
+func printInfo(w io.Writer, info *go_mpls.PlayListInfo) {
+	fmt.Fprintf(w, "Playlist: %s\n", info.Path)
+	fmt.Fprintf(w, "Duration: %s\n", formatTime(info.Duration))
+	fmt.Fprintf(w, "Angles:   %d\n", info.NumberOfAngles)
+	fmt.Fprintf(w, "Chapters: %d\n", len(info.ChaptersList))
+	fmt.Fprintf(w, "Fingerprint: %s\n", info.Fingerprint)
+	fmt.Fprintf(w, "Signature:   %s\n", info.Signature)
+
+	fmt.Fprintln(w, "Play items:")
+	for i, playItem := range info.PlayItemsList {
+		fmt.Fprintf(w, "  %3d  %s.m2ts  %s - %s  (%s)", i, playItem.ClipName,
+			formatTime(playItem.INTime), formatTime(playItem.OUTTime), formatTime(playItem.Duration))
+		if len(playItem.AngleClipsList) > 0 {
+			fmt.Fprintf(w, "  angles: %v", playItem.AngleClipsList)
+		}
+		fmt.Fprintln(w)
+		if playItem.Streams != nil {
+			printSTNTable(w, playItem.Streams)
+		}
+	}
+
+	for _, angle := range info.AnglesList {
+		fmt.Fprintf(w, "Angle %d: %s  %v\n", angle.AngleID, formatTime(angle.Duration), angle.ClipsList)
+		for i, clipName := range angle.ClipsList {
+			fmt.Fprintf(w, "  %3d  %s.m2ts\n", i, clipName)
+			if i < len(angle.StreamsList) {
+				printSTNTable(w, angle.StreamsList[i])
+			}
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func runInfo(args []string) {
+	flags := flag.NewFlagSet("mplsinfo", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of text")
+	angleID := flags.Int("angle", 0, "print the virtual playlist of one angle")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo [-json] [-angle n] file.mpls")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	mpls, err := go_mpls.Parse(flags.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	if *angleID != 0 {
+		if mpls, err = mpls.AnglePlayList(*angleID); err != nil {
+			fail(err)
+		}
+	}
+
+	info := mpls.Info()
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(info)
+	} else {
+		printInfo(os.Stdout, info)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func runLint(args []string) {
+	flags := flag.NewFlagSet("mplsinfo lint", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	disabled := flags.String("disable", "", "comma separated rule IDs to skip")
+	severities := flags.String("severity", "", "comma separated rule=level pairs, level being note, warning or error")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo lint [-format text|json|sarif] [-disable IDs] [-severity ID=level,...] file.mpls")
+		for _, rule := range go_mpls.LintRules() {
+			fmt.Fprintf(flags.Output(), "  %s  %-7s  %s\n", rule.ID, rule.Severity, rule.Description)
+		}
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config := &go_mpls.LintConfig{Severities: map[string]go_mpls.Severity{}}
+	if *disabled != "" {
+		config.DisabledRulesList = strings.Split(*disabled, ",")
+	}
+	if *severities != "" {
+		for _, pair := range strings.Split(*severities, ",") {
+			ruleID, level, _ := strings.Cut(pair, "=")
+			severity := map[string]go_mpls.Severity{"note": go_mpls.SeverityNote, "warning": go_mpls.SeverityWarning, "error": go_mpls.SeverityError}[level]
+			if severity == 0 {
+				fail(fmt.Errorf("unknown severity %q", level))
+			}
+			config.Severities[ruleID] = severity
+		}
+	}
+
+	path := flags.Arg(0)
+	mpls, err := go_mpls.Parse(path)
+	if err != nil {
+		fail(err)
+	}
+	findings := mpls.Lint(config)
+	switch *format {
+	case "text":
+		err = go_mpls.WriteLintText(os.Stdout, path, findings)
+	case "json":
+		err = go_mpls.WriteLintJSON(os.Stdout, findings)
+	case "sarif":
+		err = go_mpls.WriteLintSARIF(os.Stdout, path, findings)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fail(err)
+	}
+	for _, finding := range findings {
+		if finding.Severity == go_mpls.SeverityError {
+			os.Exit(1)
+		}
+	}
+}
+
+func runCheck(args []string) {
+	flags := flag.NewFlagSet("mplsinfo check", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of text")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo check [-json] BDMV")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	problems, err := go_mpls.CheckDiscReferences(flags.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(problems)
+	} else {
+		for _, problem := range problems {
+			if _, err = fmt.Fprintf(os.Stdout, "%s: %s: %s: %s\n", problem.PlayListPath, problem.Location, problem.Kind, problem.Reason); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		fail(err)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("mplsinfo diff", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of text")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo diff [-json] old.mpls new.mpls")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	oldMPLS, err := go_mpls.Parse(flags.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	newMPLS, err := go_mpls.Parse(flags.Arg(1))
+	if err != nil {
+		fail(err)
+	}
+	changes := go_mpls.Diff(oldMPLS, newMPLS)
+	if *jsonOutput {
+		err = go_mpls.WriteDiffJSON(os.Stdout, changes)
+	} else {
+		err = go_mpls.WriteDiffText(os.Stdout, changes)
+	}
+	if err != nil {
+		fail(err)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runGraph(args []string) {
+	flags := flag.NewFlagSet("mplsinfo graph", flag.ExitOnError)
+	format := flags.String("format", "dot", "output format: dot or json")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo graph [-format dot|json] BDMV")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	graph, err := go_mpls.BuildDiscSegmentGraph(flags.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	switch *format {
+	case "dot":
+		err = graph.WriteDOT(os.Stdout)
+	case "json":
+		err = graph.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func runExport(args []string) {
+	flags := flag.NewFlagSet("mplsinfo export", flag.ExitOnError)
+	format := flags.String("format", "edl", "output format: edl, ffconcat or m3u")
+	bdmvRoot := flags.String("bdmv", "", "BDMV directory the clip paths are resolved against (default: parent of the PLAYLIST directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo export [-format edl|ffconcat|m3u] [-bdmv BDMV] file.mpls")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+	if *bdmvRoot == "" {
+		*bdmvRoot = filepath.Dir(filepath.Dir(path))
+	}
+	mpls, err := go_mpls.Parse(path)
+	if err != nil {
+		fail(err)
+	}
+	clips, err := mpls.LoadClipInformation(*bdmvRoot)
+	if err != nil && *format != "ffconcat" {
+		fmt.Fprintf(os.Stderr, "warning: %v, times are not made relative to the clip start\n", err)
+	}
+	switch *format {
+	case "edl":
+		err = mpls.WriteMPVEDL(os.Stdout, *bdmvRoot, clips)
+	case "ffconcat":
+		err = mpls.WriteFFConcat(os.Stdout, *bdmvRoot)
+	case "m3u":
+		err = mpls.WriteM3U(os.Stdout, *bdmvRoot, clips)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func runHLS(args []string) {
+	flags := flag.NewFlagSet("mplsinfo hls", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	segmentSeconds := flags.Float64("segment", 6, "minimum segment duration in seconds")
+	bdmvRoot := flags.String("bdmv", "", "BDMV directory holding the clips (default: parent of the PLAYLIST directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo hls [-addr :8080] [-segment 6] [-bdmv BDMV] file.mpls")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+	if *bdmvRoot == "" {
+		*bdmvRoot = filepath.Dir(filepath.Dir(path))
+	}
+	mpls, err := go_mpls.Parse(path)
+	if err != nil {
+		fail(err)
+	}
+	clips, err := mpls.LoadClipInformation(*bdmvRoot)
+	if err != nil {
+		fail(err)
+	}
+	presentation, err := mpls.HLSPresentation(clips, int(*segmentSeconds*45000))
+	if err != nil {
+		fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "serving http://%s/master.m3u8\n", *addr)
+	fail(http.ListenAndServe(*addr, go_mpls.NewHLSHandler(*bdmvRoot, presentation)))
+}
+
+func runBDInfo(args []string) {
+	flags := flag.NewFlagSet("mplsinfo bdinfo", flag.ExitOnError)
+	quick := flags.Bool("quick", false, "print the quick summary only")
+	maxPackets := flags.Int("packets", 0, "number of packets scanned per clip (default: whole clips)")
+	bdmvRoot := flags.String("bdmv", "", "BDMV directory holding the clips (default: parent of the PLAYLIST directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo bdinfo [-quick] [-packets n] [-bdmv BDMV] file.mpls")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+	if *bdmvRoot == "" {
+		*bdmvRoot = filepath.Dir(filepath.Dir(path))
+	}
+	mpls, err := go_mpls.Parse(path)
+	if err != nil {
+		fail(err)
+	}
+	report, err := mpls.BDInfoReport(*bdmvRoot, *maxPackets)
+	if err != nil {
+		fail(err)
+	}
+	if *quick {
+		err = report.WriteQuickSummary(os.Stdout)
+	} else {
+		err = report.WriteFull(os.Stdout)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func runNFO(args []string) {
+	flags := flag.NewFlagSet("mplsinfo nfo", flag.ExitOnError)
+	title := flags.String("title", "", "movie title")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo nfo [-title name] file.mpls")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	mpls, err := go_mpls.Parse(flags.Arg(0))
+	if err != nil {
+		fail(err)
+	}
+	if err := mpls.WriteNFO(os.Stdout, *title); err != nil {
+		fail(err)
+	}
+}
+
+func runScan(args []string) {
+	flags := flag.NewFlagSet("mplsinfo scan", flag.ExitOnError)
+	workers := flags.Int("workers", 0, "number of playlists parsed at once (default: number of CPUs)")
+	showProgress := flags.Bool("progress", false, "report progress on standard error")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mplsinfo scan [-workers n] [-progress] DIR")
+		fmt.Fprintln(flags.Output(), "Prints one JSON object per playlist of the BDMV directories and ISO images under DIR.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	scanner := &go_mpls.Scanner{Workers: *workers}
+	if *showProgress {
+		scanner.Progress = func(progress go_mpls.ScanProgress) {
+			fmt.Fprintf(os.Stderr, "\r%d/%d playlists of %d discs, %d failed", progress.NumberOfScanned,
+				progress.NumberOfPlayLists, progress.NumberOfDiscs, progress.NumberOfFailed)
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	var encodeErr error
+	err := scanner.Scan(ctx, flags.Arg(0), func(result *go_mpls.ScanResult) {
+		if encodeErr == nil {
+			encodeErr = encoder.Encode(result)
+		}
+	})
+	if *showProgress {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err == nil {
+		err = encodeErr
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			runLint(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "graph":
+			runGraph(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "hls":
+			runHLS(os.Args[2:])
+			return
+		case "bdinfo":
+			runBDInfo(os.Args[2:])
+			return
+		case "nfo":
+			runNFO(os.Args[2:])
+			return
+		case "scan":
+			runScan(os.Args[2:])
+			return
+		}
+	}
+	runInfo(os.Args[1:])
+}
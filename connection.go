@@ -0,0 +1,137 @@
+package go_mpls
+
+import (
+	"fmt"
+)
+
+const (
+	NonSeamlessConnection        = 0x01
+	SeamlessCleanBreakConnection = 0x05
+	SeamlessConnection           = 0x06
+)
+
+// ConnectionViolation is a problem found at the boundary entering a play
+// item. PlayItemID 0 is the start of the playlist, where only the clip
+// references of the first play item are checked.
+type ConnectionViolation struct {
+	PlayItemID          int
+	ConnectionCondition int
+	Reason              string
+}
+
+// compareStreamLayouts reports the differences between the stream tables of
+// two play items. Seamless connections require the same streams in the same
+// order with the same PIDs and coding types.
+func compareStreamLayouts(previous, current *STNTable) []string {
+	var differencesList []string = nil
+	if previous == nil || current == nil {
+		if previous != current {
+			differencesList = append(differencesList, "stream table is missing on one side")
+		}
+		return differencesList
+	}
+
+	compare := func(kind string, previousStreams, currentStreams []*Stream) {
+		if len(previousStreams) != len(currentStreams) {
+			differencesList = append(differencesList, fmt.Sprintf("number of %s streams changes from %d to %d", kind, len(previousStreams), len(currentStreams)))
+			return
+		}
+		for i := range previousStreams {
+			previousStream, currentStream := previousStreams[i], currentStreams[i]
+			if previousStream.StreamEntry.RefToStreamPID != currentStream.StreamEntry.RefToStreamPID ||
+				previousStream.StreamAttributes.StreamCodingType != currentStream.StreamAttributes.StreamCodingType {
+				differencesList = append(differencesList, fmt.Sprintf("%s stream %d changes from 0x%04x %v to 0x%04x %v", kind, i+1,
+					previousStream.StreamEntry.RefToStreamPID, previousStream.StreamAttributes.StreamCodingType,
+					currentStream.StreamEntry.RefToStreamPID, currentStream.StreamAttributes.StreamCodingType))
+			}
+		}
+	}
+	compare("primary video", previous.PrimaryVideoStreamsList, current.PrimaryVideoStreamsList)
+	compare("primary audio", previous.PrimaryAudioStreamsList, current.PrimaryAudioStreamsList)
+	compare("PG", previous.PrimaryPGStreamsList, current.PrimaryPGStreamsList)
+	compare("IG", previous.PrimaryIGStreamsList, current.PrimaryIGStreamsList)
+	compare("secondary audio", previous.SecondaryAudioStreamsList, current.SecondaryAudioStreamsList)
+	compare("secondary video", previous.SecondaryVideoStreamsList, current.SecondaryVideoStreamsList)
+	return differencesList
+}
+
+// ValidateConnections checks every play item boundary against the clip
+// information in clips, keyed by clip name: the referenced STC sequences
+// must exist and hold the IN and OUT times, seamless connections (5 and 6)
+// must keep the stream layout, and connection condition 6 must keep the STC
+// continuous.
+func (mpls *MPLS) ValidateConnections(clips map[string]*CLPI) []*ConnectionViolation {
+	var violationsList []*ConnectionViolation = nil
+	report := func(playItemID int, playItem *PlayItem, format string, args ...any) {
+		violationsList = append(violationsList, &ConnectionViolation{
+			PlayItemID:          playItemID,
+			ConnectionCondition: playItem.ConnectionCondition,
+			Reason:              fmt.Sprintf(format, args...),
+		})
+	}
+
+	stcSequences := make([]*STCSequence, len(mpls.PlayList.PlayItemList))
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		for angleID := 1; angleID <= max(1, playItem.NumberOfAngles); angleID++ {
+			angle := playItem.AngleClip(angleID)
+			clpi, ok := clips[angle.ClipInformationFileName]
+			if !ok {
+				report(i, playItem, "clip information of %s is missing", angle.ClipInformationFileName)
+				continue
+			}
+			stcSequence := clpi.STCSequence(angle.RefToSTCID)
+			if stcSequence == nil {
+				report(i, playItem, "RefToSTCID %d does not exist in %s", angle.RefToSTCID, angle.ClipInformationFileName)
+				continue
+			}
+			if playItem.INTimeTicks < stcSequence.PresentationStartTime || playItem.OUTTimeTicks > stcSequence.PresentationEndTime {
+				report(i, playItem, "IN/OUT time %d-%d is outside STC sequence %d of %s (%d-%d)", playItem.INTimeTicks, playItem.OUTTimeTicks,
+					angle.RefToSTCID, angle.ClipInformationFileName, stcSequence.PresentationStartTime, stcSequence.PresentationEndTime)
+			}
+			if angleID == 1 {
+				stcSequences[i] = stcSequence
+			}
+		}
+	}
+
+	for i := 1; i < len(mpls.PlayList.PlayItemList); i++ {
+		previous, current := mpls.PlayList.PlayItemList[i-1], mpls.PlayList.PlayItemList[i]
+		switch current.ConnectionCondition {
+		case NonSeamlessConnection:
+			continue
+		case SeamlessCleanBreakConnection, SeamlessConnection:
+		default:
+			report(i, current, "connection condition %d is reserved", current.ConnectionCondition)
+			continue
+		}
+
+		for _, difference := range compareStreamLayouts(previous.STNTable, current.STNTable) {
+			report(i, current, "%s", difference)
+		}
+
+		if current.ConnectionCondition != SeamlessConnection || stcSequences[i-1] == nil || stcSequences[i] == nil {
+			continue
+		}
+		if previous.ClipInformationFileName == current.ClipInformationFileName && previous.RefToSTCID == current.RefToSTCID {
+			if current.INTimeTicks != previous.OUTTimeTicks {
+				report(i, current, "STC is not continuous: IN time %d does not follow OUT time %d", current.INTimeTicks, previous.OUTTimeTicks)
+			}
+		} else if previous.OUTTimeTicks != stcSequences[i-1].PresentationEndTime ||
+			current.INTimeTicks != stcSequences[i].PresentationStartTime ||
+			stcSequences[i].PresentationStartTime != stcSequences[i-1].PresentationEndTime {
+			report(i, current, "STC is not continuous from %s STC %d to %s STC %d", previous.ClipInformationFileName, previous.RefToSTCID,
+				current.ClipInformationFileName, current.RefToSTCID)
+		}
+	}
+	return violationsList
+}
+
+// ValidateDiscConnections loads the clip information of the playlist from
+// the disc and validates its connections.
+func (mpls *MPLS) ValidateDiscConnections(bdmvRoot string) ([]*ConnectionViolation, error) {
+	clips, err := mpls.LoadClipInformation(bdmvRoot)
+	if err != nil {
+		return nil, err
+	}
+	return mpls.ValidateConnections(clips), nil
+}
@@ -0,0 +1,83 @@
+package go_mpls
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// byteCursor reads big endian fields of an MPLS file in order. A read past
+// the end of the data records an error, returns zeros and leaves the
+// cursor in place, so a section can be decoded without checking every
+// field; the first error is kept in err.
+type byteCursor struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (c *byteCursor) remaining() int {
+	return max(0, len(c.data)-c.offset)
+}
+
+// check records an error unless n bytes are left.
+func (c *byteCursor) check(n int) bool {
+	if c.err != nil {
+		return false
+	}
+	if n < 0 || c.offset < 0 || n > len(c.data)-c.offset {
+		c.err = fmt.Errorf("invalid file: %d bytes at offset %d out of range", n, c.offset)
+		return false
+	}
+	return true
+}
+
+func (c *byteCursor) next(n int) []byte {
+	if !c.check(n) {
+		return nil
+	}
+	c.offset += n
+	return c.data[c.offset-n : c.offset : c.offset]
+}
+
+func (c *byteCursor) seek(offset int) {
+	c.offset = offset
+}
+
+func (c *byteCursor) skip(n int) {
+	c.offset += n
+}
+
+func (c *byteCursor) readUint8() int {
+	if !c.check(1) {
+		return 0
+	}
+	c.offset++
+	return int(c.data[c.offset-1])
+}
+
+func (c *byteCursor) readUint16() int {
+	if rawData := c.next(2); rawData != nil {
+		return int(binary.BigEndian.Uint16(rawData))
+	}
+	return 0
+}
+
+func (c *byteCursor) readUint32() int {
+	if rawData := c.next(4); rawData != nil {
+		return int(binary.BigEndian.Uint32(rawData))
+	}
+	return 0
+}
+
+// readBytes returns the n bytes of a fixed size field, or n zeros past the
+// end of the data.
+func (c *byteCursor) readBytes(n int) []byte {
+	if rawData := c.next(n); rawData != nil {
+		return rawData
+	}
+	return make([]byte, max(0, n))
+}
+
+func (c *byteCursor) readString(n int) string {
+	return string(c.next(n))
+}
@@ -0,0 +1,290 @@
+package go_mpls
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type ChangeKind string
+
+const (
+	AddedChange   ChangeKind = "added"
+	RemovedChange ChangeKind = "removed"
+	ChangedChange ChangeKind = "changed"
+	// MovedChange is a play item whose clip is found in both playlists but
+	// at another position in the play item order.
+	MovedChange ChangeKind = "moved"
+)
+
+// Change is one difference between two playlists. Subject names the play
+// item, stream or mark, using the play item index of the new playlist
+// except for removed play items. Field is set for changed values only; Old
+// and New hold the removed and added values in a readable form.
+type Change struct {
+	Kind    ChangeKind `json:"kind"`
+	Subject string     `json:"subject"`
+	Field   string     `json:"field,omitempty"`
+	Old     string     `json:"old,omitempty"`
+	New     string     `json:"new,omitempty"`
+}
+
+func formatTicks(ticks int) string {
+	milliseconds := (int64(ticks) + 22) / 45
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+// alignPlayItems matches the play items of two playlists by clip name with
+// a longest common subsequence, returning the index pairs in order.
+func alignPlayItems(oldItems, newItems []*PlayItem) [][2]int {
+	lengths := make([][]int, len(oldItems)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newItems)+1)
+	}
+	for i := len(oldItems) - 1; i >= 0; i-- {
+		for j := len(newItems) - 1; j >= 0; j-- {
+			if oldItems[i].ClipInformationFileName == newItems[j].ClipInformationFileName {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var pairsList [][2]int = nil
+	for i, j := 0, 0; i < len(oldItems) && j < len(newItems); {
+		switch {
+		case oldItems[i].ClipInformationFileName == newItems[j].ClipInformationFileName:
+			pairsList = append(pairsList, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairsList
+}
+
+type streamKey struct {
+	pid      int
+	language string
+}
+
+func describeStream(info *StreamInfo) string {
+	description := info.CodingType
+	if info.Format != "" {
+		description += " " + info.Format
+	}
+	if info.SubPathID != nil {
+		description += fmt.Sprintf(" (sub path %d)", *info.SubPathID)
+	}
+	return description
+}
+
+func diffStreams(subject string, oldStn, newStn *STNTable, report func(change *Change)) {
+	if oldStn == nil {
+		oldStn = &STNTable{}
+	}
+	if newStn == nil {
+		newStn = &STNTable{}
+	}
+	oldLists, newLists := stnStreamLists(oldStn), stnStreamLists(newStn)
+	names := make([]string, 0, len(oldLists))
+	for name := range oldLists {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		kind := strings.TrimSuffix(name, "StreamsList")
+		streamSubject := func(key streamKey) string {
+			if key.language == "" {
+				return fmt.Sprintf("%s %s 0x%04x", subject, kind, key.pid)
+			}
+			return fmt.Sprintf("%s %s 0x%04x %s", subject, kind, key.pid, key.language)
+		}
+		oldStreams := map[streamKey]int{}
+		for k, stream := range oldLists[name] {
+			oldStreams[streamKey{stream.StreamEntry.RefToStreamPID, stream.StreamAttributes.LanguageCode}] = k
+		}
+		newStreams := map[streamKey]int{}
+		for k, stream := range newLists[name] {
+			newStreams[streamKey{stream.StreamEntry.RefToStreamPID, stream.StreamAttributes.LanguageCode}] = k
+		}
+
+		for k, stream := range oldLists[name] {
+			key := streamKey{stream.StreamEntry.RefToStreamPID, stream.StreamAttributes.LanguageCode}
+			if _, ok := newStreams[key]; !ok {
+				report(&Change{Kind: RemovedChange, Subject: streamSubject(key), Old: fmt.Sprintf("stream %d, %s", k+1, describeStream(newStreamInfo(stream)))})
+			}
+		}
+		for k, stream := range newLists[name] {
+			key := streamKey{stream.StreamEntry.RefToStreamPID, stream.StreamAttributes.LanguageCode}
+			oldK, ok := oldStreams[key]
+			if !ok {
+				report(&Change{Kind: AddedChange, Subject: streamSubject(key), New: fmt.Sprintf("stream %d, %s", k+1, describeStream(newStreamInfo(stream)))})
+				continue
+			}
+			if oldK != k {
+				report(&Change{Kind: ChangedChange, Subject: streamSubject(key), Field: "StreamNumber", Old: fmt.Sprint(oldK + 1), New: fmt.Sprint(k + 1)})
+			}
+			oldDescription, newDescription := describeStream(newStreamInfo(oldLists[name][oldK])), describeStream(newStreamInfo(stream))
+			if oldDescription != newDescription {
+				report(&Change{Kind: ChangedChange, Subject: streamSubject(key), Field: "StreamAttributes", Old: oldDescription, New: newDescription})
+			}
+		}
+	}
+}
+
+func diffPlayItems(subject string, oldItem, newItem *PlayItem, report func(change *Change)) {
+	changed := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			report(&Change{Kind: ChangedChange, Subject: subject, Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	changed("INTime", formatTicks(oldItem.INTimeTicks), formatTicks(newItem.INTimeTicks))
+	changed("OUTTime", formatTicks(oldItem.OUTTimeTicks), formatTicks(newItem.OUTTimeTicks))
+	changed("ConnectionCondition", fmt.Sprint(oldItem.ConnectionCondition), fmt.Sprint(newItem.ConnectionCondition))
+	changed("RefToSTCID", fmt.Sprint(oldItem.RefToSTCID), fmt.Sprint(newItem.RefToSTCID))
+
+	angleClips := func(item *PlayItem) string {
+		var namesList []string = nil
+		for _, angle := range item.AnglesList {
+			namesList = append(namesList, angle.ClipInformationFileName)
+		}
+		return strings.Join(namesList, ",")
+	}
+	changed("AnglesList", angleClips(oldItem), angleClips(newItem))
+	diffStreams(subject, oldItem.STNTable, newItem.STNTable, report)
+}
+
+type markKey struct {
+	timeTicks int
+	markType  int
+}
+
+func playListMarks(mpls *MPLS) []markKey {
+	var marksList []markKey = nil
+	if mpls.PlayListMark == nil {
+		return marksList
+	}
+	for _, mark := range mpls.PlayListMark.PlayListMarksList {
+		marksList = append(marksList, markKey{int(mpls.playListTime(mark.RefToPlayItemID, mark.MarkTimeTicks) / 2), mark.MarkType})
+	}
+	return marksList
+}
+
+// Diff compares two playlists. Play items are aligned by clip name, streams
+// of aligned play items by PID and language within each stream list, and
+// marks by their time on the playlist timeline.
+func Diff(oldMPLS, newMPLS *MPLS) []*Change {
+	var changesList []*Change = nil
+	report := func(change *Change) {
+		changesList = append(changesList, change)
+	}
+	changed := func(subject, field, oldValue, newValue string) {
+		if oldValue != newValue {
+			report(&Change{Kind: ChangedChange, Subject: subject, Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	changed("playlist", "VersionNumber", fmt.Sprint(oldMPLS.VersionNumber), fmt.Sprint(newMPLS.VersionNumber))
+	if oldMPLS.ApplicationInfoPlaylist != nil && newMPLS.ApplicationInfoPlaylist != nil {
+		changed("playlist", "PlaybackType", fmt.Sprint(oldMPLS.ApplicationInfoPlaylist.PlaybackType), fmt.Sprint(newMPLS.ApplicationInfoPlaylist.PlaybackType))
+	}
+	changed("playlist", "Duration", formatTicks(oldMPLS.DurationTicks()), formatTicks(newMPLS.DurationTicks()))
+
+	oldItems, newItems := oldMPLS.PlayList.PlayItemList, newMPLS.PlayList.PlayItemList
+	playItemSubject := func(id int, item *PlayItem) string {
+		return fmt.Sprintf("play item %d (%s)", id, item.ClipInformationFileName)
+	}
+	pairs := alignPlayItems(oldItems, newItems)
+	oldMatched, newMatched := map[int]bool{}, map[int]bool{}
+	for _, pair := range pairs {
+		oldMatched[pair[0]], newMatched[pair[1]] = true, true
+	}
+
+	// A clip left over on both sides has moved rather than been replaced.
+	for j, newItem := range newItems {
+		if newMatched[j] {
+			continue
+		}
+		for i, oldItem := range oldItems {
+			if !oldMatched[i] && oldItem.ClipInformationFileName == newItem.ClipInformationFileName {
+				oldMatched[i], newMatched[j] = true, true
+				pairs = append(pairs, [2]int{i, j})
+				report(&Change{Kind: MovedChange, Subject: playItemSubject(j, newItem), Old: fmt.Sprint(i), New: fmt.Sprint(j)})
+				break
+			}
+		}
+	}
+	for i, oldItem := range oldItems {
+		if !oldMatched[i] {
+			report(&Change{Kind: RemovedChange, Subject: playItemSubject(i, oldItem), Old: formatTicks(oldItem.DurationTicks())})
+		}
+	}
+	for j, newItem := range newItems {
+		if !newMatched[j] {
+			report(&Change{Kind: AddedChange, Subject: playItemSubject(j, newItem), New: formatTicks(newItem.DurationTicks())})
+		}
+	}
+	slices.SortFunc(pairs, func(a, b [2]int) int { return a[1] - b[1] })
+	for _, pair := range pairs {
+		diffPlayItems(playItemSubject(pair[1], newItems[pair[1]]), oldItems[pair[0]], newItems[pair[1]], report)
+	}
+
+	oldMarks, newMarks := playListMarks(oldMPLS), playListMarks(newMPLS)
+	markSubject := func(mark markKey) string {
+		return fmt.Sprintf("mark at %s", formatTicks(mark.timeTicks))
+	}
+	findMark := func(marks []markKey, timeTicks int) int {
+		return slices.IndexFunc(marks, func(mark markKey) bool { return mark.timeTicks == timeTicks })
+	}
+	for _, mark := range oldMarks {
+		if findMark(newMarks, mark.timeTicks) < 0 {
+			report(&Change{Kind: RemovedChange, Subject: markSubject(mark), Old: fmt.Sprintf("mark type %d", mark.markType)})
+		}
+	}
+	for _, mark := range newMarks {
+		k := findMark(oldMarks, mark.timeTicks)
+		if k < 0 {
+			report(&Change{Kind: AddedChange, Subject: markSubject(mark), New: fmt.Sprintf("mark type %d", mark.markType)})
+		} else {
+			changed(markSubject(mark), "MarkType", fmt.Sprint(oldMarks[k].markType), fmt.Sprint(mark.markType))
+		}
+	}
+	return changesList
+}
+
+// WriteDiffText writes one line per change, prefixed with +, -, ~ or > for
+// added, removed, changed and moved.
+func WriteDiffText(w io.Writer, changes []*Change) error {
+	prefixes := map[ChangeKind]string{AddedChange: "+", RemovedChange: "-", ChangedChange: "~", MovedChange: ">"}
+	for _, change := range changes {
+		var err error
+		switch change.Kind {
+		case ChangedChange:
+			_, err = fmt.Fprintf(w, "%s %s: %s %s -> %s\n", prefixes[change.Kind], change.Subject, change.Field, change.Old, change.New)
+		case MovedChange:
+			_, err = fmt.Fprintf(w, "%s %s: moved from %s to %s\n", prefixes[change.Kind], change.Subject, change.Old, change.New)
+		default:
+			_, err = fmt.Fprintf(w, "%s %s: %s\n", prefixes[change.Kind], change.Subject, change.Old+change.New)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func WriteDiffJSON(w io.Writer, changes []*Change) error {
+	if changes == nil {
+		changes = []*Change{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(changes)
+}
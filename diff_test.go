@@ -0,0 +1,72 @@
+package go_mpls
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestStream(pid int, codingType StreamCodingType, language string) *Stream {
+	return &Stream{
+		StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: pid},
+		StreamAttributes: &StreamAttributes{StreamCodingType: codingType, AudioFormat: Stereo, LanguageCode: language},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldMPLS := &MPLS{
+		VersionNumber: 200,
+		PlayList: &PlayList{PlayItemList: []*PlayItem{
+			{ClipInformationFileName: "00001", OUTTimeTicks: 45000, STNTable: &STNTable{PrimaryAudioStreamsList: []*Stream{
+				newTestStream(0x1100, DolbyDigitalAudio, "eng"),
+				newTestStream(0x1101, DolbyDigitalAudio, "fra"),
+			}}},
+			{ClipInformationFileName: "00002", OUTTimeTicks: 45000},
+			{ClipInformationFileName: "00003", OUTTimeTicks: 45000},
+		}},
+		PlayListMark: &PlayListMark{PlayListMarksList: []*PlayListMarkItem{
+			{MarkType: EntryMark, RefToPlayItemID: 0},
+			{MarkType: EntryMark, RefToPlayItemID: 1},
+		}},
+	}
+	newMPLS := &MPLS{
+		VersionNumber: 200,
+		PlayList: &PlayList{PlayItemList: []*PlayItem{
+			{ClipInformationFileName: "00002", OUTTimeTicks: 45000},
+			{ClipInformationFileName: "00001", OUTTimeTicks: 90000, STNTable: &STNTable{PrimaryAudioStreamsList: []*Stream{
+				newTestStream(0x1101, DolbyDigitalAudio, "fra"),
+				newTestStream(0x1102, DTSAudio, "deu"),
+			}}},
+		}},
+		PlayListMark: &PlayListMark{PlayListMarksList: []*PlayListMarkItem{
+			{MarkType: EntryMark, RefToPlayItemID: 0},
+			{MarkType: EntryMark, RefToPlayItemID: 1},
+			{MarkType: EntryMark, RefToPlayItemID: 1, MarkTimeTicks: 45000},
+		}},
+	}
+
+	changes := Diff(oldMPLS, newMPLS)
+	var text bytes.Buffer
+	if err := WriteDiffText(&text, changes); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"> play item 1 (00001): moved from 0 to 1",
+		"- play item 2 (00003): 00:00:01.000",
+		"~ play item 1 (00001): OUTTime 00:00:01.000 -> 00:00:02.000",
+		"- play item 1 (00001) PrimaryAudio 0x1100 eng: stream 1, Dolby Digital stereo",
+		"+ play item 1 (00001) PrimaryAudio 0x1102 deu: stream 2, DTS stereo",
+		"~ play item 1 (00001) PrimaryAudio 0x1101 fra: StreamNumber 2 -> 1",
+		"+ mark at 00:00:02.000: mark type 1",
+	} {
+		if !strings.Contains(text.String(), line+"\n") {
+			t.Fatalf("missing %q in\n%s", line, text.String())
+		}
+	}
+	if len(changes) != 7 {
+		t.Fatalf("unexpected changes\n%s", text.String())
+	}
+	if changes := Diff(oldMPLS, oldMPLS); len(changes) != 0 {
+		t.Fatalf("unexpected changes %#v", changes)
+	}
+}
@@ -0,0 +1,170 @@
+package go_mpls
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DurationTolerance is the largest difference in milliseconds between two
+// play item durations that DurationSignature comparisons still treat as
+// the same segment.
+const DurationTolerance = 1000
+
+// Fingerprint identifies a playlist exactly. PlayItemHashesList holds one
+// SHA-256 per play item over its clip name, RefToSTCID, IN and OUT ticks,
+// angle clips and stream layout; Hash is the SHA-256 over all of them.
+type Fingerprint struct {
+	Hash               string   `json:"hash"`
+	PlayItemHashesList []string `json:"playItems"`
+}
+
+// DurationSignature describes a playlist by the durations of its play items
+// in milliseconds only, so that releases numbering their clips differently
+// still match.
+type DurationSignature struct {
+	DurationsList []int `json:"durations"`
+}
+
+func writeStreamLayout(data []byte, stn *STNTable) []byte {
+	if stn == nil {
+		return data
+	}
+	lists := stnStreamLists(stn)
+	names := make([]string, 0, len(lists))
+	for name := range lists {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		data = append(data, name...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(lists[name])))
+		for _, stream := range lists[name] {
+			data = append(data, byte(stream.StreamEntry.StreamType), byte(stream.StreamEntry.RefToSubPathID), byte(stream.StreamEntry.RefToSubClipID))
+			data = binary.BigEndian.AppendUint16(data, uint16(stream.StreamEntry.RefToStreamPID))
+			data = append(data, byte(stream.StreamAttributes.StreamCodingType))
+			data = append(data, stream.StreamAttributes.LanguageCode...)
+			data = append(data, 0)
+		}
+	}
+	return data
+}
+
+// Fingerprint hashes the play items of the playlist. Marks, sub paths and
+// application info are left out, so re-authored menus do not change it.
+func (playList *PlayList) Fingerprint() *Fingerprint {
+	fingerprint := &Fingerprint{PlayItemHashesList: []string{}}
+	total := sha256.New()
+	for _, playItem := range playList.PlayItemList {
+		var data []byte = nil
+		data = append(data, playItem.ClipInformationFileName...)
+		data = append(data, 0, byte(playItem.RefToSTCID))
+		data = binary.BigEndian.AppendUint32(data, uint32(playItem.INTimeTicks))
+		data = binary.BigEndian.AppendUint32(data, uint32(playItem.OUTTimeTicks))
+		for _, angle := range playItem.AnglesList {
+			data = append(data, angle.ClipInformationFileName...)
+			data = append(data, 0, byte(angle.RefToSTCID))
+		}
+		data = writeStreamLayout(data, playItem.STNTable)
+
+		hash := sha256.Sum256(data)
+		total.Write(hash[:])
+		fingerprint.PlayItemHashesList = append(fingerprint.PlayItemHashesList, hex.EncodeToString(hash[:]))
+	}
+	fingerprint.Hash = hex.EncodeToString(total.Sum(nil))
+	return fingerprint
+}
+
+// longestCommonSubsequence returns the largest total weight of the pairs
+// matched in order between two sequences of lengths m and n.
+func longestCommonSubsequence(m, n int, match func(i, j int) (int, bool)) int {
+	weights := make([][]int, m+1)
+	for i := range weights {
+		weights[i] = make([]int, n+1)
+	}
+	for i := m - 1; i >= 0; i-- {
+		for j := n - 1; j >= 0; j-- {
+			weights[i][j] = max(weights[i+1][j], weights[i][j+1])
+			if weight, ok := match(i, j); ok {
+				weights[i][j] = max(weights[i][j], weights[i+1][j+1]+weight)
+			}
+		}
+	}
+	return weights[0][0]
+}
+
+// Similarity returns 1 for identical fingerprints and otherwise the share
+// of play items found in the same order in both, from 0 to 1.
+func (fingerprint *Fingerprint) Similarity(other *Fingerprint) float64 {
+	if fingerprint.Hash == other.Hash {
+		return 1
+	}
+	m, n := len(fingerprint.PlayItemHashesList), len(other.PlayItemHashesList)
+	matched := longestCommonSubsequence(m, n, func(i, j int) (int, bool) {
+		return 1, fingerprint.PlayItemHashesList[i] == other.PlayItemHashesList[j]
+	})
+	return 2 * float64(matched) / float64(m+n)
+}
+
+func (fingerprint *Fingerprint) String() string {
+	return fingerprint.Hash
+}
+
+func (playList *PlayList) DurationSignature() *DurationSignature {
+	signature := &DurationSignature{DurationsList: []int{}}
+	for _, playItem := range playList.PlayItemList {
+		signature.DurationsList = append(signature.DurationsList, (playItem.DurationTicks()+22)/45)
+	}
+	return signature
+}
+
+// Similarity returns the share of the total duration of both signatures
+// covered by play items of matching duration in the same order, from 0 to
+// 1. Durations match when they differ by at most DurationTolerance.
+func (signature *DurationSignature) Similarity(other *DurationSignature) float64 {
+	total := 0
+	for _, duration := range signature.DurationsList {
+		total += duration
+	}
+	for _, duration := range other.DurationsList {
+		total += duration
+	}
+	if total == 0 {
+		return 1
+	}
+
+	matched := longestCommonSubsequence(len(signature.DurationsList), len(other.DurationsList), func(i, j int) (int, bool) {
+		a, b := signature.DurationsList[i], other.DurationsList[j]
+		return a + b, max(a, b)-min(a, b) <= DurationTolerance
+	})
+	return float64(matched) / float64(total)
+}
+
+// String encodes the signature as the play item durations separated by
+// dashes, which ParseDurationSignature reads back.
+func (signature *DurationSignature) String() string {
+	var durationsList []string = nil
+	for _, duration := range signature.DurationsList {
+		durationsList = append(durationsList, strconv.Itoa(duration))
+	}
+	return strings.Join(durationsList, "-")
+}
+
+func ParseDurationSignature(s string) (*DurationSignature, error) {
+	signature := &DurationSignature{DurationsList: []int{}}
+	if s == "" {
+		return signature, nil
+	}
+	for _, field := range strings.Split(s, "-") {
+		duration, err := strconv.Atoi(field)
+		if err != nil || duration < 0 {
+			return nil, errors.New("invalid duration signature")
+		}
+		signature.DurationsList = append(signature.DurationsList, duration)
+	}
+	return signature, nil
+}
@@ -0,0 +1,51 @@
+package go_mpls
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	stnTable := &STNTable{PrimaryAudioStreamsList: []*Stream{newTestStream(0x1100, DolbyDigitalAudio, "eng")}}
+	playList := &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00001", OUTTimeTicks: 45000 * 600, STNTable: stnTable},
+		{ClipInformationFileName: "00002", OUTTimeTicks: 45000 * 1200, STNTable: stnTable},
+		{ClipInformationFileName: "00003", OUTTimeTicks: 45000 * 300, STNTable: stnTable},
+	}}
+	regional := &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00011", OUTTimeTicks: 45000 * 600, STNTable: stnTable},
+		{ClipInformationFileName: "00012", OUTTimeTicks: 45000*1200 + 20000, STNTable: stnTable},
+		{ClipInformationFileName: "00013", OUTTimeTicks: 45000 * 30, STNTable: stnTable},
+	}}
+
+	fingerprint := playList.Fingerprint()
+	if fingerprint.Hash != playList.Fingerprint().Hash || fingerprint.Similarity(playList.Fingerprint()) != 1 {
+		t.Fatal("fingerprint is not stable")
+	}
+	if fingerprint.Similarity(regional.Fingerprint()) != 0 {
+		t.Fatal("fingerprints of different clips match")
+	}
+
+	dubbed := &PlayList{PlayItemList: slices.Clone(playList.PlayItemList)}
+	dubbed.PlayItemList[2] = &PlayItem{ClipInformationFileName: "00003", OUTTimeTicks: 45000 * 300,
+		STNTable: &STNTable{PrimaryAudioStreamsList: []*Stream{newTestStream(0x1100, DolbyDigitalAudio, "fra")}}}
+	if similarity := fingerprint.Similarity(dubbed.Fingerprint()); math.Abs(similarity-2.0/3) > 1e-9 {
+		t.Fatalf("unexpected similarity %f", similarity)
+	}
+
+	signature := playList.DurationSignature()
+	if signature.String() != "600000-1200000-300000" {
+		t.Fatalf("unexpected signature %s", signature)
+	}
+	parsed, err := ParseDurationSignature(regional.DurationSignature().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if similarity := signature.Similarity(parsed); math.Abs(similarity-3600444/3930444.0) > 1e-9 {
+		t.Fatalf("unexpected similarity %f", similarity)
+	}
+	if _, err := ParseDurationSignature("1-x"); err == nil {
+		t.Fatal("invalid signature parsed")
+	}
+}
@@ -2,10 +2,10 @@ package go_mpls
 
 import (
 	"bytes"
-	"encoding/binary"
 	"errors"
 	"os"
 	"strconv"
+	"sync"
 )
 
 func parseUOMaskTable(rawData []byte) *UOMaskTable {
@@ -43,25 +43,27 @@ func parseUOMaskTable(rawData []byte) *UOMaskTable {
 	}
 }
 
-func parseStreamEntry(rawData []byte) *StreamEntry {
-	length := int(rawData[0])
-	streamType := int(rawData[1])
+func parseStreamEntry(c *byteCursor, streamEntry *StreamEntry) {
+	start := c.offset
+	length := c.readUint8()
+	streamType := c.readUint8()
 
 	refToSubPathID := 0
 	refToSubClipID := 0
 	refToStreamPID := 0
 	if streamType == 0x01 {
-		refToStreamPID = int(binary.BigEndian.Uint16(rawData[2:4]))
+		refToStreamPID = c.readUint16()
 	} else if streamType == 0x02 {
-		refToSubPathID = int(rawData[2])
-		refToSubClipID = int(rawData[3])
-		refToStreamPID = int(binary.BigEndian.Uint16(rawData[4:6]))
+		refToSubPathID = c.readUint8()
+		refToSubClipID = c.readUint8()
+		refToStreamPID = c.readUint16()
 	} else if streamType == 0x03 || streamType == 0x04 {
-		refToSubPathID = int(rawData[2])
-		refToStreamPID = int(binary.BigEndian.Uint16(rawData[3:5]))
+		refToSubPathID = c.readUint8()
+		refToStreamPID = c.readUint16()
 	}
+	c.seek(start + length + 1)
 
-	return &StreamEntry{
+	*streamEntry = StreamEntry{
 		Length:         length,
 		StreamType:     streamType,
 		RefToSubPathID: refToSubPathID,
@@ -70,9 +72,10 @@ func parseStreamEntry(rawData []byte) *StreamEntry {
 	}
 }
 
-func parseStreamAttributes(rawData []byte) *StreamAttributes {
-	length := int(rawData[0])
-	streamCodingType := StreamCodingType(rawData[1])
+func parseStreamAttributes(c *byteCursor, streamAttributes *StreamAttributes) {
+	start := c.offset
+	length := c.readUint8()
+	streamCodingType := StreamCodingType(c.readUint8())
 
 	videoFormat := VideoFormat(0)
 	frameRate := FrameRate(0)
@@ -86,27 +89,33 @@ func parseStreamAttributes(rawData []byte) *StreamAttributes {
 	characterCode := CharacterCode(0)
 
 	if streamCodingType == 0x24 {
-		videoFormat = VideoFormat((rawData[2] & 0b11110000) >> 4)
-		frameRate = FrameRate(rawData[2] & 0b00001111)
-		dynamicRangeType = DynamicRangeType((rawData[3] & 0b11110000) >> 4)
-		colorSpace = ColorSpace(rawData[3] & 0b00001111)
-		crFlag = (rawData[4] & (1 << 7)) != 0
-		hdrPlusFlag = (rawData[4] & (1 << 6)) != 0
+		format := c.readUint8()
+		videoFormat = VideoFormat((format & 0b11110000) >> 4)
+		frameRate = FrameRate(format & 0b00001111)
+		dynamicRange := c.readUint8()
+		dynamicRangeType = DynamicRangeType((dynamicRange & 0b11110000) >> 4)
+		colorSpace = ColorSpace(dynamicRange & 0b00001111)
+		flags := c.readUint8()
+		crFlag = (flags & (1 << 7)) != 0
+		hdrPlusFlag = (flags & (1 << 6)) != 0
 	} else if streamCodingType == 0x92 {
-		characterCode = CharacterCode(rawData[2])
-		languageCode = string(rawData[3:6])
+		characterCode = CharacterCode(c.readUint8())
+		languageCode = c.readString(3)
 	} else if streamCodingType == 0x90 || streamCodingType == 0x91 {
-		languageCode = string(rawData[2:5])
+		languageCode = c.readString(3)
 	} else if streamCodingType == 0x01 || streamCodingType == 0x02 || streamCodingType == 0x1b || streamCodingType == 0xea {
-		videoFormat = VideoFormat((rawData[2] & 0b11110000) >> 4)
-		frameRate = FrameRate(rawData[2] & 0b00001111)
+		format := c.readUint8()
+		videoFormat = VideoFormat((format & 0b11110000) >> 4)
+		frameRate = FrameRate(format & 0b00001111)
 	} else {
-		audioFormat = AudioFormat((rawData[2] & 0b11110000) >> 4)
-		sampleRate = SampleRate(rawData[2] & 0b00001111)
-		languageCode = string(rawData[3:6])
+		format := c.readUint8()
+		audioFormat = AudioFormat((format & 0b11110000) >> 4)
+		sampleRate = SampleRate(format & 0b00001111)
+		languageCode = c.readString(3)
 	}
+	c.seek(start + length + 1)
 
-	return &StreamAttributes{
+	*streamAttributes = StreamAttributes{
 		Length:           length,
 		StreamCodingType: streamCodingType,
 		VideoFormat:      videoFormat,
@@ -122,56 +131,42 @@ func parseStreamAttributes(rawData []byte) *StreamAttributes {
 	}
 }
 
-func parseStreamsList(rawData []byte, number int) ([]*Stream, int) {
+// parseStreamsList decodes number streams into one allocation per kind of
+// struct rather than three per stream.
+func parseStreamsList(c *byteCursor, number int) []*Stream {
 	if number == 0 {
-		return nil, 0
+		return nil
 	}
 
-	offset := 0
-	var streamsList []*Stream
-	for i := 0; i < number; i++ {
-		streamEntry := parseStreamEntry(rawData[offset:])
-		offset += streamEntry.Length + 1
-		streamAttributes := parseStreamAttributes(rawData[offset:])
-		offset += streamAttributes.Length + 1
-
-		streamsList = append(streamsList, &Stream{
-			StreamEntry:      streamEntry,
-			StreamAttributes: streamAttributes,
-		})
+	streams := make([]Stream, number)
+	streamEntries := make([]StreamEntry, number)
+	streamAttributes := make([]StreamAttributes, number)
+	streamsList := make([]*Stream, number)
+	for i := range streamsList {
+		parseStreamEntry(c, &streamEntries[i])
+		parseStreamAttributes(c, &streamAttributes[i])
+		streams[i] = Stream{
+			StreamEntry:      &streamEntries[i],
+			StreamAttributes: &streamAttributes[i],
+		}
+		streamsList[i] = &streams[i]
 	}
 
-	return streamsList, offset
+	return streamsList
 }
 
-func parseSTNTable(rawData []byte) *STNTable {
-	length := int(binary.BigEndian.Uint16(rawData[:2]))
-	numberOfPrimaryVideoStreams := int(rawData[4])
-	numberOfPrimaryAudioStreams := int(rawData[5])
-	numberOfPrimaryPGStreams := int(rawData[6])
-	numberOfPrimaryIGStreams := int(rawData[7])
-	numberOfSecondaryAudioStreams := int(rawData[8])
-	numberOfSecondaryVideoStreams := int(rawData[9])
-	numberOfSecondaryPGStreams := int(rawData[10])
-	numberOfDVStreams := int(rawData[11])
-
-	offset := 16
-	primaryVideoStreamsList, o := parseStreamsList(rawData[offset:], numberOfPrimaryVideoStreams)
-	offset += o
-	primaryAudioStreamsList, o := parseStreamsList(rawData[offset:], numberOfPrimaryAudioStreams)
-	offset += o
-	primaryPGStreamsList, o := parseStreamsList(rawData[offset:], numberOfPrimaryPGStreams)
-	offset += o
-	secondaryPGStreamsList, o := parseStreamsList(rawData[offset:], numberOfSecondaryPGStreams)
-	offset += o
-	primaryIGStreamsList, o := parseStreamsList(rawData[offset:], numberOfPrimaryIGStreams)
-	offset += o
-	secondaryAudioStreamsList, o := parseStreamsList(rawData[offset:], numberOfSecondaryAudioStreams)
-	offset += o
-	secondaryVideoStreamsList, o := parseStreamsList(rawData[offset:], numberOfSecondaryVideoStreams)
-	offset += o
-	dvStreamsList, o := parseStreamsList(rawData[offset:], numberOfDVStreams)
-	offset += o
+func parseSTNTable(c *byteCursor) *STNTable {
+	length := c.readUint16()
+	c.skip(2)
+	numberOfPrimaryVideoStreams := c.readUint8()
+	numberOfPrimaryAudioStreams := c.readUint8()
+	numberOfPrimaryPGStreams := c.readUint8()
+	numberOfPrimaryIGStreams := c.readUint8()
+	numberOfSecondaryAudioStreams := c.readUint8()
+	numberOfSecondaryVideoStreams := c.readUint8()
+	numberOfSecondaryPGStreams := c.readUint8()
+	numberOfDVStreams := c.readUint8()
+	c.skip(4)
 
 	return &STNTable{
 		Length:                        length,
@@ -183,54 +178,62 @@ func parseSTNTable(rawData []byte) *STNTable {
 		NumberOfSecondaryVideoStreams: numberOfSecondaryVideoStreams,
 		NumberOfSecondaryPGStreams:    numberOfSecondaryPGStreams,
 		NumberOfDVStreams:             numberOfDVStreams,
-		PrimaryVideoStreamsList:       primaryVideoStreamsList,
-		PrimaryAudioStreamsList:       primaryAudioStreamsList,
-		PrimaryPGStreamsList:          primaryPGStreamsList,
-		SecondaryPGStreamsList:        secondaryPGStreamsList,
-		PrimaryIGStreamsList:          primaryIGStreamsList,
-		SecondaryAudioStreamsList:     secondaryAudioStreamsList,
-		SecondaryVideoStreamsList:     secondaryVideoStreamsList,
-		DVStreamsList:                 dvStreamsList,
+		PrimaryVideoStreamsList:       parseStreamsList(c, numberOfPrimaryVideoStreams),
+		PrimaryAudioStreamsList:       parseStreamsList(c, numberOfPrimaryAudioStreams),
+		PrimaryPGStreamsList:          parseStreamsList(c, numberOfPrimaryPGStreams),
+		SecondaryPGStreamsList:        parseStreamsList(c, numberOfSecondaryPGStreams),
+		PrimaryIGStreamsList:          parseStreamsList(c, numberOfPrimaryIGStreams),
+		SecondaryAudioStreamsList:     parseStreamsList(c, numberOfSecondaryAudioStreams),
+		SecondaryVideoStreamsList:     parseStreamsList(c, numberOfSecondaryVideoStreams),
+		DVStreamsList:                 parseStreamsList(c, numberOfDVStreams),
 	}
 }
 
-func parsePlayItem(rawData []byte) *PlayItem {
-	length := int(binary.BigEndian.Uint16(rawData[:2]))
-	clipInfoFileName := string(rawData[2:7])
-	clipCodecIdentifier := string(rawData[7:11])
-	isMultiAngle := (rawData[12] & (1 << 4)) != 0
-	connectionCondition := int(rawData[12] & 0b00001111)
-	refToSTCID := int(rawData[13])
-	inTime := float32(binary.BigEndian.Uint32(rawData[14:18])) / 45000
-	outTime := float32(binary.BigEndian.Uint32(rawData[18:22])) / 45000
-	userOperationMaskTable := parseUOMaskTable(rawData[22:])
-	playItemRandomAccessFlag := (rawData[30] & (1 << 7)) != 0
-	stillMode := int(rawData[31])
+func parsePlayItem(c *byteCursor) *PlayItem {
+	start := c.offset
+	length := c.readUint16()
+	clipInfoFileName := c.readString(5)
+	clipCodecIdentifier := c.readString(4)
+	c.skip(1)
+	flags := c.readUint8()
+	isMultiAngle := (flags & (1 << 4)) != 0
+	connectionCondition := flags & 0b00001111
+	refToSTCID := c.readUint8()
+	inTimeTicks := c.readUint32()
+	outTimeTicks := c.readUint32()
+	inTime := float32(inTimeTicks) / 45000
+	outTime := float32(outTimeTicks) / 45000
+	userOperationMaskTable := parseUOMaskTable(c.readBytes(8))
+	playItemRandomAccessFlag := (c.readUint8() & (1 << 7)) != 0
+	stillMode := c.readUint8()
 
 	stillTime := float32(0)
 	if stillMode == 0x01 {
-		stillTime = float32(binary.BigEndian.Uint16(rawData[32:34])) / 45000
+		stillTime = float32(c.readUint16())
+	} else {
+		c.skip(2)
 	}
 
-	stnTableStart := 34
 	numberOfAngles := 0
 	isDifferentAudios := false
 	isSeamlessAngleChange := false
 	var angleList []*Angle = nil
 	if isMultiAngle {
-		numberOfAngles = int(rawData[34])
-		isDifferentAudios = (rawData[35] & (1 << 1)) != 0
-		isSeamlessAngleChange = (rawData[35] & (1 << 0)) != 0
-		for i := 0; i < numberOfAngles; i++ {
+		numberOfAngles = c.readUint8()
+		angleFlags := c.readUint8()
+		isDifferentAudios = (angleFlags & (1 << 1)) != 0
+		isSeamlessAngleChange = (angleFlags & (1 << 0)) != 0
+		// angle 1 is the clip of the play item itself
+		for i := 1; i < numberOfAngles && c.err == nil; i++ {
 			angleList = append(angleList, &Angle{
-				ClipInformationFileName: string(rawData[36+10*i : 41+10*i]),
-				ClipCodecIdentifier:     string(rawData[41+10*i : 45+10*i]),
-				RefToSTCID:              int(rawData[45+10*i]),
+				ClipInformationFileName: c.readString(5),
+				ClipCodecIdentifier:     c.readString(4),
+				RefToSTCID:              c.readUint8(),
 			})
 		}
-		stnTableStart = 34 + 10*numberOfAngles
 	}
-	stnTable := parseSTNTable(rawData[stnTableStart:])
+	stnTable := parseSTNTable(c)
+	c.seek(start + length + 2)
 
 	return &PlayItem{
 		Length:                   length,
@@ -241,6 +244,8 @@ func parsePlayItem(rawData []byte) *PlayItem {
 		RefToSTCID:               refToSTCID,
 		INTime:                   inTime,
 		OUTTime:                  outTime,
+		INTimeTicks:              inTimeTicks,
+		OUTTimeTicks:             outTimeTicks,
 		UserOperationMaskTable:   userOperationMaskTable,
 		PlayItemRandomAccessFlag: playItemRandomAccessFlag,
 		StillMode:                stillMode,
@@ -253,30 +258,38 @@ func parsePlayItem(rawData []byte) *PlayItem {
 	}
 }
 
-func parseSubPlayItem(rawData []byte) *SubPlayItem {
-	length := int(binary.BigEndian.Uint16(rawData[:2]))
-	clipInformationFileName := string(rawData[2:7])
-	clipCodecIdentifier := string(rawData[7:11])
-	connectionCondition := int((rawData[14] & 0b00011110) >> 1)
-	isMultiClipEntries := (rawData[14] & (1 << 0)) != 0
-	refToSTCID := int(rawData[15])
-	inTime := float32(binary.BigEndian.Uint32(rawData[16:20])) / 45000
-	outTime := float32(binary.BigEndian.Uint32(rawData[20:24])) / 45000
-	syncPlayItemID := int(binary.BigEndian.Uint16(rawData[24:26]))
-	syncStartPTS := int(binary.BigEndian.Uint32(rawData[26:30]))
+func parseSubPlayItem(c *byteCursor) *SubPlayItem {
+	start := c.offset
+	length := c.readUint16()
+	clipInformationFileName := c.readString(5)
+	clipCodecIdentifier := c.readString(4)
+	c.skip(3)
+	flags := c.readUint8()
+	connectionCondition := (flags & 0b00011110) >> 1
+	isMultiClipEntries := (flags & (1 << 0)) != 0
+	refToSTCID := c.readUint8()
+	inTimeTicks := c.readUint32()
+	outTimeTicks := c.readUint32()
+	inTime := float32(inTimeTicks) / 45000
+	outTime := float32(outTimeTicks) / 45000
+	syncPlayItemID := c.readUint16()
+	syncStartPTS := c.readUint32()
 
 	numberOfMultiClipEntries := 0
 	var multiClipEntriesList []*MultiClipEntry = nil
 	if isMultiClipEntries {
-		numberOfMultiClipEntries = int(rawData[31])
-		for i := 0; i < numberOfMultiClipEntries; i++ {
+		// sub clip 0 is the clip of the sub play item itself
+		numberOfMultiClipEntries = c.readUint8()
+		c.skip(1)
+		for i := 1; i < numberOfMultiClipEntries && c.err == nil; i++ {
 			multiClipEntriesList = append(multiClipEntriesList, &MultiClipEntry{
-				ClipInformationFileName: string(rawData[31+10*i : 36+10*i]),
-				ClipCodecIdentifier:     string(rawData[36+10*i : 40+10*i]),
-				RefToSTCID:              int(rawData[40+10*i]),
+				ClipInformationFileName: c.readString(5),
+				ClipCodecIdentifier:     c.readString(4),
+				RefToSTCID:              c.readUint8(),
 			})
 		}
 	}
+	c.seek(start + length + 2)
 
 	return &SubPlayItem{
 		Length:                   length,
@@ -287,6 +300,8 @@ func parseSubPlayItem(rawData []byte) *SubPlayItem {
 		RefToSTCID:               refToSTCID,
 		INTime:                   inTime,
 		OUTTime:                  outTime,
+		INTimeTicks:              inTimeTicks,
+		OUTTimeTicks:             outTimeTicks,
 		SyncPlayItemID:           syncPlayItemID,
 		SyncStartPTS:             syncStartPTS,
 		NumberOfMultiClipEntries: numberOfMultiClipEntries,
@@ -294,19 +309,21 @@ func parseSubPlayItem(rawData []byte) *SubPlayItem {
 	}
 }
 
-func parseSubPath(rawData []byte) *SubPath {
-	length := int(binary.BigEndian.Uint32(rawData[:4]))
-	subPathType := SubPathType(int(rawData[5]))
-	isRepeatSubPath := (rawData[7] & (1 << 0)) != 0
-	numberOfSubPathItems := int(rawData[9])
+func parseSubPath(c *byteCursor) *SubPath {
+	start := c.offset
+	length := c.readUint32()
+	c.skip(1)
+	subPathType := SubPathType(c.readUint8())
+	c.skip(1)
+	isRepeatSubPath := (c.readUint8() & (1 << 0)) != 0
+	c.skip(1)
+	numberOfSubPathItems := c.readUint8()
 
-	offset := 10
 	var subPlayItemsList []*SubPlayItem = nil
-	for i := 0; i < numberOfSubPathItems; i++ {
-		subPlayItem := parseSubPlayItem(rawData[offset:])
-		subPlayItemsList = append(subPlayItemsList, subPlayItem)
-		offset += subPlayItem.Length + 2
+	for i := 0; i < numberOfSubPathItems && c.err == nil; i++ {
+		subPlayItemsList = append(subPlayItemsList, parseSubPlayItem(c))
 	}
+	c.seek(start + length + 4)
 
 	return &SubPath{
 		Length:               length,
@@ -317,53 +334,48 @@ func parseSubPath(rawData []byte) *SubPath {
 	}
 }
 
-func parseAppInfoPlayList(rawData []byte, channel chan *AppInfoPlayList) {
-	length := int(binary.BigEndian.Uint32(rawData[:4]))
-	playbackType := PlaybackType(rawData[5])
+func parseAppInfoPlayList(c *byteCursor) *AppInfoPlayList {
+	length := c.readUint32()
+	c.skip(1)
+	playbackType := PlaybackType(c.readUint8())
 
-	var playbackCount int
+	playbackCount := c.readUint16()
 	if playbackType == 1 {
 		playbackCount = 0
-	} else {
-		playbackCount = int(binary.BigEndian.Uint16(rawData[6:8]))
 	}
-	userOperationMaskTable := parseUOMaskTable(rawData[8:])
+	userOperationMaskTable := parseUOMaskTable(c.readBytes(8))
+	flags := c.readUint8()
 
-	channel <- &AppInfoPlayList{
+	return &AppInfoPlayList{
 		Length:                        length,
 		PlaybackType:                  playbackType,
 		PlaybackCount:                 playbackCount,
 		UOMaskTable:                   userOperationMaskTable,
-		RandomAccessFlag:              (rawData[16] & (1 << 7)) != 0,
-		AudioMixFlag:                  (rawData[16] & (1 << 6)) != 0,
-		LosslessBypassFlag:            (rawData[16] & (1 << 5)) != 0,
-		MVCBaseViewRFlag:              (rawData[16] & (1 << 4)) != 0,
-		SDRConversionNotificationFlag: (rawData[16] & (1 << 3)) != 0,
+		RandomAccessFlag:              (flags & (1 << 7)) != 0,
+		AudioMixFlag:                  (flags & (1 << 6)) != 0,
+		LosslessBypassFlag:            (flags & (1 << 5)) != 0,
+		MVCBaseViewRFlag:              (flags & (1 << 4)) != 0,
+		SDRConversionNotificationFlag: (flags & (1 << 3)) != 0,
 	}
 }
 
-func parsePlayList(rawData []byte, channel chan *PlayList) {
-	length := int(binary.BigEndian.Uint32(rawData[:4]))
-	numberOfPlayItems := int(binary.BigEndian.Uint16(rawData[6:8]))
-	numberOfSubPaths := int(binary.BigEndian.Uint16(rawData[8:10]))
+func parsePlayList(c *byteCursor) *PlayList {
+	length := c.readUint32()
+	c.skip(2)
+	numberOfPlayItems := c.readUint16()
+	numberOfSubPaths := c.readUint16()
 
-	var playItemList []*PlayItem
-	playItemStart := 10
-	for i := 0; i < numberOfPlayItems; i++ {
-		playItem := parsePlayItem(rawData[playItemStart:])
-		playItemList = append(playItemList, playItem)
-		playItemStart += playItem.Length + 2
+	var playItemList []*PlayItem = nil
+	for i := 0; i < numberOfPlayItems && c.err == nil; i++ {
+		playItemList = append(playItemList, parsePlayItem(c))
 	}
 
-	var subPathsList []*SubPath
-	subPathStart := playItemStart
-	for i := 0; i < numberOfSubPaths; i++ {
-		subPath := parseSubPath(rawData[subPathStart:])
-		subPathsList = append(subPathsList, subPath)
-		subPathStart += subPath.Length
+	var subPathsList []*SubPath = nil
+	for i := 0; i < numberOfSubPaths && c.err == nil; i++ {
+		subPathsList = append(subPathsList, parseSubPath(c))
 	}
 
-	channel <- &PlayList{
+	return &PlayList{
 		Length:            length,
 		NumberOfPlayItems: numberOfPlayItems,
 		NumberOfSubPaths:  numberOfSubPaths,
@@ -372,55 +384,73 @@ func parsePlayList(rawData []byte, channel chan *PlayList) {
 	}
 }
 
-func parsePlayListMark(rawData []byte, channel chan *PlayListMark) {
-	length := int(binary.BigEndian.Uint32(rawData[:4]))
-	numberOfPlayListMarks := int(binary.BigEndian.Uint16(rawData[4:6]))
+func parsePlayListMark(c *byteCursor) *PlayListMark {
+	length := c.readUint32()
+	numberOfPlayListMarks := c.readUint16()
 
+	// marks are fixed size, so they are checked at once and share one
+	// allocation
 	var playListMarksList []*PlayListMarkItem = nil
-	for i := 0; i < numberOfPlayListMarks; i++ {
-		playListMarksList = append(playListMarksList, &PlayListMarkItem{
-			MarkType:        int(rawData[7+14*i]),
-			RefToPlayItemID: int(binary.BigEndian.Uint16(rawData[8+14*i : 10+14*i])),
-			MarkTimeStamp:   float32(binary.BigEndian.Uint32(rawData[10+14*i:14+14*i])) / 45000,
-			EntryESPID:      int(binary.BigEndian.Uint16(rawData[14+14*i : 16+14*i])),
-			Duration:        int(binary.BigEndian.Uint32(rawData[16+14*i : 20+14*i])),
-		})
+	if numberOfPlayListMarks > 0 && c.check(14*numberOfPlayListMarks) {
+		playListMarks := make([]PlayListMarkItem, numberOfPlayListMarks)
+		playListMarksList = make([]*PlayListMarkItem, numberOfPlayListMarks)
+		for i := range playListMarks {
+			c.skip(1)
+			markType := c.readUint8()
+			refToPlayItemID := c.readUint16()
+			markTimeTicks := c.readUint32()
+			playListMarks[i] = PlayListMarkItem{
+				MarkType:        markType,
+				RefToPlayItemID: refToPlayItemID,
+				MarkTimeStamp:   float32(markTimeTicks) / 45000,
+				MarkTimeTicks:   markTimeTicks,
+				EntryESPID:      c.readUint16(),
+				Duration:        c.readUint32(),
+			}
+			playListMarksList[i] = &playListMarks[i]
+		}
 	}
 
-	channel <- &PlayListMark{
+	return &PlayListMark{
 		Length:                length,
 		NumberOfPlayListMarks: numberOfPlayListMarks,
 		PlayListMarksList:     playListMarksList,
 	}
 }
 
-func parseExtensionData(rawData []byte, channel chan *ExtensionData) {
-	length := int(binary.BigEndian.Uint32(rawData[:4]))
+// parseExtensionData returns nil for an empty extension data block. Entry
+// addresses are relative to the start of the block.
+func parseExtensionData(c *byteCursor) *ExtensionData {
+	start := c.offset
+	length := c.readUint32()
 	if length == 0 {
-		channel <- nil
+		return nil
 	}
-	dataBlockStartAddress := int(binary.BigEndian.Uint32(rawData[4:8]))
+	dataBlockStartAddress := c.readUint32()
+	c.skip(3)
 
-	numberOfExtDataEntries := int(rawData[11])
+	numberOfExtDataEntries := c.readUint8()
 	var extDataEntryItemsList []*ExtDataEntryItem = nil
-	for i := 0; i < numberOfExtDataEntries; i++ {
-		extDataType := int(binary.BigEndian.Uint16(rawData[12+12*i : 14+12*i]))
-		extDataVersion := int(binary.BigEndian.Uint16(rawData[14+12*i : 16+12*i]))
-		extDataStartAddress := int(binary.BigEndian.Uint32(rawData[16+12*i : 20+12*i]))
-		extDataLength := int(binary.BigEndian.Uint32(rawData[20+12*i : 24+12*i]))
-		extDataEntry := rawData[extDataStartAddress : extDataStartAddress+extDataLength]
-
-		extDataEntryItem := ExtDataEntryItem{
+	for i := 0; i < numberOfExtDataEntries && c.err == nil; i++ {
+		extDataType := c.readUint16()
+		extDataVersion := c.readUint16()
+		extDataStartAddress := c.readUint32()
+		extDataLength := c.readUint32()
+		entryEnd := c.offset
+		c.seek(start + extDataStartAddress)
+		extDataEntry := c.next(extDataLength)
+		c.seek(entryEnd)
+
+		extDataEntryItemsList = append(extDataEntryItemsList, &ExtDataEntryItem{
 			ExtDataType:         extDataType,
 			ExtDataVersion:      extDataVersion,
 			ExtDataStartAddress: extDataStartAddress,
 			ExtDataLength:       extDataLength,
 			ExtDataEntry:        extDataEntry,
-		}
-		extDataEntryItemsList = append(extDataEntryItemsList, &extDataEntryItem)
+		})
 	}
 
-	channel <- &ExtensionData{
+	return &ExtensionData{
 		Length:                 length,
 		DataBlockStartAddress:  dataBlockStartAddress,
 		NumberOfExtDataEntries: numberOfExtDataEntries,
@@ -433,47 +463,106 @@ func Parse(path string) (*MPLS, error) {
 	if err != nil {
 		return nil, err
 	}
+	mpls, err := ParseBytes(rawData)
+	if err != nil {
+		return nil, err
+	}
+	mpls.FilePath = path
+	return mpls, nil
+}
 
-	if !bytes.Equal(rawData[:4], []byte("MPLS")) {
+// ParseBytes parses an MPLS file read by other means than Parse, such as
+// from a disc image. FilePath is left empty. The sections are decoded one
+// after the other on the calling goroutine, and a truncated or malformed
+// file gives an error rather than a panic.
+func ParseBytes(rawData []byte) (*MPLS, error) {
+	if len(rawData) < 0x39 || !bytes.Equal(rawData[:4], []byte("MPLS")) {
 		return nil, errors.New("invalid file")
 	}
 
-	versionNumber, err := strconv.Atoi(string(rawData[0x04:0x08]))
+	c := byteCursor{data: rawData, offset: 0x04}
+	versionNumber, err := strconv.Atoi(c.readString(4))
 
 	if err != nil {
 		return nil, err
 	}
 
-	playlistStartAddress := int(binary.BigEndian.Uint32(rawData[0x08:0x0c]))
-	playlistMarkStartAddress := int(binary.BigEndian.Uint32(rawData[0x0c:0x10]))
-	extensionDataStartAddress := int(binary.BigEndian.Uint32(rawData[0x10:0x14]))
-
-	applicationInfoPlayList := make(chan *AppInfoPlayList)
-	go parseAppInfoPlayList(rawData[0x28:0x39], applicationInfoPlayList)
-
-	playList := make(chan *PlayList)
-	go parsePlayList(rawData[playlistStartAddress:], playList)
-
-	playListMark := make(chan *PlayListMark)
-	go parsePlayListMark(rawData[playlistMarkStartAddress:], playListMark)
+	playlistStartAddress := c.readUint32()
+	playlistMarkStartAddress := c.readUint32()
+	extensionDataStartAddress := c.readUint32()
+	if playlistStartAddress+4 > len(rawData) || playlistMarkStartAddress+4 > len(rawData) || extensionDataStartAddress+4 > len(rawData) {
+		return nil, errors.New("invalid file: section address out of range")
+	}
 
-	extensionData := make(chan *ExtensionData, 1)
+	c.seek(0x28)
+	applicationInfoPlayList := parseAppInfoPlayList(&c)
+	c.seek(playlistStartAddress)
+	playList := parsePlayList(&c)
+	c.seek(playlistMarkStartAddress)
+	playListMark := parsePlayListMark(&c)
+	var extensionData *ExtensionData = nil
 	if extensionDataStartAddress != 0 {
-		go parseExtensionData(rawData[extensionDataStartAddress:], extensionData)
-	} else {
-		extensionData <- nil
+		c.seek(extensionDataStartAddress)
+		extensionData = parseExtensionData(&c)
+	}
+	if c.err != nil {
+		return nil, c.err
 	}
 
 	return &MPLS{
-		FilePath:                  path,
 		RawData:                   rawData,
 		VersionNumber:             versionNumber,
 		PlaylistStartAddress:      playlistStartAddress,
 		PlaylistMarkStartAddress:  playlistMarkStartAddress,
 		ExtensionDataStartAddress: extensionDataStartAddress,
-		ApplicationInfoPlaylist:   <-applicationInfoPlayList,
-		PlayList:                  <-playList,
-		PlayListMark:              <-playListMark,
-		ExtensionData:             <-extensionData,
+		ApplicationInfoPlaylist:   applicationInfoPlayList,
+		PlayList:                  playList,
+		PlayListMark:              playListMark,
+		ExtensionData:             extensionData,
 	}, nil
 }
+
+// ParseDisc parses every playlist of the PLAYLIST directory of a disc, in
+// the order of PlayListPaths. Up to workers files are parsed at once; with
+// workers below 2 they are parsed one after the other on the calling
+// goroutine. The first error in that order is returned.
+func ParseDisc(bdmvRoot string, workers int) ([]*MPLS, error) {
+	paths, err := PlayListPaths(bdmvRoot)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	playListsList := make([]*MPLS, len(paths))
+	if workers < 2 {
+		for i, path := range paths {
+			if playListsList[i], err = Parse(path); err != nil {
+				return nil, err
+			}
+		}
+		return playListsList, nil
+	}
+
+	errorsList := make([]error, len(paths))
+	jobs := make(chan int)
+	var workersGroup sync.WaitGroup
+	for range min(workers, len(paths)) {
+		workersGroup.Add(1)
+		go func() {
+			defer workersGroup.Done()
+			for i := range jobs {
+				playListsList[i], errorsList[i] = Parse(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	workersGroup.Wait()
+	for _, err := range errorsList {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return playListsList, nil
+}
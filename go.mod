@@ -1,3 +1,10 @@
 module github.com/syxxzzr/go-mpls
 
 go 1.24.5
+
+require (
+	golang.org/x/term v0.40.0
+	golang.org/x/text v0.30.0
+)
+
+require golang.org/x/sys v0.41.0 // indirect
@@ -0,0 +1,514 @@
+package go_mpls
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// HLSSegment is a byte range of a clip served as a 188 byte transport
+// stream, starting at an entry point of the video stream.
+type HLSSegment struct {
+	ClipName string
+	// Offset and Length count bytes of 188 byte packets, the 4 byte
+	// TP_extra_header of every source packet being removed when served.
+	Offset        int64
+	Length        int64
+	DurationTicks int
+	// IsFirstOfPlayItem marks the segment starting a play item, which gets
+	// the initialization section of its clip. IsDiscontinuity is set on it
+	// unless the timestamps continue from the previous segment.
+	IsFirstOfPlayItem bool
+	IsDiscontinuity   bool
+	PlayItemID        int
+}
+
+// HLSRendition is an audio or PG stream offered as alternate rendition.
+// Audio renditions are served from the same clips with the packets of every
+// other elementary stream replaced by null packets, so they share the byte
+// ranges of the video. PG renditions are WebVTT subtitles with one segment
+// per play item. WebVTT only holds text, so each cue spans the display of a
+// PG display set and is identified by the URL of that display set rendered
+// as PNG, for players to overlay; the whole stream is also served as .sup.
+type HLSRendition struct {
+	Type      string
+	PID       int
+	Language  string
+	Name      string
+	IsDefault bool
+}
+
+// HLSPresentation is a VOD presentation of a playlist: one video media
+// playlist and one media playlist per rendition, over the same segments.
+type HLSPresentation struct {
+	VideoPID           int
+	SegmentsList       []*HLSSegment
+	RenditionsList     []*HLSRendition
+	ElementaryPIDsList []int
+	PlayItemsList      []*PlayItem
+}
+
+// HLSPresentation cuts the play items at the entry points of the primary
+// video stream found in clips, keyed by clip name, into segments of at
+// least segmentTicks, except for the last segment of each play item. A
+// segment starts at the entry point at or before the IN time, so the first
+// frames of a play item may repeat the end of the previous one. Play items
+// are marked as discontinuities unless they are connected with
+// SeamlessConnection and their segments meet exactly at entry points: a
+// clean break starts a new STC, and repeated frames step the timestamps
+// back.
+func (mpls *MPLS) HLSPresentation(clips map[string]*CLPI, segmentTicks int) (*HLSPresentation, error) {
+	if len(mpls.PlayList.PlayItemList) == 0 || mpls.PlayList.PlayItemList[0].STNTable == nil ||
+		len(mpls.PlayList.PlayItemList[0].STNTable.PrimaryVideoStreamsList) == 0 {
+		return nil, errors.New("playlist without video stream")
+	}
+	table := mpls.PlayList.PlayItemList[0].STNTable
+	presentation := &HLSPresentation{
+		VideoPID:      table.PrimaryVideoStreamsList[0].StreamEntry.RefToStreamPID,
+		PlayItemsList: mpls.PlayList.PlayItemList,
+	}
+
+	for _, streams := range stnStreamLists(table) {
+		for _, stream := range streams {
+			if isMultiplexedStream(stream) && !slices.Contains(presentation.ElementaryPIDsList, stream.StreamEntry.RefToStreamPID) {
+				presentation.ElementaryPIDsList = append(presentation.ElementaryPIDsList, stream.StreamEntry.RefToStreamPID)
+			}
+		}
+	}
+	slices.Sort(presentation.ElementaryPIDsList)
+	for k, stream := range table.PrimaryAudioStreamsList {
+		if isMultiplexedStream(stream) {
+			presentation.RenditionsList = append(presentation.RenditionsList, &HLSRendition{
+				Type:      "AUDIO",
+				PID:       stream.StreamEntry.RefToStreamPID,
+				Language:  stream.StreamAttributes.LanguageCode,
+				Name:      fmt.Sprintf("%s %s", stream.StreamAttributes.StreamCodingType, stream.StreamAttributes.AudioFormat),
+				IsDefault: k == 0,
+			})
+		}
+	}
+	for _, stream := range table.PrimaryPGStreamsList {
+		if isMultiplexedStream(stream) {
+			presentation.RenditionsList = append(presentation.RenditionsList, &HLSRendition{
+				Type:     "SUBTITLES",
+				PID:      stream.StreamEntry.RefToStreamPID,
+				Language: stream.StreamAttributes.LanguageCode,
+				Name:     fmt.Sprintf("PG 0x%04x", stream.StreamEntry.RefToStreamPID),
+			})
+		}
+	}
+
+	previousEndPTS := int64(-1)
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		clpi := clips[playItem.ClipInformationFileName]
+		if clpi == nil {
+			return nil, fmt.Errorf("clip information of %s is missing", playItem.ClipInformationFileName)
+		}
+		epMap := clpi.EPMap(presentation.VideoPID)
+		if epMap == nil || len(epMap.EntriesList) == 0 {
+			return nil, fmt.Errorf("%s has no entry points for PID 0x%04x", playItem.ClipInformationFileName, presentation.VideoPID)
+		}
+		inPTS, outPTS := int64(playItem.INTimeTicks)*2, int64(playItem.OUTTimeTicks)*2
+
+		// Entry points from the last one at or before IN up to the first one
+		// at or after OUT, which ends the last segment.
+		first := 0
+		for k, entry := range epMap.EntriesList {
+			if entry.PTS <= inPTS {
+				first = k
+			}
+		}
+		boundariesList := []*EPMapEntry{epMap.EntriesList[first]}
+		endSPN, endPTS := -1, outPTS
+		for _, entry := range epMap.EntriesList[first+1:] {
+			if entry.PTS >= outPTS {
+				endSPN, endPTS = entry.SPN, entry.PTS
+				break
+			}
+			boundariesList = append(boundariesList, entry)
+		}
+		if endSPN < 0 {
+			endSPN = clpi.ClipInfo.NumberOfSourcePackets
+		}
+		isContinuous := playItem.ConnectionCondition == SeamlessConnection && boundariesList[0].PTS == inPTS && previousEndPTS == inPTS
+		previousEndPTS = endPTS
+
+		var segment *HLSSegment = nil
+		startTicks := playItem.INTimeTicks
+		for k, entry := range boundariesList {
+			entryTicks := int(entry.PTS / 2)
+			if k == 0 {
+				entryTicks = playItem.INTimeTicks
+			}
+			if segment != nil && entryTicks-startTicks < segmentTicks {
+				continue
+			}
+			if segment != nil {
+				segment.Length = int64(entry.SPN)*TSPacketSize - segment.Offset
+				segment.DurationTicks = entryTicks - startTicks
+				presentation.SegmentsList = append(presentation.SegmentsList, segment)
+			}
+			segment = &HLSSegment{
+				ClipName:          playItem.ClipInformationFileName,
+				Offset:            int64(entry.SPN) * TSPacketSize,
+				IsFirstOfPlayItem: k == 0,
+				IsDiscontinuity:   k == 0 && i > 0 && !isContinuous,
+				PlayItemID:        i,
+			}
+			startTicks = entryTicks
+		}
+		segment.Length = int64(endSPN)*TSPacketSize - segment.Offset
+		segment.DurationTicks = playItem.OUTTimeTicks - startTicks
+		presentation.SegmentsList = append(presentation.SegmentsList, segment)
+	}
+	return presentation, nil
+}
+
+func hlsMediaPlaylistName(pid int) string {
+	return fmt.Sprintf("%d.m3u8", pid)
+}
+
+// WriteMasterPlaylist writes the multivariant playlist, with one video
+// variant and the renditions grouped as "audio" and "subs".
+func (presentation *HLSPresentation) WriteMasterPlaylist(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	var attributesList []string = nil
+	for _, groupType := range []string{"AUDIO", "SUBTITLES"} {
+		groupID := map[string]string{"AUDIO": "audio", "SUBTITLES": "subs"}[groupType]
+		found := false
+		for _, rendition := range presentation.RenditionsList {
+			if rendition.Type != groupType {
+				continue
+			}
+			found = true
+			fmt.Fprintf(writer, "#EXT-X-MEDIA:TYPE=%s,GROUP-ID=\"%s\",NAME=\"%s\"", groupType, groupID, rendition.Name)
+			if rendition.Language != "" {
+				fmt.Fprintf(writer, ",LANGUAGE=\"%s\"", rendition.Language)
+			}
+			defaultValue := "NO"
+			if rendition.IsDefault {
+				defaultValue = "YES"
+			}
+			fmt.Fprintf(writer, ",DEFAULT=%s,AUTOSELECT=YES,URI=\"%s\"\n", defaultValue, hlsMediaPlaylistName(rendition.PID))
+		}
+		if found {
+			attributesList = append(attributesList, fmt.Sprintf("%s=\"%s\"", groupType, groupID))
+		}
+	}
+
+	totalBytes, totalTicks := int64(0), 0
+	for _, segment := range presentation.SegmentsList {
+		totalBytes += segment.Length
+		totalTicks += segment.DurationTicks
+	}
+	bandwidth := int64(0)
+	if totalTicks > 0 {
+		bandwidth = totalBytes * 8 * 45000 / int64(totalTicks)
+	}
+	attributesList = append([]string{fmt.Sprintf("BANDWIDTH=%d", bandwidth)}, attributesList...)
+	fmt.Fprintf(writer, "#EXT-X-STREAM-INF:%s\n%s\n", strings.Join(attributesList, ","), hlsMediaPlaylistName(presentation.VideoPID))
+	return writer.Flush()
+}
+
+// WriteMediaPlaylist writes the media playlist of the video or of one
+// rendition, selected by PID.
+func (presentation *HLSPresentation) WriteMediaPlaylist(w io.Writer, pid int) error {
+	if presentation.isSubtitles(pid) {
+		return presentation.writeSubtitlesPlaylist(w, pid)
+	}
+	targetDuration := 0
+	for _, segment := range presentation.SegmentsList {
+		targetDuration = max(targetDuration, int(math.Round(float64(segment.DurationTicks)/45000)))
+	}
+
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:%d\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-MEDIA-SEQUENCE:0\n", targetDuration)
+	for _, segment := range presentation.SegmentsList {
+		uri := fmt.Sprintf("%s.ts?pid=%d", segment.ClipName, pid)
+		if segment.IsDiscontinuity {
+			writer.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		if segment.IsFirstOfPlayItem {
+			// Blu-ray clips start with the PAT and the PMT.
+			fmt.Fprintf(writer, "#EXT-X-MAP:URI=\"%s\",BYTERANGE=\"%d@0\"\n", uri, 2*TSPacketSize)
+		}
+		fmt.Fprintf(writer, "#EXTINF:%s,\n#EXT-X-BYTERANGE:%d@%d\n%s\n", ticksToSeconds(segment.DurationTicks), segment.Length, segment.Offset, uri)
+	}
+	writer.WriteString("#EXT-X-ENDLIST\n")
+	return writer.Flush()
+}
+
+func (presentation *HLSPresentation) isSubtitles(pid int) bool {
+	return slices.ContainsFunc(presentation.RenditionsList, func(rendition *HLSRendition) bool {
+		return rendition.PID == pid && rendition.Type == "SUBTITLES"
+	})
+}
+
+// writeSubtitlesPlaylist writes one WebVTT segment per play item, lasting
+// as long as its video segments and starting the same discontinuities.
+func (presentation *HLSPresentation) writeSubtitlesPlaylist(w io.Writer, pid int) error {
+	var segmentsList []*HLSSegment = nil
+	for _, segment := range presentation.SegmentsList {
+		if segment.IsFirstOfPlayItem {
+			segmentsList = append(segmentsList, &HLSSegment{IsDiscontinuity: segment.IsDiscontinuity, PlayItemID: segment.PlayItemID})
+		}
+		segmentsList[len(segmentsList)-1].DurationTicks += segment.DurationTicks
+	}
+	targetDuration := 0
+	for _, segment := range segmentsList {
+		targetDuration = max(targetDuration, int(math.Ceil(float64(segment.DurationTicks)/45000)))
+	}
+
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:%d\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-MEDIA-SEQUENCE:0\n", targetDuration)
+	for _, segment := range segmentsList {
+		if segment.IsDiscontinuity {
+			writer.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		fmt.Fprintf(writer, "#EXTINF:%s,\n%d_%d.vtt\n", ticksToSeconds(segment.DurationTicks), pid, segment.PlayItemID)
+	}
+	writer.WriteString("#EXT-X-ENDLIST\n")
+	return writer.Flush()
+}
+
+// readPGDisplaySets reads the display sets of a PG stream presented during
+// a play item, with times relative to its IN time.
+func (presentation *HLSPresentation) readPGDisplaySets(bdmvRoot string, pid, playItemID int) ([]*DisplaySet, error) {
+	playItem := presentation.PlayItemsList[playItemID]
+	segments, err := readGraphicsStream(playItem.ClipStreamPath(bdmvRoot), pid, int64(playItem.INTimeTicks)*2, int64(playItem.OUTTimeTicks)*2, 0)
+	if err != nil {
+		return nil, err
+	}
+	return GroupDisplaySets(segments), nil
+}
+
+func formatWebVTTTime(pts int64) string {
+	milliseconds := pts / 90
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+// writePGWebVTT writes one cue per display set showing objects, from its
+// PTS to the next display set or endPTS. The timestamps of the display sets
+// are relative to mpegTS, the 90 kHz transport stream time of the segment
+// start. The identifier of the cue at index k is imageURL(k).
+func writePGWebVTT(w io.Writer, displaySets []*DisplaySet, mpegTS, endPTS int64, imageURL func(int) string) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:%d,LOCAL:00:00:00.000\n\n", mpegTS)
+	for k, displaySet := range displaySets {
+		composition := displaySet.PresentationComposition
+		if composition == nil || composition.NumberOfCompositionObjects == 0 {
+			continue
+		}
+		end := endPTS
+		for _, next := range displaySets[k+1:] {
+			if next.PresentationComposition != nil {
+				end = next.PTS
+				break
+			}
+		}
+		if end <= displaySet.PTS {
+			continue
+		}
+		fmt.Fprintf(writer, "%s\n%s --> %s\n\n", imageURL(k), formatWebVTTTime(displaySet.PTS), formatWebVTTTime(end))
+	}
+	return writer.Flush()
+}
+
+var nullPacket = append([]byte{0x47, 0x1f, 0xff, 0x10}, bytes.Repeat([]byte{0xff}, TSPacketSize-4)...)
+
+// tsReader reads an m2ts file as a 188 byte transport stream, replacing
+// the packets of the PIDs in nulledPIDs with null packets.
+type tsReader struct {
+	file       *os.File
+	size       int64
+	position   int64
+	nulledPIDs []int
+}
+
+func (reader *tsReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += reader.position
+	case io.SeekEnd:
+		offset += reader.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	reader.position = offset
+	return offset, nil
+}
+
+func (reader *tsReader) Read(p []byte) (int, error) {
+	if reader.position >= reader.size {
+		return 0, io.EOF
+	}
+	packetIndex, skip := reader.position/TSPacketSize, reader.position%TSPacketSize
+	count := min((skip+int64(len(p))+TSPacketSize-1)/TSPacketSize, (reader.size-packetIndex*TSPacketSize)/TSPacketSize)
+	rawData := make([]byte, count*M2TSPacketSize)
+	n, err := reader.file.ReadAt(rawData, packetIndex*M2TSPacketSize)
+	if n < len(rawData) && err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	packets := make([]byte, 0, count*TSPacketSize)
+	for offset := 0; offset+M2TSPacketSize <= n; offset += M2TSPacketSize {
+		packet := rawData[offset+4 : offset+M2TSPacketSize]
+		if pid := int(packet[1]&0x1f)<<8 | int(packet[2]); slices.Contains(reader.nulledPIDs, pid) {
+			packet = nullPacket
+		}
+		packets = append(packets, packet...)
+	}
+	if int64(len(packets)) <= skip {
+		return 0, io.ErrUnexpectedEOF
+	}
+	copied := copy(p, packets[skip:])
+	reader.position += int64(copied)
+	return copied, nil
+}
+
+// NewHLSHandler serves the presentation of a playlist from the clips of a
+// disc: master.m3u8, one <pid>.m3u8 media playlist per stream and the
+// clips as <clip>.ts?pid=<pid>, with range requests. PG renditions are
+// served as <pid>_<play item>.vtt segments, <pid>_<play item>_<display
+// set>.png images and <pid>.sup.
+func NewHLSHandler(bdmvRoot string, presentation *HLSPresentation) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		name := path.Base(r.URL.Path)
+		switch {
+		case name == "master.m3u8":
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+			presentation.WriteMasterPlaylist(w)
+		case strings.HasSuffix(name, ".m3u8"):
+			pid, err := strconv.Atoi(strings.TrimSuffix(name, ".m3u8"))
+			if err != nil || (pid != presentation.VideoPID && !slices.ContainsFunc(presentation.RenditionsList, func(rendition *HLSRendition) bool {
+				return rendition.PID == pid
+			})) {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+			presentation.WriteMediaPlaylist(w, pid)
+		case strings.HasSuffix(name, ".vtt"), strings.HasSuffix(name, ".png"), strings.HasSuffix(name, ".sup"):
+			servePGRendition(w, r, bdmvRoot, presentation, name)
+		case strings.HasSuffix(name, ".ts"):
+			clipName := strings.TrimSuffix(name, ".ts")
+			if !slices.ContainsFunc(presentation.SegmentsList, func(segment *HLSSegment) bool { return segment.ClipName == clipName }) {
+				http.NotFound(w, r)
+				return
+			}
+			pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+			if err != nil {
+				http.Error(w, "invalid pid", http.StatusBadRequest)
+				return
+			}
+			file, err := os.Open(filepath.Join(bdmvRoot, "STREAM", clipName+".m2ts"))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			defer file.Close()
+			info, err := file.Stat()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			reader := &tsReader{file: file, size: info.Size() / M2TSPacketSize * TSPacketSize}
+			for _, elementaryPID := range presentation.ElementaryPIDsList {
+				if elementaryPID != pid {
+					reader.nulledPIDs = append(reader.nulledPIDs, elementaryPID)
+				}
+			}
+			w.Header().Set("Content-Type", "video/mp2t")
+			http.ServeContent(w, r, name, info.ModTime(), reader)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return mux
+}
+
+// servePGRendition serves the WebVTT segments, the display set images and
+// the .sup of a PG rendition, named <pid>_<play item>.vtt,
+// <pid>_<play item>_<display set>.png and <pid>.sup.
+func servePGRendition(w http.ResponseWriter, r *http.Request, bdmvRoot string, presentation *HLSPresentation, name string) {
+	extension := path.Ext(name)
+	var numbersList []int = nil
+	for _, field := range strings.Split(strings.TrimSuffix(name, extension), "_") {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		numbersList = append(numbersList, number)
+	}
+	if len(numbersList) != map[string]int{".sup": 1, ".vtt": 2, ".png": 3}[extension] || !presentation.isSubtitles(numbersList[0]) {
+		http.NotFound(w, r)
+		return
+	}
+	pid := numbersList[0]
+
+	if extension == ".sup" {
+		segments, err := extractGraphicsStream(bdmvRoot, presentation.PlayItemsList, pid)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		WriteSUP(w, segments)
+		return
+	}
+
+	playItemID := numbersList[1]
+	if playItemID < 0 || playItemID >= len(presentation.PlayItemsList) {
+		http.NotFound(w, r)
+		return
+	}
+	displaySets, err := presentation.readPGDisplaySets(bdmvRoot, pid, playItemID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if extension == ".vtt" {
+		playItem := presentation.PlayItemsList[playItemID]
+		w.Header().Set("Content-Type", "text/vtt")
+		writePGWebVTT(w, displaySets, int64(playItem.INTimeTicks)*2, int64(playItem.DurationTicks())*2, func(k int) string {
+			return fmt.Sprintf("%d_%d_%d.png", pid, playItemID, k)
+		})
+		return
+	}
+
+	index := numbersList[2]
+	if index < 0 || index >= len(displaySets) {
+		http.NotFound(w, r)
+		return
+	}
+	decoder := NewGraphicsDecoder()
+	for _, displaySet := range displaySets[:index] {
+		decoder.update(displaySet)
+	}
+	img, err := decoder.Decode(displaySets[index])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	png.Encode(w, img)
+}
@@ -0,0 +1,200 @@
+package go_mpls
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHLS(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	writeCLPI(t, bdmvRoot, "00001", buildCLPI(0, 900000))
+	writeCLPI(t, bdmvRoot, "00002", buildCLPI(0, 900000))
+
+	// a caption shown from 1 to 2 seconds by a PG stream, whose packets
+	// replace video and audio packets from packet 500
+	var pg []byte
+	for _, segment := range append(buildDisplaySet(90000, false), &GraphicsSegment{
+		SegmentType: PresentationCompositionSegment,
+		Data:        []byte{0x07, 0x80, 0x04, 0x38, 0x10, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00},
+	}, &GraphicsSegment{SegmentType: EndOfDisplaySetSegment}) {
+		pg = append(pg, byte(segment.SegmentType), byte(len(segment.Data)>>8), byte(len(segment.Data)))
+		pg = append(pg, segment.Data...)
+	}
+	pgPackets := [][]byte{
+		buildM2TSPacket(0x1200, true, buildPES(0xbd, 90000, pg[:len(pg)-17])),
+		buildM2TSPacket(0x1200, true, buildPES(0xbd, 180000, pg[len(pg)-17:])),
+	}
+
+	var m2ts bytes.Buffer
+	m2ts.Write(buildM2TSPacket(PATPID, true, buildSection(0x00, 1, []byte{0x00, 0x01, 0xe1, 0x00})))
+	m2ts.Write(buildM2TSPacket(0x0100, true, buildSection(0x02, 1, []byte{0xf0, 0x11, 0xf0, 0x00, byte(PresentationGraphics), 0xf2, 0x00, 0xf0, 0x00})))
+	for k := 2; k < 1000; k++ {
+		if k >= 500 && k-500 < len(pgPackets) {
+			m2ts.Write(pgPackets[k-500])
+			continue
+		}
+		pid := []int{0x1011, 0x1100}[k%2]
+		packet := make([]byte, M2TSPacketSize)
+		packet[4], packet[5], packet[6] = 0x47, byte(pid>>8), byte(pid)
+		m2ts.Write(packet)
+	}
+	if err := os.MkdirAll(filepath.Join(bdmvRoot, "STREAM"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, clipName := range []string{"00001", "00002"} {
+		if err := os.WriteFile(filepath.Join(bdmvRoot, "STREAM", clipName+".m2ts"), m2ts.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stnTable := &STNTable{
+		PrimaryVideoStreamsList: []*Stream{newTestStream(0x1011, HEVCVideo, "")},
+		PrimaryAudioStreamsList: []*Stream{newTestStream(0x1100, DolbyDigitalAudio, "eng")},
+		PrimaryPGStreamsList:    []*Stream{newTestStream(0x1200, PresentationGraphics, "eng")},
+	}
+	mpls := &MPLS{PlayList: &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00001", ConnectionCondition: 1, OUTTimeTicks: 900000, STNTable: stnTable},
+		{ClipInformationFileName: "00002", ConnectionCondition: 1, OUTTimeTicks: 450000, STNTable: stnTable},
+	}}}
+	clips, err := mpls.LoadClipInformation(bdmvRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	presentation, err := mpls.HLSPresentation(clips, 45000*6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(presentation.SegmentsList) != 3 {
+		t.Fatalf("unexpected segments %d", len(presentation.SegmentsList))
+	}
+	if segment := presentation.SegmentsList[1]; segment.Offset != 64*188 || segment.Length != 936*188 || segment.DurationTicks != 900000-526848 {
+		t.Fatalf("unexpected segment %#v", segment)
+	}
+	if segment := presentation.SegmentsList[2]; !segment.IsDiscontinuity || segment.Length != 64*188 || segment.DurationTicks != 450000 {
+		t.Fatalf("unexpected segment %#v", segment)
+	}
+
+	server := httptest.NewServer(NewHLSHandler(bdmvRoot, presentation))
+	defer server.Close()
+	get := func(path, byteRange string) string {
+		request, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if byteRange != "" {
+			request.Header.Set("Range", byteRange)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	master := get("/master.m3u8", "")
+	if !strings.Contains(master, `#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",NAME="Dolby Digital stereo",LANGUAGE="eng",DEFAULT=YES,AUTOSELECT=YES,URI="4352.m3u8"`) ||
+		!strings.Contains(master, `#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="PG 0x1200",LANGUAGE="eng",DEFAULT=NO,AUTOSELECT=YES,URI="4608.m3u8"`) ||
+		!strings.Contains(master, `AUDIO="audio",SUBTITLES="subs"`+"\n4113.m3u8\n") {
+		t.Fatalf("unexpected master playlist\n%s", master)
+	}
+
+	subtitles := get("/4608.m3u8", "")
+	if !strings.Contains(subtitles, "#EXT-X-TARGETDURATION:20\n") ||
+		!strings.Contains(subtitles, "#EXTINF:20.000000,\n4608_0.vtt\n#EXT-X-DISCONTINUITY\n#EXTINF:10.000000,\n4608_1.vtt\n") {
+		t.Fatalf("unexpected subtitles playlist\n%s", subtitles)
+	}
+	if webVTT := get("/4608_0.vtt", ""); webVTT != "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:0,LOCAL:00:00:00.000\n\n4608_0_0.png\n00:00:01.000 --> 00:00:02.000\n\n" {
+		t.Fatalf("unexpected WebVTT segment\n%s", webVTT)
+	}
+	img, err := png.Decode(strings.NewReader(get("/4608_0_0.png", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := img.(*image.NRGBA).NRGBAAt(10, 20); img.Bounds().Dx() != 1920 || c.A != 255 {
+		t.Fatalf("unexpected caption image %v %v", img.Bounds(), c)
+	}
+	segments, err := ParseSUP(strings.NewReader(get("/4608.sup", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 14 || segments[0].PTS != 90000 || segments[7].PTS != 1800000+90000 {
+		t.Fatalf("unexpected .sup segments %d", len(segments))
+	}
+	for _, path := range []string{"/4608_2.vtt", "/4608_0_9.png", "/4352.sup", "/4352_0.vtt"} {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusNotFound {
+			t.Fatalf("%s served with %s", path, response.Status)
+		}
+	}
+	media := get("/4352.m3u8", "")
+	if !strings.Contains(media, "#EXT-X-TARGETDURATION:12\n") ||
+		!strings.Contains(media, "#EXT-X-MAP:URI=\"00001.ts?pid=4352\",BYTERANGE=\"376@0\"\n#EXTINF:11.707733,\n#EXT-X-BYTERANGE:12032@0\n00001.ts?pid=4352\n") ||
+		!strings.Contains(media, "#EXT-X-DISCONTINUITY\n#EXT-X-MAP:URI=\"00002.ts?pid=4352\"") || !strings.HasSuffix(media, "#EXT-X-ENDLIST\n") {
+		t.Fatalf("unexpected media playlist\n%s", media)
+	}
+
+	data := get("/00001.ts?pid=4352", "bytes=376-751")
+	if len(data) != 376 || data[0] != 0x47 || data[1] != 0x1f || data[188+1] != 0x11 || data[188+2] != 0x00 {
+		t.Fatalf("unexpected packets % x", []byte(data[:8]))
+	}
+	if data := get("/00001.ts?pid=4352", "bytes=2-5"); data != string(m2ts.Bytes()[4+2:4+6]) {
+		t.Fatalf("unexpected range % x", []byte(data))
+	}
+	if data := get("/00001.ts?pid=4113", ""); len(data) != 1000*188 {
+		t.Fatalf("unexpected size %d", len(data))
+	}
+}
+
+func TestHLSDiscontinuity(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	writeCLPI(t, bdmvRoot, "00001", buildCLPI(0, 900000))
+	writeCLPI(t, bdmvRoot, "00002", buildCLPI(0, 900000))
+	stnTable := &STNTable{PrimaryVideoStreamsList: []*Stream{newTestStream(0x1011, HEVCVideo, "")}}
+
+	// the second entry point of the clips is at 526848 ticks
+	for _, test := range []struct {
+		connectionCondition int
+		inTimeTicks         int
+		isDiscontinuity     bool
+	}{
+		{SeamlessConnection, 526848, false},
+		{SeamlessCleanBreakConnection, 526848, true},
+		{NonSeamlessConnection, 526848, true},
+		{SeamlessConnection, 600000, true},
+	} {
+		mpls := &MPLS{PlayList: &PlayList{PlayItemList: []*PlayItem{
+			{ClipInformationFileName: "00001", ConnectionCondition: 1, OUTTimeTicks: 526848, STNTable: stnTable},
+			{ClipInformationFileName: "00002", ConnectionCondition: test.connectionCondition, INTimeTicks: test.inTimeTicks, OUTTimeTicks: 900000, STNTable: stnTable},
+		}}}
+		clips, err := mpls.LoadClipInformation(bdmvRoot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		presentation, err := mpls.HLSPresentation(clips, 45000*6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, segment := range presentation.SegmentsList {
+			if segment.ClipName == "00002" && segment.IsFirstOfPlayItem && segment.IsDiscontinuity != test.isDiscontinuity {
+				t.Fatalf("unexpected discontinuity %v for connection condition %d at %d", segment.IsDiscontinuity, test.connectionCondition, test.inTimeTicks)
+			}
+		}
+	}
+}
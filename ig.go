@@ -0,0 +1,562 @@
+package go_mpls
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+const (
+	MultiplexedStreamModel = 0
+	OutOfMuxStreamModel    = 1
+)
+
+const (
+	AlwaysOnUIModel = 0
+	PopUpUIModel    = 1
+)
+
+const (
+	BranchCommandGroup  = 0
+	CompareCommandGroup = 1
+	SetCommandGroup     = 2
+)
+
+// noReference is used by button and object references that point nowhere.
+const noReference = 0xffff
+
+type Effect struct {
+	Duration                   int
+	PaletteID                  int
+	NumberOfCompositionObjects int
+	CompositionObjectsList     []*CompositionObject
+}
+
+type EffectSequence struct {
+	NumberOfWindows int
+	WindowsList     []*WindowDefinition
+	NumberOfEffects int
+	EffectsList     []*Effect
+}
+
+type NeighborInfo struct {
+	UpperButtonID int
+	LowerButtonID int
+	LeftButtonID  int
+	RightButtonID int
+}
+
+type ButtonState struct {
+	SoundID       int
+	StartObjectID int
+	EndObjectID   int
+	RepeatFlag    bool
+	CompleteFlag  bool
+}
+
+// NavigationCommand is one 12 byte HDMV navigation command.
+type NavigationCommand struct {
+	OperandCount      int
+	CommandGroup      int
+	CommandSubGroup   int
+	ImmediateOperand1 bool
+	ImmediateOperand2 bool
+	BranchOption      int
+	CompareOption     int
+	SetOption         int
+	Destination       uint32
+	Source            uint32
+}
+
+type Button struct {
+	ButtonID                   int
+	NumericSelectValue         int
+	AutoActionFlag             bool
+	X                          int
+	Y                          int
+	NeighborInfo               *NeighborInfo
+	NormalState                *ButtonState
+	SelectedState              *ButtonState
+	ActivatedState             *ButtonState
+	NumberOfNavigationCommands int
+	NavigationCommandsList     []*NavigationCommand
+}
+
+type ButtonOverlapGroup struct {
+	DefaultValidButtonID int
+	NumberOfButtons      int
+	ButtonsList          []*Button
+}
+
+type Page struct {
+	PageID                   int
+	PageVersion              int
+	UOMaskTable              *UOMaskTable
+	InEffects                *EffectSequence
+	OutEffects               *EffectSequence
+	AnimationFrameRateCode   int
+	DefaultSelectedButtonID  int
+	DefaultActivatedButtonID int
+	PaletteID                int
+	NumberOfBOGs             int
+	BOGsList                 []*ButtonOverlapGroup
+}
+
+type InteractiveComposition struct {
+	Width                 int
+	Height                int
+	FrameRate             int
+	CompositionNumber     int
+	CompositionState      int
+	StreamModel           int
+	UIModel               int
+	CompositionTimeOutPTS int64
+	SelectionTimeOutPTS   int64
+	UserTimeOutDuration   int
+	NumberOfPages         int
+	PagesList             []*Page
+}
+
+// ButtonTarget tells where activating a button leads, as far as it can be
+// told from its own navigation commands.
+type ButtonTarget struct {
+	PageID        int
+	ButtonID      int
+	PlayListsList []int
+	TitlesList    []int
+	PagesList     []int
+}
+
+func parseCompositionObject(r *bitReader) *CompositionObject {
+	compositionObject := &CompositionObject{
+		ObjectID:  r.readInt(16),
+		WindowID:  r.readInt(8),
+		IsCropped: r.readFlag(),
+		IsForced:  r.readFlag(),
+	}
+	r.skipBits(6)
+	compositionObject.X = r.readInt(16)
+	compositionObject.Y = r.readInt(16)
+	if compositionObject.IsCropped {
+		compositionObject.CropX = r.readInt(16)
+		compositionObject.CropY = r.readInt(16)
+		compositionObject.CropWidth = r.readInt(16)
+		compositionObject.CropHeight = r.readInt(16)
+	}
+	return compositionObject
+}
+
+func parseEffectSequence(r *bitReader) *EffectSequence {
+	effectSequence := &EffectSequence{NumberOfWindows: r.readInt(8)}
+	for i := 0; i < effectSequence.NumberOfWindows && !r.overrun(); i++ {
+		effectSequence.WindowsList = append(effectSequence.WindowsList, &WindowDefinition{
+			WindowID: r.readInt(8),
+			X:        r.readInt(16),
+			Y:        r.readInt(16),
+			Width:    r.readInt(16),
+			Height:   r.readInt(16),
+		})
+	}
+
+	effectSequence.NumberOfEffects = r.readInt(8)
+	for i := 0; i < effectSequence.NumberOfEffects && !r.overrun(); i++ {
+		effect := &Effect{
+			Duration:                   r.readInt(24),
+			PaletteID:                  r.readInt(8),
+			NumberOfCompositionObjects: r.readInt(8),
+		}
+		for j := 0; j < effect.NumberOfCompositionObjects && !r.overrun(); j++ {
+			effect.CompositionObjectsList = append(effect.CompositionObjectsList, parseCompositionObject(r))
+		}
+		effectSequence.EffectsList = append(effectSequence.EffectsList, effect)
+	}
+	return effectSequence
+}
+
+func parseNavigationCommand(r *bitReader) *NavigationCommand {
+	command := &NavigationCommand{
+		OperandCount:      r.readInt(3),
+		CommandGroup:      r.readInt(2),
+		CommandSubGroup:   r.readInt(3),
+		ImmediateOperand1: r.readFlag(),
+		ImmediateOperand2: r.readFlag(),
+	}
+	r.skipBits(2)
+	command.BranchOption = r.readInt(4)
+	r.skipBits(4)
+	command.CompareOption = r.readInt(4)
+	r.skipBits(3)
+	command.SetOption = r.readInt(5)
+	command.Destination = uint32(r.readBits(32))
+	command.Source = uint32(r.readBits(32))
+	return command
+}
+
+func parseButton(r *bitReader) *Button {
+	button := &Button{
+		ButtonID:           r.readInt(16),
+		NumericSelectValue: r.readInt(16),
+		AutoActionFlag:     r.readFlag(),
+	}
+	r.skipBits(7)
+	button.X = r.readInt(16)
+	button.Y = r.readInt(16)
+	button.NeighborInfo = &NeighborInfo{
+		UpperButtonID: r.readInt(16),
+		LowerButtonID: r.readInt(16),
+		LeftButtonID:  r.readInt(16),
+		RightButtonID: r.readInt(16),
+	}
+
+	button.NormalState = &ButtonState{StartObjectID: r.readInt(16), EndObjectID: r.readInt(16), RepeatFlag: r.readFlag(), CompleteFlag: r.readFlag()}
+	r.skipBits(6)
+	button.SelectedState = &ButtonState{SoundID: r.readInt(8), StartObjectID: r.readInt(16), EndObjectID: r.readInt(16), RepeatFlag: r.readFlag(), CompleteFlag: r.readFlag()}
+	r.skipBits(6)
+	button.ActivatedState = &ButtonState{SoundID: r.readInt(8), StartObjectID: r.readInt(16), EndObjectID: r.readInt(16)}
+
+	button.NumberOfNavigationCommands = r.readInt(16)
+	for i := 0; i < button.NumberOfNavigationCommands && !r.overrun(); i++ {
+		button.NavigationCommandsList = append(button.NavigationCommandsList, parseNavigationCommand(r))
+	}
+	return button
+}
+
+func parsePage(r *bitReader) *Page {
+	page := &Page{
+		PageID:      r.readInt(8),
+		PageVersion: r.readInt(8),
+	}
+	uoMask := make([]byte, 8)
+	for i := range uoMask {
+		uoMask[i] = byte(r.readBits(8))
+	}
+	page.UOMaskTable = parseUOMaskTable(uoMask)
+	page.InEffects = parseEffectSequence(r)
+	page.OutEffects = parseEffectSequence(r)
+	page.AnimationFrameRateCode = r.readInt(8)
+	page.DefaultSelectedButtonID = r.readInt(16)
+	page.DefaultActivatedButtonID = r.readInt(16)
+	page.PaletteID = r.readInt(8)
+
+	page.NumberOfBOGs = r.readInt(8)
+	for i := 0; i < page.NumberOfBOGs && !r.overrun(); i++ {
+		bog := &ButtonOverlapGroup{
+			DefaultValidButtonID: r.readInt(16),
+			NumberOfButtons:      r.readInt(8),
+		}
+		for j := 0; j < bog.NumberOfButtons && !r.overrun(); j++ {
+			bog.ButtonsList = append(bog.ButtonsList, parseButton(r))
+		}
+		page.BOGsList = append(page.BOGsList, bog)
+	}
+	return page
+}
+
+// parseInteractiveComposition decodes an ICS whose fragments have already
+// been joined. header holds the first 8 bytes of the first fragment.
+func parseInteractiveComposition(header, rawData []byte) (*InteractiveComposition, error) {
+	if len(header) < 8 || len(rawData) < 3 {
+		return nil, errors.New("invalid interactive composition segment")
+	}
+	dataLength := int(rawData[0])<<16 | int(rawData[1])<<8 | int(rawData[2])
+	if 3+dataLength > len(rawData) {
+		return nil, errors.New("interactive composition is truncated")
+	}
+
+	composition := &InteractiveComposition{
+		Width:             int(header[0])<<8 | int(header[1]),
+		Height:            int(header[2])<<8 | int(header[3]),
+		FrameRate:         int(header[4] >> 4),
+		CompositionNumber: int(header[5])<<8 | int(header[6]),
+		CompositionState:  int(header[7]),
+	}
+
+	r := newBitReader(rawData[3 : 3+dataLength])
+	composition.StreamModel = r.readInt(1)
+	composition.UIModel = r.readInt(1)
+	r.skipBits(6)
+	if composition.StreamModel == MultiplexedStreamModel {
+		r.skipBits(7)
+		composition.CompositionTimeOutPTS = int64(r.readBits(33))
+		r.skipBits(7)
+		composition.SelectionTimeOutPTS = int64(r.readBits(33))
+	}
+	composition.UserTimeOutDuration = r.readInt(24)
+
+	composition.NumberOfPages = r.readInt(8)
+	for i := 0; i < composition.NumberOfPages && !r.overrun(); i++ {
+		composition.PagesList = append(composition.PagesList, parsePage(r))
+	}
+	if r.overrun() {
+		return nil, errors.New("interactive composition is truncated")
+	}
+	return composition, nil
+}
+
+func (composition *InteractiveComposition) Page(pageID int) *Page {
+	for _, page := range composition.PagesList {
+		if page.PageID == pageID {
+			return page
+		}
+	}
+	return nil
+}
+
+func (page *Page) Button(buttonID int) *Button {
+	for _, bog := range page.BOGsList {
+		if button := bog.Button(buttonID); button != nil {
+			return button
+		}
+	}
+	return nil
+}
+
+func (bog *ButtonOverlapGroup) Button(buttonID int) *Button {
+	for _, button := range bog.ButtonsList {
+		if button.ButtonID == buttonID {
+			return button
+		}
+	}
+	return nil
+}
+
+func (command *NavigationCommand) operand(value uint32, immediate bool) string {
+	if immediate {
+		return fmt.Sprint(value)
+	}
+	if value&0x80000000 != 0 {
+		return fmt.Sprintf("PSR%d", value&0x7f)
+	}
+	return fmt.Sprintf("GPR%d", value&0xfff)
+}
+
+var branchCommandNames = map[int][]string{
+	0: {"Nop", "Goto", "Break"},
+	1: {"JumpObject", "JumpTitle", "CallObject", "CallTitle", "Resume"},
+	2: {"PlayPL", "PlayPLatPI", "PlayPLatMK", "TerminatePL", "LinkPI", "LinkMK"},
+}
+
+var compareCommandNames = []string{"", "BC", "EQ", "NE", "GE", "GT", "LE", "LT"}
+
+var setCommandNames = map[int][]string{
+	0: {"", "Move", "Swap", "Add", "Sub", "Mul", "Div", "Mod", "Rnd", "And", "Or", "Xor", "Bitset", "Bitclr", "ShiftL", "ShiftR"},
+	1: {"", "SetStream", "SetNVTimer", "SetButtonPage", "EnableButton", "DisableButton", "SetSecondaryStream", "PopUpMenuOff", "StillOn", "StillOff", "SetOutputMode", "SetStreamSS"},
+}
+
+func lookupCommandName(names []string, option int) string {
+	if option < len(names) && names[option] != "" {
+		return names[option]
+	}
+	return ""
+}
+
+func (command *NavigationCommand) Name() string {
+	name := ""
+	switch command.CommandGroup {
+	case BranchCommandGroup:
+		name = lookupCommandName(branchCommandNames[command.CommandSubGroup], command.BranchOption)
+	case CompareCommandGroup:
+		name = lookupCommandName(compareCommandNames, command.CompareOption)
+	case SetCommandGroup:
+		name = lookupCommandName(setCommandNames[command.CommandSubGroup], command.SetOption)
+	}
+	if name == "" {
+		return fmt.Sprintf("Unknown(%d/%d)", command.CommandGroup, command.CommandSubGroup)
+	}
+	return name
+}
+
+// String disassembles the command, e.g. "PlayPL 800" or "Move GPR3, 5".
+func (command *NavigationCommand) String() string {
+	text := command.Name()
+	if command.OperandCount > 0 {
+		text += " " + command.operand(command.Destination, command.ImmediateOperand1)
+	}
+	if command.OperandCount > 1 {
+		text += ", " + command.operand(command.Source, command.ImmediateOperand2)
+	}
+	return text
+}
+
+func (command *NavigationCommand) isBranch(subGroup int, options ...int) bool {
+	if command.CommandGroup != BranchCommandGroup || command.CommandSubGroup != subGroup {
+		return false
+	}
+	for _, option := range options {
+		if command.BranchOption == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Targets follows the navigation commands of every button on every page and
+// collects the playlists, titles and pages they lead to. Register operands
+// are resolved when the button sets the register to a constant beforehand.
+func (composition *InteractiveComposition) Targets() []*ButtonTarget {
+	var targetsList []*ButtonTarget = nil
+	for _, page := range composition.PagesList {
+		for _, bog := range page.BOGsList {
+			for _, button := range bog.ButtonsList {
+				target := &ButtonTarget{PageID: page.PageID, ButtonID: button.ButtonID}
+				registers := map[uint32]uint32{}
+				resolve := func(value uint32, immediate bool) (int, bool) {
+					if immediate {
+						return int(value), true
+					}
+					resolved, ok := registers[value]
+					return int(resolved), ok
+				}
+
+				for _, command := range button.NavigationCommandsList {
+					switch {
+					case command.CommandGroup == SetCommandGroup && command.CommandSubGroup == 0 && command.SetOption == 1:
+						if value, ok := resolve(command.Source, command.ImmediateOperand2); ok && !command.ImmediateOperand1 {
+							registers[command.Destination] = uint32(value)
+						} else {
+							delete(registers, command.Destination)
+						}
+					case command.CommandGroup == SetCommandGroup && command.CommandSubGroup == 1 && command.SetOption == 3:
+						if command.ImmediateOperand2 && command.Source&0x80000000 != 0 {
+							target.PagesList = append(target.PagesList, int(command.Source&0xff))
+						}
+					case command.isBranch(2, 0, 1, 2):
+						if value, ok := resolve(command.Destination, command.ImmediateOperand1); ok {
+							target.PlayListsList = append(target.PlayListsList, value)
+						}
+					case command.isBranch(1, 1, 3):
+						if value, ok := resolve(command.Destination, command.ImmediateOperand1); ok {
+							target.TitlesList = append(target.TitlesList, value)
+						}
+					}
+				}
+				targetsList = append(targetsList, target)
+			}
+		}
+	}
+	return targetsList
+}
+
+// ExtractIGStream collects the segments of an IG stream, which may be
+// multiplexed with the play items or carried by an interactive graphics
+// menu sub path.
+func (mpls *MPLS) ExtractIGStream(bdmvRoot string, stream *Stream) ([]*GraphicsSegment, error) {
+	if stream.StreamEntry.StreamType == 0x02 {
+		return mpls.extractSubPathGraphicsStream(bdmvRoot, stream)
+	}
+	return mpls.ExtractGraphicsStream(bdmvRoot, stream.StreamEntry.RefToStreamPID)
+}
+
+// extractSubPathGraphicsStream reads the clips of the sub path a stream
+// refers to and moves the segments to playlist time.
+func (mpls *MPLS) extractSubPathGraphicsStream(bdmvRoot string, stream *Stream) ([]*GraphicsSegment, error) {
+	if stream.StreamEntry.RefToSubPathID >= len(mpls.PlayList.SubPathsList) {
+		return nil, errors.New("stream refers to a missing sub path")
+	}
+	subPath := mpls.PlayList.SubPathsList[stream.StreamEntry.RefToSubPathID]
+
+	var segmentsList []*GraphicsSegment = nil
+	for _, subPlayItem := range subPath.SubPlayItemsList {
+		path := filepath.Join(bdmvRoot, "STREAM", subPlayItem.ClipName(stream.StreamEntry.RefToSubClipID)+".m2ts")
+		inTime := int64(subPlayItem.INTimeTicks) * 2
+		outTime := int64(subPlayItem.OUTTimeTicks) * 2
+		offset := mpls.playListTime(subPlayItem.SyncPlayItemID, subPlayItem.SyncStartPTS)
+		segments, err := readGraphicsStream(path, stream.StreamEntry.RefToStreamPID, inTime, outTime, offset)
+		if err != nil {
+			return nil, err
+		}
+		segmentsList = append(segmentsList, segments...)
+	}
+	return segmentsList, nil
+}
+
+// DecodePage draws a page of the interactive composition of displaySet with
+// the button selectedButtonID in its selected state and every other visible
+// button in its normal state. Effects and animations are not played; only
+// the first object of each state is drawn.
+func (decoder *GraphicsDecoder) DecodePage(displaySet *DisplaySet, pageID, selectedButtonID int) (*image.NRGBA, error) {
+	decoder.update(displaySet)
+
+	composition := displaySet.InteractiveComposition
+	if composition == nil {
+		return nil, errors.New("display set without interactive composition")
+	}
+	page := composition.Page(pageID)
+	if page == nil {
+		return nil, fmt.Errorf("page %d is not defined", pageID)
+	}
+	canvas := image.NewNRGBA(image.Rect(0, 0, composition.Width, composition.Height))
+
+	palette, ok := decoder.palettes[page.PaletteID]
+	if !ok {
+		return nil, fmt.Errorf("palette %d is not defined", page.PaletteID)
+	}
+	colors := make([]color.NRGBA, 256)
+	for id, entry := range palette.Entries {
+		colors[id] = entry.RGBA(composition.Height > 576)
+	}
+
+	for _, bog := range page.BOGsList {
+		button := bog.Button(selectedButtonID)
+		if button == nil {
+			button = bog.Button(bog.DefaultValidButtonID)
+		}
+		if button == nil {
+			continue
+		}
+
+		state := button.NormalState
+		if button.ButtonID == selectedButtonID {
+			state = button.SelectedState
+		}
+		if state.StartObjectID == noReference {
+			continue
+		}
+		object, ok := decoder.objects[state.StartObjectID]
+		if !ok {
+			return nil, fmt.Errorf("object %d is not defined", state.StartObjectID)
+		}
+		pixels := decodeRLE(object)
+		for y := 0; y < object.Height; y++ {
+			for x := 0; x < object.Width; x++ {
+				canvas.SetNRGBA(button.X+x, button.Y+y, colors[pixels[y*object.Width+x]])
+			}
+		}
+	}
+	return canvas, nil
+}
+
+// ExportIGPages renders every page of every interactive composition into dir
+// as <index>_page<id>.png, with the default selected button highlighted.
+func ExportIGPages(displaySets []*DisplaySet, dir string) error {
+	decoder := NewGraphicsDecoder()
+	for i, displaySet := range displaySets {
+		if displaySet.InteractiveComposition == nil {
+			decoder.update(displaySet)
+			continue
+		}
+		for _, page := range displaySet.InteractiveComposition.PagesList {
+			img, err := decoder.DecodePage(displaySet, page.PageID, page.DefaultSelectedButtonID)
+			if err != nil {
+				return err
+			}
+
+			file, err := os.Create(filepath.Join(dir, fmt.Sprintf("%05d_page%d.png", i, page.PageID)))
+			if err != nil {
+				return err
+			}
+			err = png.Encode(file, img)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package go_mpls
+
+import (
+	"testing"
+)
+
+func writeNavigationCommand(w *bitWriter, group, subGroup, option int, immediate1, immediate2 bool, destination, source uint32) {
+	w.writeBits(2, 3)
+	w.writeBits(uint64(group), 2)
+	w.writeBits(uint64(subGroup), 3)
+	for _, immediate := range []bool{immediate1, immediate2} {
+		if immediate {
+			w.writeBits(1, 1)
+		} else {
+			w.writeBits(0, 1)
+		}
+	}
+	w.writeBits(0, 2)
+	if group == BranchCommandGroup {
+		w.writeBits(uint64(option), 4)
+		w.writeBits(0, 16)
+	} else {
+		w.writeBits(0, 15)
+		w.writeBits(uint64(option), 5)
+	}
+	w.writeBits(uint64(destination), 32)
+	w.writeBits(uint64(source), 32)
+}
+
+func writeButton(w *bitWriter, buttonID, x int, objectID int) {
+	w.writeBits(uint64(buttonID), 16)
+	w.writeBits(uint64(buttonID), 16)
+	w.writeBits(0, 8)
+	w.writeBits(uint64(x), 16)
+	w.writeBits(0, 16)
+	w.writeBits(noReference, 16)
+	w.writeBits(noReference, 16)
+	w.writeBits(uint64(3-buttonID), 16)
+	w.writeBits(uint64(3-buttonID), 16)
+	w.writeBits(uint64(objectID), 16)
+	w.writeBits(uint64(objectID), 16)
+	w.writeBits(0, 8)
+	w.writeBits(0xff, 8)
+	w.writeBits(uint64(objectID+1), 16)
+	w.writeBits(uint64(objectID+1), 16)
+	w.writeBits(0, 8)
+	w.writeBits(0xff, 8)
+	w.writeBits(noReference, 16)
+	w.writeBits(noReference, 16)
+}
+
+func buildInteractiveComposition() []byte {
+	w := &bitWriter{}
+	w.writeBits(0x40, 8)
+	w.writeBits(0, 40)
+	w.writeBits(0, 40)
+	w.writeBits(0, 24)
+	w.writeBits(1, 8)
+
+	w.writeBits(0, 8)
+	w.writeBits(0, 8)
+	w.writeBits(0, 64)
+	w.writeBits(0, 16)
+	w.writeBits(0, 16)
+	w.writeBits(0, 8)
+	w.writeBits(1, 16)
+	w.writeBits(noReference, 16)
+	w.writeBits(0, 8)
+	w.writeBits(1, 8)
+
+	w.writeBits(1, 16)
+	w.writeBits(2, 8)
+	writeButton(w, 1, 0, 0)
+	w.writeBits(2, 16)
+	writeNavigationCommand(w, SetCommandGroup, 0, 1, false, true, 0, 800)
+	writeNavigationCommand(w, BranchCommandGroup, 2, 0, false, false, 0, 0)
+	writeButton(w, 2, 4, 2)
+	w.writeBits(1, 16)
+	writeNavigationCommand(w, BranchCommandGroup, 1, 1, true, false, 2, 0)
+
+	length := len(w.data)
+	return append([]byte{byte(length >> 16), byte(length >> 8), byte(length)}, w.data...)
+}
+
+func buildButtonObject(objectID int, colorIndex byte) []byte {
+	return []byte{0x00, byte(objectID), 0x00, 0xc0, 0x00, 0x00, 0x08, 0x00, 0x02, 0x00, 0x01,
+		colorIndex, colorIndex, 0x00, 0x00}
+}
+
+func TestDecodeInteractiveGraphics(t *testing.T) {
+	header := []byte{0x07, 0x80, 0x04, 0x38, 0x10, 0x00, 0x01, 0x80}
+	composition := buildInteractiveComposition()
+	split := len(composition) / 2
+	segments := []*GraphicsSegment{
+		{SegmentType: InteractiveCompositionSegment, PTS: 90000, Data: append(append(append([]byte(nil), header...), 0x80), composition[:split]...)},
+		{SegmentType: InteractiveCompositionSegment, PTS: 90000, Data: append(append(append([]byte(nil), header...), 0x40), composition[split:]...)},
+		{SegmentType: PaletteDefinitionSegment, PTS: 90000, Data: []byte{0x00, 0x00, 0x01, 0xeb, 0x80, 0x80, 0xff, 0x02, 0x10, 0x80, 0x80, 0xff}},
+		{SegmentType: ObjectDefinitionSegment, PTS: 90000, Data: buildButtonObject(0, 0x02)},
+		{SegmentType: ObjectDefinitionSegment, PTS: 90000, Data: buildButtonObject(1, 0x01)},
+		{SegmentType: ObjectDefinitionSegment, PTS: 90000, Data: buildButtonObject(2, 0x02)},
+		{SegmentType: EndOfDisplaySetSegment, PTS: 90000},
+	}
+
+	displaySets := GroupDisplaySets(segments)
+	ic := displaySets[0].InteractiveComposition
+	if ic == nil || ic.Width != 1920 || ic.UIModel != PopUpUIModel || ic.StreamModel != MultiplexedStreamModel || len(ic.PagesList) != 1 {
+		t.Fatalf("unexpected interactive composition %#v", ic)
+	}
+	button := ic.PagesList[0].Button(2)
+	if button == nil || button.X != 4 || button.NeighborInfo.LeftButtonID != 1 || len(button.NavigationCommandsList) != 1 {
+		t.Fatalf("unexpected button %#v", button)
+	}
+	if text := ic.PagesList[0].Button(1).NavigationCommandsList[0].String(); text != "Move GPR0, 800" {
+		t.Fatalf("unexpected disassembly %q", text)
+	}
+
+	targets := ic.Targets()
+	if len(targets) != 2 || len(targets[0].PlayListsList) != 1 || targets[0].PlayListsList[0] != 800 ||
+		len(targets[1].TitlesList) != 1 || targets[1].TitlesList[0] != 2 {
+		t.Fatalf("unexpected targets %#v %#v", targets[0], targets[1])
+	}
+
+	img, err := NewGraphicsDecoder().DecodePage(displaySets[0], 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := img.NRGBAAt(0, 0); c.R != 255 || c.A != 255 {
+		t.Fatalf("selected button not highlighted %v", c)
+	}
+	if c := img.NRGBAAt(4, 0); c.A != 0 {
+		t.Fatalf("unexpected pixel %v", c)
+	}
+}
@@ -0,0 +1,191 @@
+package go_mpls
+
+import (
+	"fmt"
+)
+
+const EntryMark = 0x01
+
+type Chapter struct {
+	Number     int
+	PlayItemID int
+	// TimeTicks is the 45 kHz position of the chapter on the playlist
+	// timeline, which starts at 0 with the first play item.
+	TimeTicks int
+}
+
+func (chapter *Chapter) Seconds() float64 {
+	return float64(chapter.TimeTicks) / 45000
+}
+
+// Name returns the generic chapter title, as playlist marks carry no names.
+func (chapter *Chapter) Name() string {
+	return fmt.Sprintf("Chapter %02d", chapter.Number)
+}
+
+// StreamInfo, PlayItemInfo, AngleInfo and PlayListInfo are a flattened view
+// of a playlist meant for JSON output. The StreamsList of an angle holds the
+// streams of each of its clips, in the order of ClipsList.
+type StreamInfo struct {
+	PID        int    `json:"pid"`
+	CodingType string `json:"codingType"`
+	Format     string `json:"format,omitempty"`
+	Language   string `json:"language,omitempty"`
+	SubPathID  *int   `json:"subPathID,omitempty"`
+}
+
+type STNTableInfo struct {
+	VideoStreamsList []*StreamInfo `json:"video,omitempty"`
+	AudioStreamsList []*StreamInfo `json:"audio,omitempty"`
+	PGStreamsList    []*StreamInfo `json:"pg,omitempty"`
+	IGStreamsList    []*StreamInfo `json:"ig,omitempty"`
+}
+
+type PlayItemInfo struct {
+	ClipName            string        `json:"clip"`
+	INTime              float64       `json:"inTime"`
+	OUTTime             float64       `json:"outTime"`
+	Duration            float64       `json:"duration"`
+	ConnectionCondition int           `json:"connectionCondition"`
+	AngleClipsList      []string      `json:"angleClips,omitempty"`
+	Streams             *STNTableInfo `json:"streams,omitempty"`
+}
+
+type AngleInfo struct {
+	AngleID     int             `json:"angle"`
+	Duration    float64         `json:"duration"`
+	ClipsList   []string        `json:"clips"`
+	StreamsList []*STNTableInfo `json:"streams"`
+}
+
+type PlayListInfo struct {
+	Path           string          `json:"path"`
+	Duration       float64         `json:"duration"`
+	NumberOfAngles int             `json:"numberOfAngles"`
+	PlayItemsList  []*PlayItemInfo `json:"playItems"`
+	ChaptersList   []float64       `json:"chapters"`
+	AnglesList     []*AngleInfo    `json:"angles,omitempty"`
+	Fingerprint    string          `json:"fingerprint"`
+	Signature      string          `json:"durationSignature"`
+}
+
+func (item *PlayItem) DurationTicks() int {
+	return item.OUTTimeTicks - item.INTimeTicks
+}
+
+// DurationTicks returns the 45 kHz length of the playlist.
+func (mpls *MPLS) DurationTicks() int {
+	duration := 0
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		duration += playItem.DurationTicks()
+	}
+	return duration
+}
+
+func (mpls *MPLS) Duration() float64 {
+	return float64(mpls.DurationTicks()) / 45000
+}
+
+// Chapters returns the entry marks on the playlist timeline.
+func (mpls *MPLS) Chapters() []*Chapter {
+	var chaptersList []*Chapter = nil
+	if mpls.PlayListMark == nil {
+		return chaptersList
+	}
+	for _, mark := range mpls.PlayListMark.PlayListMarksList {
+		if mark.MarkType != EntryMark || mark.RefToPlayItemID >= len(mpls.PlayList.PlayItemList) {
+			continue
+		}
+		chaptersList = append(chaptersList, &Chapter{
+			Number:     len(chaptersList) + 1,
+			PlayItemID: mark.RefToPlayItemID,
+			TimeTicks:  int(mpls.playListTime(mark.RefToPlayItemID, mark.MarkTimeTicks) / 2),
+		})
+	}
+	return chaptersList
+}
+
+func newStreamInfo(stream *Stream) *StreamInfo {
+	info := &StreamInfo{
+		PID:        stream.StreamEntry.RefToStreamPID,
+		CodingType: stream.StreamAttributes.StreamCodingType.String(),
+		Language:   stream.StreamAttributes.LanguageCode,
+	}
+	if stream.StreamEntry.StreamType == 0x02 || stream.StreamEntry.StreamType == 0x04 {
+		subPathID := stream.StreamEntry.RefToSubPathID
+		info.SubPathID = &subPathID
+	}
+	switch stream.StreamAttributes.StreamCodingType {
+	case MPEG1Video, MPEG2Video, MPEG4AVCVideo, MPEG4MVCVideo, SMTPEVC1Video, HEVCVideo:
+		info.Format = fmt.Sprintf("%v %v", stream.StreamAttributes.VideoFormat, stream.StreamAttributes.DynamicRangeType)
+	case PresentationGraphics, InteractiveGraphics, TextSubtitle:
+	default:
+		info.Format = stream.StreamAttributes.AudioFormat.String()
+	}
+	return info
+}
+
+func newStreamInfosList(streams []*Stream) []*StreamInfo {
+	var infosList []*StreamInfo = nil
+	for _, stream := range streams {
+		infosList = append(infosList, newStreamInfo(stream))
+	}
+	return infosList
+}
+
+func (stn *STNTable) Info() *STNTableInfo {
+	return &STNTableInfo{
+		VideoStreamsList: newStreamInfosList(stn.PrimaryVideoStreamsList),
+		AudioStreamsList: newStreamInfosList(stn.PrimaryAudioStreamsList),
+		PGStreamsList:    newStreamInfosList(stn.PrimaryPGStreamsList),
+		IGStreamsList:    newStreamInfosList(stn.PrimaryIGStreamsList),
+	}
+}
+
+// Info builds the JSON view of the playlist, with one angle entry per angle
+// when the playlist has multi-angle play items.
+func (mpls *MPLS) Info() *PlayListInfo {
+	info := &PlayListInfo{
+		Path:           mpls.FilePath,
+		Duration:       mpls.Duration(),
+		NumberOfAngles: mpls.NumberOfAngles(),
+		ChaptersList:   []float64{},
+		Fingerprint:    mpls.PlayList.Fingerprint().String(),
+		Signature:      mpls.PlayList.DurationSignature().String(),
+	}
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		playItemInfo := &PlayItemInfo{
+			ClipName:            playItem.ClipInformationFileName,
+			INTime:              float64(playItem.INTimeTicks) / 45000,
+			OUTTime:             float64(playItem.OUTTimeTicks) / 45000,
+			Duration:            float64(playItem.DurationTicks()) / 45000,
+			ConnectionCondition: playItem.ConnectionCondition,
+		}
+		for _, angle := range playItem.AnglesList {
+			playItemInfo.AngleClipsList = append(playItemInfo.AngleClipsList, angle.ClipInformationFileName)
+		}
+		if playItem.STNTable != nil {
+			playItemInfo.Streams = playItem.STNTable.Info()
+		}
+		info.PlayItemsList = append(info.PlayItemsList, playItemInfo)
+	}
+	for _, chapter := range mpls.Chapters() {
+		info.ChaptersList = append(info.ChaptersList, chapter.Seconds())
+	}
+
+	if info.NumberOfAngles > 1 {
+		for angleID, anglePlayList := range mpls.ExpandAngles() {
+			angleInfo := &AngleInfo{AngleID: angleID + 1, Duration: anglePlayList.Duration()}
+			for _, playItem := range anglePlayList.PlayList.PlayItemList {
+				angleInfo.ClipsList = append(angleInfo.ClipsList, playItem.ClipInformationFileName)
+				streams := &STNTableInfo{}
+				if playItem.STNTable != nil {
+					streams = playItem.STNTable.Info()
+				}
+				angleInfo.StreamsList = append(angleInfo.StreamsList, streams)
+			}
+			info.AnglesList = append(info.AnglesList, angleInfo)
+		}
+	}
+	return info
+}
@@ -0,0 +1,120 @@
+// Package fixture builds the MPLS playlists and UDF disc images used by the
+// tests of the library and of the commands. It does not import the library,
+// so its own tests can use it too; stream types and other typed constants
+// are passed as plain numbers.
+package fixture
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Stream returns a stream entry of a stream of the main path with PID pid,
+// followed by its stream attributes.
+func Stream(pid int, attributes ...byte) []byte {
+	data := []byte{9, 0x01, byte(pid >> 8), byte(pid), 0, 0, 0, 0, 0, 0}
+	data = append(data, byte(len(attributes)))
+	return append(data, attributes...)
+}
+
+// STNTable takes streams as primary video and primary audio streams, in
+// that order.
+func STNTable(numberOfVideoStreams int, streams ...[]byte) []byte {
+	data := make([]byte, 16)
+	data[4] = byte(numberOfVideoStreams)
+	data[5] = byte(len(streams) - numberOfVideoStreams)
+	for _, stream := range streams {
+		data = append(data, stream...)
+	}
+	binary.BigEndian.PutUint16(data[0:2], uint16(len(data)-2))
+	return data
+}
+
+func PlayItem(clip string, connectionCondition, inTime, outTime int, stnTable []byte) []byte {
+	data := make([]byte, 34)
+	copy(data[2:11], clip+"M2TS")
+	data[12] = byte(connectionCondition)
+	binary.BigEndian.PutUint32(data[14:18], uint32(inTime))
+	binary.BigEndian.PutUint32(data[18:22], uint32(outTime))
+	data = append(data, stnTable...)
+	binary.BigEndian.PutUint16(data[0:2], uint16(len(data)-2))
+	return data
+}
+
+// SubPath returns a sub path with one sub play item of clip lasting one
+// second.
+func SubPath(subPathType int, clip string, syncPlayItemID int) []byte {
+	subPlayItem := make([]byte, 30)
+	binary.BigEndian.PutUint16(subPlayItem[0:2], 28)
+	copy(subPlayItem[2:11], clip+"M2TS")
+	binary.BigEndian.PutUint32(subPlayItem[20:24], 45000)
+	binary.BigEndian.PutUint16(subPlayItem[24:26], uint16(syncPlayItemID))
+
+	data := []byte{0, 0, 0, 0, 0, byte(subPathType), 0, 0, 0, 1}
+	data = append(data, subPlayItem...)
+	binary.BigEndian.PutUint32(data[0:4], uint32(len(data)-4))
+	return data
+}
+
+// Mark returns an entry mark of play item playItemID at timeTicks.
+func Mark(playItemID, timeTicks int) []byte {
+	data := make([]byte, 14)
+	data[1] = 0x01
+	binary.BigEndian.PutUint16(data[2:4], uint16(playItemID))
+	binary.BigEndian.PutUint32(data[4:8], uint32(timeTicks))
+	binary.BigEndian.PutUint16(data[8:10], 0xffff)
+	return data
+}
+
+// MPLS returns a standard playback playlist of the given version, like
+// "0200", without extension data.
+func MPLS(version string, playItems, subPaths, marks [][]byte) []byte {
+	data := make([]byte, 0x28)
+	copy(data, "MPLS"+version)
+	appInfo := make([]byte, 18)
+	binary.BigEndian.PutUint32(appInfo[0:4], 14)
+	appInfo[5] = 1
+	data = append(data, appInfo...)
+
+	binary.BigEndian.PutUint32(data[0x08:0x0c], uint32(len(data)))
+	playList := []byte{0, 0, 0, 0, 0, 0, 0, byte(len(playItems)), 0, byte(len(subPaths))}
+	for _, playItem := range playItems {
+		playList = append(playList, playItem...)
+	}
+	for _, subPath := range subPaths {
+		playList = append(playList, subPath...)
+	}
+	binary.BigEndian.PutUint32(playList[0:4], uint32(len(playList)-4))
+	data = append(data, playList...)
+
+	binary.BigEndian.PutUint32(data[0x0c:0x10], uint32(len(data)))
+	playListMark := []byte{0, 0, 0, 0, 0, byte(len(marks))}
+	for _, mark := range marks {
+		playListMark = append(playListMark, mark...)
+	}
+	binary.BigEndian.PutUint32(playListMark[0:4], uint32(len(playListMark)-4))
+	return append(data, playListMark...)
+}
+
+// MovieMPLS returns a playlist of one play item of clip 00000 lasting
+// outTicks, with an HEVC 2160p video stream and an entry mark at every
+// given tick.
+func MovieMPLS(outTicks int, marks ...int) []byte {
+	stnTable := STNTable(1, Stream(0x1011, 0x24, 0x81, 0x12, 0x00, 0x00))
+	var marksList [][]byte = nil
+	for _, ticks := range marks {
+		marksList = append(marksList, Mark(0, ticks))
+	}
+	return MPLS("0300", [][]byte{PlayItem("00000", 1, 0, outTicks, stnTable)}, nil, marksList)
+}
+
+// WriteFile writes rawData to dir/name and returns the path of the file.
+func WriteFile(t testing.TB, dir, name string, rawData []byte) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, rawData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
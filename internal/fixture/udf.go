@@ -0,0 +1,156 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"maps"
+	"slices"
+)
+
+const UDFSectorSize = 2048
+
+const (
+	udfAnchorVolumeDescriptorPointer = 2
+	udfPartitionDescriptor           = 5
+	udfLogicalVolumeDescriptor       = 6
+	udfTerminatingDescriptor         = 8
+	udfFileSetDescriptor             = 256
+	udfFileIdentifierDescriptor      = 257
+	udfExtendedFileEntry             = 266
+)
+
+// udfImage lays out a BD-ROM like image: partition 0 starts at sector 300
+// and the metadata partition maps its blocks to blocks 30 onwards of it.
+type udfImage struct {
+	rawData []byte
+}
+
+func (image *udfImage) sector(n int) []byte {
+	if len(image.rawData) < (n+1)*UDFSectorSize {
+		image.rawData = append(image.rawData, make([]byte, (n+1)*UDFSectorSize-len(image.rawData))...)
+	}
+	return image.rawData[n*UDFSectorSize : (n+1)*UDFSectorSize]
+}
+
+func PutUDFLongAD(rawData []byte, length, block, partitionRef int) {
+	binary.LittleEndian.PutUint32(rawData[0:4], uint32(length))
+	binary.LittleEndian.PutUint32(rawData[4:8], uint32(block))
+	binary.LittleEndian.PutUint16(rawData[8:10], uint16(partitionRef))
+}
+
+// PutUDFFileEntry writes an extended file entry modified on 2024-05-17
+// whose allocation descriptors, of type adType, are allocationDescriptors.
+func PutUDFFileEntry(rawData []byte, fileType byte, size int, adType uint16, allocationDescriptors []byte) {
+	binary.LittleEndian.PutUint16(rawData[0:2], udfExtendedFileEntry)
+	rawData[27] = fileType
+	binary.LittleEndian.PutUint16(rawData[34:36], adType)
+	binary.LittleEndian.PutUint64(rawData[56:64], uint64(size))
+	binary.LittleEndian.PutUint16(rawData[92:94], 1<<12|60)
+	binary.LittleEndian.PutUint16(rawData[94:96], 2024)
+	rawData[96], rawData[97] = 5, 17
+	binary.LittleEndian.PutUint32(rawData[212:216], uint32(len(allocationDescriptors)))
+	copy(rawData[216:], allocationDescriptors)
+}
+
+func udfDirectory(names []string, blocks []int, isDir bool) []byte {
+	var directory bytes.Buffer
+	parent := make([]byte, 40)
+	binary.LittleEndian.PutUint16(parent[0:2], udfFileIdentifierDescriptor)
+	parent[18] = 0b1010
+	directory.Write(parent)
+	for i, name := range names {
+		fid := make([]byte, (38+1+len(name)+3)&^3)
+		binary.LittleEndian.PutUint16(fid[0:2], udfFileIdentifierDescriptor)
+		if isDir {
+			fid[18] = 0b10
+		}
+		fid[19] = byte(1 + len(name))
+		PutUDFLongAD(fid[20:36], UDFSectorSize, blocks[i], 1)
+		fid[38] = 8
+		copy(fid[39:], name)
+		directory.Write(fid)
+	}
+	return directory.Bytes()
+}
+
+// UDF returns an image holding files in BDMV/PLAYLIST. The root, BDMV and
+// PLAYLIST directories are blocks 1 to 3 of the metadata partition and the
+// entries of the files, in name order, blocks 4 onwards. File i is stored
+// from block 100+20*i of partition 0; a file longer than a sector is split
+// into two extents, its second sector onwards being stored from block
+// 110+20*i, so files must be at most 11 sectors long.
+func UDF(files map[string][]byte) []byte {
+	image := &udfImage{}
+	anchor := image.sector(256)
+	binary.LittleEndian.PutUint16(anchor[0:2], udfAnchorVolumeDescriptorPointer)
+	binary.LittleEndian.PutUint32(anchor[16:20], 4*UDFSectorSize)
+	binary.LittleEndian.PutUint32(anchor[20:24], 257)
+
+	partitionDescriptor := image.sector(257)
+	binary.LittleEndian.PutUint16(partitionDescriptor[0:2], udfPartitionDescriptor)
+	binary.LittleEndian.PutUint32(partitionDescriptor[188:192], 300)
+
+	logicalVolumeDescriptor := image.sector(258)
+	binary.LittleEndian.PutUint16(logicalVolumeDescriptor[0:2], udfLogicalVolumeDescriptor)
+	binary.LittleEndian.PutUint32(logicalVolumeDescriptor[212:216], UDFSectorSize)
+	PutUDFLongAD(logicalVolumeDescriptor[248:264], UDFSectorSize, 0, 1)
+	binary.LittleEndian.PutUint32(logicalVolumeDescriptor[264:268], 6+64)
+	binary.LittleEndian.PutUint32(logicalVolumeDescriptor[268:272], 2)
+	copy(logicalVolumeDescriptor[440:], []byte{1, 6, 1, 0, 0, 0})
+	metadataMap := logicalVolumeDescriptor[446 : 446+64]
+	metadataMap[0], metadataMap[1] = 2, 64
+	copy(metadataMap[5:], "*UDF Metadata Partition")
+	binary.LittleEndian.PutUint32(metadataMap[40:44], 20)
+
+	binary.LittleEndian.PutUint16(image.sector(259)[0:2], udfTerminatingDescriptor)
+
+	names := slices.Sorted(maps.Keys(files))
+	metadataExtent := make([]byte, 8)
+	binary.LittleEndian.PutUint32(metadataExtent[0:4], uint32(4+len(names))*UDFSectorSize)
+	binary.LittleEndian.PutUint32(metadataExtent[4:8], 30)
+	PutUDFFileEntry(image.sector(300+20), 250, (4+len(names))*UDFSectorSize, 0, metadataExtent)
+
+	fileSetDescriptor := image.sector(300 + 30)
+	binary.LittleEndian.PutUint16(fileSetDescriptor[0:2], udfFileSetDescriptor)
+	PutUDFLongAD(fileSetDescriptor[400:416], UDFSectorSize, 1, 1)
+
+	root := udfDirectory([]string{"BDMV"}, []int{2}, true)
+	PutUDFFileEntry(image.sector(300+31), 4, len(root), 3, root)
+	bdmv := udfDirectory([]string{"PLAYLIST"}, []int{3}, true)
+	PutUDFFileEntry(image.sector(300+32), 4, len(bdmv), 3, bdmv)
+	var blocksList []int = nil
+	for i, name := range names {
+		blocksList = append(blocksList, 4+i)
+		content := files[name]
+		split := min(len(content), UDFSectorSize)
+		extents := make([]byte, 32)
+		PutUDFLongAD(extents[0:16], split, 100+20*i, 0)
+		PutUDFLongAD(extents[16:32], len(content)-split, 110+20*i, 0)
+		switch {
+		case len(content) == 0:
+			extents = nil
+		case len(content) <= UDFSectorSize:
+			extents = extents[:16]
+		}
+		PutUDFFileEntry(image.sector(300+34+i), 5, len(content), 1, extents)
+		copy(image.sector(300+100+20*i), content[:split])
+		for k := 0; k*UDFSectorSize < len(content)-split; k++ {
+			copy(image.sector(300+110+20*i+k), content[split+k*UDFSectorSize:])
+		}
+	}
+	playList := udfDirectory(names, blocksList, false)
+	PutUDFFileEntry(image.sector(300+33), 4, len(playList), 3, playList)
+	return image.rawData
+}
+
+// UDFEntry returns the file entry of block n of the metadata partition of
+// an image built by UDF.
+func UDFEntry(rawData []byte, n int) []byte {
+	return rawData[(300+30+n)*UDFSectorSize : (300+31+n)*UDFSectorSize]
+}
+
+// UDFMetadataFileEntry returns the file entry of the metadata file of an
+// image built by UDF.
+func UDFMetadataFileEntry(rawData []byte) []byte {
+	return rawData[(300+20)*UDFSectorSize : (300+21)*UDFSectorSize]
+}
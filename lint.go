@@ -0,0 +1,542 @@
+package go_mpls
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+type Severity int
+
+const (
+	SeverityNote Severity = iota + 1
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the SARIF level of the severity.
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityNote:
+		return "note"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "none"
+}
+
+func (severity Severity) MarshalText() ([]byte, error) {
+	return []byte(severity.String()), nil
+}
+
+type LintRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	check       func(mpls *MPLS, report func(location, format string, args ...any))
+}
+
+type LintFinding struct {
+	RuleID   string   `json:"ruleId"`
+	Severity Severity `json:"severity"`
+	Location string   `json:"location"`
+	Message  string   `json:"message"`
+}
+
+// LintConfig turns rules off or changes their severity. A nil config runs
+// every rule with its default severity.
+type LintConfig struct {
+	DisabledRulesList []string
+	Severities        map[string]Severity
+}
+
+func (config *LintConfig) severity(rule *LintRule) (Severity, bool) {
+	if config == nil {
+		return rule.Severity, true
+	}
+	if slices.Contains(config.DisabledRulesList, rule.ID) {
+		return 0, false
+	}
+	if severity, ok := config.Severities[rule.ID]; ok {
+		return severity, true
+	}
+	return rule.Severity, true
+}
+
+func playItemLocation(i int) string {
+	return fmt.Sprintf("PlayList.PlayItemList[%d]", i)
+}
+
+func subPlayItemLocation(i, j int) string {
+	return fmt.Sprintf("PlayList.SubPathsList[%d].SubPlayItemsList[%d]", i, j)
+}
+
+// forEachSTNTable calls f for the stream table of every play item.
+func (mpls *MPLS) forEachSTNTable(f func(location string, stn *STNTable)) {
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		if playItem.STNTable != nil {
+			f(playItemLocation(i)+".STNTable", playItem.STNTable)
+		}
+	}
+}
+
+func stnStreamLists(stn *STNTable) map[string][]*Stream {
+	return map[string][]*Stream{
+		"PrimaryVideoStreamsList":   stn.PrimaryVideoStreamsList,
+		"PrimaryAudioStreamsList":   stn.PrimaryAudioStreamsList,
+		"PrimaryPGStreamsList":      stn.PrimaryPGStreamsList,
+		"SecondaryPGStreamsList":    stn.SecondaryPGStreamsList,
+		"PrimaryIGStreamsList":      stn.PrimaryIGStreamsList,
+		"SecondaryAudioStreamsList": stn.SecondaryAudioStreamsList,
+		"SecondaryVideoStreamsList": stn.SecondaryVideoStreamsList,
+		"DVStreamsList":             stn.DVStreamsList,
+	}
+}
+
+// forEachStream calls f for every stream of every stream table, in a stable
+// order.
+func (mpls *MPLS) forEachStream(f func(location string, stream *Stream)) {
+	mpls.forEachSTNTable(func(location string, stn *STNTable) {
+		lists := stnStreamLists(stn)
+		names := make([]string, 0, len(lists))
+		for name := range lists {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			for k, stream := range lists[name] {
+				f(fmt.Sprintf("%s.%s[%d]", location, name, k), stream)
+			}
+		}
+	})
+}
+
+func checkVersion(mpls *MPLS, report func(location, format string, args ...any)) {
+	if !slices.Contains([]int{100, 200, 300}, mpls.VersionNumber) {
+		report("VersionNumber", "version %04d is not defined", mpls.VersionNumber)
+		return
+	}
+
+	requiredVersion, feature := 100, ""
+	require := func(version int, name string) {
+		if version > requiredVersion {
+			requiredVersion, feature = version, name
+		}
+	}
+	for _, subPath := range mpls.PlayList.SubPathsList {
+		switch subPath.SubPathType {
+		case StereoscopicVideo, StereoscopicIGMenu:
+			require(200, "stereoscopic sub paths")
+		case DolbyVisionEnhancement:
+			require(300, "Dolby Vision enhancement layers")
+		}
+	}
+	mpls.forEachStream(func(location string, stream *Stream) {
+		attributes := stream.StreamAttributes
+		switch {
+		case attributes.StreamCodingType == HEVCVideo || attributes.VideoFormat == VF2160P:
+			require(300, "HEVC or 2160p video")
+		case attributes.StreamCodingType == MPEG4MVCVideo:
+			require(200, "MVC video")
+		}
+	})
+	if mpls.VersionNumber < requiredVersion {
+		report("VersionNumber", "version %04d does not allow %s, which need %04d", mpls.VersionNumber, feature, requiredVersion)
+	}
+}
+
+// checkCounts walks the entries of every section in the order they are
+// stored. The parser reads as many entries as a NumberOf field counts,
+// whatever the Length of their section, so a count that is too large shows
+// as entries running past the end of the section.
+func checkCounts(mpls *MPLS, report func(location, format string, args ...any)) {
+	check := func(location string, number, available int, sizes []int) int {
+		held := 0
+		for _, size := range sizes {
+			if size > available {
+				break
+			}
+			available -= size
+			held++
+		}
+		if number > held {
+			report(location, "count is %d but the section holds %d entries", number, held)
+		}
+		return available
+	}
+	// angle 1 and the first clip of a sub play item are stored in the
+	// entry itself
+	fixedSizes := func(count int) []int {
+		sizes := []int{0}
+		for range count {
+			sizes = append(sizes, 10)
+		}
+		return sizes
+	}
+
+	playItemSizes := make([]int, 0, len(mpls.PlayList.PlayItemList))
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		playItemSizes = append(playItemSizes, playItem.Length+2)
+	}
+	subPathSizes := make([]int, 0, len(mpls.PlayList.SubPathsList))
+	for _, subPath := range mpls.PlayList.SubPathsList {
+		subPathSizes = append(subPathSizes, subPath.Length+4)
+	}
+	available := check("PlayList.NumberOfPlayItems", mpls.PlayList.NumberOfPlayItems, mpls.PlayList.Length-6, playItemSizes)
+	check("PlayList.NumberOfSubPaths", mpls.PlayList.NumberOfSubPaths, available, subPathSizes)
+	if mpls.PlayListMark != nil {
+		check("PlayListMark.NumberOfPlayListMarks", mpls.PlayListMark.NumberOfPlayListMarks, mpls.PlayListMark.Length-2,
+			slices.Repeat([]int{14}, len(mpls.PlayListMark.PlayListMarksList)))
+	}
+
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		if playItem.IsMultiAngle && playItem.STNTable != nil {
+			check(playItemLocation(i)+".NumberOfAngles", playItem.NumberOfAngles,
+				playItem.Length-32-2-playItem.STNTable.Length-2, fixedSizes(len(playItem.AnglesList)))
+		}
+	}
+	mpls.forEachSTNTable(func(location string, stn *STNTable) {
+		available := stn.Length - 14
+		for _, group := range []struct {
+			name    string
+			number  int
+			streams []*Stream
+		}{
+			{"NumberOfPrimaryVideoStreams", stn.NumberOfPrimaryVideoStreams, stn.PrimaryVideoStreamsList},
+			{"NumberOfPrimaryAudioStreams", stn.NumberOfPrimaryAudioStreams, stn.PrimaryAudioStreamsList},
+			{"NumberOfPrimaryPGStreams", stn.NumberOfPrimaryPGStreams, stn.PrimaryPGStreamsList},
+			{"NumberOfSecondaryPGStreams", stn.NumberOfSecondaryPGStreams, stn.SecondaryPGStreamsList},
+			{"NumberOfPrimaryIGStreams", stn.NumberOfPrimaryIGStreams, stn.PrimaryIGStreamsList},
+			{"NumberOfSecondaryAudioStreams", stn.NumberOfSecondaryAudioStreams, stn.SecondaryAudioStreamsList},
+			{"NumberOfSecondaryVideoStreams", stn.NumberOfSecondaryVideoStreams, stn.SecondaryVideoStreamsList},
+			{"NumberOfDVStreams", stn.NumberOfDVStreams, stn.DVStreamsList},
+		} {
+			sizes := make([]int, 0, len(group.streams))
+			for _, stream := range group.streams {
+				sizes = append(sizes, stream.StreamEntry.Length+1+stream.StreamAttributes.Length+1)
+			}
+			available = check(location+"."+group.name, group.number, available, sizes)
+		}
+	})
+	for i, subPath := range mpls.PlayList.SubPathsList {
+		subPlayItemSizes := make([]int, 0, len(subPath.SubPlayItemsList))
+		for _, subPlayItem := range subPath.SubPlayItemsList {
+			subPlayItemSizes = append(subPlayItemSizes, subPlayItem.Length+2)
+		}
+		check(fmt.Sprintf("PlayList.SubPathsList[%d].NumberOfSubPlayItems", i), subPath.NumberOfSubPlayItems, subPath.Length-6, subPlayItemSizes)
+		for j, subPlayItem := range subPath.SubPlayItemsList {
+			if subPlayItem.IsMultiClipEntries {
+				check(subPlayItemLocation(i, j)+".NumberOfMultiClipEntries", subPlayItem.NumberOfMultiClipEntries,
+					subPlayItem.Length-28-2, fixedSizes(len(subPlayItem.MultiClipEntriesList)))
+			}
+		}
+	}
+}
+
+// stnTableLength returns the Length an STN table holding its streams has.
+func stnTableLength(stn *STNTable) int {
+	length := 14
+	for _, streams := range stnStreamLists(stn) {
+		for _, stream := range streams {
+			length += stream.StreamEntry.Length + 1 + stream.StreamAttributes.Length + 1
+		}
+	}
+	return length
+}
+
+func checkLengths(mpls *MPLS, report func(location, format string, args ...any)) {
+	check := func(location string, length, expected int) {
+		if length != expected {
+			report(location, "length is %d but the content takes %d bytes", length, expected)
+		}
+	}
+
+	if mpls.ApplicationInfoPlaylist != nil && mpls.ApplicationInfoPlaylist.Length < 14 {
+		report("ApplicationInfoPlaylist.Length", "length %d is shorter than the 14 bytes defined", mpls.ApplicationInfoPlaylist.Length)
+	}
+
+	playListLength := 6
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		expected := 32
+		if playItem.IsMultiAngle {
+			expected += 2 + 10*len(playItem.AnglesList)
+		}
+		if playItem.STNTable != nil {
+			check(playItemLocation(i)+".STNTable.Length", playItem.STNTable.Length, stnTableLength(playItem.STNTable))
+			expected += playItem.STNTable.Length + 2
+		}
+		check(playItemLocation(i)+".Length", playItem.Length, expected)
+		playListLength += playItem.Length + 2
+	}
+	for i, subPath := range mpls.PlayList.SubPathsList {
+		subPathLength := 6
+		for j, subPlayItem := range subPath.SubPlayItemsList {
+			expected := 28
+			if subPlayItem.IsMultiClipEntries {
+				expected += 2 + 10*len(subPlayItem.MultiClipEntriesList)
+			}
+			check(subPlayItemLocation(i, j)+".Length", subPlayItem.Length, expected)
+			subPathLength += subPlayItem.Length + 2
+		}
+		check(fmt.Sprintf("PlayList.SubPathsList[%d].Length", i), subPath.Length, subPathLength)
+		playListLength += subPath.Length + 4
+	}
+	check("PlayList.Length", mpls.PlayList.Length, playListLength)
+	if mpls.PlaylistMarkStartAddress > 0 && mpls.PlaylistStartAddress+4+mpls.PlayList.Length > mpls.PlaylistMarkStartAddress {
+		report("PlayList.Length", "playlist runs into the playlist marks at 0x%x", mpls.PlaylistMarkStartAddress)
+	}
+
+	if mpls.PlayListMark != nil {
+		check("PlayListMark.Length", mpls.PlayListMark.Length, 2+14*len(mpls.PlayListMark.PlayListMarksList))
+	}
+}
+
+func checkMarks(mpls *MPLS, report func(location, format string, args ...any)) {
+	if mpls.PlayListMark == nil {
+		return
+	}
+	for i, mark := range mpls.PlayListMark.PlayListMarksList {
+		location := fmt.Sprintf("PlayListMark.PlayListMarksList[%d]", i)
+		if mark.RefToPlayItemID >= len(mpls.PlayList.PlayItemList) {
+			report(location+".RefToPlayItemID", "play item %d does not exist", mark.RefToPlayItemID)
+			continue
+		}
+		playItem := mpls.PlayList.PlayItemList[mark.RefToPlayItemID]
+		if mark.MarkTimeTicks < playItem.INTimeTicks || mark.MarkTimeTicks > playItem.OUTTimeTicks {
+			report(location+".MarkTimeStamp", "mark time %d is outside play item %d (%d-%d)", mark.MarkTimeTicks,
+				mark.RefToPlayItemID, playItem.INTimeTicks, playItem.OUTTimeTicks)
+		}
+	}
+}
+
+func checkSyncPlayItems(mpls *MPLS, report func(location, format string, args ...any)) {
+	for i, subPath := range mpls.PlayList.SubPathsList {
+		for j, subPlayItem := range subPath.SubPlayItemsList {
+			if subPlayItem.SyncPlayItemID >= len(mpls.PlayList.PlayItemList) {
+				report(subPlayItemLocation(i, j)+".SyncPlayItemID", "play item %d does not exist", subPlayItem.SyncPlayItemID)
+			}
+		}
+	}
+}
+
+func checkTimes(mpls *MPLS, report func(location, format string, args ...any)) {
+	if len(mpls.PlayList.PlayItemList) == 0 {
+		report("PlayList.PlayItemList", "playlist has no play item")
+	}
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		if playItem.OUTTimeTicks <= playItem.INTimeTicks {
+			report(playItemLocation(i), "OUT time %d is not after IN time %d", playItem.OUTTimeTicks, playItem.INTimeTicks)
+		}
+	}
+	for i, subPath := range mpls.PlayList.SubPathsList {
+		for j, subPlayItem := range subPath.SubPlayItemsList {
+			if subPlayItem.OUTTimeTicks <= subPlayItem.INTimeTicks {
+				report(subPlayItemLocation(i, j), "OUT time %d is not after IN time %d", subPlayItem.OUTTimeTicks, subPlayItem.INTimeTicks)
+			}
+		}
+	}
+}
+
+func checkEnums(mpls *MPLS, report func(location, format string, args ...any)) {
+	check := func(location string, value int, legal ...int) {
+		if !slices.Contains(legal, value) {
+			report(location, "value %d is not defined", value)
+		}
+	}
+
+	if mpls.ApplicationInfoPlaylist != nil {
+		check("ApplicationInfoPlaylist.PlaybackType", int(mpls.ApplicationInfoPlaylist.PlaybackType), int(StandardPlay), int(RandomPlay), int(ShufflePlay))
+	}
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		check(playItemLocation(i)+".ConnectionCondition", playItem.ConnectionCondition, NonSeamlessConnection, SeamlessCleanBreakConnection, SeamlessConnection)
+		check(playItemLocation(i)+".StillMode", playItem.StillMode, NoStill, FiniteStill, InfiniteStill)
+	}
+	for i, subPath := range mpls.PlayList.SubPathsList {
+		check(fmt.Sprintf("PlayList.SubPathsList[%d].SubPathType", i), int(subPath.SubPathType), 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	}
+	if mpls.PlayListMark != nil {
+		for i, mark := range mpls.PlayListMark.PlayListMarksList {
+			check(fmt.Sprintf("PlayListMark.PlayListMarksList[%d].MarkType", i), mark.MarkType, EntryMark, 2)
+		}
+	}
+
+	mpls.forEachStream(func(location string, stream *Stream) {
+		attributes := stream.StreamAttributes
+		check(location+".StreamEntry.StreamType", stream.StreamEntry.StreamType, 1, 2, 3, 4)
+		codingType := int(attributes.StreamCodingType)
+		check(location+".StreamAttributes.StreamCodingType", codingType, 0x01, 0x02, 0x03, 0x04, 0x1b, 0x20, 0x24, 0x80,
+			0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x90, 0x91, 0x92, 0xa1, 0xa2, 0xea)
+		switch attributes.StreamCodingType {
+		case MPEG1Video, MPEG2Video, MPEG4AVCVideo, MPEG4MVCVideo, SMTPEVC1Video, HEVCVideo:
+			check(location+".StreamAttributes.VideoFormat", int(attributes.VideoFormat), 1, 2, 3, 4, 5, 6, 7, 8)
+			check(location+".StreamAttributes.FrameRate", int(attributes.FrameRate), 1, 2, 3, 4, 6, 7)
+		case PresentationGraphics, InteractiveGraphics:
+		case TextSubtitle:
+			check(location+".StreamAttributes.CharacterCode", int(attributes.CharacterCode), 1, 2, 3, 4, 5, 6, 7)
+		default:
+			check(location+".StreamAttributes.AudioFormat", int(attributes.AudioFormat), int(Mono), Stereo, MultiChannel, StereoAndMultiChannel)
+			check(location+".StreamAttributes.SampleRate", int(attributes.SampleRate), int(SR48KHz), SR96KHz, SR192KHz, SR48And192KHz, SR48And96KHz)
+		}
+	})
+}
+
+func checkLanguageCodes(mpls *MPLS, report func(location, format string, args ...any)) {
+	mpls.forEachStream(func(location string, stream *Stream) {
+		switch stream.StreamAttributes.StreamCodingType {
+		case MPEG1Video, MPEG2Video, MPEG4AVCVideo, MPEG4MVCVideo, SMTPEVC1Video, HEVCVideo:
+			return
+		}
+		code := stream.StreamAttributes.LanguageCode
+		if _, err := language.ParseBase(code); err != nil || len(code) != 3 {
+			report(location+".StreamAttributes.LanguageCode", "%q is not an ISO 639-2 language code", code)
+		}
+	})
+}
+
+var lintRulesList = []*LintRule{
+	{ID: "MPLS001", Severity: SeverityError, Description: "version number is defined and allows the features used", check: checkVersion},
+	{ID: "MPLS002", Severity: SeverityError, Description: "NumberOf fields count no more entries than their section holds", check: checkCounts},
+	{ID: "MPLS003", Severity: SeverityError, Description: "Length fields agree with the bytes of their content", check: checkLengths},
+	{ID: "MPLS004", Severity: SeverityError, Description: "playlist marks refer to existing play items and lie inside them", check: checkMarks},
+	{ID: "MPLS005", Severity: SeverityError, Description: "sub play items synchronise with existing play items", check: checkSyncPlayItems},
+	{ID: "MPLS006", Severity: SeverityWarning, Description: "enumerated fields hold defined values", check: checkEnums},
+	{ID: "MPLS007", Severity: SeverityWarning, Description: "language codes are ISO 639-2 codes", check: checkLanguageCodes},
+	{ID: "MPLS008", Severity: SeverityError, Description: "play items are not empty", check: checkTimes},
+}
+
+// LintRules returns the rules run by Lint.
+func LintRules() []*LintRule {
+	return lintRulesList
+}
+
+func (mpls *MPLS) Lint(config *LintConfig) []*LintFinding {
+	var findingsList []*LintFinding = nil
+	for _, rule := range lintRulesList {
+		severity, enabled := config.severity(rule)
+		if !enabled {
+			continue
+		}
+		rule.check(mpls, func(location, format string, args ...any) {
+			findingsList = append(findingsList, &LintFinding{
+				RuleID:   rule.ID,
+				Severity: severity,
+				Location: location,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		})
+	}
+	return findingsList
+}
+
+func WriteLintText(w io.Writer, path string, findings []*LintFinding) error {
+	for _, finding := range findings {
+		if _, err := fmt.Fprintf(w, "%s: %s: %s [%s] %s\n", path, finding.Severity, finding.Location, finding.RuleID, finding.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func WriteLintJSON(w io.Writer, findings []*LintFinding) error {
+	if findings == nil {
+		findings = []*LintFinding{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(findings)
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifDriver struct {
+	Name  string       `json:"name"`
+	Rules []*sarifRule `json:"rules"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool      `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Version string      `json:"version"`
+	Schema  string      `json:"$schema"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+// WriteLintSARIF writes the findings as a SARIF 2.1.0 log with path as the
+// analysed artifact.
+func WriteLintSARIF(w io.Writer, path string, findings []*LintFinding) error {
+	run := &sarifRun{Tool: sarifTool{Driver: sarifDriver{Name: "go-mpls"}}, Results: []*sarifResult{}}
+	for _, rule := range lintRulesList {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, &sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: rule.Severity.String()},
+		})
+	}
+	for _, finding := range findings {
+		run.Results = append(run.Results, &sarifResult{
+			RuleID:  finding.RuleID,
+			Level:   finding.Severity.String(),
+			Message: sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: path}},
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: finding.Location}},
+			}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []*sarifRun{run},
+	})
+}
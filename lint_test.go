@@ -0,0 +1,106 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/syxxzzr/go-mpls/internal/fixture"
+)
+
+func TestLint(t *testing.T) {
+	stnTable := fixture.STNTable(1,
+		fixture.Stream(0x1011, byte(HEVCVideo), 0x81, 0x12, 0x00, 0x00),
+		fixture.Stream(0x1100, byte(DolbyDigitalAudio), 0x61, 'e', 'n', 'g'),
+		fixture.Stream(0x1101, byte(DolbyDigitalAudio), 0x61, 'x', 'x', '1'))
+	rawData := fixture.MPLS("0200",
+		[][]byte{
+			fixture.PlayItem("00001", 1, 0, 90000, stnTable),
+			fixture.PlayItem("00002", 5, 0, 90000, stnTable),
+		},
+		[][]byte{fixture.SubPath(int(TextSubtitlePath), "00003", 0), fixture.SubPath(int(TextSubtitlePath), "00004", 2)},
+		[][]byte{fixture.Mark(0, 0), fixture.Mark(3, 0)})
+	path := fixture.WriteFile(t, t.TempDir(), "00800.mpls", rawData)
+
+	mpls, err := Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mpls.PlayList.SubPathsList) != 2 || mpls.PlayList.SubPathsList[1].SubPlayItemsList[0].ClipInformationFileName != "00004" {
+		t.Fatalf("unexpected sub paths %#v", mpls.PlayList.SubPathsList)
+	}
+
+	findings := mpls.Lint(nil)
+	ruleIDs := map[string]int{}
+	for _, finding := range findings {
+		ruleIDs[finding.RuleID]++
+	}
+	if len(findings) != 5 || ruleIDs["MPLS001"] != 1 || ruleIDs["MPLS004"] != 1 || ruleIDs["MPLS005"] != 1 || ruleIDs["MPLS007"] != 2 {
+		var text bytes.Buffer
+		WriteLintText(&text, path, findings)
+		t.Fatalf("unexpected findings\n%s", text.String())
+	}
+
+	findings = mpls.Lint(&LintConfig{DisabledRulesList: []string{"MPLS007"}, Severities: map[string]Severity{"MPLS001": SeverityNote}})
+	if len(findings) != 3 || findings[0].Severity != SeverityNote {
+		t.Fatalf("unexpected configured findings %#v", findings)
+	}
+
+	var sarif bytes.Buffer
+	if err := WriteLintSARIF(&sarif, path, findings); err != nil {
+		t.Fatal(err)
+	}
+	var log map[string]any
+	if err := json.Unmarshal(sarif.Bytes(), &log); err != nil || log["version"] != "2.1.0" {
+		t.Fatalf("invalid SARIF %s", sarif.String())
+	}
+	if !strings.Contains(sarif.String(), `"ruleId": "MPLS004"`) || !strings.Contains(sarif.String(), `"level": "note"`) {
+		t.Fatalf("unexpected SARIF %s", sarif.String())
+	}
+}
+
+func TestLintCounts(t *testing.T) {
+	audio := fixture.Stream(0x1100, byte(DolbyDigitalAudio), 0x61, 'e', 'n', 'g')
+	stnTable := fixture.STNTable(1, fixture.Stream(0x1011, byte(HEVCVideo), 0x81, 0x12, 0x00, 0x00), audio)
+	rawData := fixture.MPLS("0300", [][]byte{fixture.PlayItem("00001", 1, 0, 90000, stnTable)}, nil,
+		[][]byte{fixture.Mark(0, 0), fixture.Mark(0, 45000)})
+	countFindings := func(rawData []byte) []*LintFinding {
+		mpls, err := ParseBytes(rawData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var findingsList []*LintFinding = nil
+		for _, finding := range mpls.Lint(nil) {
+			if finding.RuleID == "MPLS002" {
+				findingsList = append(findingsList, finding)
+			}
+		}
+		return findingsList
+	}
+	if findings := countFindings(rawData); len(findings) != 0 {
+		t.Fatalf("unexpected findings %#v", findings[0])
+	}
+
+	// the STN table ends before the audio stream it counts, and a third
+	// mark is read from the bytes following the mark section
+	binary.BigEndian.PutUint16(stnTable[0:2], uint16(len(stnTable)-2-len(audio)))
+	rawData = fixture.MPLS("0300", [][]byte{fixture.PlayItem("00001", 1, 0, 90000, stnTable)}, nil,
+		[][]byte{fixture.Mark(0, 0), fixture.Mark(0, 45000)})
+	markStart := int(binary.BigEndian.Uint32(rawData[0x0c:0x10]))
+	binary.BigEndian.PutUint16(rawData[markStart+4:markStart+6], 3)
+	rawData = append(rawData, make([]byte, 14)...)
+
+	findings := countFindings(rawData)
+	if len(findings) != 2 {
+		t.Fatalf("unexpected findings %#v", findings)
+	}
+	if findings[0].Location != "PlayListMark.NumberOfPlayListMarks" || findings[0].Message != "count is 3 but the section holds 2 entries" {
+		t.Fatalf("unexpected finding %#v", findings[0])
+	}
+	if findings[1].Location != "PlayList.PlayItemList[0].STNTable.NumberOfPrimaryAudioStreams" ||
+		findings[1].Message != "count is 1 but the section holds 0 entries" {
+		t.Fatalf("unexpected finding %#v", findings[1])
+	}
+}
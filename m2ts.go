@@ -0,0 +1,596 @@
+package go_mpls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	TSPacketSize   = 188
+	M2TSPacketSize = 192
+)
+
+const (
+	PATPID  = 0x0000
+	NullPID = 0x1fff
+)
+
+type TSPacket struct {
+	ArrivalTimeStamp           int
+	CopyPermissionIndicator    int
+	TransportErrorIndicator    bool
+	PayloadUnitStartIndicator  bool
+	TransportPriority          bool
+	PID                        int
+	TransportScramblingControl int
+	ContinuityCounter          int
+	HasAdaptationField         bool
+	DiscontinuityIndicator     bool
+	RandomAccessIndicator      bool
+	PCR                        int64
+	Payload                    []byte
+}
+
+type ProgramAssociation struct {
+	ProgramNumber int
+	ProgramMapPID int
+}
+
+type ProgramAssociationTable struct {
+	TransportStreamID int
+	ProgramsList      []*ProgramAssociation
+}
+
+type ElementaryStreamInfo struct {
+	StreamCodingType StreamCodingType
+	PID              int
+	Descriptors      []byte
+}
+
+type ProgramMapTable struct {
+	ProgramNumber         int
+	PCRPID                int
+	ElementaryStreamsList []*ElementaryStreamInfo
+}
+
+type PESPacket struct {
+	PID      int
+	StreamID int
+	PTS      int64
+	DTS      int64
+	Payload  []byte
+}
+
+type ClipStream struct {
+	PID              int
+	StreamCodingType StreamCodingType
+	NumberOfPackets  int
+	FirstPTS         int64
+	LastPTS          int64
+}
+
+type ClipStreamInfo struct {
+	FilePath        string
+	NumberOfPackets int
+	PCRPID          int
+	FirstPCR        int64
+	LastPCR         int64
+	StreamsList     []*ClipStream
+}
+
+type StreamMismatch struct {
+	Stream   *Stream
+	PID      int
+	Expected StreamCodingType
+	Actual   StreamCodingType
+	Reason   string
+}
+
+// ClipVerification holds the mismatches between the STN table of a play
+// item and the clip presented for one of its angles, AngleID being 1 for
+// the clip of the play item itself.
+type ClipVerification struct {
+	PlayItem       *PlayItem
+	AngleID        int
+	ClipStreamInfo *ClipStreamInfo
+	MismatchesList []*StreamMismatch
+}
+
+type M2TSReader struct {
+	reader *bufio.Reader
+	buffer [M2TSPacketSize]byte
+}
+
+func NewM2TSReader(r io.Reader) *M2TSReader {
+	return &M2TSReader{reader: bufio.NewReaderSize(r, M2TSPacketSize*1024)}
+}
+
+func (r *M2TSReader) ReadPacket() (*TSPacket, error) {
+	if _, err := io.ReadFull(r.reader, r.buffer[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return parseM2TSPacket(r.buffer[:])
+}
+
+func parseM2TSPacket(rawData []byte) (*TSPacket, error) {
+	packet, err := parseTSPacket(rawData[4:])
+	if err != nil {
+		return nil, err
+	}
+	packet.CopyPermissionIndicator = int((rawData[0] & 0b11000000) >> 6)
+	packet.ArrivalTimeStamp = int(binary.BigEndian.Uint32(rawData[0:4]) & 0x3fffffff)
+	return packet, nil
+}
+
+func parseTSPacket(rawData []byte) (*TSPacket, error) {
+	if len(rawData) < TSPacketSize || rawData[0] != 0x47 {
+		return nil, errors.New("lost transport stream sync")
+	}
+
+	packet := &TSPacket{
+		TransportErrorIndicator:    (rawData[1] & (1 << 7)) != 0,
+		PayloadUnitStartIndicator:  (rawData[1] & (1 << 6)) != 0,
+		TransportPriority:          (rawData[1] & (1 << 5)) != 0,
+		PID:                        int(binary.BigEndian.Uint16(rawData[1:3]) & 0x1fff),
+		TransportScramblingControl: int((rawData[3] & 0b11000000) >> 6),
+		HasAdaptationField:         (rawData[3] & (1 << 5)) != 0,
+		ContinuityCounter:          int(rawData[3] & 0b00001111),
+		PCR:                        -1,
+	}
+	hasPayload := (rawData[3] & (1 << 4)) != 0
+
+	payloadStart := 4
+	if packet.HasAdaptationField {
+		adaptationFieldLength := int(rawData[4])
+		payloadStart = 5 + adaptationFieldLength
+		if payloadStart > TSPacketSize {
+			return nil, errors.New("invalid adaptation field length")
+		}
+		if adaptationFieldLength > 0 {
+			flags := rawData[5]
+			packet.DiscontinuityIndicator = (flags & (1 << 7)) != 0
+			packet.RandomAccessIndicator = (flags & (1 << 6)) != 0
+			if (flags&(1<<4)) != 0 && adaptationFieldLength >= 7 {
+				base := int64(rawData[6])<<25 | int64(rawData[7])<<17 | int64(rawData[8])<<9 | int64(rawData[9])<<1 | int64(rawData[10])>>7
+				extension := int64(rawData[10]&0x01)<<8 | int64(rawData[11])
+				packet.PCR = base*300 + extension
+			}
+		}
+	}
+	if hasPayload {
+		packet.Payload = rawData[payloadStart:TSPacketSize]
+	}
+
+	return packet, nil
+}
+
+func parsePAT(rawData []byte) *ProgramAssociationTable {
+	sectionLength := int(binary.BigEndian.Uint16(rawData[1:3]) & 0x0fff)
+	transportStreamID := int(binary.BigEndian.Uint16(rawData[3:5]))
+
+	var programsList []*ProgramAssociation = nil
+	for offset := 8; offset+4 <= 3+sectionLength-4; offset += 4 {
+		programsList = append(programsList, &ProgramAssociation{
+			ProgramNumber: int(binary.BigEndian.Uint16(rawData[offset : offset+2])),
+			ProgramMapPID: int(binary.BigEndian.Uint16(rawData[offset+2:offset+4]) & 0x1fff),
+		})
+	}
+
+	return &ProgramAssociationTable{
+		TransportStreamID: transportStreamID,
+		ProgramsList:      programsList,
+	}
+}
+
+func parsePMT(rawData []byte) *ProgramMapTable {
+	sectionLength := int(binary.BigEndian.Uint16(rawData[1:3]) & 0x0fff)
+	programNumber := int(binary.BigEndian.Uint16(rawData[3:5]))
+	pcrPID := int(binary.BigEndian.Uint16(rawData[8:10]) & 0x1fff)
+	programInfoLength := int(binary.BigEndian.Uint16(rawData[10:12]) & 0x0fff)
+
+	var elementaryStreamsList []*ElementaryStreamInfo = nil
+	end := 3 + sectionLength - 4
+	for offset := 12 + programInfoLength; offset+5 <= end; {
+		esInfoLength := int(binary.BigEndian.Uint16(rawData[offset+3:offset+5]) & 0x0fff)
+		if offset+5+esInfoLength > end {
+			break
+		}
+		elementaryStreamsList = append(elementaryStreamsList, &ElementaryStreamInfo{
+			StreamCodingType: StreamCodingType(rawData[offset]),
+			PID:              int(binary.BigEndian.Uint16(rawData[offset+1:offset+3]) & 0x1fff),
+			Descriptors:      rawData[offset+5 : offset+5+esInfoLength],
+		})
+		offset += 5 + esInfoLength
+	}
+
+	return &ProgramMapTable{
+		ProgramNumber:         programNumber,
+		PCRPID:                pcrPID,
+		ElementaryStreamsList: elementaryStreamsList,
+	}
+}
+
+func readTimeStamp(rawData []byte) int64 {
+	return int64(rawData[0]&0b00001110)<<29 | int64(rawData[1])<<22 | int64(rawData[2]&0b11111110)<<14 | int64(rawData[3])<<7 | int64(rawData[4])>>1
+}
+
+func parsePESPacket(pid int, rawData []byte) (*PESPacket, error) {
+	if len(rawData) < 6 || rawData[0] != 0x00 || rawData[1] != 0x00 || rawData[2] != 0x01 {
+		return nil, errors.New("invalid PES start code")
+	}
+
+	packet := &PESPacket{
+		PID:      pid,
+		StreamID: int(rawData[3]),
+		PTS:      -1,
+		DTS:      -1,
+	}
+
+	switch packet.StreamID {
+	case 0xbc, 0xbe, 0xbf, 0xf0, 0xf1, 0xf2, 0xf8, 0xff:
+		packet.Payload = rawData[6:]
+		return packet, nil
+	}
+
+	if len(rawData) < 9 {
+		return nil, errors.New("PES header too short")
+	}
+	ptsDTSFlags := (rawData[7] & 0b11000000) >> 6
+	headerEnd := 9 + int(rawData[8])
+	if headerEnd > len(rawData) {
+		return nil, errors.New("PES header too short")
+	}
+	if ptsDTSFlags&0b10 != 0 && headerEnd >= 14 {
+		packet.PTS = readTimeStamp(rawData[9:14])
+	}
+	if ptsDTSFlags == 0b11 && headerEnd >= 19 {
+		packet.DTS = readTimeStamp(rawData[14:19])
+	}
+	packet.Payload = rawData[headerEnd:]
+
+	return packet, nil
+}
+
+type Demuxer struct {
+	PAT       *ProgramAssociationTable
+	PMTsList  []*ProgramMapTable
+	reader    *M2TSReader
+	pmtPIDs   map[int]bool
+	esPIDs    map[int]StreamCodingType
+	sections  map[int][]byte
+	pesBuffer map[int][]byte
+	pending   []*PESPacket
+	eof       bool
+}
+
+func NewDemuxer(r io.Reader) *Demuxer {
+	return &Demuxer{
+		reader:    NewM2TSReader(r),
+		pmtPIDs:   map[int]bool{},
+		esPIDs:    map[int]StreamCodingType{},
+		sections:  map[int][]byte{},
+		pesBuffer: map[int][]byte{},
+	}
+}
+
+func (d *Demuxer) StreamCodingType(pid int) (StreamCodingType, bool) {
+	streamCodingType, ok := d.esPIDs[pid]
+	return streamCodingType, ok
+}
+
+// ReadPacket returns the next transport packet. PSI tables and PES packets are
+// tracked as a side effect; completed PES packets are collected by ReadPES or
+// PendingPES.
+func (d *Demuxer) ReadPacket() (*TSPacket, error) {
+	packet, err := d.reader.ReadPacket()
+	if err != nil {
+		return nil, err
+	}
+	// An adaptation field can fill the whole packet and leave an empty
+	// payload, even with the payload unit start indicator set.
+	if packet.TransportErrorIndicator || len(packet.Payload) == 0 {
+		return packet, nil
+	}
+
+	if packet.PID == PATPID || d.pmtPIDs[packet.PID] {
+		d.pushSection(packet)
+	} else if _, ok := d.esPIDs[packet.PID]; ok {
+		d.pushPES(packet)
+	}
+
+	return packet, nil
+}
+
+func (d *Demuxer) ReadPES() (*PESPacket, error) {
+	for len(d.pending) == 0 {
+		if d.eof {
+			return nil, io.EOF
+		}
+		if _, err := d.ReadPacket(); err != nil {
+			if err != io.EOF {
+				return nil, err
+			}
+			d.eof = true
+			d.Flush()
+		}
+	}
+
+	packet := d.pending[0]
+	d.pending = d.pending[1:]
+	return packet, nil
+}
+
+func (d *Demuxer) PendingPES() []*PESPacket {
+	pending := d.pending
+	d.pending = nil
+	return pending
+}
+
+// Flush completes every PES packet still being reassembled, for use once the
+// input is exhausted.
+func (d *Demuxer) Flush() {
+	for pid := range d.pesBuffer {
+		d.completePES(pid)
+	}
+}
+
+func (d *Demuxer) pushSection(packet *TSPacket) {
+	payload := packet.Payload
+	if packet.PayloadUnitStartIndicator {
+		pointerField := int(payload[0])
+		if 1+pointerField > len(payload) {
+			return
+		}
+		d.sections[packet.PID] = append([]byte(nil), payload[1+pointerField:]...)
+	} else if section, ok := d.sections[packet.PID]; ok {
+		d.sections[packet.PID] = append(section, payload...)
+	} else {
+		return
+	}
+
+	section := d.sections[packet.PID]
+	if len(section) < 3 {
+		return
+	}
+	sectionLength := int(binary.BigEndian.Uint16(section[1:3]) & 0x0fff)
+	if len(section) < 3+sectionLength {
+		return
+	}
+	delete(d.sections, packet.PID)
+
+	if section[0] == 0x00 && packet.PID == PATPID && sectionLength >= 9 {
+		d.PAT = parsePAT(section)
+		for _, program := range d.PAT.ProgramsList {
+			if program.ProgramNumber != 0 {
+				d.pmtPIDs[program.ProgramMapPID] = true
+			}
+		}
+	} else if section[0] == 0x02 && sectionLength >= 13 {
+		pmt := parsePMT(section)
+		for i, existing := range d.PMTsList {
+			if existing.ProgramNumber == pmt.ProgramNumber {
+				d.PMTsList = append(d.PMTsList[:i], d.PMTsList[i+1:]...)
+				break
+			}
+		}
+		d.PMTsList = append(d.PMTsList, pmt)
+		for _, es := range pmt.ElementaryStreamsList {
+			d.esPIDs[es.PID] = es.StreamCodingType
+		}
+	}
+}
+
+func (d *Demuxer) pushPES(packet *TSPacket) {
+	if packet.PayloadUnitStartIndicator {
+		d.completePES(packet.PID)
+		d.pesBuffer[packet.PID] = append([]byte(nil), packet.Payload...)
+	} else if buffer, ok := d.pesBuffer[packet.PID]; ok {
+		d.pesBuffer[packet.PID] = append(buffer, packet.Payload...)
+	} else {
+		return
+	}
+
+	buffer := d.pesBuffer[packet.PID]
+	if len(buffer) >= 6 {
+		pesPacketLength := int(binary.BigEndian.Uint16(buffer[4:6]))
+		if pesPacketLength != 0 && len(buffer) >= 6+pesPacketLength {
+			d.pesBuffer[packet.PID] = buffer[:6+pesPacketLength]
+			d.completePES(packet.PID)
+		}
+	}
+}
+
+func (d *Demuxer) completePES(pid int) {
+	buffer, ok := d.pesBuffer[pid]
+	if !ok {
+		return
+	}
+	delete(d.pesBuffer, pid)
+
+	pesPacket, err := parsePESPacket(pid, buffer)
+	if err == nil {
+		d.pending = append(d.pending, pesPacket)
+	}
+}
+
+func (mpls *MPLS) BDMVRoot() string {
+	return filepath.Dir(filepath.Dir(mpls.FilePath))
+}
+
+func (item *PlayItem) ClipStreamPath(bdmvRoot string) string {
+	return filepath.Join(bdmvRoot, "STREAM", item.ClipInformationFileName+".m2ts")
+}
+
+func (item *PlayItem) ClipInformationPath(bdmvRoot string) string {
+	return filepath.Join(bdmvRoot, "CLIPINF", item.ClipInformationFileName+".clpi")
+}
+
+func (stn *STNTable) Streams() []*Stream {
+	var streams []*Stream
+	streams = append(streams, stn.PrimaryVideoStreamsList...)
+	streams = append(streams, stn.PrimaryAudioStreamsList...)
+	streams = append(streams, stn.PrimaryPGStreamsList...)
+	streams = append(streams, stn.SecondaryPGStreamsList...)
+	streams = append(streams, stn.PrimaryIGStreamsList...)
+	streams = append(streams, stn.SecondaryAudioStreamsList...)
+	streams = append(streams, stn.SecondaryVideoStreamsList...)
+	streams = append(streams, stn.DVStreamsList...)
+	return streams
+}
+
+func (info *ClipStreamInfo) Stream(pid int) *ClipStream {
+	for _, stream := range info.StreamsList {
+		if stream.PID == pid {
+			return stream
+		}
+	}
+	return nil
+}
+
+// ScanClipReader demuxes at most maxPackets packets (all of them if maxPackets
+// is not positive) and reports the streams announced in the PMT.
+func ScanClipReader(r io.Reader, maxPackets int) (*ClipStreamInfo, error) {
+	demuxer := NewDemuxer(r)
+	info := &ClipStreamInfo{
+		PCRPID:   -1,
+		FirstPCR: -1,
+		LastPCR:  -1,
+	}
+	counts := map[int]int{}
+	firstPTS := map[int]int64{}
+	lastPTS := map[int]int64{}
+
+	for maxPackets <= 0 || info.NumberOfPackets < maxPackets {
+		packet, err := demuxer.ReadPacket()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		info.NumberOfPackets++
+		counts[packet.PID]++
+
+		if packet.PCR >= 0 && (info.PCRPID < 0 || packet.PID == info.PCRPID) {
+			if info.FirstPCR < 0 {
+				info.FirstPCR = packet.PCR
+			}
+			info.LastPCR = packet.PCR
+		}
+		if info.PCRPID < 0 && len(demuxer.PMTsList) > 0 {
+			info.PCRPID = demuxer.PMTsList[0].PCRPID
+		}
+
+		for _, pesPacket := range demuxer.PendingPES() {
+			if pesPacket.PTS < 0 {
+				continue
+			}
+			if _, ok := firstPTS[pesPacket.PID]; !ok {
+				firstPTS[pesPacket.PID] = pesPacket.PTS
+			}
+			lastPTS[pesPacket.PID] = pesPacket.PTS
+		}
+	}
+
+	for _, pmt := range demuxer.PMTsList {
+		for _, es := range pmt.ElementaryStreamsList {
+			first, ok := firstPTS[es.PID]
+			if !ok {
+				first = -1
+			}
+			last, ok := lastPTS[es.PID]
+			if !ok {
+				last = -1
+			}
+			info.StreamsList = append(info.StreamsList, &ClipStream{
+				PID:              es.PID,
+				StreamCodingType: es.StreamCodingType,
+				NumberOfPackets:  counts[es.PID],
+				FirstPTS:         first,
+				LastPTS:          last,
+			})
+		}
+	}
+
+	return info, nil
+}
+
+func ScanClip(path string, maxPackets int) (*ClipStreamInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := ScanClipReader(file, maxPackets)
+	if err != nil {
+		return nil, err
+	}
+	info.FilePath = path
+	return info, nil
+}
+
+// VerifyStreams compares the STN table against the streams found in the play
+// item's clip. Streams carried by out-of-mux sub paths live in other clips and
+// are not checked.
+func (item *PlayItem) VerifyStreams(info *ClipStreamInfo) []*StreamMismatch {
+	var mismatchesList []*StreamMismatch = nil
+	if item.STNTable == nil {
+		return mismatchesList
+	}
+
+	for _, stream := range item.STNTable.Streams() {
+		if stream.StreamEntry.StreamType == 0x02 {
+			continue
+		}
+
+		pid := stream.StreamEntry.RefToStreamPID
+		expected := stream.StreamAttributes.StreamCodingType
+		clipStream := info.Stream(pid)
+		if clipStream == nil {
+			mismatchesList = append(mismatchesList, &StreamMismatch{
+				Stream:   stream,
+				PID:      pid,
+				Expected: expected,
+				Reason:   "stream PID not found in clip",
+			})
+		} else if clipStream.StreamCodingType != expected {
+			mismatchesList = append(mismatchesList, &StreamMismatch{
+				Stream:   stream,
+				PID:      pid,
+				Expected: expected,
+				Actual:   clipStream.StreamCodingType,
+				Reason:   "stream coding type differs from clip",
+			})
+		}
+	}
+
+	return mismatchesList
+}
+
+func (mpls *MPLS) VerifyClipStreams(bdmvRoot string, maxPackets int) ([]*ClipVerification, error) {
+	var clipVerificationsList []*ClipVerification = nil
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		for angleID := 1; angleID <= max(1, playItem.NumberOfAngles); angleID++ {
+			angle := playItem.AngleClip(angleID)
+			info, err := ScanClip(filepath.Join(bdmvRoot, "STREAM", angle.ClipInformationFileName+".m2ts"), maxPackets)
+			if err != nil {
+				return nil, err
+			}
+			clipVerificationsList = append(clipVerificationsList, &ClipVerification{
+				PlayItem:       playItem,
+				AngleID:        angleID,
+				ClipStreamInfo: info,
+				MismatchesList: playItem.VerifyStreams(info),
+			})
+		}
+	}
+	return clipVerificationsList, nil
+}
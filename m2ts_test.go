@@ -0,0 +1,194 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func buildM2TSPacket(pid int, pusi bool, payload []byte) []byte {
+	packet := make([]byte, M2TSPacketSize)
+	packet[4] = 0x47
+	binary.BigEndian.PutUint16(packet[5:7], uint16(pid))
+	if pusi {
+		packet[5] |= 0x40
+	}
+
+	stuffing := TSPacketSize - 4 - len(payload)
+	if stuffing == 0 {
+		packet[7] = 0x10
+	} else {
+		packet[7] = 0x30
+		packet[8] = byte(stuffing - 1)
+		if stuffing > 1 {
+			for i := 10; i < 8+stuffing; i++ {
+				packet[i] = 0xff
+			}
+		}
+	}
+	copy(packet[4+4+stuffing:], payload)
+	return packet
+}
+
+func buildSection(tableID byte, tableIDExtension int, body []byte) []byte {
+	section := []byte{tableID, 0, 0, byte(tableIDExtension >> 8), byte(tableIDExtension), 0xc1, 0, 0}
+	section = append(section, body...)
+	section = append(section, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(section[1:3], uint16(0xb000|(len(section)-3)))
+	return append([]byte{0}, section...)
+}
+
+func buildPES(streamID byte, pts int64, payload []byte) []byte {
+	pes := []byte{0x00, 0x00, 0x01, streamID, 0, 0, 0x80, 0x80, 5,
+		byte(0x21 | (pts>>29)&0x0e), byte(pts >> 22), byte(0x01 | (pts>>14)&0xfe), byte(pts >> 7), byte(0x01 | (pts<<1)&0xfe)}
+	pes = append(pes, payload...)
+	binary.BigEndian.PutUint16(pes[4:6], uint16(len(pes)-6))
+	return pes
+}
+
+// buildTestClip returns a clip with one program carrying the given PIDs and a
+// single PES packet per elementary stream.
+func buildTestClip(streams map[int]StreamCodingType, payloads map[int][]byte) []byte {
+	var clip []byte
+	clip = append(clip, buildM2TSPacket(PATPID, true, buildSection(0x00, 1, []byte{0x00, 0x01, 0xe1, 0x00}))...)
+
+	pmtBody := []byte{0xe0 | 0x10, 0x11, 0xf0, 0x00}
+	for pid := 0x1011; pid < 0x1fff; pid++ {
+		if streamCodingType, ok := streams[pid]; ok {
+			pmtBody = append(pmtBody, byte(streamCodingType), byte(0xe0|pid>>8), byte(pid), 0xf0, 0x00)
+		}
+	}
+	clip = append(clip, buildM2TSPacket(0x0100, true, buildSection(0x02, 1, pmtBody))...)
+
+	for pid := 0x1011; pid < 0x1fff; pid++ {
+		if payload, ok := payloads[pid]; ok {
+			pes := buildPES(0xbd, 90000, payload)
+			for start := 0; start < len(pes); start += TSPacketSize - 4 {
+				end := min(start+TSPacketSize-4, len(pes))
+				clip = append(clip, buildM2TSPacket(pid, start == 0, pes[start:end])...)
+			}
+		}
+	}
+	return clip
+}
+
+func TestDemuxer(t *testing.T) {
+	payload := bytes.Repeat([]byte{0xaa}, 300)
+	clip := buildTestClip(
+		map[int]StreamCodingType{0x1011: HEVCVideo, 0x1100: DolbyDigitalAudio},
+		map[int][]byte{0x1100: payload},
+	)
+
+	demuxer := NewDemuxer(bytes.NewReader(clip))
+	pesPacket, err := demuxer.ReadPES()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pesPacket.PID != 0x1100 || pesPacket.PTS != 90000 || !bytes.Equal(pesPacket.Payload, payload) {
+		t.Fatalf("unexpected PES packet %#v", pesPacket)
+	}
+	if _, err := demuxer.ReadPES(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+	if streamCodingType, ok := demuxer.StreamCodingType(0x1011); !ok || streamCodingType != HEVCVideo {
+		t.Fatalf("PMT not parsed: %#v", demuxer.PMTsList)
+	}
+}
+
+func TestDemuxerEmptyPayload(t *testing.T) {
+	clip := buildTestClip(
+		map[int]StreamCodingType{0x1100: DolbyDigitalAudio},
+		map[int][]byte{0x1100: {0xaa}},
+	)
+	// adaptation_field_length 183 with the payload unit start indicator set
+	empty := append(buildM2TSPacket(PATPID, true, nil), buildM2TSPacket(0x1100, true, nil)...)
+	if empty[8] != 183 {
+		t.Fatalf("unexpected adaptation field length %d", empty[8])
+	}
+	clip = slices.Concat(empty, clip[:2*M2TSPacketSize], empty, clip[2*M2TSPacketSize:])
+
+	demuxer := NewDemuxer(bytes.NewReader(clip))
+	pesPacket, err := demuxer.ReadPES()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pesPacket.PID != 0x1100 || !bytes.Equal(pesPacket.Payload, []byte{0xaa}) {
+		t.Fatalf("unexpected PES packet %#v", pesPacket)
+	}
+}
+
+func TestVerifyStreams(t *testing.T) {
+	clip := buildTestClip(
+		map[int]StreamCodingType{0x1011: HEVCVideo, 0x1100: DolbyDigitalAudio},
+		map[int][]byte{0x1100: {0x0b, 0x77}},
+	)
+	info, err := ScanClipReader(bytes.NewReader(clip), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.StreamsList) != 2 || info.Stream(0x1100).FirstPTS != 90000 {
+		t.Fatalf("unexpected clip info %#v", info.StreamsList)
+	}
+
+	item := &PlayItem{STNTable: &STNTable{
+		PrimaryVideoStreamsList: []*Stream{{
+			StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: 0x1011},
+			StreamAttributes: &StreamAttributes{StreamCodingType: HEVCVideo},
+		}},
+		PrimaryAudioStreamsList: []*Stream{{
+			StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: 0x1100},
+			StreamAttributes: &StreamAttributes{StreamCodingType: DTSHDMasterAudio},
+		}, {
+			StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: 0x1101},
+			StreamAttributes: &StreamAttributes{StreamCodingType: DolbyDigitalAudio},
+		}},
+	}}
+
+	mismatches := item.VerifyStreams(info)
+	if len(mismatches) != 2 {
+		t.Fatalf("expected 2 mismatches, got %d", len(mismatches))
+	}
+	if mismatches[0].PID != 0x1100 || mismatches[0].Actual != DolbyDigitalAudio {
+		t.Fatalf("unexpected mismatch %#v", mismatches[0])
+	}
+	if mismatches[1].PID != 0x1101 {
+		t.Fatalf("unexpected mismatch %#v", mismatches[1])
+	}
+}
+
+func TestVerifyClipStreams(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(bdmvRoot, "STREAM"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for clipName, streamCodingType := range map[string]StreamCodingType{"00001": DolbyDigitalAudio, "00002": DolbyDigitalAudio, "00003": DTSAudio} {
+		clip := buildTestClip(map[int]StreamCodingType{0x1011: HEVCVideo, 0x1100: streamCodingType}, nil)
+		if err := os.WriteFile(filepath.Join(bdmvRoot, "STREAM", clipName+".m2ts"), clip, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stnTable := &STNTable{
+		PrimaryVideoStreamsList: []*Stream{newTestStream(0x1011, HEVCVideo, "")},
+		PrimaryAudioStreamsList: []*Stream{newTestStream(0x1100, DolbyDigitalAudio, "eng")},
+	}
+	mpls := &MPLS{PlayList: &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00001", IsMultiAngle: true, NumberOfAngles: 3, STNTable: stnTable,
+			AnglesList: []*Angle{{ClipInformationFileName: "00002"}, {ClipInformationFileName: "00003"}}},
+	}}}
+	verifications, err := mpls.VerifyClipStreams(bdmvRoot, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(verifications) != 3 || len(verifications[0].MismatchesList) != 0 || len(verifications[1].MismatchesList) != 0 {
+		t.Fatalf("unexpected verifications %#v", verifications)
+	}
+	if verification := verifications[2]; verification.AngleID != 3 || len(verification.MismatchesList) != 1 ||
+		verification.MismatchesList[0].Actual != DTSAudio {
+		t.Fatalf("angle 3 not verified %#v", verification)
+	}
+}
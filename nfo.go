@@ -0,0 +1,202 @@
+package go_mpls
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+)
+
+// NFOMovie is the movie.nfo document read by Kodi and Jellyfin. Only the
+// elements that can be derived from the playlist are filled.
+type NFOMovie struct {
+	XMLName  xml.Name      `xml:"movie"`
+	Title    string        `xml:"title,omitempty"`
+	Runtime  int           `xml:"runtime"`
+	FileInfo *NFOFileInfo  `xml:"fileinfo"`
+	Chapters []*NFOChapter `xml:"chapters>chapter,omitempty"`
+}
+
+type NFOFileInfo struct {
+	StreamDetails *NFOStreamDetails `xml:"streamdetails"`
+}
+
+type NFOStreamDetails struct {
+	VideoList    []*NFOVideo    `xml:"video"`
+	AudioList    []*NFOAudio    `xml:"audio"`
+	SubtitleList []*NFOSubtitle `xml:"subtitle"`
+}
+
+// NFOVideo uses the codec and HDR type names of Kodi. Aspect is left out for
+// standard definition streams, whose display aspect ratio is not in the
+// playlist.
+type NFOVideo struct {
+	Codec             string `xml:"codec"`
+	Aspect            string `xml:"aspect,omitempty"`
+	Width             int    `xml:"width,omitempty"`
+	Height            int    `xml:"height,omitempty"`
+	DurationInSeconds int    `xml:"durationinseconds"`
+	HDRType           string `xml:"hdrtype"`
+}
+
+// NFOAudio counts multi-channel streams as 6 channels, as the playlist does
+// not tell 5.1 and 7.1 apart.
+type NFOAudio struct {
+	Codec    string `xml:"codec"`
+	Language string `xml:"language,omitempty"`
+	Channels int    `xml:"channels,omitempty"`
+}
+
+type NFOSubtitle struct {
+	Language string `xml:"language,omitempty"`
+}
+
+// NFOChapter is not part of the Kodi schema, which both Kodi and Jellyfin
+// ignore unknown elements of.
+type NFOChapter struct {
+	Name  string `xml:"name"`
+	Start string `xml:"start"`
+}
+
+func nfoVideoCodec(codingType StreamCodingType) string {
+	switch codingType {
+	case MPEG1Video:
+		return "mpeg1video"
+	case MPEG2Video:
+		return "mpeg2video"
+	case MPEG4AVCVideo, MPEG4MVCVideo:
+		return "h264"
+	case SMTPEVC1Video:
+		return "vc1"
+	case HEVCVideo:
+		return "hevc"
+	}
+	return ""
+}
+
+func nfoAudioCodec(codingType StreamCodingType) string {
+	switch codingType {
+	case MPEG1Audio, MPEG2Audio:
+		return "mp2"
+	case LPCMAudio:
+		return "pcm_bluray"
+	case DolbyDigitalAudio:
+		return "ac3"
+	case DTSAudio, DTSHDAudio:
+		return "dca"
+	case DolbyDigitalTureHDAudio:
+		return "truehd"
+	case DolbyDigitalPlusAudioPri, DolbyDigitalPlusAudioSec:
+		return "eac3"
+	case DTSHDHighResolutionAudio:
+		return "dtshd_hra"
+	case DTSHDMasterAudio:
+		return "dtshd_ma"
+	}
+	return ""
+}
+
+func videoFormatSize(videoFormat VideoFormat) (int, int) {
+	switch videoFormat {
+	case VF480I, VF480P:
+		return 720, 480
+	case VF576I, VF576P:
+		return 720, 576
+	case VF720P:
+		return 1280, 720
+	case VF1080I, VF1080P:
+		return 1920, 1080
+	case VF2160P:
+		return 3840, 2160
+	}
+	return 0, 0
+}
+
+func nfoHDRType(stn *STNTable, attributes *StreamAttributes) string {
+	if attributes.StreamCodingType != HEVCVideo {
+		return ""
+	}
+	if attributes.DynamicRangeType == DolbyVision || len(stn.DVStreamsList) > 0 {
+		return "dolbyvision"
+	}
+	if attributes.DynamicRangeType == HDR10 {
+		return "hdr10"
+	}
+	return ""
+}
+
+// NFO builds the movie.nfo of the playlist. The streams are those of the
+// first play item.
+func (mpls *MPLS) NFO(title string) *NFOMovie {
+	durationSeconds := (mpls.DurationTicks() + 22500) / 45000
+	movie := &NFOMovie{
+		Title:    title,
+		Runtime:  (durationSeconds + 30) / 60,
+		FileInfo: &NFOFileInfo{StreamDetails: &NFOStreamDetails{}},
+	}
+	for _, chapter := range mpls.Chapters() {
+		movie.Chapters = append(movie.Chapters, &NFOChapter{Name: chapter.Name(), Start: formatTicks(chapter.TimeTicks)})
+	}
+	if len(mpls.PlayList.PlayItemList) == 0 || mpls.PlayList.PlayItemList[0].STNTable == nil {
+		return movie
+	}
+
+	stn := mpls.PlayList.PlayItemList[0].STNTable
+	details := movie.FileInfo.StreamDetails
+	for _, stream := range stn.PrimaryVideoStreamsList {
+		video := &NFOVideo{
+			Codec:             nfoVideoCodec(stream.StreamAttributes.StreamCodingType),
+			DurationInSeconds: durationSeconds,
+			HDRType:           nfoHDRType(stn, stream.StreamAttributes),
+		}
+		video.Width, video.Height = videoFormatSize(stream.StreamAttributes.VideoFormat)
+		if video.Height >= 720 {
+			video.Aspect = fmt.Sprintf("%.6f", float64(video.Width)/float64(video.Height))
+		}
+		details.VideoList = append(details.VideoList, video)
+	}
+	for _, stream := range stn.PrimaryAudioStreamsList {
+		audio := &NFOAudio{
+			Codec:    nfoAudioCodec(stream.StreamAttributes.StreamCodingType),
+			Language: stream.StreamAttributes.LanguageCode,
+		}
+		switch stream.StreamAttributes.AudioFormat {
+		case Mono:
+			audio.Channels = 1
+		case Stereo:
+			audio.Channels = 2
+		case MultiChannel, StereoAndMultiChannel:
+			audio.Channels = 6
+		}
+		details.AudioList = append(details.AudioList, audio)
+	}
+	for _, stream := range stn.PrimaryPGStreamsList {
+		details.SubtitleList = append(details.SubtitleList, &NFOSubtitle{Language: stream.StreamAttributes.LanguageCode})
+	}
+	return movie
+}
+
+func (mpls *MPLS) WriteNFO(w io.Writer, title string) error {
+	if _, err := io.WriteString(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>`+"\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "    ")
+	if err := encoder.Encode(mpls.NFO(title)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (mpls *MPLS) ExportNFO(path, title string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = mpls.WriteNFO(file, title)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestNFO(t *testing.T) {
+	video := newTestStream(0x1011, HEVCVideo, "")
+	video.StreamAttributes.VideoFormat = VF2160P
+	video.StreamAttributes.DynamicRangeType = HDR10
+	audio := newTestStream(0x1100, DolbyDigitalTureHDAudio, "eng")
+	audio.StreamAttributes.AudioFormat = MultiChannel
+	stnTable := &STNTable{
+		PrimaryVideoStreamsList: []*Stream{video},
+		PrimaryAudioStreamsList: []*Stream{audio, newTestStream(0x1101, DolbyDigitalAudio, "fra")},
+		PrimaryPGStreamsList:    []*Stream{newTestStream(0x1200, PresentationGraphics, "eng")},
+	}
+	mpls := &MPLS{
+		PlayList: &PlayList{PlayItemList: []*PlayItem{
+			{ClipInformationFileName: "00001", OUTTimeTicks: 45000 * 3000, STNTable: stnTable},
+			{ClipInformationFileName: "00002", OUTTimeTicks: 45000 * 2400, STNTable: stnTable},
+		}},
+		PlayListMark: &PlayListMark{PlayListMarksList: []*PlayListMarkItem{
+			{MarkType: EntryMark, MarkTimeTicks: 0},
+			{MarkType: EntryMark, RefToPlayItemID: 1, MarkTimeTicks: 45000 * 600},
+		}},
+	}
+
+	var buffer bytes.Buffer
+	if err := mpls.WriteNFO(&buffer, "Movie"); err != nil {
+		t.Fatal(err)
+	}
+	var movie NFOMovie
+	if err := xml.Unmarshal(buffer.Bytes(), &movie); err != nil {
+		t.Fatal(err)
+	}
+	if movie.Title != "Movie" || movie.Runtime != 90 {
+		t.Fatalf("unexpected movie %#v", movie)
+	}
+	if len(movie.Chapters) != 2 || movie.Chapters[1].Start != "01:00:00.000" {
+		t.Fatalf("unexpected chapters %#v", movie.Chapters)
+	}
+	details := movie.FileInfo.StreamDetails
+	if len(details.VideoList) != 1 || *details.VideoList[0] != (NFOVideo{
+		Codec: "hevc", Aspect: "1.777778", Width: 3840, Height: 2160, DurationInSeconds: 5400, HDRType: "hdr10",
+	}) {
+		t.Fatalf("unexpected video %#v", details.VideoList)
+	}
+	if len(details.AudioList) != 2 || *details.AudioList[0] != (NFOAudio{Codec: "truehd", Language: "eng", Channels: 6}) ||
+		*details.AudioList[1] != (NFOAudio{Codec: "ac3", Language: "fra", Channels: 2}) {
+		t.Fatalf("unexpected audio %#v", details.AudioList)
+	}
+	if len(details.SubtitleList) != 1 || details.SubtitleList[0].Language != "eng" {
+		t.Fatalf("unexpected subtitles %#v", details.SubtitleList)
+	}
+}
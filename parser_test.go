@@ -1,9 +1,16 @@
 package go_mpls
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"testing"
+
+	"github.com/syxxzzr/go-mpls/internal/fixture"
 )
 
 // NOTICE: the environment variable named `MPLS_PATH` which pointed to the *.mpls file should be set before test
@@ -15,3 +22,225 @@ func TestParse(t *testing.T) {
 		fmt.Printf("%#v\n", mpls)
 	}
 }
+
+func TestParseStillTime(t *testing.T) {
+	playItem := fixture.PlayItem("00001", 1, 0, 45000, fixture.STNTable(0))
+	playItem[31] = 0x01
+	binary.BigEndian.PutUint16(playItem[32:34], 30)
+	mpls, err := Parse(fixture.WriteFile(t, t.TempDir(), "00800.mpls", fixture.MPLS("0200", [][]byte{playItem}, nil, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playItem := mpls.PlayList.PlayItemList[0]; playItem.StillMode != 0x01 || playItem.StillTime != 30 {
+		t.Fatalf("unexpected still %d %v", playItem.StillMode, playItem.StillTime)
+	}
+}
+
+func TestParseMultiAngle(t *testing.T) {
+	playItem := fixture.PlayItem("00001", 1, 0, 45000, nil)
+	playItem[12] |= 0x10
+	playItem = append(playItem, 3, 0x01)
+	playItem = append(playItem, "00002M2TS\x00"...)
+	playItem = append(playItem, "00003M2TS\x01"...)
+	playItem = append(playItem, fixture.STNTable(1, fixture.Stream(0x1011, byte(MPEG4AVCVideo), 0x61))...)
+	binary.BigEndian.PutUint16(playItem[0:2], uint16(len(playItem)-2))
+	mark := make([]byte, 14)
+	mark[1] = 0x01
+	binary.BigEndian.PutUint32(mark[4:8], 45000*90)
+	mpls, err := Parse(fixture.WriteFile(t, t.TempDir(), "00800.mpls", fixture.MPLS("0200", [][]byte{playItem}, nil, [][]byte{mark})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := mpls.PlayList.PlayItemList[0]
+	if !parsed.IsMultiAngle || parsed.NumberOfAngles != 3 || !parsed.IsSeamlessAngleChange || len(parsed.AnglesList) != 2 {
+		t.Fatalf("unexpected angles %#v", parsed)
+	}
+	if angle := parsed.AnglesList[1]; angle.ClipInformationFileName != "00003" || angle.ClipCodecIdentifier != "M2TS" || angle.RefToSTCID != 1 {
+		t.Fatalf("unexpected angle %#v", angle)
+	}
+	if parsed.STNTable.NumberOfPrimaryVideoStreams != 1 || parsed.STNTable.PrimaryVideoStreamsList[0].StreamEntry.RefToStreamPID != 0x1011 {
+		t.Fatalf("STN table read from the wrong offset %#v", parsed.STNTable)
+	}
+	if mark := mpls.PlayListMark.PlayListMarksList[0]; mark.MarkTimeTicks != 45000*90 || mark.MarkTimeStamp != 90 {
+		t.Fatalf("unexpected mark time %d %v", mark.MarkTimeTicks, mark.MarkTimeStamp)
+	}
+}
+
+func TestParseMultiClipSubPath(t *testing.T) {
+	subPlayItem := make([]byte, 32)
+	copy(subPlayItem[2:11], "00010M2TS")
+	subPlayItem[14] = 0x01
+	binary.BigEndian.PutUint32(subPlayItem[20:24], 45000)
+	subPlayItem[30] = 3
+	subPlayItem = append(subPlayItem, "00011M2TS\x00"...)
+	subPlayItem = append(subPlayItem, "00012M2TS\x02"...)
+	binary.BigEndian.PutUint16(subPlayItem[0:2], uint16(len(subPlayItem)-2))
+	subPath := append([]byte{0, 0, 0, 0, 0, byte(TextSubtitlePath), 0, 0, 0, 1}, subPlayItem...)
+	binary.BigEndian.PutUint32(subPath[0:4], uint32(len(subPath)-4))
+
+	stnTable := fixture.STNTable(2,
+		fixture.Stream(0x1011, byte(MPEG4AVCVideo), 0x66),
+		fixture.Stream(0x1012, byte(MPEG4AVCVideo), 0x67))
+	rawData := fixture.MPLS("0200", [][]byte{fixture.PlayItem("00001", 1, 0, 45000, stnTable)},
+		[][]byte{subPath, fixture.SubPath(int(TextSubtitlePath), "00020", 0)}, nil)
+	mpls, err := Parse(fixture.WriteFile(t, t.TempDir(), "00800.mpls", rawData))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subPathsList := mpls.PlayList.SubPathsList
+	if len(subPathsList) != 2 || subPathsList[1].SubPlayItemsList[0].ClipInformationFileName != "00020" {
+		t.Fatalf("second sub path read from the wrong offset %#v", subPathsList)
+	}
+	parsed := subPathsList[0].SubPlayItemsList[0]
+	if !parsed.IsMultiClipEntries || parsed.NumberOfMultiClipEntries != 3 || len(parsed.MultiClipEntriesList) != 2 || parsed.OUTTimeTicks != 45000 {
+		t.Fatalf("unexpected sub play item %#v", parsed)
+	}
+	if entry := parsed.MultiClipEntriesList[1]; entry.ClipInformationFileName != "00012" || entry.RefToSTCID != 2 {
+		t.Fatalf("unexpected multi clip entry %#v", entry)
+	}
+
+	videoStreamsList := mpls.PlayList.PlayItemList[0].STNTable.PrimaryVideoStreamsList
+	if videoStreamsList[0].StreamAttributes.FrameRate != FR50FPS || videoStreamsList[1].StreamAttributes.FrameRate != FR59D94FPS {
+		t.Fatalf("unexpected frame rates %v %v", videoStreamsList[0].StreamAttributes.FrameRate, videoStreamsList[1].StreamAttributes.FrameRate)
+	}
+}
+
+func TestParseBytesTruncated(t *testing.T) {
+	rawData := benchmarkMPLS()
+	if _, err := ParseBytes(rawData); err != nil {
+		t.Fatal(err)
+	}
+	for length := 0; length < len(rawData); length++ {
+		if _, err := ParseBytes(rawData[:length]); err == nil {
+			t.Fatalf("no error for %d of %d bytes", length, len(rawData))
+		}
+	}
+
+	// a mark count larger than the marks present
+	binary.BigEndian.PutUint16(rawData[len(rawData)-14*16-2:], 0xffff)
+	if _, err := ParseBytes(rawData); err == nil {
+		t.Fatal("no error for a mark count out of range")
+	}
+}
+
+func TestParseBytesExtensionData(t *testing.T) {
+	rawData := fixture.MPLS("0200", [][]byte{fixture.PlayItem("00001", 1, 0, 45000, fixture.STNTable(0))}, nil, nil)
+	extensionDataStartAddress := len(rawData)
+	binary.BigEndian.PutUint32(rawData[0x10:0x14], uint32(extensionDataStartAddress))
+
+	mpls, err := ParseBytes(append(rawData, 0, 0, 0, 0))
+	if err != nil || mpls.ExtensionData != nil {
+		t.Fatalf("unexpected extension data %#v %v", mpls, err)
+	}
+
+	extensionData := make([]byte, 24)
+	binary.BigEndian.PutUint32(extensionData[0:4], 24)
+	extensionData[11] = 1
+	binary.BigEndian.PutUint16(extensionData[12:14], 3)
+	binary.BigEndian.PutUint32(extensionData[16:20], 24)
+	binary.BigEndian.PutUint32(extensionData[20:24], 4)
+	extensionData = append(extensionData, "pip!"...)
+	mpls, err = ParseBytes(append(rawData, extensionData...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mpls.ExtensionData.ExtDataEntryItemsList) != 1 || !bytes.Equal(mpls.ExtensionData.ExtDataEntryItemsList[0].ExtDataEntry, []byte("pip!")) {
+		t.Fatalf("unexpected extension data %#v", mpls.ExtensionData)
+	}
+
+	binary.BigEndian.PutUint32(extensionData[20:24], 5)
+	if _, err := ParseBytes(append(rawData, extensionData...)); err == nil {
+		t.Fatal("no error for an entry out of range")
+	}
+}
+
+func TestParseDisc(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	playListDir := filepath.Join(bdmvRoot, "PLAYLIST")
+	if err := os.Mkdir(playListDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		clip := fmt.Sprintf("%05d", i)
+		fixture.WriteFile(t, playListDir, clip+".mpls", fixture.MPLS("0200", [][]byte{fixture.PlayItem(clip, 1, 0, 45000, fixture.STNTable(0))}, nil, nil))
+	}
+
+	for _, workers := range []int{0, 1, 3, 8} {
+		playListsList, err := ParseDisc(bdmvRoot, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(playListsList) != 5 {
+			t.Fatalf("unexpected playlists %d with %d workers", len(playListsList), workers)
+		}
+		for i, mpls := range playListsList {
+			if clip := mpls.PlayList.PlayItemList[0].ClipInformationFileName; clip != fmt.Sprintf("%05d", i) {
+				t.Fatalf("playlist %d is %s with %d workers", i, clip, workers)
+			}
+		}
+	}
+
+	fixture.WriteFile(t, playListDir, "00002.mpls", []byte("MPLS0200"))
+	for _, workers := range []int{1, 3} {
+		if _, err := ParseDisc(bdmvRoot, workers); err == nil {
+			t.Fatalf("no error with %d workers", workers)
+		}
+	}
+}
+
+// benchmarkMPLS builds a feature playlist of eight play items with seventeen
+// streams each, two chapters per play item and a sub path.
+func benchmarkMPLS() []byte {
+	streamsList := [][]byte{fixture.Stream(0x1011, byte(HEVCVideo), 0x81, 0x12, 0x00, 0x00)}
+	for i := 0; i < 6; i++ {
+		streamsList = append(streamsList, fixture.Stream(0x1100+i, byte(DTSHDMasterAudio), 0x61, 'e', 'n', 'g'))
+	}
+	for i := 0; i < 10; i++ {
+		streamsList = append(streamsList, fixture.Stream(0x1200+i, byte(PresentationGraphics), 'f', 'r', 'a'))
+	}
+	stnTable := fixture.STNTable(1, streamsList...)
+	var playItemsList, marksList [][]byte
+	for i := 0; i < 8; i++ {
+		playItemsList = append(playItemsList, fixture.PlayItem(fmt.Sprintf("%05d", i), 1, 0, 45000*600, stnTable))
+		marksList = append(marksList, fixture.Mark(i, 0), fixture.Mark(i, 45000*300))
+	}
+	return fixture.MPLS("0300", playItemsList, [][]byte{fixture.SubPath(int(TextSubtitlePath), "00100", 0)}, marksList)
+}
+
+func BenchmarkParseBytes(b *testing.B) {
+	rawData := benchmarkMPLS()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ParseBytes(rawData); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseDisc parses the playlists of a large disc from a BDMV
+// tree, one after the other and with a worker per CPU.
+func BenchmarkParseDisc(b *testing.B) {
+	bdmvRoot := b.TempDir()
+	if err := os.Mkdir(filepath.Join(bdmvRoot, "PLAYLIST"), 0o755); err != nil {
+		b.Fatal(err)
+	}
+	rawData := benchmarkMPLS()
+	for i := range 2000 {
+		if err := os.WriteFile(filepath.Join(bdmvRoot, "PLAYLIST", fmt.Sprintf("%05d.mpls", i)), rawData, 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, workers := range slices.Compact([]int{1, runtime.GOMAXPROCS(0)}) {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := ParseDisc(bdmvRoot, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+package go_mpls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	PaletteDefinitionSegment       = 0x14
+	ObjectDefinitionSegment        = 0x15
+	PresentationCompositionSegment = 0x16
+	WindowDefinitionSegment        = 0x17
+	InteractiveCompositionSegment  = 0x18
+	EndOfDisplaySetSegment         = 0x80
+	DialogStyleSegment             = 0x81
+	DialogPresentationSegment      = 0x82
+)
+
+const epochStart = 0x80
+
+type GraphicsSegment struct {
+	SegmentType int
+	PTS         int64
+	DTS         int64
+	Data        []byte
+}
+
+type CompositionObject struct {
+	ObjectID   int
+	WindowID   int
+	IsCropped  bool
+	IsForced   bool
+	X          int
+	Y          int
+	CropX      int
+	CropY      int
+	CropWidth  int
+	CropHeight int
+}
+
+type PresentationComposition struct {
+	Width                      int
+	Height                     int
+	FrameRate                  int
+	CompositionNumber          int
+	CompositionState           int
+	PaletteUpdateFlag          bool
+	PaletteID                  int
+	NumberOfCompositionObjects int
+	CompositionObjectsList     []*CompositionObject
+}
+
+type WindowDefinition struct {
+	WindowID int
+	X        int
+	Y        int
+	Width    int
+	Height   int
+}
+
+type PaletteEntry struct {
+	Y     int
+	Cr    int
+	Cb    int
+	Alpha int
+}
+
+type Palette struct {
+	PaletteID      int
+	PaletteVersion int
+	Entries        map[int]*PaletteEntry
+}
+
+type GraphicsObject struct {
+	ObjectID      int
+	ObjectVersion int
+	Width         int
+	Height        int
+	DataLength    int
+	Data          []byte
+}
+
+type DisplaySet struct {
+	PTS                     int64
+	PresentationComposition *PresentationComposition
+	InteractiveComposition  *InteractiveComposition
+	WindowsList             []*WindowDefinition
+	PalettesList            []*Palette
+	ObjectsList             []*GraphicsObject
+	SegmentsList            []*GraphicsSegment
+}
+
+type PGStreamSummary struct {
+	NumberOfDisplaySets    int
+	NumberOfCaptions       int
+	NumberOfForcedCaptions int
+}
+
+func (summary *PGStreamSummary) IsForcedOnly() bool {
+	return summary.NumberOfCaptions > 0 && summary.NumberOfCaptions == summary.NumberOfForcedCaptions
+}
+
+func (displaySet *DisplaySet) HasForcedObjects() bool {
+	if displaySet.PresentationComposition == nil {
+		return false
+	}
+	for _, compositionObject := range displaySet.PresentationComposition.CompositionObjectsList {
+		if compositionObject.IsForced {
+			return true
+		}
+	}
+	return false
+}
+
+func parseGraphicsSegments(rawData []byte, pts, dts int64) []*GraphicsSegment {
+	var segmentsList []*GraphicsSegment = nil
+	for offset := 0; offset+3 <= len(rawData); {
+		segmentLength := int(binary.BigEndian.Uint16(rawData[offset+1 : offset+3]))
+		if offset+3+segmentLength > len(rawData) {
+			break
+		}
+		segmentsList = append(segmentsList, &GraphicsSegment{
+			SegmentType: int(rawData[offset]),
+			PTS:         pts,
+			DTS:         dts,
+			Data:        rawData[offset+3 : offset+3+segmentLength],
+		})
+		offset += 3 + segmentLength
+	}
+	return segmentsList
+}
+
+func parsePresentationComposition(rawData []byte) *PresentationComposition {
+	if len(rawData) < 11 {
+		return nil
+	}
+
+	numberOfCompositionObjects := int(rawData[10])
+	var compositionObjectsList []*CompositionObject = nil
+	offset := 11
+	for i := 0; i < numberOfCompositionObjects && offset+8 <= len(rawData); i++ {
+		compositionObject := &CompositionObject{
+			ObjectID:  int(binary.BigEndian.Uint16(rawData[offset : offset+2])),
+			WindowID:  int(rawData[offset+2]),
+			IsCropped: (rawData[offset+3] & (1 << 7)) != 0,
+			IsForced:  (rawData[offset+3] & (1 << 6)) != 0,
+			X:         int(binary.BigEndian.Uint16(rawData[offset+4 : offset+6])),
+			Y:         int(binary.BigEndian.Uint16(rawData[offset+6 : offset+8])),
+		}
+		offset += 8
+		if compositionObject.IsCropped && offset+8 <= len(rawData) {
+			compositionObject.CropX = int(binary.BigEndian.Uint16(rawData[offset : offset+2]))
+			compositionObject.CropY = int(binary.BigEndian.Uint16(rawData[offset+2 : offset+4]))
+			compositionObject.CropWidth = int(binary.BigEndian.Uint16(rawData[offset+4 : offset+6]))
+			compositionObject.CropHeight = int(binary.BigEndian.Uint16(rawData[offset+6 : offset+8]))
+			offset += 8
+		}
+		compositionObjectsList = append(compositionObjectsList, compositionObject)
+	}
+
+	return &PresentationComposition{
+		Width:                      int(binary.BigEndian.Uint16(rawData[0:2])),
+		Height:                     int(binary.BigEndian.Uint16(rawData[2:4])),
+		FrameRate:                  int(rawData[4]),
+		CompositionNumber:          int(binary.BigEndian.Uint16(rawData[5:7])),
+		CompositionState:           int(rawData[7]),
+		PaletteUpdateFlag:          (rawData[8] & (1 << 7)) != 0,
+		PaletteID:                  int(rawData[9]),
+		NumberOfCompositionObjects: numberOfCompositionObjects,
+		CompositionObjectsList:     compositionObjectsList,
+	}
+}
+
+func parseWindowDefinitions(rawData []byte) []*WindowDefinition {
+	var windowsList []*WindowDefinition = nil
+	if len(rawData) < 1 {
+		return windowsList
+	}
+	for i := 0; i < int(rawData[0]) && 10+9*i <= len(rawData); i++ {
+		windowsList = append(windowsList, &WindowDefinition{
+			WindowID: int(rawData[1+9*i]),
+			X:        int(binary.BigEndian.Uint16(rawData[2+9*i : 4+9*i])),
+			Y:        int(binary.BigEndian.Uint16(rawData[4+9*i : 6+9*i])),
+			Width:    int(binary.BigEndian.Uint16(rawData[6+9*i : 8+9*i])),
+			Height:   int(binary.BigEndian.Uint16(rawData[8+9*i : 10+9*i])),
+		})
+	}
+	return windowsList
+}
+
+func parsePalette(rawData []byte) *Palette {
+	if len(rawData) < 2 {
+		return nil
+	}
+
+	entries := map[int]*PaletteEntry{}
+	for offset := 2; offset+5 <= len(rawData); offset += 5 {
+		entries[int(rawData[offset])] = &PaletteEntry{
+			Y:     int(rawData[offset+1]),
+			Cr:    int(rawData[offset+2]),
+			Cb:    int(rawData[offset+3]),
+			Alpha: int(rawData[offset+4]),
+		}
+	}
+
+	return &Palette{
+		PaletteID:      int(rawData[0]),
+		PaletteVersion: int(rawData[1]),
+		Entries:        entries,
+	}
+}
+
+// GroupDisplaySets splits a segment sequence at END segments. Objects split
+// over several ODS fragments are joined into one GraphicsObject.
+func GroupDisplaySets(segments []*GraphicsSegment) []*DisplaySet {
+	var displaySetsList []*DisplaySet = nil
+	displaySet := &DisplaySet{}
+	var pendingObject *GraphicsObject
+	var pendingCompositionHeader, pendingComposition []byte
+
+	for _, segment := range segments {
+		if len(displaySet.SegmentsList) == 0 {
+			displaySet.PTS = segment.PTS
+		}
+		displaySet.SegmentsList = append(displaySet.SegmentsList, segment)
+
+		switch segment.SegmentType {
+		case PresentationCompositionSegment:
+			displaySet.PTS = segment.PTS
+			displaySet.PresentationComposition = parsePresentationComposition(segment.Data)
+		case InteractiveCompositionSegment:
+			if len(segment.Data) < 9 {
+				continue
+			}
+			sequence := segment.Data[8]
+			if sequence&0x80 != 0 {
+				displaySet.PTS = segment.PTS
+				pendingCompositionHeader = segment.Data[:8]
+				pendingComposition = append([]byte(nil), segment.Data[9:]...)
+			} else if pendingCompositionHeader != nil {
+				pendingComposition = append(pendingComposition, segment.Data[9:]...)
+			}
+			if sequence&0x40 != 0 && pendingCompositionHeader != nil {
+				displaySet.InteractiveComposition, _ = parseInteractiveComposition(pendingCompositionHeader, pendingComposition)
+				pendingCompositionHeader, pendingComposition = nil, nil
+			}
+		case WindowDefinitionSegment:
+			displaySet.WindowsList = append(displaySet.WindowsList, parseWindowDefinitions(segment.Data)...)
+		case PaletteDefinitionSegment:
+			if palette := parsePalette(segment.Data); palette != nil {
+				displaySet.PalettesList = append(displaySet.PalettesList, palette)
+			}
+		case ObjectDefinitionSegment:
+			if len(segment.Data) < 4 {
+				continue
+			}
+			sequence := segment.Data[3]
+			if sequence&0x80 != 0 && len(segment.Data) >= 11 {
+				pendingObject = &GraphicsObject{
+					ObjectID:      int(binary.BigEndian.Uint16(segment.Data[0:2])),
+					ObjectVersion: int(segment.Data[2]),
+					DataLength:    int(segment.Data[4])<<16 | int(segment.Data[5])<<8 | int(segment.Data[6]),
+					Width:         int(binary.BigEndian.Uint16(segment.Data[7:9])),
+					Height:        int(binary.BigEndian.Uint16(segment.Data[9:11])),
+					Data:          append([]byte(nil), segment.Data[11:]...),
+				}
+			} else if pendingObject != nil {
+				pendingObject.Data = append(pendingObject.Data, segment.Data[4:]...)
+			}
+			if sequence&0x40 != 0 && pendingObject != nil {
+				displaySet.ObjectsList = append(displaySet.ObjectsList, pendingObject)
+				pendingObject = nil
+			}
+		case EndOfDisplaySetSegment:
+			displaySetsList = append(displaySetsList, displaySet)
+			displaySet = &DisplaySet{}
+		}
+	}
+
+	return displaySetsList
+}
+
+func SummarizePGS(displaySets []*DisplaySet) *PGStreamSummary {
+	summary := &PGStreamSummary{NumberOfDisplaySets: len(displaySets)}
+	for _, displaySet := range displaySets {
+		if displaySet.PresentationComposition == nil || displaySet.PresentationComposition.NumberOfCompositionObjects == 0 {
+			continue
+		}
+		summary.NumberOfCaptions++
+		if displaySet.HasForcedObjects() {
+			summary.NumberOfForcedCaptions++
+		}
+	}
+	return summary
+}
+
+func WriteSUP(w io.Writer, segments []*GraphicsSegment) error {
+	writer := bufio.NewWriter(w)
+	header := make([]byte, 13)
+	for _, segment := range segments {
+		copy(header[0:2], "PG")
+		binary.BigEndian.PutUint32(header[2:6], uint32(segment.PTS))
+		binary.BigEndian.PutUint32(header[6:10], uint32(max(segment.DTS, 0)))
+		header[10] = byte(segment.SegmentType)
+		binary.BigEndian.PutUint16(header[11:13], uint16(len(segment.Data)))
+		if _, err := writer.Write(header); err != nil {
+			return err
+		}
+		if _, err := writer.Write(segment.Data); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+func ParseSUP(r io.Reader) ([]*GraphicsSegment, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 13)
+	var segmentsList []*GraphicsSegment = nil
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return segmentsList, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if header[0] != 'P' || header[1] != 'G' {
+			return nil, errors.New("invalid file")
+		}
+
+		data := make([]byte, binary.BigEndian.Uint16(header[11:13]))
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		segmentsList = append(segmentsList, &GraphicsSegment{
+			SegmentType: int(header[10]),
+			PTS:         int64(binary.BigEndian.Uint32(header[2:6])),
+			DTS:         int64(binary.BigEndian.Uint32(header[6:10])),
+			Data:        data,
+		})
+	}
+}
+
+// readGraphicsStream collects the segments of one graphics PID from a clip,
+// keeping those presented within [inTime, outTime) and moving them by offset.
+// All times are 90 kHz ticks.
+func readGraphicsStream(path string, pid int, inTime, outTime, offset int64) ([]*GraphicsSegment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	demuxer := NewDemuxer(file)
+	var segmentsList []*GraphicsSegment = nil
+	for {
+		pesPacket, err := demuxer.ReadPES()
+		if err == io.EOF {
+			return segmentsList, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pesPacket.PID != pid {
+			continue
+		}
+		pts := pesPacket.PTS
+		if pts < 0 {
+			pts = inTime
+		} else if pts < inTime || pts >= outTime {
+			continue
+		}
+
+		dts := pesPacket.DTS
+		if dts < 0 {
+			dts = pts
+		}
+		segmentsList = append(segmentsList, parseGraphicsSegments(pesPacket.Payload, pts-inTime+offset, dts-inTime+offset)...)
+	}
+}
+
+// ExtractGraphicsStream collects the segments of a PG, IG or text subtitle
+// stream of the playlist with timestamps rebased to playlist time, so a
+// stream spread over several play items reads as one continuous stream.
+func (mpls *MPLS) ExtractGraphicsStream(bdmvRoot string, pid int) ([]*GraphicsSegment, error) {
+	return extractGraphicsStream(bdmvRoot, mpls.PlayList.PlayItemList, pid)
+}
+
+func extractGraphicsStream(bdmvRoot string, playItems []*PlayItem, pid int) ([]*GraphicsSegment, error) {
+	var segmentsList []*GraphicsSegment = nil
+	offset := int64(0)
+	for _, playItem := range playItems {
+		inTime := int64(playItem.INTimeTicks) * 2
+		outTime := int64(playItem.OUTTimeTicks) * 2
+		segments, err := readGraphicsStream(playItem.ClipStreamPath(bdmvRoot), pid, inTime, outTime, offset)
+		if err != nil {
+			return nil, err
+		}
+		segmentsList = append(segmentsList, segments...)
+		offset += outTime - inTime
+	}
+	return segmentsList, nil
+}
+
+func (mpls *MPLS) ExtractPGStream(bdmvRoot string, stream *Stream) ([]*GraphicsSegment, error) {
+	if stream.StreamEntry.StreamType == 0x02 {
+		return mpls.extractSubPathGraphicsStream(bdmvRoot, stream)
+	}
+	return mpls.ExtractGraphicsStream(bdmvRoot, stream.StreamEntry.RefToStreamPID)
+}
+
+func (mpls *MPLS) ExportSUP(bdmvRoot string, stream *Stream, path string) error {
+	segments, err := mpls.ExtractPGStream(bdmvRoot, stream)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteSUP(file, segments)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func decodeRLE(object *GraphicsObject) []byte {
+	pixels := make([]byte, object.Width*object.Height)
+	data := object.Data
+	x, y := 0, 0
+	put := func(colorIndex byte, run int) {
+		for i := 0; i < run && x < object.Width; i++ {
+			if y < object.Height {
+				pixels[y*object.Width+x] = colorIndex
+			}
+			x++
+		}
+	}
+
+	for offset := 0; offset < len(data) && y < object.Height; {
+		b := data[offset]
+		offset++
+		if b != 0 {
+			put(b, 1)
+			continue
+		}
+		if offset >= len(data) {
+			break
+		}
+		flags := data[offset]
+		offset++
+		if flags == 0 {
+			x = 0
+			y++
+			continue
+		}
+
+		run := int(flags & 0x3f)
+		if flags&0x40 != 0 && offset < len(data) {
+			run = run<<8 | int(data[offset])
+			offset++
+		}
+		colorIndex := byte(0)
+		if flags&0x80 != 0 && offset < len(data) {
+			colorIndex = data[offset]
+			offset++
+		}
+		put(colorIndex, run)
+	}
+	return pixels
+}
+
+func clampColor(value float64) uint8 {
+	if value < 0 {
+		return 0
+	}
+	if value > 255 {
+		return 255
+	}
+	return uint8(value + 0.5)
+}
+
+// RGBA converts the entry with BT.709 coefficients for HD graphics and BT.601
+// ones for SD graphics.
+func (entry *PaletteEntry) RGBA(isHD bool) color.NRGBA {
+	y := 1.164 * float64(entry.Y-16)
+	cb := float64(entry.Cb - 128)
+	cr := float64(entry.Cr - 128)
+	if isHD {
+		return color.NRGBA{
+			R: clampColor(y + 1.793*cr),
+			G: clampColor(y - 0.213*cb - 0.533*cr),
+			B: clampColor(y + 2.112*cb),
+			A: uint8(entry.Alpha),
+		}
+	}
+	return color.NRGBA{
+		R: clampColor(y + 1.596*cr),
+		G: clampColor(y - 0.392*cb - 0.813*cr),
+		B: clampColor(y + 2.017*cb),
+		A: uint8(entry.Alpha),
+	}
+}
+
+// GraphicsDecoder keeps the palettes and objects of the current epoch, since
+// a display set may reference definitions sent by earlier display sets.
+type GraphicsDecoder struct {
+	palettes map[int]*Palette
+	objects  map[int]*GraphicsObject
+}
+
+func NewGraphicsDecoder() *GraphicsDecoder {
+	return &GraphicsDecoder{
+		palettes: map[int]*Palette{},
+		objects:  map[int]*GraphicsObject{},
+	}
+}
+
+func (decoder *GraphicsDecoder) update(displaySet *DisplaySet) {
+	if (displaySet.PresentationComposition != nil && displaySet.PresentationComposition.CompositionState&epochStart != 0) ||
+		(displaySet.InteractiveComposition != nil && displaySet.InteractiveComposition.CompositionState&epochStart != 0) {
+		decoder.palettes = map[int]*Palette{}
+		decoder.objects = map[int]*GraphicsObject{}
+	}
+	for _, palette := range displaySet.PalettesList {
+		decoder.palettes[palette.PaletteID] = palette
+	}
+	for _, object := range displaySet.ObjectsList {
+		decoder.objects[object.ObjectID] = object
+	}
+}
+
+func (decoder *GraphicsDecoder) Decode(displaySet *DisplaySet) (*image.NRGBA, error) {
+	decoder.update(displaySet)
+
+	composition := displaySet.PresentationComposition
+	if composition == nil {
+		return nil, errors.New("display set without presentation composition")
+	}
+	canvas := image.NewNRGBA(image.Rect(0, 0, composition.Width, composition.Height))
+
+	palette, ok := decoder.palettes[composition.PaletteID]
+	if !ok {
+		if composition.NumberOfCompositionObjects == 0 {
+			return canvas, nil
+		}
+		return nil, fmt.Errorf("palette %d is not defined", composition.PaletteID)
+	}
+	colors := make([]color.NRGBA, 256)
+	for id, entry := range palette.Entries {
+		colors[id] = entry.RGBA(composition.Height > 576)
+	}
+
+	for _, compositionObject := range composition.CompositionObjectsList {
+		object, ok := decoder.objects[compositionObject.ObjectID]
+		if !ok {
+			return nil, fmt.Errorf("object %d is not defined", compositionObject.ObjectID)
+		}
+		pixels := decodeRLE(object)
+
+		source := image.Rect(0, 0, object.Width, object.Height)
+		if compositionObject.IsCropped {
+			source = image.Rect(compositionObject.CropX, compositionObject.CropY,
+				compositionObject.CropX+compositionObject.CropWidth, compositionObject.CropY+compositionObject.CropHeight).Intersect(source)
+		}
+		for y := source.Min.Y; y < source.Max.Y; y++ {
+			for x := source.Min.X; x < source.Max.X; x++ {
+				canvas.SetNRGBA(compositionObject.X+x-source.Min.X, compositionObject.Y+y-source.Min.Y, colors[pixels[y*object.Width+x]])
+			}
+		}
+	}
+
+	return canvas, nil
+}
+
+// ExportPGSImages renders every display set into dir as <index>_<pts>.png.
+func ExportPGSImages(displaySets []*DisplaySet, dir string) error {
+	decoder := NewGraphicsDecoder()
+	for i, displaySet := range displaySets {
+		img, err := decoder.Decode(displaySet)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("%05d_%d.png", i, displaySet.PTS)))
+		if err != nil {
+			return err
+		}
+		err = png.Encode(file, img)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package go_mpls
+
+import (
+	"bytes"
+	"testing"
+)
+
+func buildDisplaySet(pts int64, forced bool) []*GraphicsSegment {
+	flags := byte(0x00)
+	if forced {
+		flags = 0x40
+	}
+	return []*GraphicsSegment{
+		{SegmentType: PresentationCompositionSegment, PTS: pts, Data: []byte{
+			0x07, 0x80, 0x04, 0x38, 0x10, 0x00, 0x01, 0x80, 0x00, 0x00, 0x01,
+			0x00, 0x00, 0x00, flags, 0x00, 0x0a, 0x00, 0x14}},
+		{SegmentType: WindowDefinitionSegment, PTS: pts, Data: []byte{
+			0x01, 0x00, 0x00, 0x0a, 0x00, 0x14, 0x00, 0x04, 0x00, 0x02}},
+		{SegmentType: PaletteDefinitionSegment, PTS: pts, Data: []byte{
+			0x00, 0x00, 0x01, 0xeb, 0x80, 0x80, 0xff}},
+		{SegmentType: ObjectDefinitionSegment, PTS: pts, Data: []byte{
+			0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x0c, 0x00, 0x04, 0x00, 0x02,
+			0x00, 0x82, 0x01, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00}},
+		{SegmentType: EndOfDisplaySetSegment, PTS: pts},
+	}
+}
+
+func TestDecodePGS(t *testing.T) {
+	segments := append(buildDisplaySet(90000, false), buildDisplaySet(180000, true)...)
+
+	var sup bytes.Buffer
+	if err := WriteSUP(&sup, segments); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSUP(&sup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(segments) || parsed[5].PTS != 180000 {
+		t.Fatalf("unexpected segments after round trip %#v", parsed)
+	}
+
+	displaySets := GroupDisplaySets(parsed)
+	if len(displaySets) != 2 || len(displaySets[0].ObjectsList) != 1 {
+		t.Fatalf("unexpected display sets %#v", displaySets)
+	}
+	summary := SummarizePGS(displaySets)
+	if summary.NumberOfCaptions != 2 || summary.NumberOfForcedCaptions != 1 || summary.IsForcedOnly() {
+		t.Fatalf("unexpected summary %#v", summary)
+	}
+
+	img, err := NewGraphicsDecoder().Decode(displaySets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 1920 || img.Bounds().Dy() != 1080 {
+		t.Fatalf("unexpected canvas %v", img.Bounds())
+	}
+	if c := img.NRGBAAt(10, 20); c.R != 255 || c.G != 255 || c.B != 255 || c.A != 255 {
+		t.Fatalf("unexpected pixel %v", c)
+	}
+	if c := img.NRGBAAt(12, 20); c.A != 0 {
+		t.Fatalf("unexpected pixel %v", c)
+	}
+	if c := img.NRGBAAt(10, 21); c.A != 0 {
+		t.Fatalf("unexpected pixel %v", c)
+	}
+}
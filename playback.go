@@ -0,0 +1,300 @@
+package go_mpls
+
+import (
+	"math/rand/v2"
+)
+
+const (
+	NoStill       = 0x00
+	FiniteStill   = 0x01
+	InfiniteStill = 0x02
+)
+
+// UserOperation names a field of UOMaskTable.
+type UserOperation string
+
+const (
+	MenuCallOperation                         UserOperation = "MenuCall"
+	TitleSearchOperation                      UserOperation = "TitleSearch"
+	ChapterSearchOperation                    UserOperation = "ChapterSearch"
+	TimeSearchOperation                       UserOperation = "TimeSearch"
+	SkipToNextPointOperation                  UserOperation = "SkipToNextPoint"
+	SkipToPrevPointOperation                  UserOperation = "SkipToPrevPoint"
+	StopOperation                             UserOperation = "Stop"
+	PauseOnOperation                          UserOperation = "PauseOn"
+	StillOffOperation                         UserOperation = "StillOff"
+	ForwardPlayOperation                      UserOperation = "ForwardPlay"
+	BackwardPlayOperation                     UserOperation = "BackwardPlay"
+	ResumeOperation                           UserOperation = "Resume"
+	MoveUpSelectedButtonOperation             UserOperation = "MoveUpSelectedButton"
+	MoveDownSelectedButtonOperation           UserOperation = "MoveDownSelectedButton"
+	MoveLeftSelectedButtonOperation           UserOperation = "MoveLeftSelectedButton"
+	MoveRightSelectedButtonOperation          UserOperation = "MoveRightSelectedButton"
+	SelectButtonOperation                     UserOperation = "SelectButton"
+	ActivateButtonOperation                   UserOperation = "ActivateButton"
+	SelectAndActivateButtonOperation          UserOperation = "SelectAndActivateButton"
+	PrimaryAudioStreamNumberChangeOperation   UserOperation = "PrimaryAudioStreamNumberChange"
+	AngleNumberChangeOperation                UserOperation = "AngleNumberChange"
+	PopupOnOperation                          UserOperation = "PopupOn"
+	PopupOffOperation                         UserOperation = "PopupOff"
+	PrimaryPGEnableDisableOperation           UserOperation = "PrimaryPGEnableDisable"
+	PrimaryPGStreamNumberChangeOperation      UserOperation = "PrimaryPGStreamNumberChange"
+	SecondaryVideoEnableDisableOperation      UserOperation = "SecondaryVideoEnableDisable"
+	SecondaryVideoStreamNumberChangeOperation UserOperation = "SecondaryVideoStreamNumberChange"
+	SecondaryAudioEnableDisableOperation      UserOperation = "SecondaryAudioEnableDisable"
+	SecondaryAudioStreamNumberChangeOperation UserOperation = "SecondaryAudioStreamNumberChange"
+	SecondaryPGStreamNumberChangeOperation    UserOperation = "SecondaryPGStreamNumberChange"
+)
+
+// UserOperationsList holds every user operation, in the order of the UO
+// mask table.
+var UserOperationsList = []UserOperation{
+	MenuCallOperation,
+	TitleSearchOperation,
+	ChapterSearchOperation,
+	TimeSearchOperation,
+	SkipToNextPointOperation,
+	SkipToPrevPointOperation,
+	StopOperation,
+	PauseOnOperation,
+	StillOffOperation,
+	ForwardPlayOperation,
+	BackwardPlayOperation,
+	ResumeOperation,
+	MoveUpSelectedButtonOperation,
+	MoveDownSelectedButtonOperation,
+	MoveLeftSelectedButtonOperation,
+	MoveRightSelectedButtonOperation,
+	SelectButtonOperation,
+	ActivateButtonOperation,
+	SelectAndActivateButtonOperation,
+	PrimaryAudioStreamNumberChangeOperation,
+	AngleNumberChangeOperation,
+	PopupOnOperation,
+	PopupOffOperation,
+	PrimaryPGEnableDisableOperation,
+	PrimaryPGStreamNumberChangeOperation,
+	SecondaryVideoEnableDisableOperation,
+	SecondaryVideoStreamNumberChangeOperation,
+	SecondaryAudioEnableDisableOperation,
+	SecondaryAudioStreamNumberChangeOperation,
+	SecondaryPGStreamNumberChangeOperation,
+}
+
+// PlaybackEvent is one step of a simulated playback. Times are 45 kHz ticks
+// on the simulated timeline, which grows with every played item and still.
+type PlaybackEvent struct {
+	PlayItemID      int
+	IsStill         bool
+	IsInfiniteStill bool
+	StartTicks      int
+	DurationTicks   int
+	INTimeTicks     int
+	OUTTimeTicks    int
+}
+
+type PlaybackSimulation struct {
+	PlayItemIDsList    []int
+	EventsList         []*PlaybackEvent
+	DurationTicks      int
+	HasInfiniteStill   bool
+	NumberOfStillTicks int
+}
+
+// mask returns the field of the table masking the operation, or nil for
+// an unknown operation.
+func (table *UOMaskTable) mask(operation UserOperation) *bool {
+	switch operation {
+	case MenuCallOperation:
+		return &table.MenuCall
+	case TitleSearchOperation:
+		return &table.TitleSearch
+	case ChapterSearchOperation:
+		return &table.ChapterSearch
+	case TimeSearchOperation:
+		return &table.TimeSearch
+	case SkipToNextPointOperation:
+		return &table.SkipToNextPoint
+	case SkipToPrevPointOperation:
+		return &table.SkipToPrevPoint
+	case StopOperation:
+		return &table.Stop
+	case PauseOnOperation:
+		return &table.PauseOn
+	case StillOffOperation:
+		return &table.StillOff
+	case ForwardPlayOperation:
+		return &table.ForwardPlay
+	case BackwardPlayOperation:
+		return &table.BackwardPlay
+	case ResumeOperation:
+		return &table.Resume
+	case MoveUpSelectedButtonOperation:
+		return &table.MoveUpSelectedButton
+	case MoveDownSelectedButtonOperation:
+		return &table.MoveDownSelectedButton
+	case MoveLeftSelectedButtonOperation:
+		return &table.MoveLeftSelectedButton
+	case MoveRightSelectedButtonOperation:
+		return &table.MoveRightSelectedButton
+	case SelectButtonOperation:
+		return &table.SelectButton
+	case ActivateButtonOperation:
+		return &table.ActivateButton
+	case SelectAndActivateButtonOperation:
+		return &table.SelectAndActivateButton
+	case PrimaryAudioStreamNumberChangeOperation:
+		return &table.PrimaryAudioStreamNumberChange
+	case AngleNumberChangeOperation:
+		return &table.AngleNumberChange
+	case PopupOnOperation:
+		return &table.PopupOn
+	case PopupOffOperation:
+		return &table.PopupOff
+	case PrimaryPGEnableDisableOperation:
+		return &table.PrimaryPGEnableDisable
+	case PrimaryPGStreamNumberChangeOperation:
+		return &table.PrimaryPGStreamNumberChange
+	case SecondaryVideoEnableDisableOperation:
+		return &table.SecondaryVideoEnableDisable
+	case SecondaryVideoStreamNumberChangeOperation:
+		return &table.SecondaryVideoStreamNumberChange
+	case SecondaryAudioEnableDisableOperation:
+		return &table.SecondaryAudioEnableDisable
+	case SecondaryAudioStreamNumberChangeOperation:
+		return &table.SecondaryAudioStreamNumberChange
+	case SecondaryPGStreamNumberChangeOperation:
+		return &table.SecondaryPGStreamNumberChange
+	}
+	return nil
+}
+
+// IsMasked reports whether the table forbids the operation.
+func (table *UOMaskTable) IsMasked(operation UserOperation) bool {
+	if table == nil {
+		return false
+	}
+	mask := table.mask(operation)
+	return mask != nil && *mask
+}
+
+// CombineUOMaskTables masks every operation masked by any of the tables.
+func CombineUOMaskTables(tables ...*UOMaskTable) *UOMaskTable {
+	combined := &UOMaskTable{}
+	for _, table := range tables {
+		for _, operation := range UserOperationsList {
+			if table.IsMasked(operation) {
+				*combined.mask(operation) = true
+			}
+		}
+	}
+	return combined
+}
+
+// IsUserOperationAllowed checks an operation against the mask of the
+// playlist combined with the mask of the play item being presented.
+func (mpls *MPLS) IsUserOperationAllowed(playItemID int, operation UserOperation) bool {
+	var playItemMask *UOMaskTable
+	if playItemID >= 0 && playItemID < len(mpls.PlayList.PlayItemList) {
+		playItemMask = mpls.PlayList.PlayItemList[playItemID].UserOperationMaskTable
+	}
+	return !CombineUOMaskTables(mpls.ApplicationInfoPlaylist.UOMaskTable, playItemMask).IsMasked(operation)
+}
+
+// PlayItemSequence returns the order in which play items are presented.
+// Random playback draws PlaybackCount items with repetition, shuffle
+// playback draws them without repetition. The same seed always gives the
+// same sequence.
+func (mpls *MPLS) PlayItemSequence(seed uint64) []int {
+	numberOfPlayItems := len(mpls.PlayList.PlayItemList)
+	var sequenceList []int = nil
+	random := rand.New(rand.NewPCG(seed, seed))
+
+	switch mpls.ApplicationInfoPlaylist.PlaybackType {
+	case RandomPlay:
+		for i := 0; i < mpls.ApplicationInfoPlaylist.PlaybackCount && numberOfPlayItems > 0; i++ {
+			sequenceList = append(sequenceList, random.IntN(numberOfPlayItems))
+		}
+	case ShufflePlay:
+		for i, playItemID := range random.Perm(numberOfPlayItems) {
+			if i >= mpls.ApplicationInfoPlaylist.PlaybackCount {
+				break
+			}
+			sequenceList = append(sequenceList, playItemID)
+		}
+	default:
+		for i := 0; i < numberOfPlayItems; i++ {
+			sequenceList = append(sequenceList, i)
+		}
+	}
+	return sequenceList
+}
+
+// SimulatePlayback lays the play item sequence out on a timeline. A finite
+// still adds its still time after the item. An infinite still is recorded
+// with no duration and playback goes on as if the user released it.
+func (mpls *MPLS) SimulatePlayback(seed uint64) *PlaybackSimulation {
+	simulation := &PlaybackSimulation{PlayItemIDsList: mpls.PlayItemSequence(seed)}
+	for _, playItemID := range simulation.PlayItemIDsList {
+		playItem := mpls.PlayList.PlayItemList[playItemID]
+		duration := playItem.DurationTicks()
+		simulation.EventsList = append(simulation.EventsList, &PlaybackEvent{
+			PlayItemID:    playItemID,
+			StartTicks:    simulation.DurationTicks,
+			DurationTicks: duration,
+			INTimeTicks:   playItem.INTimeTicks,
+			OUTTimeTicks:  playItem.OUTTimeTicks,
+		})
+		simulation.DurationTicks += duration
+
+		switch playItem.StillMode {
+		case FiniteStill:
+			stillTicks := int(playItem.StillTime) * 45000
+			simulation.EventsList = append(simulation.EventsList, &PlaybackEvent{
+				PlayItemID:    playItemID,
+				IsStill:       true,
+				StartTicks:    simulation.DurationTicks,
+				DurationTicks: stillTicks,
+				INTimeTicks:   playItem.OUTTimeTicks,
+				OUTTimeTicks:  playItem.OUTTimeTicks,
+			})
+			simulation.DurationTicks += stillTicks
+			simulation.NumberOfStillTicks += stillTicks
+		case InfiniteStill:
+			simulation.EventsList = append(simulation.EventsList, &PlaybackEvent{
+				PlayItemID:      playItemID,
+				IsStill:         true,
+				IsInfiniteStill: true,
+				StartTicks:      simulation.DurationTicks,
+				INTimeTicks:     playItem.OUTTimeTicks,
+				OUTTimeTicks:    playItem.OUTTimeTicks,
+			})
+			simulation.HasInfiniteStill = true
+		}
+	}
+	return simulation
+}
+
+// EventAt returns the event presented at the given timeline position. An
+// infinite still is returned for its own start position.
+func (simulation *PlaybackSimulation) EventAt(ticks int) *PlaybackEvent {
+	for _, event := range simulation.EventsList {
+		if ticks >= event.StartTicks && (ticks < event.StartTicks+event.DurationTicks || (event.IsInfiniteStill && ticks == event.StartTicks)) {
+			return event
+		}
+	}
+	return nil
+}
+
+// IsUserOperationAllowed checks an operation requested at the given
+// timeline position. StillOff is only meaningful during a still.
+func (simulation *PlaybackSimulation) IsUserOperationAllowed(mpls *MPLS, ticks int, operation UserOperation) bool {
+	event := simulation.EventAt(ticks)
+	if event == nil {
+		return false
+	}
+	if operation == StillOffOperation && !event.IsStill {
+		return false
+	}
+	return mpls.IsUserOperationAllowed(event.PlayItemID, operation)
+}
@@ -0,0 +1,88 @@
+package go_mpls
+
+import (
+	"slices"
+	"testing"
+)
+
+func buildPlaybackPlayList(playbackType PlaybackType, playbackCount int) *MPLS {
+	var playItemsList []*PlayItem = nil
+	for i := 0; i < 4; i++ {
+		playItemsList = append(playItemsList, &PlayItem{
+			INTimeTicks:            45000,
+			OUTTimeTicks:           45000 * (i + 2),
+			UserOperationMaskTable: &UOMaskTable{},
+		})
+	}
+	playItemsList[1].StillMode = FiniteStill
+	playItemsList[1].StillTime = 5
+	playItemsList[1].UserOperationMaskTable.StillOff = true
+	playItemsList[3].StillMode = InfiniteStill
+
+	return &MPLS{
+		ApplicationInfoPlaylist: &AppInfoPlayList{
+			PlaybackType:  playbackType,
+			PlaybackCount: playbackCount,
+			UOMaskTable:   &UOMaskTable{TimeSearch: true},
+		},
+		PlayList: &PlayList{PlayItemList: playItemsList},
+	}
+}
+
+func TestSimulatePlayback(t *testing.T) {
+	mpls := buildPlaybackPlayList(StandardPlay, 0)
+	simulation := mpls.SimulatePlayback(1)
+	if !slices.Equal(simulation.PlayItemIDsList, []int{0, 1, 2, 3}) || len(simulation.EventsList) != 6 {
+		t.Fatalf("unexpected sequence %v", simulation.PlayItemIDsList)
+	}
+	if simulation.DurationTicks != 45000*15 || simulation.NumberOfStillTicks != 45000*5 || !simulation.HasInfiniteStill {
+		t.Fatalf("unexpected timeline %#v", simulation)
+	}
+	if event := simulation.EventAt(45000 * 4); !event.IsStill || event.PlayItemID != 1 {
+		t.Fatalf("unexpected event %#v", event)
+	}
+
+	if simulation.IsUserOperationAllowed(mpls, 45000*4, StillOffOperation) ||
+		simulation.IsUserOperationAllowed(mpls, 0, TimeSearchOperation) ||
+		!simulation.IsUserOperationAllowed(mpls, 0, ChapterSearchOperation) ||
+		!simulation.IsUserOperationAllowed(mpls, simulation.DurationTicks, StillOffOperation) {
+		t.Fatal("unexpected user operation mask")
+	}
+
+	shuffle := buildPlaybackPlayList(ShufflePlay, 3)
+	sequence := shuffle.PlayItemSequence(42)
+	if len(sequence) != 3 || !slices.Equal(sequence, shuffle.PlayItemSequence(42)) {
+		t.Fatalf("shuffle is not deterministic %v", sequence)
+	}
+	seen := map[int]bool{}
+	for _, playItemID := range sequence {
+		if seen[playItemID] {
+			t.Fatalf("shuffle repeated a play item %v", sequence)
+		}
+		seen[playItemID] = true
+	}
+	if len(buildPlaybackPlayList(RandomPlay, 10).PlayItemSequence(7)) != 10 {
+		t.Fatal("unexpected random sequence length")
+	}
+}
+
+func TestUOMaskTable(t *testing.T) {
+	if len(UserOperationsList) != 30 {
+		t.Fatalf("unexpected number of user operations %d", len(UserOperationsList))
+	}
+	for _, operation := range UserOperationsList {
+		table := &UOMaskTable{}
+		*table.mask(operation) = true
+		for _, other := range UserOperationsList {
+			if table.IsMasked(other) != (other == operation) {
+				t.Fatalf("masking %s masks %s", operation, other)
+			}
+		}
+		if !CombineUOMaskTables(nil, &UOMaskTable{}, table).IsMasked(operation) {
+			t.Fatalf("%s lost when combining tables", operation)
+		}
+	}
+	if (&UOMaskTable{}).IsMasked("Unknown") {
+		t.Fatal("unknown operation masked")
+	}
+}
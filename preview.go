@@ -0,0 +1,161 @@
+package go_mpls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// previewSegment is the part of a play item between two playlist times.
+type previewSegment struct {
+	playItem *PlayItem
+	path     string
+	// inTimeTicks and outTimeTicks are clip times, made relative to the
+	// start of the STC sequence when its clip information is known.
+	inTimeTicks  int
+	outTimeTicks int
+}
+
+// clipStartTicks returns the presentation start time of the STC sequence of
+// a play item, or 0 when clips does not hold its clip information.
+func clipStartTicks(clips map[string]*CLPI, playItem *PlayItem) int {
+	if clpi := clips[playItem.ClipInformationFileName]; clpi != nil {
+		if stcSequence := clpi.STCSequence(playItem.RefToSTCID); stcSequence != nil {
+			return stcSequence.PresentationStartTime
+		}
+	}
+	return 0
+}
+
+// previewSegments splits the playlist time range [start, end) at the play
+// item boundaries.
+func (mpls *MPLS) previewSegments(bdmvRoot string, clips map[string]*CLPI, start, end int) []*previewSegment {
+	var segmentsList []*previewSegment = nil
+	offset := 0
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		from, to := max(start, offset), min(end, offset+playItem.DurationTicks())
+		if from < to {
+			clipStart := clipStartTicks(clips, playItem)
+			segmentsList = append(segmentsList, &previewSegment{
+				playItem:     playItem,
+				path:         filepath.Join(bdmvRoot, "STREAM", playItem.ClipInformationFileName+".m2ts"),
+				inTimeTicks:  playItem.INTimeTicks + from - offset - clipStart,
+				outTimeTicks: playItem.INTimeTicks + to - offset - clipStart,
+			})
+		}
+		offset += playItem.DurationTicks()
+	}
+	return segmentsList
+}
+
+func ticksToSeconds(ticks int) string {
+	return fmt.Sprintf("%d.%06d", ticks/45000, ticks%45000*1000000/45000)
+}
+
+// WriteMPVEDL writes the playlist as an mpv EDL file with one segment per
+// play item, titled with its clip name. mpv rebases the timestamps of a
+// file to start at zero, so the trims are relative to the STC sequence
+// start taken from clips; clip information missing from clips, or a nil
+// map, leaves the times as they are.
+func (mpls *MPLS) WriteMPVEDL(w io.Writer, bdmvRoot string, clips map[string]*CLPI) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString("# mpv EDL v0\n")
+	for _, segment := range mpls.previewSegments(bdmvRoot, clips, 0, mpls.DurationTicks()) {
+		fmt.Fprintf(writer, "%%%d%%%s,start=%s,length=%s,title=%s\n", len(segment.path), segment.path,
+			ticksToSeconds(segment.inTimeTicks), ticksToSeconds(segment.outTimeTicks-segment.inTimeTicks),
+			segment.playItem.ClipInformationFileName)
+	}
+	return writer.Flush()
+}
+
+// WriteFFConcat writes the playlist as an ffmpeg concat demuxer list. The
+// concat demuxer seeks in the timestamps of the transport stream, so the
+// inpoint and outpoint of every file are the IN and OUT times of its play
+// item.
+func (mpls *MPLS) WriteFFConcat(w io.Writer, bdmvRoot string) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString("ffconcat version 1.0\n")
+	for _, segment := range mpls.previewSegments(bdmvRoot, nil, 0, mpls.DurationTicks()) {
+		fmt.Fprintf(writer, "file '%s'\ninpoint %s\noutpoint %s\n", strings.ReplaceAll(segment.path, "'", `'\''`),
+			ticksToSeconds(segment.inTimeTicks), ticksToSeconds(segment.outTimeTicks))
+	}
+	return writer.Flush()
+}
+
+// WriteM3U writes the playlist as an extended M3U with one entry per
+// chapter, or per play item when it has no chapters. A chapter spanning
+// several play items gets one entry per play item, all with the chapter
+// name. Trims use #EXTVLCOPT start-time and stop-time, relative to the STC
+// sequence start like WriteMPVEDL.
+func (mpls *MPLS) WriteM3U(w io.Writer, bdmvRoot string, clips map[string]*CLPI) error {
+	type entry struct {
+		name       string
+		start, end int
+	}
+	var entriesList []*entry = nil
+	chapters := mpls.Chapters()
+	for k, chapter := range chapters {
+		end := mpls.DurationTicks()
+		if k+1 < len(chapters) {
+			end = chapters[k+1].TimeTicks
+		}
+		entriesList = append(entriesList, &entry{chapter.Name(), chapter.TimeTicks, end})
+	}
+	if len(entriesList) == 0 {
+		offset := 0
+		for _, playItem := range mpls.PlayList.PlayItemList {
+			entriesList = append(entriesList, &entry{playItem.ClipInformationFileName, offset, offset + playItem.DurationTicks()})
+			offset += playItem.DurationTicks()
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	writer.WriteString("#EXTM3U\n")
+	for _, entry := range entriesList {
+		for _, segment := range mpls.previewSegments(bdmvRoot, clips, entry.start, entry.end) {
+			fmt.Fprintf(writer, "#EXTINF:%d,%s\n#EXTVLCOPT:start-time=%s\n#EXTVLCOPT:stop-time=%s\n%s\n",
+				(segment.outTimeTicks-segment.inTimeTicks+22500)/45000, entry.name,
+				ticksToSeconds(segment.inTimeTicks), ticksToSeconds(segment.outTimeTicks), segment.path)
+		}
+	}
+	return writer.Flush()
+}
+
+func (mpls *MPLS) ExportMPVEDL(path, bdmvRoot string, clips map[string]*CLPI) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = mpls.WriteMPVEDL(file, bdmvRoot, clips)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (mpls *MPLS) ExportFFConcat(path, bdmvRoot string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = mpls.WriteFFConcat(file, bdmvRoot)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (mpls *MPLS) ExportM3U(path, bdmvRoot string, clips map[string]*CLPI) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = mpls.WriteM3U(file, bdmvRoot, clips)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package go_mpls
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreviewExports(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	writeCLPI(t, bdmvRoot, "00001", buildCLPI(45000, 45000*100))
+	clips := map[string]*CLPI{}
+	clpi, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", "00001.clpi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clips["00001"] = clpi
+
+	mpls := &MPLS{
+		PlayList: &PlayList{PlayItemList: []*PlayItem{
+			{ClipInformationFileName: "00001", INTimeTicks: 45000 * 2, OUTTimeTicks: 45000 * 62},
+			{ClipInformationFileName: "00002", INTimeTicks: 0, OUTTimeTicks: 45000 * 30},
+		}},
+		PlayListMark: &PlayListMark{PlayListMarksList: []*PlayListMarkItem{
+			{MarkType: EntryMark, RefToPlayItemID: 0, MarkTimeTicks: 45000 * 2},
+			{MarkType: EntryMark, RefToPlayItemID: 0, MarkTimeTicks: 45000 * 32},
+		}},
+	}
+	clip1 := filepath.Join("BDMV", "STREAM", "00001.m2ts")
+	clip2 := filepath.Join("BDMV", "STREAM", "00002.m2ts")
+
+	var buffer bytes.Buffer
+	if err := mpls.WriteMPVEDL(&buffer, "BDMV", clips); err != nil {
+		t.Fatal(err)
+	}
+	expected := "# mpv EDL v0\n" +
+		"%22%" + clip1 + ",start=1.000000,length=60.000000,title=00001\n" +
+		"%22%" + clip2 + ",start=0.000000,length=30.000000,title=00002\n"
+	if buffer.String() != expected {
+		t.Fatalf("unexpected EDL\n%s", buffer.String())
+	}
+
+	buffer.Reset()
+	if err := mpls.WriteFFConcat(&buffer, "BDMV"); err != nil {
+		t.Fatal(err)
+	}
+	expected = "ffconcat version 1.0\n" +
+		"file '" + clip1 + "'\ninpoint 2.000000\noutpoint 62.000000\n" +
+		"file '" + clip2 + "'\ninpoint 0.000000\noutpoint 30.000000\n"
+	if buffer.String() != expected {
+		t.Fatalf("unexpected concat list\n%s", buffer.String())
+	}
+
+	buffer.Reset()
+	if err := mpls.WriteM3U(&buffer, "BDMV", clips); err != nil {
+		t.Fatal(err)
+	}
+	expected = "#EXTM3U\n" +
+		"#EXTINF:30,Chapter 01\n#EXTVLCOPT:start-time=1.000000\n#EXTVLCOPT:stop-time=31.000000\n" + clip1 + "\n" +
+		"#EXTINF:30,Chapter 02\n#EXTVLCOPT:start-time=31.000000\n#EXTVLCOPT:stop-time=61.000000\n" + clip1 + "\n" +
+		"#EXTINF:30,Chapter 02\n#EXTVLCOPT:start-time=0.000000\n#EXTVLCOPT:stop-time=30.000000\n" + clip2 + "\n"
+	if buffer.String() != expected {
+		t.Fatalf("unexpected M3U\n%s", buffer.String())
+	}
+}
@@ -0,0 +1,230 @@
+package go_mpls
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type ReferenceProblemKind string
+
+const (
+	// DanglingReference points to a clip, sub path, sub clip, STC sequence or
+	// PID that does not exist.
+	DanglingReference ReferenceProblemKind = "dangling"
+	// AmbiguousReference resolves to more than one stream of the clip.
+	AmbiguousReference ReferenceProblemKind = "ambiguous"
+)
+
+// ReferenceProblem is a reference of a playlist that does not resolve to
+// exactly one target. Location uses the same paths as LintFinding.
+type ReferenceProblem struct {
+	PlayListPath string               `json:"playlist"`
+	Location     string               `json:"location"`
+	Kind         ReferenceProblemKind `json:"kind"`
+	Reason       string               `json:"reason"`
+}
+
+// streamClips resolves the sub path part of a stream entry of play item
+// playItemID to the clips that carry the stream.
+func (mpls *MPLS) streamClips(playItemID int, entry *StreamEntry, report func(kind ReferenceProblemKind, format string, args ...any)) []string {
+	playItem := mpls.PlayList.PlayItemList[playItemID]
+	mainClips := func() []string {
+		var namesList []string = nil
+		for angleID := 1; angleID <= max(1, playItem.NumberOfAngles); angleID++ {
+			namesList = append(namesList, playItem.AngleClip(angleID).ClipInformationFileName)
+		}
+		return namesList
+	}
+
+	switch entry.StreamType {
+	case 0x01:
+		return mainClips()
+	case 0x02, 0x03, 0x04:
+	default:
+		report(DanglingReference, "stream type %d is reserved", entry.StreamType)
+		return nil
+	}
+
+	if entry.RefToSubPathID >= len(mpls.PlayList.SubPathsList) {
+		report(DanglingReference, "RefToSubPathID %d does not exist, the playlist has %d sub paths", entry.RefToSubPathID, len(mpls.PlayList.SubPathsList))
+		return nil
+	}
+	// In-mux sub paths carry their streams in the clip of the play item.
+	if entry.StreamType == 0x03 {
+		return mainClips()
+	}
+
+	var namesList []string = nil
+	subPath := mpls.PlayList.SubPathsList[entry.RefToSubPathID]
+	for j, subPlayItem := range subPath.SubPlayItemsList {
+		if entry.StreamType == 0x02 && entry.RefToSubClipID > len(subPlayItem.MultiClipEntriesList) {
+			report(DanglingReference, "RefToSubClipID %d does not exist in %s, it has %d clips", entry.RefToSubClipID,
+				subPlayItemLocation(entry.RefToSubPathID, j), len(subPlayItem.MultiClipEntriesList)+1)
+			continue
+		}
+		name := subPlayItem.ClipInformationFileName
+		if entry.StreamType == 0x02 {
+			name = subPlayItem.ClipName(entry.RefToSubClipID)
+		}
+		namesList = append(namesList, name)
+	}
+	return namesList
+}
+
+// resolvePID reports a PID that is missing from the program info of clpi or
+// found more than once with different coding types. A PID of another coding
+// type than the stream table expects is dangling too.
+func resolvePID(clpi *CLPI, clipName string, pid int, codingType StreamCodingType, report func(kind ReferenceProblemKind, format string, args ...any)) {
+	var matchesList []*ProgramStream = nil
+	if clpi.ProgramInfo != nil {
+		for _, programSequence := range clpi.ProgramInfo.ProgramSequencesList {
+			for _, stream := range programSequence.StreamsList {
+				if stream.PID == pid {
+					matchesList = append(matchesList, stream)
+				}
+			}
+		}
+	}
+
+	if len(matchesList) == 0 {
+		report(DanglingReference, "PID 0x%04x does not exist in %s", pid, clipName)
+		return
+	}
+	var codingTypesList []StreamCodingType = nil
+	for _, stream := range matchesList {
+		if stream.StreamAttributes != nil && !slices.Contains(codingTypesList, stream.StreamAttributes.StreamCodingType) {
+			codingTypesList = append(codingTypesList, stream.StreamAttributes.StreamCodingType)
+		}
+	}
+	if len(codingTypesList) > 1 {
+		report(AmbiguousReference, "PID 0x%04x is %v in %s", pid, codingTypesList, clipName)
+	} else if len(codingTypesList) == 1 && codingTypesList[0] != codingType {
+		report(DanglingReference, "PID 0x%04x is %v in %s, not %v", pid, codingTypesList[0], clipName, codingType)
+	}
+}
+
+// CheckReferences resolves every stream entry of every stream table and every
+// clip of every sub path against the sub paths of the playlist and the clip
+// information in clips, keyed by clip name. A clip missing from clips is
+// reported as a dangling reference.
+func (mpls *MPLS) CheckReferences(clips map[string]*CLPI) []*ReferenceProblem {
+	var problemsList []*ReferenceProblem = nil
+	reporter := func(location string) func(kind ReferenceProblemKind, format string, args ...any) {
+		return func(kind ReferenceProblemKind, format string, args ...any) {
+			problemsList = append(problemsList, &ReferenceProblem{
+				PlayListPath: mpls.FilePath,
+				Location:     location,
+				Kind:         kind,
+				Reason:       fmt.Sprintf(format, args...),
+			})
+		}
+	}
+	checkClip := func(location, name string, stcID int) {
+		report := reporter(location)
+		clpi, ok := clips[name]
+		if !ok || clpi == nil {
+			report(DanglingReference, "clip information of %s is missing", name)
+		} else if clpi.STCSequence(stcID) == nil {
+			report(DanglingReference, "RefToSTCID %d does not exist in %s", stcID, name)
+		}
+	}
+
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		checkClip(playItemLocation(i), playItem.ClipInformationFileName, playItem.RefToSTCID)
+		for k, angle := range playItem.AnglesList {
+			checkClip(fmt.Sprintf("%s.AnglesList[%d]", playItemLocation(i), k), angle.ClipInformationFileName, angle.RefToSTCID)
+		}
+	}
+	for i, subPath := range mpls.PlayList.SubPathsList {
+		for j, subPlayItem := range subPath.SubPlayItemsList {
+			checkClip(subPlayItemLocation(i, j), subPlayItem.ClipInformationFileName, subPlayItem.RefToSTCID)
+			for k, multiClipEntry := range subPlayItem.MultiClipEntriesList {
+				checkClip(fmt.Sprintf("%s.MultiClipEntriesList[%d]", subPlayItemLocation(i, j), k),
+					multiClipEntry.ClipInformationFileName, multiClipEntry.RefToSTCID)
+			}
+		}
+	}
+
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		if playItem.STNTable == nil {
+			continue
+		}
+		lists := stnStreamLists(playItem.STNTable)
+		names := make([]string, 0, len(lists))
+		for name := range lists {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			for k, stream := range lists[name] {
+				if stream.StreamEntry == nil {
+					continue
+				}
+				report := reporter(fmt.Sprintf("%s.STNTable.%s[%d]", playItemLocation(i), name, k))
+				var codingType StreamCodingType
+				if stream.StreamAttributes != nil {
+					codingType = stream.StreamAttributes.StreamCodingType
+				}
+				for _, clipName := range mpls.streamClips(i, stream.StreamEntry, report) {
+					if clpi := clips[clipName]; clpi != nil {
+						resolvePID(clpi, clipName, stream.StreamEntry.RefToStreamPID, codingType, report)
+					}
+				}
+			}
+		}
+	}
+	return problemsList
+}
+
+// PlayListPaths returns the MPLS files of the PLAYLIST directory of a disc,
+// sorted by name.
+func PlayListPaths(bdmvRoot string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(bdmvRoot, "PLAYLIST"))
+	if err != nil {
+		return nil, err
+	}
+	var pathsList []string = nil
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".mpls") {
+			pathsList = append(pathsList, filepath.Join(bdmvRoot, "PLAYLIST", entry.Name()))
+		}
+	}
+	return pathsList, nil
+}
+
+// CheckDiscReferences checks the references of every playlist of a disc.
+// Clip information is parsed once per clip; a missing CLPI file is reported
+// as a dangling reference of every playlist using the clip, while any other
+// parse error is returned.
+func CheckDiscReferences(bdmvRoot string) ([]*ReferenceProblem, error) {
+	paths, err := PlayListPaths(bdmvRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	clips := map[string]*CLPI{}
+	var problemsList []*ReferenceProblem = nil
+	for _, path := range paths {
+		mpls, err := Parse(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range mpls.ClipNames() {
+			if _, ok := clips[name]; ok {
+				continue
+			}
+			clpi, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", name+".clpi"))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			clips[name] = clpi
+		}
+		problemsList = append(problemsList, mpls.CheckReferences(clips)...)
+	}
+	return problemsList, nil
+}
@@ -0,0 +1,68 @@
+package go_mpls
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/syxxzzr/go-mpls/internal/fixture"
+)
+
+func TestCheckDiscReferences(t *testing.T) {
+	bdmvRoot := t.TempDir()
+	writeCLPI(t, bdmvRoot, "00001", buildCLPI(0, 90000))
+	if err := os.MkdirAll(filepath.Join(bdmvRoot, "PLAYLIST"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	subPathStream := []byte{9, 0x02, 2, 0, 0x12, 0x00, 0, 0, 0, 0, 5, byte(PresentationGraphics), 'e', 'n', 'g', 0}
+	stnTable := fixture.STNTable(1,
+		fixture.Stream(0x1011, byte(HEVCVideo), 0x81, 0x12, 0x00, 0x00),
+		fixture.Stream(0x1100, byte(DolbyDigitalAudio), 0x61, 'e', 'n', 'g'),
+		fixture.Stream(0x1101, byte(DolbyDigitalAudio), 0x61, 'f', 'r', 'a'),
+		fixture.Stream(0x1011, byte(DolbyDigitalAudio), 0x61, 'd', 'e', 'u'),
+		subPathStream)
+	fixture.WriteFile(t, filepath.Join(bdmvRoot, "PLAYLIST"), "00800.mpls", fixture.MPLS("0300",
+		[][]byte{fixture.PlayItem("00001", 1, 0, 90000, stnTable)},
+		[][]byte{fixture.SubPath(int(TextSubtitlePath), "00009", 0)},
+		[][]byte{fixture.Mark(0, 0)}))
+
+	problems, err := CheckDiscReferences(bdmvRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reasonsList []string = nil
+	for _, problem := range problems {
+		if problem.Kind != DanglingReference || !strings.HasSuffix(problem.PlayListPath, "00800.mpls") {
+			t.Fatalf("unexpected problem %#v", problem)
+		}
+		reasonsList = append(reasonsList, problem.Location+": "+problem.Reason)
+	}
+	reasons := strings.Join(reasonsList, "\n")
+	if len(problems) != 4 || !strings.Contains(reasons, "clip information of 00009 is missing") ||
+		!strings.Contains(reasons, "PID 0x1101 does not exist in 00001") ||
+		!strings.Contains(reasons, "PID 0x1011 is HEVC in 00001, not Dolby Digital") ||
+		!strings.Contains(reasons, "PrimaryAudioStreamsList[3]: RefToSubPathID 2 does not exist") {
+		t.Fatalf("unexpected problems\n%s", reasons)
+	}
+
+	clpi, err := ParseCLPI(filepath.Join(bdmvRoot, "CLIPINF", "00001.clpi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clpi.ProgramInfo.ProgramSequencesList = append(clpi.ProgramInfo.ProgramSequencesList, &ProgramSequence{
+		StreamsList: []*ProgramStream{{PID: 0x1100, StreamAttributes: &StreamAttributes{StreamCodingType: DTSAudio}}},
+	})
+	mpls := &MPLS{PlayList: &PlayList{PlayItemList: []*PlayItem{{
+		ClipInformationFileName: "00001",
+		STNTable: &STNTable{PrimaryAudioStreamsList: []*Stream{{
+			StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: 0x1100},
+			StreamAttributes: &StreamAttributes{StreamCodingType: DolbyDigitalAudio},
+		}}},
+	}}}}
+	problems = mpls.CheckReferences(map[string]*CLPI{"00001": clpi})
+	if len(problems) != 1 || problems[0].Kind != AmbiguousReference {
+		t.Fatalf("unexpected problems %#v", problems)
+	}
+}
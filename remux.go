@@ -0,0 +1,328 @@
+package go_mpls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TrackSelection picks the streams of a remux by PID, in output order. Nil
+// lists select every stream of the kind; an empty non-nil list selects
+// none. Only streams multiplexed into the clips of the play items can be
+// selected, so sub path and text subtitle streams are left out.
+type TrackSelection struct {
+	VideoPIDsList          []int
+	AudioPIDsList          []int
+	SubtitlePIDsList       []int
+	DefaultAudioPID        int
+	DefaultSubtitlePID     int
+	ForcedSubtitlePIDsList []int
+	// TrackIDs maps PIDs to mkvmerge track IDs. PIDs missing from it get
+	// the position of the PID in ascending order among the multiplexed
+	// streams of the first play item, which is how mkvmerge numbers the
+	// tracks of a transport stream.
+	TrackIDs map[int]int
+}
+
+// remuxTrack is a selected stream with its mkvmerge track ID.
+type remuxTrack struct {
+	stream    *Stream
+	trackID   int
+	isDefault bool
+	isForced  bool
+}
+
+// TrackSelection selects every multiplexed video, audio and PG stream of the
+// table, with the audio and PG streams chosen by a player as defaults. A PG
+// stream selected without its display flag only shows forced subtitles.
+func (table *STNTable) TrackSelection(selection *StreamSelection) *TrackSelection {
+	trackSelection := &TrackSelection{VideoPIDsList: []int{}, AudioPIDsList: []int{}, SubtitlePIDsList: []int{}}
+	for _, stream := range table.PrimaryVideoStreamsList {
+		trackSelection.VideoPIDsList = append(trackSelection.VideoPIDsList, stream.StreamEntry.RefToStreamPID)
+	}
+	for _, stream := range table.PrimaryAudioStreamsList {
+		trackSelection.AudioPIDsList = append(trackSelection.AudioPIDsList, stream.StreamEntry.RefToStreamPID)
+	}
+	for _, stream := range table.PrimaryPGStreamsList {
+		trackSelection.SubtitlePIDsList = append(trackSelection.SubtitlePIDsList, stream.StreamEntry.RefToStreamPID)
+	}
+	if selection == nil {
+		return trackSelection
+	}
+	if stream := selection.PrimaryAudioStream(table); stream != nil {
+		trackSelection.DefaultAudioPID = stream.StreamEntry.RefToStreamPID
+	}
+	if stream := selection.PGStream(table); stream != nil {
+		if selection.PGDisplayFlag {
+			trackSelection.DefaultSubtitlePID = stream.StreamEntry.RefToStreamPID
+		} else {
+			trackSelection.ForcedSubtitlePIDsList = append(trackSelection.ForcedSubtitlePIDsList, stream.StreamEntry.RefToStreamPID)
+		}
+	}
+	return trackSelection
+}
+
+func isMultiplexedStream(stream *Stream) bool {
+	return stream.StreamEntry.StreamType == 0x01 || stream.StreamEntry.StreamType == 0x03
+}
+
+// remuxTracks resolves the selection against the stream table of the first
+// play item, in output order: video, audio, then subtitles.
+func (mpls *MPLS) remuxTracks(selection *TrackSelection) ([]*remuxTrack, error) {
+	if len(mpls.PlayList.PlayItemList) == 0 || mpls.PlayList.PlayItemList[0].STNTable == nil {
+		return nil, errors.New("playlist without streams")
+	}
+	table := mpls.PlayList.PlayItemList[0].STNTable
+	if selection == nil {
+		selection = &TrackSelection{}
+	}
+
+	var pidsList []int = nil
+	for _, streams := range [][]*Stream{table.PrimaryVideoStreamsList, table.PrimaryAudioStreamsList, table.PrimaryPGStreamsList,
+		table.SecondaryAudioStreamsList, table.SecondaryVideoStreamsList} {
+		for _, stream := range streams {
+			if isMultiplexedStream(stream) && !slices.Contains(pidsList, stream.StreamEntry.RefToStreamPID) {
+				pidsList = append(pidsList, stream.StreamEntry.RefToStreamPID)
+			}
+		}
+	}
+	slices.Sort(pidsList)
+
+	var tracksList []*remuxTrack = nil
+	add := func(kind string, streams []*Stream, pids []int) error {
+		if pids == nil {
+			for _, stream := range streams {
+				if isMultiplexedStream(stream) {
+					pids = append(pids, stream.StreamEntry.RefToStreamPID)
+				}
+			}
+		}
+		for _, pid := range pids {
+			k := slices.IndexFunc(streams, func(stream *Stream) bool {
+				return isMultiplexedStream(stream) && stream.StreamEntry.RefToStreamPID == pid
+			})
+			if k < 0 {
+				return fmt.Errorf("no multiplexed %s stream with PID 0x%04x", kind, pid)
+			}
+			trackID, ok := selection.TrackIDs[pid]
+			if !ok {
+				trackID = slices.Index(pidsList, pid)
+			}
+			tracksList = append(tracksList, &remuxTrack{
+				stream:    streams[k],
+				trackID:   trackID,
+				isDefault: pid == selection.DefaultAudioPID || pid == selection.DefaultSubtitlePID,
+				isForced:  slices.Contains(selection.ForcedSubtitlePIDsList, pid),
+			})
+		}
+		return nil
+	}
+	if err := add("video", table.PrimaryVideoStreamsList, selection.VideoPIDsList); err != nil {
+		return nil, err
+	}
+	if err := add("audio", table.PrimaryAudioStreamsList, selection.AudioPIDsList); err != nil {
+		return nil, err
+	}
+	if err := add("PG", table.PrimaryPGStreamsList, selection.SubtitlePIDsList); err != nil {
+		return nil, err
+	}
+	return tracksList, nil
+}
+
+func (mpls *MPLS) clipPaths(bdmvRoot string) []string {
+	var pathsList []string = nil
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		pathsList = append(pathsList, filepath.Join(bdmvRoot, "STREAM", playItem.ClipInformationFileName+".m2ts"))
+	}
+	return pathsList
+}
+
+func joinTrackIDs(tracks []*remuxTrack, kind func(StreamCodingType) bool) string {
+	var idsList []string = nil
+	for _, track := range tracks {
+		if kind(track.stream.StreamAttributes.StreamCodingType) {
+			idsList = append(idsList, fmt.Sprint(track.trackID))
+		}
+	}
+	return strings.Join(idsList, ",")
+}
+
+func isVideoCodingType(codingType StreamCodingType) bool {
+	return slices.Contains([]StreamCodingType{MPEG1Video, MPEG2Video, MPEG4AVCVideo, MPEG4MVCVideo, SMTPEVC1Video, HEVCVideo}, codingType)
+}
+
+func isSubtitleCodingType(codingType StreamCodingType) bool {
+	return codingType == PresentationGraphics || codingType == TextSubtitle
+}
+
+func isAudioCodingType(codingType StreamCodingType) bool {
+	return !isVideoCodingType(codingType) && !isSubtitleCodingType(codingType) && codingType != InteractiveGraphics
+}
+
+// MkvmergeArgs builds the mkvmerge command line remuxing the clips of the
+// playlist into outputPath, appending the clip of every play item to the
+// first one. mkvmerge reads the clips whole, so IN and OUT times inside a
+// clip are not applied. chaptersPath, when not empty, is passed as chapter
+// file, for example one written by WriteOGMChapters.
+func (mpls *MPLS) MkvmergeArgs(bdmvRoot string, selection *TrackSelection, outputPath, chaptersPath string) ([]string, error) {
+	tracks, err := mpls.remuxTracks(selection)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"mkvmerge", "--output", outputPath}
+	if chaptersPath != "" {
+		args = append(args, "--chapters", chaptersPath)
+	}
+	for i, path := range mpls.clipPaths(bdmvRoot) {
+		for _, selector := range []struct {
+			option   string
+			noOption string
+			kind     func(StreamCodingType) bool
+		}{
+			{"--video-tracks", "--no-video", isVideoCodingType},
+			{"--audio-tracks", "--no-audio", isAudioCodingType},
+			{"--subtitle-tracks", "--no-subtitles", isSubtitleCodingType},
+		} {
+			if ids := joinTrackIDs(tracks, selector.kind); ids != "" {
+				args = append(args, selector.option, ids)
+			} else {
+				args = append(args, selector.noOption)
+			}
+		}
+		for _, track := range tracks {
+			if language := track.stream.StreamAttributes.LanguageCode; language != "" {
+				args = append(args, "--language", fmt.Sprintf("%d:%s", track.trackID, language))
+			}
+			if isVideoCodingType(track.stream.StreamAttributes.StreamCodingType) {
+				continue
+			}
+			flag := "no"
+			if track.isDefault {
+				flag = "yes"
+			}
+			args = append(args, "--default-track-flag", fmt.Sprintf("%d:%s", track.trackID, flag))
+			if track.isForced {
+				args = append(args, "--forced-display-flag", fmt.Sprintf("%d:yes", track.trackID))
+			}
+		}
+		if i > 0 {
+			path = "+" + path
+		}
+		args = append(args, path)
+	}
+
+	var orderList []string = nil
+	for _, track := range tracks {
+		orderList = append(orderList, fmt.Sprintf("0:%d", track.trackID))
+	}
+	args = append(args, "--track-order", strings.Join(orderList, ","))
+	var appendList []string = nil
+	for i := 1; i < len(mpls.PlayList.PlayItemList); i++ {
+		for _, track := range tracks {
+			appendList = append(appendList, fmt.Sprintf("%d:%d:%d:%d", i, track.trackID, i-1, track.trackID))
+		}
+	}
+	if len(appendList) > 0 {
+		args = append(args, "--append-to", strings.Join(appendList, ","))
+	}
+	return args, nil
+}
+
+// FFmpegArgs builds the ffmpeg command line copying the selected streams of
+// the concatenated clips into outputPath. The clips are joined with the
+// concat protocol, so like MkvmergeArgs it ignores IN and OUT times inside a
+// clip. chaptersPath, when not empty, is read as a second input in
+// FFMETADATA format, for example one written by WriteFFMetadataChapters.
+func (mpls *MPLS) FFmpegArgs(bdmvRoot string, selection *TrackSelection, outputPath, chaptersPath string) ([]string, error) {
+	tracks, err := mpls.remuxTracks(selection)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"ffmpeg", "-i", "concat:" + strings.Join(mpls.clipPaths(bdmvRoot), "|")}
+	if chaptersPath != "" {
+		args = append(args, "-i", chaptersPath, "-map_metadata", "1", "-map_chapters", "1")
+	}
+	for _, track := range tracks {
+		args = append(args, "-map", fmt.Sprintf("0:i:0x%x", track.stream.StreamEntry.RefToStreamPID))
+	}
+	args = append(args, "-c", "copy")
+	for i, track := range tracks {
+		if language := track.stream.StreamAttributes.LanguageCode; language != "" {
+			args = append(args, fmt.Sprintf("-metadata:s:%d", i), "language="+language)
+		}
+		disposition := "0"
+		switch {
+		case track.isDefault && track.isForced:
+			disposition = "default+forced"
+		case track.isDefault:
+			disposition = "default"
+		case track.isForced:
+			disposition = "forced"
+		case isVideoCodingType(track.stream.StreamAttributes.StreamCodingType):
+			disposition = "default"
+		}
+		args = append(args, fmt.Sprintf("-disposition:%d", i), disposition)
+	}
+	return append(args, outputPath), nil
+}
+
+// WriteOGMChapters writes the chapters of the playlist in the simple
+// chapter format read by mkvmerge.
+func (mpls *MPLS) WriteOGMChapters(w io.Writer) error {
+	for _, chapter := range mpls.Chapters() {
+		if _, err := fmt.Fprintf(w, "CHAPTER%02d=%s\nCHAPTER%02dNAME=%s\n", chapter.Number, formatTicks(chapter.TimeTicks),
+			chapter.Number, chapter.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFFMetadataChapters writes the chapters of the playlist as an ffmpeg
+// metadata file. Every chapter ends where the next one starts.
+func (mpls *MPLS) WriteFFMetadataChapters(w io.Writer) error {
+	if _, err := io.WriteString(w, ";FFMETADATA1\n"); err != nil {
+		return err
+	}
+	chapters := mpls.Chapters()
+	for k, chapter := range chapters {
+		end := mpls.DurationTicks()
+		if k+1 < len(chapters) {
+			end = chapters[k+1].TimeTicks
+		}
+		if _, err := fmt.Fprintf(w, "\n[CHAPTER]\nTIMEBASE=1/45000\nSTART=%d\nEND=%d\ntitle=%s\n", chapter.TimeTicks, end, chapter.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mpls *MPLS) ExportOGMChapters(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = mpls.WriteOGMChapters(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (mpls *MPLS) ExportFFMetadataChapters(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = mpls.WriteFFMetadataChapters(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
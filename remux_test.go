@@ -0,0 +1,73 @@
+package go_mpls
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemuxArgs(t *testing.T) {
+	stnTable := &STNTable{
+		PrimaryVideoStreamsList: []*Stream{newTestStream(0x1011, HEVCVideo, "")},
+		PrimaryAudioStreamsList: []*Stream{newTestStream(0x1100, DolbyDigitalAudio, "eng"), newTestStream(0x1101, DTSAudio, "fra")},
+		PrimaryPGStreamsList:    []*Stream{newTestStream(0x1200, PresentationGraphics, "eng")},
+	}
+	mpls := &MPLS{
+		PlayList: &PlayList{PlayItemList: []*PlayItem{
+			{ClipInformationFileName: "00001", OUTTimeTicks: 45000 * 60, STNTable: stnTable},
+			{ClipInformationFileName: "00002", OUTTimeTicks: 45000 * 30, STNTable: stnTable},
+		}},
+		PlayListMark: &PlayListMark{PlayListMarksList: []*PlayListMarkItem{
+			{MarkType: EntryMark, RefToPlayItemID: 0},
+			{MarkType: EntryMark, RefToPlayItemID: 1},
+		}},
+	}
+	selection := stnTable.TrackSelection(&StreamSelection{PrimaryAudioStreamNumber: 2, PGStreamNumber: 1})
+	selection.AudioPIDsList = []int{0x1101, 0x1100}
+	stream := filepath.Join("BDMV", "STREAM")
+
+	args, err := mpls.MkvmergeArgs("BDMV", selection, "out.mkv", "chapters.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(args, " ")
+	for _, part := range []string{
+		"--video-tracks 0 --audio-tracks 2,1 --subtitle-tracks 3 --language 2:fra --default-track-flag 2:yes --language 1:eng --default-track-flag 1:no --language 3:eng --default-track-flag 3:no --forced-display-flag 3:yes " + filepath.Join(stream, "00001.m2ts"),
+		"+" + filepath.Join(stream, "00002.m2ts") + " --track-order 0:0,0:2,0:1,0:3 --append-to 1:0:0:0,1:2:0:2,1:1:0:1,1:3:0:3",
+	} {
+		if !strings.Contains(joined, part) {
+			t.Fatalf("missing %q in %q", part, joined)
+		}
+	}
+
+	args, err = mpls.FFmpegArgs("BDMV", selection, "out.mkv", "chapters.ffmeta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined = strings.Join(args, " ")
+	if !strings.HasPrefix(joined, "ffmpeg -i concat:"+filepath.Join(stream, "00001.m2ts")+"|"+filepath.Join(stream, "00002.m2ts")+" -i chapters.ffmeta") ||
+		!strings.Contains(joined, "-map 0:i:0x1011 -map 0:i:0x1101 -map 0:i:0x1100 -map 0:i:0x1200 -c copy -disposition:0 default -metadata:s:1 language=fra -disposition:1 default") ||
+		!strings.HasSuffix(joined, "-metadata:s:3 language=eng -disposition:3 forced out.mkv") {
+		t.Fatalf("unexpected ffmpeg args %q", joined)
+	}
+
+	if _, err := mpls.MkvmergeArgs("BDMV", &TrackSelection{AudioPIDsList: []int{0x1300}}, "out.mkv", ""); err == nil {
+		t.Fatal("missing PID selected")
+	}
+
+	var chapters bytes.Buffer
+	if err := mpls.WriteOGMChapters(&chapters); err != nil {
+		t.Fatal(err)
+	}
+	if chapters.String() != "CHAPTER01=00:00:00.000\nCHAPTER01NAME=Chapter 01\nCHAPTER02=00:01:00.000\nCHAPTER02NAME=Chapter 02\n" {
+		t.Fatalf("unexpected chapters %q", chapters.String())
+	}
+	chapters.Reset()
+	if err := mpls.WriteFFMetadataChapters(&chapters); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(chapters.String(), "[CHAPTER]\nTIMEBASE=1/45000\nSTART=2700000\nEND=4050000\ntitle=Chapter 02\n") {
+		t.Fatalf("unexpected FFMETADATA %q", chapters.String())
+	}
+}
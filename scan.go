@@ -0,0 +1,244 @@
+package go_mpls
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ScanResult is the outcome of parsing one playlist of a library scan. A
+// disc that cannot be read at all gives a single result with an empty
+// PlayList.
+type ScanResult struct {
+	Disc     string        `json:"disc"`
+	PlayList string        `json:"playlist,omitempty"`
+	Info     *PlayListInfo `json:"info,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ScanProgress counts the discs and playlists found so far and the
+// playlists already parsed. The totals grow while the tree is walked.
+type ScanProgress struct {
+	NumberOfDiscs     int
+	NumberOfPlayLists int
+	NumberOfScanned   int
+	NumberOfFailed    int
+}
+
+// Scanner parses every playlist of the discs under a directory: BDMV
+// directories holding a PLAYLIST directory, and ISO images.
+type Scanner struct {
+	// Workers is the number of playlists parsed at once, runtime.NumCPU()
+	// when not positive.
+	Workers int
+	// Progress, when set, is called after every result, from the goroutine
+	// calling Scan.
+	Progress func(progress ScanProgress)
+}
+
+type scanJob struct {
+	disc    string
+	fsys    fs.FS
+	path    string
+	release func()
+}
+
+// scanEvent is either a result or the discovery of a disc and its number
+// of playlists.
+type scanEvent struct {
+	result            *ScanResult
+	numberOfPlayLists int
+}
+
+func (job *scanJob) run() (result *ScanResult) {
+	result = &ScanResult{Disc: job.disc, PlayList: strings.TrimSuffix(path.Base(job.path), path.Ext(job.path))}
+	defer func() {
+		if r := recover(); r != nil {
+			result.Info, result.Error = nil, fmt.Sprintf("invalid file: %v", r)
+		}
+	}()
+	rawData, err := fs.ReadFile(job.fsys, job.path)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	mpls, err := ParseBytes(rawData)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	mpls.FilePath = filepath.Join(job.disc, filepath.FromSlash(job.path))
+	result.Info = mpls.Info()
+	return result
+}
+
+// recoverDisc turns a panic while reading a disc image into an error, so
+// that a corrupt image fails alone rather than the whole scan.
+func recoverDisc(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("invalid disc: %v", r)
+	}
+}
+
+func readDisc(fsys fs.FS, dir string) (entries []fs.DirEntry, err error) {
+	defer recoverDisc(&err)
+	return fs.ReadDir(fsys, dir)
+}
+
+func openDisc(file *os.File) (udf *UDF, err error) {
+	defer recoverDisc(&err)
+	return NewUDF(file)
+}
+
+// scanDisc queues the playlists of a disc. closer, when not nil, is closed
+// once they have all been parsed or dropped.
+func scanDisc(ctx context.Context, disc string, fsys fs.FS, bdmvRoot string, closer io.Closer, jobs chan<- *scanJob, events chan<- *scanEvent) error {
+	entries, err := readDisc(fsys, path.Join(bdmvRoot, "PLAYLIST"))
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		events <- &scanEvent{result: &ScanResult{Disc: disc, Error: err.Error()}}
+		return nil
+	}
+	var pathsList []string = nil
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(path.Ext(entry.Name()), ".mpls") {
+			pathsList = append(pathsList, path.Join(bdmvRoot, "PLAYLIST", entry.Name()))
+		}
+	}
+
+	var pending sync.WaitGroup
+	pending.Add(len(pathsList))
+	if closer != nil {
+		go func() {
+			pending.Wait()
+			closer.Close()
+		}()
+	}
+	events <- &scanEvent{numberOfPlayLists: len(pathsList)}
+	for i, playListPath := range pathsList {
+		select {
+		case jobs <- &scanJob{disc: disc, fsys: fsys, path: playListPath, release: pending.Done}:
+		case <-ctx.Done():
+			for range pathsList[i:] {
+				pending.Done()
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (scanner *Scanner) walk(ctx context.Context, root string, jobs chan<- *scanJob, events chan<- *scanEvent) error {
+	return filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if filePath == root {
+				return err
+			}
+			events <- &scanEvent{result: &ScanResult{Disc: filePath, Error: err.Error()}}
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			if !strings.EqualFold(entry.Name(), "BDMV") {
+				return nil
+			}
+			if info, err := os.Stat(filepath.Join(filePath, "PLAYLIST")); err != nil || !info.IsDir() {
+				return nil
+			}
+			if err := scanDisc(ctx, filePath, os.DirFS(filePath), ".", nil, jobs, events); err != nil {
+				return err
+			}
+			return fs.SkipDir
+		}
+		if !entry.Type().IsRegular() || !strings.EqualFold(filepath.Ext(filePath), ".iso") {
+			return nil
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			events <- &scanEvent{result: &ScanResult{Disc: filePath, Error: err.Error()}}
+			return nil
+		}
+		udf, err := openDisc(file)
+		if err != nil {
+			file.Close()
+			events <- &scanEvent{result: &ScanResult{Disc: filePath, Error: err.Error()}}
+			return nil
+		}
+		return scanDisc(ctx, filePath, udf, "BDMV", file, jobs, events)
+	})
+}
+
+// Scan walks root and calls results with the outcome of every playlist, in
+// the order they are parsed. Discs and playlists that cannot be read are
+// reported as results with an error and do not stop the scan. Scan returns
+// once every queued playlist is done, with ctx.Err() when ctx is cancelled
+// and the error of the walk when root cannot be read.
+func (scanner *Scanner) Scan(ctx context.Context, root string, results func(result *ScanResult)) error {
+	workers := scanner.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan *scanJob)
+	events := make(chan *scanEvent)
+	var workersGroup sync.WaitGroup
+	for range workers {
+		workersGroup.Add(1)
+		go func() {
+			defer workersGroup.Done()
+			for job := range jobs {
+				if ctx.Err() == nil {
+					events <- &scanEvent{result: job.run()}
+				}
+				job.release()
+			}
+		}()
+	}
+
+	walkErr := make(chan error, 1)
+	go func() {
+		walkErr <- scanner.walk(ctx, root, jobs, events)
+		close(jobs)
+		workersGroup.Wait()
+		close(events)
+	}()
+
+	progress := ScanProgress{}
+	for event := range events {
+		if event.result == nil {
+			progress.NumberOfDiscs++
+			progress.NumberOfPlayLists += event.numberOfPlayLists
+			continue
+		}
+		if event.result.PlayList != "" {
+			progress.NumberOfScanned++
+		}
+		if event.result.Error != "" {
+			progress.NumberOfFailed++
+		}
+		if results != nil {
+			results(event.result)
+		}
+		if scanner.Progress != nil {
+			scanner.Progress(progress)
+		}
+	}
+	if err := <-walkErr; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,102 @@
+package go_mpls
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/syxxzzr/go-mpls/internal/fixture"
+)
+
+func TestScanner(t *testing.T) {
+	root := t.TempDir()
+	stnTable := fixture.STNTable(1, fixture.Stream(0x1011, byte(MPEG4AVCVideo), 0x61))
+	rawData := fixture.MPLS("0200", [][]byte{fixture.PlayItem("00001", 1, 0, 45000, stnTable)}, nil, nil)
+
+	playListDir := filepath.Join(root, "a", "Disc 1", "BDMV", "PLAYLIST")
+	if err := os.MkdirAll(playListDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fixture.WriteFile(t, playListDir, "00800.mpls", rawData)
+	fixture.WriteFile(t, playListDir, "00801.mpls", rawData)
+	fixture.WriteFile(t, playListDir, "00802.mpls", []byte("MPLS0200"))
+	if err := os.WriteFile(filepath.Join(root, "a", "Disc 2.iso"), fixture.UDF(map[string][]byte{"00800.mpls": rawData, "00801.mpls": nil}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "broken.iso"), []byte("not a disc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var resultsList []*ScanResult
+	var progressList []ScanProgress
+	scanner := &Scanner{Workers: 2, Progress: func(progress ScanProgress) { progressList = append(progressList, progress) }}
+	if err := scanner.Scan(context.Background(), root, func(result *ScanResult) { resultsList = append(resultsList, result) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(resultsList) != 6 {
+		t.Fatalf("unexpected results %d", len(resultsList))
+	}
+	var okList, failedList []string
+	for _, result := range resultsList {
+		name := filepath.Base(result.Disc) + "/" + result.PlayList
+		if result.Error != "" {
+			failedList = append(failedList, name)
+		} else {
+			okList = append(okList, name)
+		}
+	}
+	slices.Sort(okList)
+	slices.Sort(failedList)
+	if !slices.Equal(okList, []string{"BDMV/00800", "BDMV/00801", "Disc 2.iso/00800"}) {
+		t.Fatalf("unexpected results %v", okList)
+	}
+	if !slices.Equal(failedList, []string{"BDMV/00802", "Disc 2.iso/00801", "broken.iso/"}) {
+		t.Fatalf("unexpected failures %v", failedList)
+	}
+	if last := progressList[len(progressList)-1]; last != (ScanProgress{NumberOfDiscs: 2, NumberOfPlayLists: 5, NumberOfScanned: 5, NumberOfFailed: 3}) {
+		t.Fatalf("unexpected progress %#v", last)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := scanner.Scan(ctx, root, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestScannerCorruptImage(t *testing.T) {
+	root := t.TempDir()
+	stnTable := fixture.STNTable(1, fixture.Stream(0x1011, byte(MPEG4AVCVideo), 0x61))
+	rawData := fixture.MPLS("0200", [][]byte{fixture.PlayItem("00001", 1, 0, 45000, stnTable)}, nil, nil)
+
+	// a playlist larger than its extents and a PLAYLIST directory of a
+	// huge size
+	oversized := fixture.UDF(map[string][]byte{"00800.mpls": rawData, "00801.mpls": nil})
+	binary.LittleEndian.PutUint64(fixture.UDFEntry(oversized, 4)[56:64], uint64(len(rawData)+udfSectorSize))
+	hugeDirectory := fixture.UDF(map[string][]byte{"00800.mpls": rawData, "00801.mpls": nil})
+	binary.LittleEndian.PutUint64(fixture.UDFEntry(hugeDirectory, 3)[56:64], 1<<63)
+	for name, image := range map[string][]byte{"oversized.iso": oversized, "directory.iso": hugeDirectory} {
+		if err := os.WriteFile(filepath.Join(root, name), image, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var failedList []string
+	scanner := &Scanner{Workers: 2}
+	err := scanner.Scan(context.Background(), root, func(result *ScanResult) {
+		if result.Error != "" {
+			failedList = append(failedList, filepath.Base(result.Disc)+"/"+result.PlayList)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(failedList)
+	if !slices.Equal(failedList, []string{"directory.iso/", "oversized.iso/"}) {
+		t.Fatalf("unexpected failures %v", failedList)
+	}
+}
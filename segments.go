@@ -0,0 +1,206 @@
+package go_mpls
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Segment is a range of a clip that every playlist of a SegmentGraph plays
+// either entirely or not at all. Times are 45 kHz ticks.
+type Segment struct {
+	ID            int      `json:"id"`
+	ClipName      string   `json:"clip"`
+	RefToSTCID    int      `json:"stcID"`
+	INTimeTicks   int      `json:"inTime"`
+	OUTTimeTicks  int      `json:"outTime"`
+	PlayListsList []string `json:"playlists"`
+}
+
+// SegmentEdge connects two segments played one after the other.
+type SegmentEdge struct {
+	From          int      `json:"from"`
+	To            int      `json:"to"`
+	PlayListsList []string `json:"playlists"`
+}
+
+// SegmentPath is the order in which a playlist plays the segments.
+type SegmentPath struct {
+	PlayList       string `json:"playlist"`
+	SegmentIDsList []int  `json:"segments"`
+}
+
+// SegmentGraph splits the play items of several playlists at every IN and
+// OUT time used on the same clip, so that shared footage becomes shared
+// segments. Only the clips of angle 1 are considered.
+type SegmentGraph struct {
+	SegmentsList []*Segment     `json:"segments"`
+	EdgesList    []*SegmentEdge `json:"edges"`
+	PathsList    []*SegmentPath `json:"paths"`
+}
+
+type segmentClip struct {
+	name  string
+	stcID int
+}
+
+func playListName(mpls *MPLS, i int) string {
+	if mpls.FilePath == "" {
+		return fmt.Sprintf("playlist %d", i)
+	}
+	return strings.TrimSuffix(filepath.Base(mpls.FilePath), filepath.Ext(mpls.FilePath))
+}
+
+func BuildSegmentGraph(playLists []*MPLS) *SegmentGraph {
+	boundaries := map[segmentClip][]int{}
+	for _, mpls := range playLists {
+		for _, playItem := range mpls.PlayList.PlayItemList {
+			clip := segmentClip{playItem.ClipInformationFileName, playItem.RefToSTCID}
+			boundaries[clip] = append(boundaries[clip], playItem.INTimeTicks, playItem.OUTTimeTicks)
+		}
+	}
+
+	// Every pair of neighbouring boundaries of a clip is a candidate segment;
+	// the ones no playlist plays are dropped below.
+	graph := &SegmentGraph{SegmentsList: []*Segment{}, EdgesList: []*SegmentEdge{}, PathsList: []*SegmentPath{}}
+	clips := make([]segmentClip, 0, len(boundaries))
+	for clip := range boundaries {
+		clips = append(clips, clip)
+	}
+	slices.SortFunc(clips, func(a, b segmentClip) int {
+		return cmp.Or(cmp.Compare(a.name, b.name), cmp.Compare(a.stcID, b.stcID))
+	})
+	var candidatesList []*Segment = nil
+	for _, clip := range clips {
+		times := boundaries[clip]
+		slices.Sort(times)
+		times = slices.Compact(times)
+		for k := 0; k+1 < len(times); k++ {
+			candidatesList = append(candidatesList, &Segment{ClipName: clip.name, RefToSTCID: clip.stcID, INTimeTicks: times[k], OUTTimeTicks: times[k+1]})
+		}
+	}
+
+	edges := map[[2]*Segment]*SegmentEdge{}
+	pathSegments := make([][]*Segment, len(playLists))
+	for i, mpls := range playLists {
+		name := playListName(mpls, i)
+		var segmentsList []*Segment = nil
+		for _, playItem := range mpls.PlayList.PlayItemList {
+			for _, segment := range candidatesList {
+				if segment.ClipName == playItem.ClipInformationFileName && segment.RefToSTCID == playItem.RefToSTCID &&
+					segment.INTimeTicks >= playItem.INTimeTicks && segment.OUTTimeTicks <= playItem.OUTTimeTicks {
+					segmentsList = append(segmentsList, segment)
+				}
+			}
+		}
+		for k, segment := range segmentsList {
+			if !slices.Contains(segment.PlayListsList, name) {
+				segment.PlayListsList = append(segment.PlayListsList, name)
+			}
+			if k == 0 {
+				continue
+			}
+			key := [2]*Segment{segmentsList[k-1], segment}
+			if edges[key] == nil {
+				edges[key] = &SegmentEdge{}
+			}
+			if !slices.Contains(edges[key].PlayListsList, name) {
+				edges[key].PlayListsList = append(edges[key].PlayListsList, name)
+			}
+		}
+		pathSegments[i] = segmentsList
+	}
+
+	for _, segment := range candidatesList {
+		if len(segment.PlayListsList) > 0 {
+			segment.ID = len(graph.SegmentsList)
+			graph.SegmentsList = append(graph.SegmentsList, segment)
+		}
+	}
+	for i, segmentsList := range pathSegments {
+		path := &SegmentPath{PlayList: playListName(playLists[i], i), SegmentIDsList: []int{}}
+		for _, segment := range segmentsList {
+			path.SegmentIDsList = append(path.SegmentIDsList, segment.ID)
+		}
+		graph.PathsList = append(graph.PathsList, path)
+	}
+	for key, edge := range edges {
+		edge.From, edge.To = key[0].ID, key[1].ID
+		graph.EdgesList = append(graph.EdgesList, edge)
+	}
+	slices.SortFunc(graph.EdgesList, func(a, b *SegmentEdge) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To))
+	})
+	return graph
+}
+
+// BuildDiscSegmentGraph builds the segment graph of every playlist of a
+// disc.
+func BuildDiscSegmentGraph(bdmvRoot string) (*SegmentGraph, error) {
+	playListsList, err := ParseDisc(bdmvRoot, 1)
+	if err != nil {
+		return nil, err
+	}
+	return BuildSegmentGraph(playListsList), nil
+}
+
+// BranchPoints returns the segments after which playlists continue with
+// different segments, or end while others continue.
+func (graph *SegmentGraph) BranchPoints() []*Segment {
+	var segmentsList []*Segment = nil
+	for _, segment := range graph.SegmentsList {
+		successors := 0
+		continuing := 0
+		for _, edge := range graph.EdgesList {
+			if edge.From == segment.ID {
+				successors++
+				continuing += len(edge.PlayListsList)
+			}
+		}
+		if successors > 1 || (successors == 1 && continuing < len(segment.PlayListsList)) {
+			segmentsList = append(segmentsList, segment)
+		}
+	}
+	return segmentsList
+}
+
+// IsShared reports whether every playlist of the graph plays the segment.
+func (graph *SegmentGraph) IsShared(segment *Segment) bool {
+	return len(segment.PlayListsList) == len(graph.PathsList)
+}
+
+// WriteDOT writes the graph in Graphviz format. Segments played by only some
+// of the playlists are filled, and edges not taken by every playlist are
+// labelled with the playlists that take them.
+func (graph *SegmentGraph) WriteDOT(w io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString("digraph segments {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, segment := range graph.SegmentsList {
+		fmt.Fprintf(&builder, "\ts%d [label=\"%s\\n%s - %s\\n%s\"", segment.ID, segment.ClipName,
+			formatTicks(segment.INTimeTicks), formatTicks(segment.OUTTimeTicks), strings.Join(segment.PlayListsList, ", "))
+		if !graph.IsShared(segment) {
+			builder.WriteString(", style=filled, fillcolor=lightyellow")
+		}
+		builder.WriteString("];\n")
+	}
+	for _, edge := range graph.EdgesList {
+		fmt.Fprintf(&builder, "\ts%d -> s%d", edge.From, edge.To)
+		if len(edge.PlayListsList) != len(graph.PathsList) {
+			fmt.Fprintf(&builder, " [label=\"%s\"]", strings.Join(edge.PlayListsList, ", "))
+		}
+		builder.WriteString(";\n")
+	}
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func (graph *SegmentGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(graph)
+}
@@ -0,0 +1,58 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuildSegmentGraph(t *testing.T) {
+	theatrical := &MPLS{FilePath: "BDMV/PLAYLIST/00800.mpls", PlayList: &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00001", OUTTimeTicks: 4500},
+		{ClipInformationFileName: "00002", OUTTimeTicks: 2250},
+		{ClipInformationFileName: "00003", OUTTimeTicks: 4500},
+	}}}
+	extended := &MPLS{FilePath: "BDMV/PLAYLIST/00801.mpls", PlayList: &PlayList{PlayItemList: []*PlayItem{
+		{ClipInformationFileName: "00001", OUTTimeTicks: 4500},
+		{ClipInformationFileName: "00002", OUTTimeTicks: 3600},
+		{ClipInformationFileName: "00004", OUTTimeTicks: 900},
+		{ClipInformationFileName: "00003", OUTTimeTicks: 4500},
+	}}}
+
+	graph := BuildSegmentGraph([]*MPLS{theatrical, extended})
+	if len(graph.SegmentsList) != 5 || len(graph.EdgesList) != 5 {
+		t.Fatalf("unexpected graph %d segments, %d edges", len(graph.SegmentsList), len(graph.EdgesList))
+	}
+	if path := graph.PathsList[1]; path.PlayList != "00801" || len(path.SegmentIDsList) != 5 {
+		t.Fatalf("unexpected path %#v", path)
+	}
+	branchPoints := graph.BranchPoints()
+	if len(branchPoints) != 1 || branchPoints[0].ClipName != "00002" || branchPoints[0].OUTTimeTicks != 2250 {
+		t.Fatalf("unexpected branch points %#v", branchPoints)
+	}
+	for _, segment := range graph.SegmentsList {
+		shared := segment.ClipName != "00004" && segment.INTimeTicks != 2250
+		if graph.IsShared(segment) != shared {
+			t.Fatalf("unexpected sharing of %#v", segment)
+		}
+	}
+
+	var dot bytes.Buffer
+	if err := graph.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dot.String(), `s2 [label="00002\n00:00:00.050 - 00:00:00.080\n00801", style=filled`) ||
+		!strings.Contains(dot.String(), `s1 -> s3 [label="00800"];`) {
+		t.Fatalf("unexpected DOT\n%s", dot.String())
+	}
+
+	var data bytes.Buffer
+	if err := graph.WriteJSON(&data); err != nil {
+		t.Fatal(err)
+	}
+	var decoded SegmentGraph
+	if err := json.Unmarshal(data.Bytes(), &decoded); err != nil || len(decoded.SegmentsList) != 5 {
+		t.Fatalf("invalid JSON %s", data.String())
+	}
+}
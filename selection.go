@@ -0,0 +1,154 @@
+package go_mpls
+
+import (
+	"slices"
+	"strings"
+)
+
+// PlayerSettings holds the player status registers that take part in the
+// initial stream selection.
+type PlayerSettings struct {
+	// AudioLanguage, SubtitleLanguage and MenuLanguage are ISO 639-2 codes
+	// as held by PSR16, PSR17 and PSR18. An empty code has no preference.
+	AudioLanguage    string
+	SubtitleLanguage string
+	MenuLanguage     string
+	// AudioCodingTypesList lists the audio coding types the player decodes.
+	// An empty list means every coding type.
+	AudioCodingTypesList []StreamCodingType
+	SurroundCapable      bool
+	TextSubtitleCapable  bool
+	// ForcedSubtitlesOnly keeps subtitles off and selects the PG stream
+	// matching the audio language, so only its forced captions are shown.
+	ForcedSubtitlesOnly bool
+}
+
+// StreamSelection holds the initial stream numbers chosen for a play item.
+// Stream numbers are 1-based indexes into the STN table lists, 0 is none.
+type StreamSelection struct {
+	PrimaryAudioStreamNumber   int
+	PGStreamNumber             int
+	PGDisplayFlag              bool
+	IGStreamNumber             int
+	SelectableAudioStreamsList []int
+	SelectablePGStreamsList    []int
+	SelectableIGStreamsList    []int
+}
+
+func matchLanguage(stream *Stream, language string) bool {
+	return language != "" && strings.EqualFold(stream.StreamAttributes.LanguageCode, language)
+}
+
+func (settings *PlayerSettings) canDecodeAudio(stream *Stream) bool {
+	return len(settings.AudioCodingTypesList) == 0 || slices.Contains(settings.AudioCodingTypesList, stream.StreamAttributes.StreamCodingType)
+}
+
+func (settings *PlayerSettings) canPresentSurround(stream *Stream) bool {
+	return settings.SurroundCapable || (stream.StreamAttributes.AudioFormat != MultiChannel && stream.StreamAttributes.AudioFormat != StereoAndMultiChannel)
+}
+
+func (settings *PlayerSettings) canDecodeSubtitle(stream *Stream) bool {
+	return stream.StreamAttributes.StreamCodingType != TextSubtitle || settings.TextSubtitleCapable
+}
+
+// selectByPriority returns the first selectable stream number that passes
+// the most conditions, trying the condition sets in order.
+func selectByPriority(streams []*Stream, selectable []int, conditions ...func(*Stream) bool) int {
+	for _, condition := range conditions {
+		for _, number := range selectable {
+			if condition(streams[number-1]) {
+				return number
+			}
+		}
+	}
+	if len(selectable) > 0 {
+		return selectable[0]
+	}
+	return 0
+}
+
+func selectableStreams(streams []*Stream, playable func(*Stream) bool) []int {
+	var numbersList []int = nil
+	for i, stream := range streams {
+		if playable(stream) {
+			numbersList = append(numbersList, i+1)
+		}
+	}
+	return numbersList
+}
+
+// SelectStreams predicts the streams a player starts with. Audio streams
+// are ranked as the PSR1 procedure does: decodable, preferred language and
+// fully presentable first, then language only, then presentable only. The
+// PG stream follows PSR2: the preferred subtitle language is shown when
+// found; otherwise a stream is still selected but not displayed.
+func (table *STNTable) SelectStreams(settings *PlayerSettings) *StreamSelection {
+	selection := &StreamSelection{
+		SelectableAudioStreamsList: selectableStreams(table.PrimaryAudioStreamsList, settings.canDecodeAudio),
+		SelectablePGStreamsList:    selectableStreams(table.PrimaryPGStreamsList, settings.canDecodeSubtitle),
+		SelectableIGStreamsList:    selectableStreams(table.PrimaryIGStreamsList, func(*Stream) bool { return true }),
+	}
+
+	selection.PrimaryAudioStreamNumber = selectByPriority(table.PrimaryAudioStreamsList, selection.SelectableAudioStreamsList,
+		func(stream *Stream) bool {
+			return matchLanguage(stream, settings.AudioLanguage) && settings.canPresentSurround(stream)
+		},
+		func(stream *Stream) bool { return matchLanguage(stream, settings.AudioLanguage) },
+		settings.canPresentSurround,
+	)
+
+	audioLanguage := settings.AudioLanguage
+	if selection.PrimaryAudioStreamNumber > 0 {
+		audioLanguage = table.PrimaryAudioStreamsList[selection.PrimaryAudioStreamNumber-1].StreamAttributes.LanguageCode
+	}
+	if settings.ForcedSubtitlesOnly {
+		selection.PGStreamNumber = selectByPriority(table.PrimaryPGStreamsList, selection.SelectablePGStreamsList,
+			func(stream *Stream) bool { return matchLanguage(stream, audioLanguage) })
+	} else {
+		selection.PGStreamNumber = selectByPriority(table.PrimaryPGStreamsList, selection.SelectablePGStreamsList,
+			func(stream *Stream) bool { return matchLanguage(stream, settings.SubtitleLanguage) })
+		selection.PGDisplayFlag = selection.PGStreamNumber > 0 &&
+			matchLanguage(table.PrimaryPGStreamsList[selection.PGStreamNumber-1], settings.SubtitleLanguage)
+	}
+
+	selection.IGStreamNumber = selectByPriority(table.PrimaryIGStreamsList, selection.SelectableIGStreamsList,
+		func(stream *Stream) bool { return matchLanguage(stream, settings.MenuLanguage) })
+	return selection
+}
+
+func streamByNumber(streams []*Stream, number int) *Stream {
+	if number < 1 || number > len(streams) {
+		return nil
+	}
+	return streams[number-1]
+}
+
+func (selection *StreamSelection) PrimaryAudioStream(table *STNTable) *Stream {
+	return streamByNumber(table.PrimaryAudioStreamsList, selection.PrimaryAudioStreamNumber)
+}
+
+func (selection *StreamSelection) PGStream(table *STNTable) *Stream {
+	return streamByNumber(table.PrimaryPGStreamsList, selection.PGStreamNumber)
+}
+
+func (selection *StreamSelection) IGStream(table *STNTable) *Stream {
+	return streamByNumber(table.PrimaryIGStreamsList, selection.IGStreamNumber)
+}
+
+// NextAudioStream returns the stream number the audio change key moves to.
+func (selection *StreamSelection) NextAudioStream() int {
+	return nextStreamNumber(selection.SelectableAudioStreamsList, selection.PrimaryAudioStreamNumber)
+}
+
+// NextPGStream returns the stream number the subtitle change key moves to.
+func (selection *StreamSelection) NextPGStream() int {
+	return nextStreamNumber(selection.SelectablePGStreamsList, selection.PGStreamNumber)
+}
+
+func nextStreamNumber(selectable []int, current int) int {
+	if len(selectable) == 0 {
+		return 0
+	}
+	index := slices.Index(selectable, current)
+	return selectable[(index+1)%len(selectable)]
+}
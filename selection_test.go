@@ -0,0 +1,50 @@
+package go_mpls
+
+import (
+	"testing"
+)
+
+func buildSelectionStream(codingType StreamCodingType, audioFormat AudioFormat, language string) *Stream {
+	return &Stream{
+		StreamEntry:      &StreamEntry{StreamType: 0x01},
+		StreamAttributes: &StreamAttributes{StreamCodingType: codingType, AudioFormat: audioFormat, LanguageCode: language},
+	}
+}
+
+func TestSelectStreams(t *testing.T) {
+	table := &STNTable{
+		PrimaryAudioStreamsList: []*Stream{
+			buildSelectionStream(DolbyDigitalTureHDAudio, MultiChannel, "eng"),
+			buildSelectionStream(DolbyDigitalAudio, MultiChannel, "jpn"),
+			buildSelectionStream(DolbyDigitalAudio, Stereo, "jpn"),
+		},
+		PrimaryPGStreamsList: []*Stream{
+			buildSelectionStream(PresentationGraphics, 0, "eng"),
+			buildSelectionStream(TextSubtitle, 0, "jpn"),
+			buildSelectionStream(PresentationGraphics, 0, "jpn"),
+		},
+		PrimaryIGStreamsList: []*Stream{
+			buildSelectionStream(InteractiveGraphics, 0, "eng"),
+		},
+	}
+
+	selection := table.SelectStreams(&PlayerSettings{
+		AudioLanguage:        "jpn",
+		SubtitleLanguage:     "JPN",
+		AudioCodingTypesList: []StreamCodingType{DolbyDigitalAudio},
+	})
+	if selection.PrimaryAudioStreamNumber != 3 || len(selection.SelectableAudioStreamsList) != 2 {
+		t.Fatalf("unexpected audio selection %#v", selection)
+	}
+	if selection.PGStreamNumber != 3 || !selection.PGDisplayFlag || selection.NextPGStream() != 1 {
+		t.Fatalf("unexpected PG selection %#v", selection)
+	}
+	if selection.IGStreamNumber != 1 || selection.NextAudioStream() != 2 {
+		t.Fatalf("unexpected selection %#v", selection)
+	}
+
+	selection = table.SelectStreams(&PlayerSettings{AudioLanguage: "fra", SurroundCapable: true, ForcedSubtitlesOnly: true})
+	if selection.PrimaryAudioStreamNumber != 1 || selection.PGStreamNumber != 1 || selection.PGDisplayFlag {
+		t.Fatalf("unexpected forced subtitle selection %#v", selection)
+	}
+}
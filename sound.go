@@ -0,0 +1,197 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+)
+
+type BitsPerSample int
+
+const (
+	BPS16 BitsPerSample = iota + 1
+	BPS20
+	BPS24
+)
+
+type SoundData struct {
+	FilePath                  string
+	RawData                   []byte
+	VersionNumber             int
+	SoundDataStartAddress     int
+	ExtensionDataStartAddress int
+	Length                    int
+	NumberOfSoundEntries      int
+	SoundEntriesList          []*SoundEntry
+}
+
+type SoundEntry struct {
+	AudioFormat           AudioFormat
+	SampleRate            SampleRate
+	BitsPerSample         BitsPerSample
+	SoundDataStartAddress int
+	NumberOfFrames        int
+	SoundData             []byte
+}
+
+func (entry *SoundEntry) NumberOfChannels() int {
+	switch entry.AudioFormat {
+	case Mono:
+		return 1
+	case Stereo:
+		return 2
+	}
+	return 0
+}
+
+func (entry *SoundEntry) SamplingFrequency() int {
+	switch entry.SampleRate {
+	case SR48KHz:
+		return 48000
+	case SR96KHz:
+		return 96000
+	case SR192KHz:
+		return 192000
+	}
+	return 0
+}
+
+func (entry *SoundEntry) BitsPerSampleValue() int {
+	switch entry.BitsPerSample {
+	case BPS16:
+		return 16
+	case BPS20:
+		return 20
+	case BPS24:
+		return 24
+	}
+	return 0
+}
+
+func (entry *SoundEntry) Duration() float32 {
+	sampleRate := entry.SamplingFrequency()
+	if sampleRate == 0 {
+		return 0
+	}
+	return float32(entry.NumberOfFrames) / float32(sampleRate)
+}
+
+// WriteWAV writes the entry as a RIFF/WAVE file. Sound data is stored big-endian
+// on the disc, so samples are byte-swapped to the little-endian order WAV expects.
+func (entry *SoundEntry) WriteWAV(w io.Writer) error {
+	channels := entry.NumberOfChannels()
+	sampleRate := entry.SamplingFrequency()
+	bits := entry.BitsPerSampleValue()
+	if channels == 0 || sampleRate == 0 || bits == 0 {
+		return errors.New("unsupported sound attributes")
+	}
+
+	containerBits := (bits + 7) / 8 * 8
+	blockAlign := channels * containerBits / 8
+	dataSize := len(entry.SoundData) - len(entry.SoundData)%blockAlign
+
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:36], uint16(containerBits))
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	sampleBytes := containerBits / 8
+	samples := make([]byte, dataSize)
+	for i := 0; i < dataSize; i += sampleBytes {
+		for j := 0; j < sampleBytes; j++ {
+			samples[i+j] = entry.SoundData[i+sampleBytes-1-j]
+		}
+	}
+	_, err := w.Write(samples)
+	return err
+}
+
+func (entry *SoundEntry) ExportWAV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = entry.WriteWAV(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func parseSoundEntry(rawData []byte) *SoundEntry {
+	return &SoundEntry{
+		AudioFormat:           AudioFormat((rawData[0] & 0b11110000) >> 4),
+		SampleRate:            SampleRate(rawData[0] & 0b00001111),
+		BitsPerSample:         BitsPerSample((rawData[1] & 0b11000000) >> 6),
+		SoundDataStartAddress: int(binary.BigEndian.Uint32(rawData[2:6])),
+		NumberOfFrames:        int(binary.BigEndian.Uint32(rawData[6:10])),
+	}
+}
+
+func ParseSound(path string) (*SoundData, error) {
+	rawData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rawData) < 0x2e || !bytes.Equal(rawData[:4], []byte("BCLK")) {
+		return nil, errors.New("invalid file")
+	}
+
+	versionNumber, err := strconv.Atoi(string(rawData[0x04:0x08]))
+	if err != nil {
+		return nil, err
+	}
+
+	soundDataStartAddress := int(binary.BigEndian.Uint32(rawData[0x08:0x0c]))
+	extensionDataStartAddress := int(binary.BigEndian.Uint32(rawData[0x0c:0x10]))
+	length := int(binary.BigEndian.Uint32(rawData[0x28:0x2c]))
+	numberOfSoundEntries := int(rawData[0x2d])
+
+	if len(rawData) < 0x2e+10*numberOfSoundEntries {
+		return nil, errors.New("invalid file")
+	}
+
+	var soundEntriesList []*SoundEntry = nil
+	for i := 0; i < numberOfSoundEntries; i++ {
+		soundEntry := parseSoundEntry(rawData[0x2e+10*i:])
+
+		bits := soundEntry.BitsPerSampleValue()
+		size := soundEntry.NumberOfFrames * soundEntry.NumberOfChannels() * ((bits + 7) / 8)
+		start := soundDataStartAddress + soundEntry.SoundDataStartAddress
+		if start+size > len(rawData) {
+			return nil, errors.New("sound data out of range")
+		}
+		soundEntry.SoundData = rawData[start : start+size]
+
+		soundEntriesList = append(soundEntriesList, soundEntry)
+	}
+
+	return &SoundData{
+		FilePath:                  path,
+		RawData:                   rawData,
+		VersionNumber:             versionNumber,
+		SoundDataStartAddress:     soundDataStartAddress,
+		ExtensionDataStartAddress: extensionDataStartAddress,
+		Length:                    length,
+		NumberOfSoundEntries:      numberOfSoundEntries,
+		SoundEntriesList:          soundEntriesList,
+	}, nil
+}
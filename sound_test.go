@@ -0,0 +1,56 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func buildSound(samples []byte) []byte {
+	rawData := make([]byte, 0x38)
+	copy(rawData[0:8], "BCLK0200")
+	binary.BigEndian.PutUint32(rawData[0x08:0x0c], 0x38)
+	binary.BigEndian.PutUint32(rawData[0x28:0x2c], 12)
+	rawData[0x2d] = 1
+	rawData[0x2e] = 0x31
+	rawData[0x2f] = 0x40
+	binary.BigEndian.PutUint32(rawData[0x30:0x34], 0)
+	binary.BigEndian.PutUint32(rawData[0x34:0x38], uint32(len(samples)/4))
+	return append(rawData, samples...)
+}
+
+func TestParseSound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sound.bdmv")
+	if err := os.WriteFile(path, buildSound([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sound, err := ParseSound(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sound.VersionNumber != 200 || sound.NumberOfSoundEntries != 1 {
+		t.Fatalf("unexpected header %#v", sound)
+	}
+
+	entry := sound.SoundEntriesList[0]
+	if entry.NumberOfChannels() != 2 || entry.SamplingFrequency() != 48000 || entry.BitsPerSampleValue() != 16 {
+		t.Fatalf("unexpected attributes %#v", entry)
+	}
+	if entry.NumberOfFrames != 2 || len(entry.SoundData) != 8 {
+		t.Fatalf("unexpected sound data %#v", entry)
+	}
+
+	var wav bytes.Buffer
+	if err := entry.WriteWAV(&wav); err != nil {
+		t.Fatal(err)
+	}
+	if wav.Len() != 44+8 || string(wav.Bytes()[0:4]) != "RIFF" || string(wav.Bytes()[8:12]) != "WAVE" {
+		t.Fatalf("unexpected wav header % x", wav.Bytes()[:12])
+	}
+	if !bytes.Equal(wav.Bytes()[44:], []byte{0x02, 0x01, 0x04, 0x03, 0x06, 0x05, 0x08, 0x07}) {
+		t.Fatalf("samples not swapped: % x", wav.Bytes()[44:])
+	}
+}
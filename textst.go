@@ -0,0 +1,613 @@
+package go_mpls
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+type TextRegionStyle struct {
+	RegionStyleID            int
+	RegionX                  int
+	RegionY                  int
+	RegionWidth              int
+	RegionHeight             int
+	BackgroundPaletteEntryID int
+	TextBoxX                 int
+	TextBoxY                 int
+	TextBoxWidth             int
+	TextBoxHeight            int
+	TextFlow                 int
+	HorizontalAlignment      int
+	VerticalAlignment        int
+	LineSpace                int
+	FontID                   int
+	FontStyle                int
+	FontSize                 int
+	FontColor                int
+	OutlineColor             int
+	OutlineThickness         int
+}
+
+type TextUserStyle struct {
+	UserStyleID        int
+	RegionXDelta       int
+	RegionYDelta       int
+	FontSizeDelta      int
+	TextBoxXDelta      int
+	TextBoxYDelta      int
+	TextBoxWidthDelta  int
+	TextBoxHeightDelta int
+	LineSpaceDelta     int
+}
+
+type DialogStyle struct {
+	PlayerStyleFlag                    bool
+	NumberOfRegionStyles               int
+	NumberOfUserStyles                 int
+	RegionStylesList                   []*TextRegionStyle
+	UserStylesList                     []*TextUserStyle
+	Palette                            map[int]*PaletteEntry
+	NumberOfDialogPresentationSegments int
+}
+
+type DialogTextSpan struct {
+	Text      string
+	IsNewLine bool
+	Bold      bool
+	Italic    bool
+	FontSize  int
+	FontColor int
+}
+
+type DialogRegion struct {
+	ContinuousPresentFlag bool
+	ForcedOnFlag          bool
+	RegionStyleID         int
+	SpansList             []*DialogTextSpan
+}
+
+type DialogPresentation struct {
+	StartPTS          int64
+	EndPTS            int64
+	PaletteUpdateFlag bool
+	Palette           map[int]*PaletteEntry
+	NumberOfRegions   int
+	RegionsList       []*DialogRegion
+}
+
+type TextSubtitleStream struct {
+	DialogStyle             *DialogStyle
+	DialogPresentationsList []*DialogPresentation
+}
+
+const (
+	textStringData       = 0x01
+	changeFontSetData    = 0x02
+	changeFontStyleData  = 0x03
+	changeFontSizeData   = 0x04
+	changeFontColorData  = 0x05
+	lineBreakData        = 0x0a
+	endOfInlineStyleData = 0x0b
+	textRegionStyleSize  = 29
+	textUserStyleSize    = 15
+	fontStyleBold        = 1 << 0
+	fontStyleItalic      = 1 << 1
+)
+
+// characterEncoding returns the decoder of a TextST character code. GB2312
+// text is stored as EUC-CN, which x/text has no decoder for; GBK keeps the
+// EUC-CN byte layout for the whole GB2312 range, so it decodes the same text.
+func characterEncoding(characterCode CharacterCode) encoding.Encoding {
+	switch characterCode {
+	case UTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	case ShiftJIS:
+		return japanese.ShiftJIS
+	case KSC5601:
+		return korean.EUCKR
+	case GB18030:
+		return simplifiedchinese.GB18030
+	case GB2312:
+		return simplifiedchinese.GBK
+	case BIG5:
+		return traditionalchinese.Big5
+	}
+	return encoding.Nop
+}
+
+func signedDelta(value int, bits int) int {
+	if value&(1<<(bits-1)) != 0 {
+		return -(value & (1<<(bits-1) - 1))
+	}
+	return value
+}
+
+func parseTextPalette(rawData []byte) (map[int]*PaletteEntry, int) {
+	if len(rawData) < 2 {
+		return nil, len(rawData)
+	}
+	length := int(binary.BigEndian.Uint16(rawData[0:2]))
+	end := min(2+length, len(rawData))
+
+	palette := map[int]*PaletteEntry{}
+	for offset := 2; offset+5 <= end; offset += 5 {
+		palette[int(rawData[offset])] = &PaletteEntry{
+			Y:     int(rawData[offset+1]),
+			Cr:    int(rawData[offset+2]),
+			Cb:    int(rawData[offset+3]),
+			Alpha: int(rawData[offset+4]),
+		}
+	}
+	return palette, end
+}
+
+func parseDialogStyle(rawData []byte) (*DialogStyle, error) {
+	if len(rawData) < 4 {
+		return nil, errors.New("dialog style segment too short")
+	}
+
+	numberOfRegionStyles := int(rawData[2])
+	numberOfUserStyles := int(rawData[3])
+	offset := 4
+	if offset+numberOfRegionStyles*textRegionStyleSize+numberOfUserStyles*textUserStyleSize > len(rawData) {
+		return nil, errors.New("dialog style segment too short")
+	}
+
+	var regionStylesList []*TextRegionStyle = nil
+	for i := 0; i < numberOfRegionStyles; i++ {
+		data := rawData[offset : offset+textRegionStyleSize]
+		regionStylesList = append(regionStylesList, &TextRegionStyle{
+			RegionStyleID:            int(data[0]),
+			RegionX:                  int(binary.BigEndian.Uint16(data[1:3])),
+			RegionY:                  int(binary.BigEndian.Uint16(data[3:5])),
+			RegionWidth:              int(binary.BigEndian.Uint16(data[5:7])),
+			RegionHeight:             int(binary.BigEndian.Uint16(data[7:9])),
+			BackgroundPaletteEntryID: int(data[9]),
+			TextBoxX:                 int(binary.BigEndian.Uint16(data[11:13])),
+			TextBoxY:                 int(binary.BigEndian.Uint16(data[13:15])),
+			TextBoxWidth:             int(binary.BigEndian.Uint16(data[15:17])),
+			TextBoxHeight:            int(binary.BigEndian.Uint16(data[17:19])),
+			TextFlow:                 int(data[19]),
+			HorizontalAlignment:      int(data[20]),
+			VerticalAlignment:        int(data[21]),
+			LineSpace:                int(data[22]),
+			FontID:                   int(data[23]),
+			FontStyle:                int(data[24]),
+			FontSize:                 int(data[25]),
+			FontColor:                int(data[26]),
+			OutlineColor:             int(data[27]),
+			OutlineThickness:         int(data[28]),
+		})
+		offset += textRegionStyleSize
+	}
+
+	var userStylesList []*TextUserStyle = nil
+	for i := 0; i < numberOfUserStyles; i++ {
+		data := rawData[offset : offset+textUserStyleSize]
+		userStylesList = append(userStylesList, &TextUserStyle{
+			UserStyleID:        int(data[0]),
+			RegionXDelta:       signedDelta(int(binary.BigEndian.Uint16(data[1:3])), 16),
+			RegionYDelta:       signedDelta(int(binary.BigEndian.Uint16(data[3:5])), 16),
+			FontSizeDelta:      signedDelta(int(data[5]), 8),
+			TextBoxXDelta:      signedDelta(int(binary.BigEndian.Uint16(data[6:8])), 16),
+			TextBoxYDelta:      signedDelta(int(binary.BigEndian.Uint16(data[8:10])), 16),
+			TextBoxWidthDelta:  signedDelta(int(binary.BigEndian.Uint16(data[10:12])), 16),
+			TextBoxHeightDelta: signedDelta(int(binary.BigEndian.Uint16(data[12:14])), 16),
+			LineSpaceDelta:     signedDelta(int(data[14]), 8),
+		})
+		offset += textUserStyleSize
+	}
+
+	palette, end := parseTextPalette(rawData[offset:])
+	offset += end
+	numberOfDialogPresentationSegments := 0
+	if offset+2 <= len(rawData) {
+		numberOfDialogPresentationSegments = int(binary.BigEndian.Uint16(rawData[offset : offset+2]))
+	}
+
+	return &DialogStyle{
+		PlayerStyleFlag:                    (rawData[0] & (1 << 7)) != 0,
+		NumberOfRegionStyles:               numberOfRegionStyles,
+		NumberOfUserStyles:                 numberOfUserStyles,
+		RegionStylesList:                   regionStylesList,
+		UserStylesList:                     userStylesList,
+		Palette:                            palette,
+		NumberOfDialogPresentationSegments: numberOfDialogPresentationSegments,
+	}, nil
+}
+
+func parseDialogRegionData(rawData []byte, decoder *encoding.Decoder) ([]*DialogTextSpan, error) {
+	var spansList []*DialogTextSpan = nil
+	bold, italic := false, false
+	fontSize, fontColor := -1, -1
+
+	for offset := 0; offset+3 <= len(rawData); {
+		if rawData[offset] != 0x1b {
+			offset++
+			continue
+		}
+		dataType := rawData[offset+1]
+		dataLength := int(rawData[offset+2])
+		if offset+3+dataLength > len(rawData) {
+			break
+		}
+		data := rawData[offset+3 : offset+3+dataLength]
+		offset += 3 + dataLength
+
+		switch dataType {
+		case textStringData:
+			text, err := decoder.Bytes(data)
+			if err != nil {
+				return nil, err
+			}
+			spansList = append(spansList, &DialogTextSpan{
+				Text:      string(text),
+				Bold:      bold,
+				Italic:    italic,
+				FontSize:  fontSize,
+				FontColor: fontColor,
+			})
+		case changeFontStyleData:
+			if len(data) >= 1 {
+				bold = data[0]&fontStyleBold != 0
+				italic = data[0]&fontStyleItalic != 0
+			}
+		case changeFontSizeData:
+			if len(data) >= 1 {
+				fontSize = int(data[0])
+			}
+		case changeFontColorData:
+			if len(data) >= 1 {
+				fontColor = int(data[0])
+			}
+		case lineBreakData:
+			spansList = append(spansList, &DialogTextSpan{IsNewLine: true})
+		case endOfInlineStyleData:
+			bold, italic = false, false
+			fontSize, fontColor = -1, -1
+		}
+	}
+	return spansList, nil
+}
+
+func parseDialogPresentation(rawData []byte, characterCode CharacterCode) (*DialogPresentation, error) {
+	if len(rawData) < 12 {
+		return nil, errors.New("dialog presentation segment too short")
+	}
+
+	presentation := &DialogPresentation{
+		StartPTS:          int64(rawData[0]&0x01)<<32 | int64(binary.BigEndian.Uint32(rawData[1:5])),
+		EndPTS:            int64(rawData[5]&0x01)<<32 | int64(binary.BigEndian.Uint32(rawData[6:10])),
+		PaletteUpdateFlag: (rawData[10] & (1 << 7)) != 0,
+	}
+	offset := 11
+	if presentation.PaletteUpdateFlag {
+		palette, end := parseTextPalette(rawData[offset:])
+		presentation.Palette = palette
+		offset += end
+	}
+	if offset >= len(rawData) {
+		return nil, errors.New("dialog presentation segment too short")
+	}
+	presentation.NumberOfRegions = int(rawData[offset])
+	offset++
+
+	decoder := characterEncoding(characterCode).NewDecoder()
+	for i := 0; i < presentation.NumberOfRegions; i++ {
+		if offset+4 > len(rawData) {
+			return nil, errors.New("dialog presentation segment too short")
+		}
+		dataLength := int(binary.BigEndian.Uint16(rawData[offset+2 : offset+4]))
+		if offset+4+dataLength > len(rawData) {
+			return nil, errors.New("dialog presentation segment too short")
+		}
+		spansList, err := parseDialogRegionData(rawData[offset+4:offset+4+dataLength], decoder)
+		if err != nil {
+			return nil, err
+		}
+		presentation.RegionsList = append(presentation.RegionsList, &DialogRegion{
+			ContinuousPresentFlag: (rawData[offset] & (1 << 7)) != 0,
+			ForcedOnFlag:          (rawData[offset] & (1 << 6)) != 0,
+			RegionStyleID:         int(rawData[offset+1]),
+			SpansList:             spansList,
+		})
+		offset += 4 + dataLength
+	}
+
+	return presentation, nil
+}
+
+// DecodeTextSubtitle converts the dialog segments of a text subtitle stream,
+// decoding strings from the character code declared in the STN table to UTF-8.
+func DecodeTextSubtitle(segments []*GraphicsSegment, characterCode CharacterCode) (*TextSubtitleStream, error) {
+	subtitle := &TextSubtitleStream{}
+	for _, segment := range segments {
+		switch segment.SegmentType {
+		case DialogStyleSegment:
+			dialogStyle, err := parseDialogStyle(segment.Data)
+			if err != nil {
+				return nil, err
+			}
+			subtitle.DialogStyle = dialogStyle
+		case DialogPresentationSegment:
+			presentation, err := parseDialogPresentation(segment.Data, characterCode)
+			if err != nil {
+				return nil, err
+			}
+			subtitle.DialogPresentationsList = append(subtitle.DialogPresentationsList, presentation)
+		}
+	}
+	return subtitle, nil
+}
+
+// playListTime converts a 45 kHz presentation time of the given play item to
+// 90 kHz playlist time.
+func (mpls *MPLS) playListTime(playItemID int, pts int) int64 {
+	offset := int64(0)
+	for i, playItem := range mpls.PlayList.PlayItemList {
+		if i == playItemID {
+			return offset + int64(pts-playItem.INTimeTicks)*2
+		}
+		offset += int64(playItem.OUTTimeTicks-playItem.INTimeTicks) * 2
+	}
+	return offset
+}
+
+func (subPlayItem *SubPlayItem) ClipName(subClipID int) string {
+	if subClipID > 0 && subClipID <= len(subPlayItem.MultiClipEntriesList) {
+		return subPlayItem.MultiClipEntriesList[subClipID-1].ClipInformationFileName
+	}
+	return subPlayItem.ClipInformationFileName
+}
+
+// ExtractTextSubtitle reads the text subtitle clip of an out-of-mux sub path
+// stream and moves its dialogs to playlist time.
+func (mpls *MPLS) ExtractTextSubtitle(bdmvRoot string, stream *Stream) (*TextSubtitleStream, error) {
+	if stream.StreamEntry.StreamType != 0x02 || stream.StreamEntry.RefToSubPathID >= len(mpls.PlayList.SubPathsList) {
+		return nil, errors.New("stream is not carried by a sub path")
+	}
+	subPath := mpls.PlayList.SubPathsList[stream.StreamEntry.RefToSubPathID]
+
+	subtitle := &TextSubtitleStream{}
+	for _, subPlayItem := range subPath.SubPlayItemsList {
+		path := filepath.Join(bdmvRoot, "STREAM", subPlayItem.ClipName(stream.StreamEntry.RefToSubClipID)+".m2ts")
+		segments, err := readGraphicsStream(path, stream.StreamEntry.RefToStreamPID, 0, 1<<33, 0)
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := DecodeTextSubtitle(segments, stream.StreamAttributes.CharacterCode)
+		if err != nil {
+			return nil, err
+		}
+
+		inTime := int64(subPlayItem.INTimeTicks) * 2
+		outTime := int64(subPlayItem.OUTTimeTicks) * 2
+		offset := mpls.playListTime(subPlayItem.SyncPlayItemID, subPlayItem.SyncStartPTS)
+		if subtitle.DialogStyle == nil {
+			subtitle.DialogStyle = decoded.DialogStyle
+		}
+		for _, presentation := range decoded.DialogPresentationsList {
+			if presentation.EndPTS <= inTime || presentation.StartPTS >= outTime {
+				continue
+			}
+			presentation.StartPTS = max(presentation.StartPTS, inTime) - inTime + offset
+			presentation.EndPTS = min(presentation.EndPTS, outTime) - inTime + offset
+			subtitle.DialogPresentationsList = append(subtitle.DialogPresentationsList, presentation)
+		}
+	}
+	return subtitle, nil
+}
+
+func (subtitle *TextSubtitleStream) regionStyle(regionStyleID int) *TextRegionStyle {
+	if subtitle.DialogStyle == nil {
+		return nil
+	}
+	for _, regionStyle := range subtitle.DialogStyle.RegionStylesList {
+		if regionStyle.RegionStyleID == regionStyleID {
+			return regionStyle
+		}
+	}
+	return nil
+}
+
+func formatSRTTime(pts int64) string {
+	milliseconds := pts / 90
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+func formatASSTime(pts int64) string {
+	centiseconds := pts / 900
+	return fmt.Sprintf("%d:%02d:%02d.%02d", centiseconds/360000, centiseconds/6000%60, centiseconds/100%60, centiseconds%100)
+}
+
+// srtEscaper keeps dialog text from being read as SRT markup.
+var srtEscaper = strings.NewReplacer("<", "&lt;", ">", "&gt;")
+
+// assEscaper keeps dialog text from being read as ASS override blocks or
+// escapes, the way libass and ffmpeg expect literal characters.
+var assEscaper = strings.NewReplacer(`\`, `\\`, "{", `\{`, "}", `\}`, "\n", `\N`)
+
+func WriteSRT(w io.Writer, subtitle *TextSubtitleStream) error {
+	writer := bufio.NewWriter(w)
+	index := 0
+	for _, presentation := range subtitle.DialogPresentationsList {
+		var lines []string
+		for _, region := range presentation.RegionsList {
+			var text strings.Builder
+			for _, span := range region.SpansList {
+				switch {
+				case span.IsNewLine:
+					text.WriteString("\n")
+				case span.Bold && span.Italic:
+					text.WriteString("<b><i>" + srtEscaper.Replace(span.Text) + "</i></b>")
+				case span.Bold:
+					text.WriteString("<b>" + srtEscaper.Replace(span.Text) + "</b>")
+				case span.Italic:
+					text.WriteString("<i>" + srtEscaper.Replace(span.Text) + "</i>")
+				default:
+					text.WriteString(srtEscaper.Replace(span.Text))
+				}
+			}
+			if text.Len() > 0 {
+				lines = append(lines, text.String())
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		index++
+		fmt.Fprintf(writer, "%d\n%s --> %s\n%s\n\n", index, formatSRTTime(presentation.StartPTS), formatSRTTime(presentation.EndPTS), strings.Join(lines, "\n"))
+	}
+	return writer.Flush()
+}
+
+func assColor(palette map[int]*PaletteEntry, id int) string {
+	entry, ok := palette[id]
+	if !ok {
+		return "&H00FFFFFF"
+	}
+	c := entry.RGBA(true)
+	return fmt.Sprintf("&H%02X%02X%02X%02X", 255-c.A, c.B, c.G, c.R)
+}
+
+// assAlignment maps the TextST alignments (1 left/top, 2 center, 3 right/bottom)
+// to the numpad layout used by ASS.
+func assAlignment(horizontal, vertical int) int {
+	column := min(max(horizontal, 1), 3)
+	switch vertical {
+	case 1:
+		return 6 + column
+	case 2:
+		return 3 + column
+	}
+	return column
+}
+
+func assPosition(style *TextRegionStyle) (int, int) {
+	x := style.RegionX + style.TextBoxX
+	y := style.RegionY + style.TextBoxY
+	switch style.HorizontalAlignment {
+	case 2:
+		x += style.TextBoxWidth / 2
+	case 3:
+		x += style.TextBoxWidth
+	}
+	switch style.VerticalAlignment {
+	case 2:
+		y += style.TextBoxHeight / 2
+	case 3:
+		y += style.TextBoxHeight
+	}
+	return x, y
+}
+
+// WriteASS writes one ASS style per TextST region style and positions every
+// dialog region at the text box of its region style on a canvas of the given
+// video size.
+func WriteASS(w io.Writer, subtitle *TextSubtitleStream, width, height int) error {
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "[Script Info]\nScriptType: v4.00+\nPlayResX: %d\nPlayResY: %d\nWrapStyle: 2\n\n", width, height)
+	fmt.Fprintf(writer, "[V4+ Styles]\nFormat: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, "+
+		"Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, "+
+		"MarginL, MarginR, MarginV, Encoding\n")
+
+	palette := map[int]*PaletteEntry{}
+	if subtitle.DialogStyle != nil {
+		palette = subtitle.DialogStyle.Palette
+		for _, style := range subtitle.DialogStyle.RegionStylesList {
+			bold, italic := 0, 0
+			if style.FontStyle&fontStyleBold != 0 {
+				bold = -1
+			}
+			if style.FontStyle&fontStyleItalic != 0 {
+				italic = -1
+			}
+			fmt.Fprintf(writer, "Style: Region%d,Arial,%d,%s,%s,%s,%s,%d,%d,0,0,100,100,0,0,1,%d,0,%d,0,0,0,1\n",
+				style.RegionStyleID, style.FontSize, assColor(palette, style.FontColor), assColor(palette, style.FontColor),
+				assColor(palette, style.OutlineColor), assColor(palette, style.BackgroundPaletteEntryID),
+				bold, italic, style.OutlineThickness, assAlignment(style.HorizontalAlignment, style.VerticalAlignment))
+		}
+	} else {
+		fmt.Fprintf(writer, "Style: Default,Arial,48,&H00FFFFFF,&H00FFFFFF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,2,0,2,0,0,40,1\n")
+	}
+	fmt.Fprintf(writer, "\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+
+	for _, presentation := range subtitle.DialogPresentationsList {
+		if presentation.Palette != nil {
+			palette = presentation.Palette
+		}
+		for _, region := range presentation.RegionsList {
+			var text strings.Builder
+			styleName := "Default"
+			if style := subtitle.regionStyle(region.RegionStyleID); style != nil {
+				styleName = fmt.Sprintf("Region%d", style.RegionStyleID)
+				x, y := assPosition(style)
+				fmt.Fprintf(&text, "{\\an%d\\pos(%d,%d)}", assAlignment(style.HorizontalAlignment, style.VerticalAlignment), x, y)
+			}
+			for _, span := range region.SpansList {
+				if span.IsNewLine {
+					text.WriteString("\\N")
+					continue
+				}
+				var tags []string
+				if span.Bold {
+					tags = append(tags, "\\b1")
+				}
+				if span.Italic {
+					tags = append(tags, "\\i1")
+				}
+				if span.FontSize >= 0 {
+					tags = append(tags, fmt.Sprintf("\\fs%d", span.FontSize))
+				}
+				if span.FontColor >= 0 {
+					tags = append(tags, "\\1c&H"+assColor(palette, span.FontColor)[4:]+"&")
+				}
+				if len(tags) > 0 {
+					text.WriteString("{" + strings.Join(tags, "") + "}" + assEscaper.Replace(span.Text) + "{\\r}")
+				} else {
+					text.WriteString(assEscaper.Replace(span.Text))
+				}
+			}
+			fmt.Fprintf(writer, "Dialogue: 0,%s,%s,%s,,0,0,0,,%s\n", formatASSTime(presentation.StartPTS), formatASSTime(presentation.EndPTS), styleName, text.String())
+		}
+	}
+	return writer.Flush()
+}
+
+func ExportSRT(subtitle *TextSubtitleStream, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteSRT(file, subtitle)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func ExportASS(subtitle *TextSubtitleStream, path string, width, height int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = WriteASS(file, subtitle, width, height)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
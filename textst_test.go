@@ -0,0 +1,110 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func buildDialogStyle() []byte {
+	data := []byte{0x00, 0x00, 0x01, 0x00}
+	region := make([]byte, textRegionStyleSize)
+	binary.BigEndian.PutUint16(region[1:3], 0)
+	binary.BigEndian.PutUint16(region[3:5], 880)
+	binary.BigEndian.PutUint16(region[5:7], 1920)
+	binary.BigEndian.PutUint16(region[7:9], 200)
+	binary.BigEndian.PutUint16(region[11:13], 160)
+	binary.BigEndian.PutUint16(region[13:15], 20)
+	binary.BigEndian.PutUint16(region[15:17], 1600)
+	binary.BigEndian.PutUint16(region[17:19], 160)
+	region[20] = 2
+	region[21] = 3
+	region[25] = 56
+	region[26] = 1
+	data = append(data, region...)
+	data = append(data, 0x00, 0x05, 0x01, 0xeb, 0x80, 0x80, 0xff)
+	return append(data, 0x00, 0x01)
+}
+
+func buildDialogPresentation(start, end int64, text []byte) []byte {
+	data := []byte{byte(start >> 32), 0, 0, 0, 0, byte(end >> 32), 0, 0, 0, 0, 0x00, 0x01}
+	binary.BigEndian.PutUint32(data[1:5], uint32(start))
+	binary.BigEndian.PutUint32(data[6:10], uint32(end))
+
+	var region []byte
+	region = append(region, 0x1b, changeFontStyleData, 0x03, fontStyleItalic, 0x00, 0x00)
+	region = append(region, 0x1b, textStringData, byte(len(text)))
+	region = append(region, text...)
+	region = append(region, 0x1b, endOfInlineStyleData, 0x00)
+	region = append(region, 0x1b, lineBreakData, 0x00)
+	region = append(region, 0x1b, textStringData, 0x02, 'O', 'K')
+
+	data = append(data, 0x40, 0x00, 0x00, 0x00)
+	binary.BigEndian.PutUint16(data[len(data)-2:], uint16(len(region)))
+	return append(data, region...)
+}
+
+func TestDecodeTextSubtitle(t *testing.T) {
+	segments := []*GraphicsSegment{
+		{SegmentType: DialogStyleSegment, Data: buildDialogStyle()},
+		{SegmentType: DialogPresentationSegment, Data: buildDialogPresentation(90000, 270045, []byte{0x82, 0xb1, 0x82, 0xf1})},
+	}
+
+	subtitle, err := DecodeTextSubtitle(segments, ShiftJIS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subtitle.DialogStyle == nil || len(subtitle.DialogStyle.RegionStylesList) != 1 || subtitle.DialogStyle.NumberOfDialogPresentationSegments != 1 {
+		t.Fatalf("unexpected dialog style %#v", subtitle.DialogStyle)
+	}
+	region := subtitle.DialogPresentationsList[0].RegionsList[0]
+	if !region.ForcedOnFlag || len(region.SpansList) != 3 || region.SpansList[0].Text != "こん" || !region.SpansList[0].Italic {
+		t.Fatalf("unexpected region %#v", region)
+	}
+
+	var srt bytes.Buffer
+	if err := WriteSRT(&srt, subtitle); err != nil {
+		t.Fatal(err)
+	}
+	if srt.String() != "1\n00:00:01,000 --> 00:00:03,000\n<i>こん</i>\nOK\n\n" {
+		t.Fatalf("unexpected SRT %q", srt.String())
+	}
+
+	var ass bytes.Buffer
+	if err := WriteASS(&ass, subtitle, 1920, 1080); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ass.String(), "Style: Region0,Arial,56,&H00FFFFFF") ||
+		!strings.Contains(ass.String(), "Dialogue: 0,0:00:01.00,0:00:03.00,Region0,,0,0,0,,{\\an2\\pos(960,1060)}{\\i1}こん{\\r}\\NOK") {
+		t.Fatalf("unexpected ASS %s", ass.String())
+	}
+}
+
+func TestWriteTextSubtitleEscaping(t *testing.T) {
+	text := append([]byte(`{\b1}<u>`), 0xd6, 0xd0)
+	segments := []*GraphicsSegment{
+		{SegmentType: DialogStyleSegment, Data: buildDialogStyle()},
+		{SegmentType: DialogPresentationSegment, Data: buildDialogPresentation(90000, 270045, text)},
+	}
+	subtitle, err := DecodeTextSubtitle(segments, GB2312)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srt bytes.Buffer
+	if err := WriteSRT(&srt, subtitle); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(srt.String(), "<i>{\\b1}&lt;u&gt;中</i>\nOK\n") {
+		t.Fatalf("unexpected SRT %q", srt.String())
+	}
+
+	var ass bytes.Buffer
+	if err := WriteASS(&ass, subtitle, 1920, 1080); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ass.String(), "{\\i1}\\{\\\\b1\\}<u>中{\\r}\\NOK\n") {
+		t.Fatalf("unexpected ASS %s", ass.String())
+	}
+}
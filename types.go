@@ -51,13 +51,15 @@ const (
 	VF2160P
 )
 
+// Frame rates of video stream attributes. 0x05 is reserved, so FR50FPS and
+// FR59D94FPS are 0x06 and 0x07.
 const (
 	FR23D98FPS FrameRate = iota + 1
 	FR24FPS
 	FR25FPS
 	FR29D97FPS
-	FR50FPS
-	FR59D94FPS
+	FR50FPS    FrameRate = 0x06
+	FR59D94FPS FrameRate = 0x07
 )
 
 const (
@@ -143,10 +145,13 @@ type PlayListMark struct {
 	PlayListMarksList     []*PlayListMarkItem
 }
 
+// PlayListMarkItem is a mark of the playlist. MarkTimeTicks is the mark time
+// in 45 kHz ticks as stored, MarkTimeStamp the same time in seconds.
 type PlayListMarkItem struct {
 	MarkType        int
 	RefToPlayItemID int
 	MarkTimeStamp   float32
+	MarkTimeTicks   int
 	EntryESPID      int
 	Duration        int
 }
@@ -261,6 +266,11 @@ type PlayList struct {
 	RefToStreamPID    int
 }
 
+// PlayItem is a clip, or a range of it, played by the playlist. StillTime
+// is the duration in seconds of a finite still (StillMode 0x01), as stored
+// in the play item, not in 45 kHz ticks. For a multi-angle play item,
+// NumberOfAngles counts the clip of the play item itself as angle 1, and
+// AnglesList holds angles 2 to NumberOfAngles, one entry fewer.
 type PlayItem struct {
 	Length                   int
 	ClipInformationFileName  string
@@ -270,6 +280,8 @@ type PlayItem struct {
 	RefToSTCID               int
 	INTime                   float32
 	OUTTime                  float32
+	INTimeTicks              int
+	OUTTimeTicks             int
 	UserOperationMaskTable   *UOMaskTable
 	PlayItemRandomAccessFlag bool
 	StillMode                int
@@ -287,6 +299,10 @@ type MultiClipEntry struct {
 	RefToSTCID              int
 }
 
+// SubPlayItem is a clip played along the play items by a sub path. With
+// IsMultiClipEntries, NumberOfMultiClipEntries counts the clip of the sub
+// play item itself as sub clip 0, and MultiClipEntriesList holds the other
+// ones, one entry fewer.
 type SubPlayItem struct {
 	Length                   int
 	ClipInformationFileName  string
@@ -296,6 +312,8 @@ type SubPlayItem struct {
 	RefToSTCID               int
 	INTime                   float32
 	OUTTime                  float32
+	INTimeTicks              int
+	OUTTimeTicks             int
 	SyncPlayItemID           int
 	SyncStartPTS             int
 	NumberOfMultiClipEntries int
@@ -309,3 +327,105 @@ type SubPath struct {
 	NumberOfSubPlayItems int
 	SubPlayItemsList     []*SubPlayItem
 }
+
+func (videoFormat VideoFormat) String() string {
+	switch videoFormat {
+	case VF480I:
+		return "480i"
+	case VF576I:
+		return "576i"
+	case VF480P:
+		return "480p"
+	case VF1080I:
+		return "1080i"
+	case VF720P:
+		return "720p"
+	case VF1080P:
+		return "1080p"
+	case VF576P:
+		return "576p"
+	case VF2160P:
+		return "2160p"
+	}
+	return "unknown"
+}
+
+func (dynamicRangeType DynamicRangeType) String() string {
+	switch dynamicRangeType {
+	case SDR:
+		return "SDR"
+	case HDR10:
+		return "HDR10"
+	case DolbyVision:
+		return "Dolby Vision"
+	}
+	return "unknown"
+}
+
+func (colorSpace ColorSpace) String() string {
+	switch colorSpace {
+	case BT709:
+		return "BT.709"
+	case BT2020:
+		return "BT.2020"
+	}
+	return "reserved"
+}
+
+func (streamCodingType StreamCodingType) String() string {
+	switch streamCodingType {
+	case MPEG1Video:
+		return "MPEG-1 Video"
+	case MPEG2Video:
+		return "MPEG-2 Video"
+	case MPEG4AVCVideo:
+		return "AVC"
+	case MPEG4MVCVideo:
+		return "MVC"
+	case SMTPEVC1Video:
+		return "VC-1"
+	case HEVCVideo:
+		return "HEVC"
+	case MPEG1Audio:
+		return "MPEG-1 Audio"
+	case MPEG2Audio:
+		return "MPEG-2 Audio"
+	case LPCMAudio:
+		return "LPCM"
+	case DolbyDigitalAudio:
+		return "Dolby Digital"
+	case DTSAudio:
+		return "DTS"
+	case DolbyDigitalTureHDAudio:
+		return "Dolby TrueHD"
+	case DolbyDigitalPlusAudioPri, DolbyDigitalPlusAudioSec:
+		return "Dolby Digital Plus"
+	case DTSHDHighResolutionAudio:
+		return "DTS-HD High Resolution Audio"
+	case DTSHDMasterAudio:
+		return "DTS-HD Master Audio"
+	case DTSHDAudio:
+		return "DTS Express"
+	case PresentationGraphics:
+		return "Presentation Graphics"
+	case InteractiveGraphics:
+		return "Interactive Graphics"
+	case TextSubtitle:
+		return "Text Subtitle"
+	}
+	return "unknown"
+}
+
+func (audioFormat AudioFormat) String() string {
+	switch audioFormat {
+	case Mono:
+		return "mono"
+	case Stereo:
+		return "stereo"
+	case MultiChannel:
+		return "multi-channel"
+	case StereoAndMultiChannel:
+		return "stereo and multi-channel"
+	}
+	return "unknown"
+}
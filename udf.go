@@ -0,0 +1,515 @@
+package go_mpls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const udfSectorSize = 2048
+
+// udfMaxDirectorySize bounds the file identifiers of a directory read at
+// once. Directories of a disc take a few sectors.
+const udfMaxDirectorySize = 16 << 20
+
+const (
+	udfAnchorVolumeDescriptorPointer = 2
+	udfPartitionDescriptor           = 5
+	udfLogicalVolumeDescriptor       = 6
+	udfTerminatingDescriptor         = 8
+	udfFileSetDescriptor             = 256
+	udfFileIdentifierDescriptor      = 257
+	udfFileEntry                     = 261
+	udfExtendedFileEntry             = 266
+)
+
+// udfExtent is a run of bytes starting at a block of a partition. Unrecorded
+// extents read as zeros.
+type udfExtent struct {
+	partitionRef int
+	block        uint32
+	length       int64
+	isRecorded   bool
+}
+
+// udfPartition is a partition map of the logical volume. Metadata
+// partitions, used by every BD-ROM, hold their blocks in the extents of the
+// metadata file.
+type udfPartition struct {
+	start           int64
+	metadataList    []*udfExtent
+	isMetadata      bool
+	partitionNumber int
+}
+
+type udfNode struct {
+	name        string
+	isDir       bool
+	size        int64
+	modTime     time.Time
+	extentsList []*udfExtent
+	embedded    []byte
+}
+
+// UDF reads the file system of a Blu-ray disc image, so that playlists can
+// be parsed without mounting the image. It implements fs.FS with the paths
+// of the disc, like BDMV/PLAYLIST/00800.mpls. Only plain and metadata
+// partitions are supported, which covers BD-ROM images.
+type UDF struct {
+	r              io.ReaderAt
+	partitionsList []*udfPartition
+	root           *udfNode
+}
+
+func udfLongAD(rawData []byte) *udfExtent {
+	length := binary.LittleEndian.Uint32(rawData[0:4])
+	return &udfExtent{
+		partitionRef: int(binary.LittleEndian.Uint16(rawData[8:10])),
+		block:        binary.LittleEndian.Uint32(rawData[4:8]),
+		length:       int64(length & 0x3fffffff),
+		isRecorded:   length>>30 == 0,
+	}
+}
+
+func udfTimestamp(rawData []byte) time.Time {
+	typeAndTimezone := binary.LittleEndian.Uint16(rawData[0:2])
+	location := time.UTC
+	// The offset is a signed 12 bit number of minutes, -2047 meaning none.
+	if offset := int(int16(typeAndTimezone<<4) >> 4); typeAndTimezone>>12 == 1 && offset != -2047 {
+		location = time.FixedZone("", offset*60)
+	}
+	microseconds := int(rawData[9])*10000 + int(rawData[10])*100 + int(rawData[11])
+	return time.Date(int(binary.LittleEndian.Uint16(rawData[2:4])), time.Month(rawData[4]), int(rawData[5]),
+		int(rawData[6]), int(rawData[7]), int(rawData[8]), microseconds*1000, location)
+}
+
+// udfName decodes an OSTA compressed unicode file identifier.
+func udfName(rawData []byte) string {
+	if len(rawData) == 0 {
+		return ""
+	}
+	if rawData[0] == 16 {
+		units := make([]uint16, (len(rawData)-1)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(rawData[1+2*i:])
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(rawData)-1)
+	for i, b := range rawData[1:] {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func (udf *UDF) readSector(sector int64) ([]byte, error) {
+	rawData := make([]byte, udfSectorSize)
+	if _, err := udf.r.ReadAt(rawData, sector*udfSectorSize); err != nil {
+		return nil, err
+	}
+	return rawData, nil
+}
+
+// resolve maps a byte position of a partition to an offset of the image,
+// along with the number of bytes stored contiguously from there.
+func (udf *UDF) resolve(partitionRef int, position int64) (int64, int64, error) {
+	if partitionRef >= len(udf.partitionsList) {
+		return 0, 0, fmt.Errorf("udf: partition reference %d out of range", partitionRef)
+	}
+	partition := udf.partitionsList[partitionRef]
+	if !partition.isMetadata {
+		return partition.start*udfSectorSize + position, math.MaxInt64, nil
+	}
+	for _, extent := range partition.metadataList {
+		if position < extent.length {
+			if extent.partitionRef >= len(udf.partitionsList) || udf.partitionsList[extent.partitionRef].isMetadata {
+				return 0, 0, errors.New("udf: metadata extent outside of a physical partition")
+			}
+			offset, contiguous, err := udf.resolve(extent.partitionRef, int64(extent.block)*udfSectorSize+position)
+			return offset, min(contiguous, extent.length-position), err
+		}
+		position -= extent.length
+	}
+	return 0, 0, errors.New("udf: position beyond the metadata partition")
+}
+
+func (udf *UDF) readBlock(partitionRef int, block uint32) ([]byte, error) {
+	offset, _, err := udf.resolve(partitionRef, int64(block)*udfSectorSize)
+	if err != nil {
+		return nil, err
+	}
+	rawData := make([]byte, udfSectorSize)
+	if _, err := udf.r.ReadAt(rawData, offset); err != nil {
+		return nil, err
+	}
+	return rawData, nil
+}
+
+func (udf *UDF) readFileEntry(icb *udfExtent) (*udfNode, error) {
+	rawData, err := udf.readBlock(icb.partitionRef, icb.block)
+	if err != nil {
+		return nil, err
+	}
+	var lengthOfExtendedAttributes, lengthOfAllocationDescriptors, allocationDescriptorsStart, modTimeStart int
+	switch binary.LittleEndian.Uint16(rawData[0:2]) {
+	case udfFileEntry:
+		modTimeStart = 84
+		lengthOfExtendedAttributes = int(binary.LittleEndian.Uint32(rawData[168:172]))
+		lengthOfAllocationDescriptors = int(binary.LittleEndian.Uint32(rawData[172:176]))
+		allocationDescriptorsStart = 176
+	case udfExtendedFileEntry:
+		modTimeStart = 92
+		lengthOfExtendedAttributes = int(binary.LittleEndian.Uint32(rawData[208:212]))
+		lengthOfAllocationDescriptors = int(binary.LittleEndian.Uint32(rawData[212:216]))
+		allocationDescriptorsStart = 216
+	default:
+		return nil, fmt.Errorf("udf: no file entry at block %d", icb.block)
+	}
+	allocationDescriptorsStart += lengthOfExtendedAttributes
+	if allocationDescriptorsStart+lengthOfAllocationDescriptors > len(rawData) {
+		return nil, fmt.Errorf("udf: invalid file entry at block %d", icb.block)
+	}
+	allocationDescriptors := rawData[allocationDescriptorsStart : allocationDescriptorsStart+lengthOfAllocationDescriptors]
+
+	node := &udfNode{
+		isDir:   rawData[27] == 4,
+		size:    int64(binary.LittleEndian.Uint64(rawData[56:64])),
+		modTime: udfTimestamp(rawData[modTimeStart : modTimeStart+12]),
+	}
+	adType := binary.LittleEndian.Uint16(rawData[34:36]) & 0b111
+	for i := 0; adType <= 1 && i+8 <= len(allocationDescriptors); i += 8 << adType {
+		if binary.LittleEndian.Uint32(allocationDescriptors[i:i+4])>>30 == 3 {
+			return nil, fmt.Errorf("udf: continued allocation descriptors at block %d are not supported", icb.block)
+		}
+	}
+	switch adType {
+	case 0:
+		for i := 0; i+8 <= len(allocationDescriptors); i += 8 {
+			length := binary.LittleEndian.Uint32(allocationDescriptors[i : i+4])
+			node.extentsList = append(node.extentsList, &udfExtent{
+				partitionRef: icb.partitionRef,
+				block:        binary.LittleEndian.Uint32(allocationDescriptors[i+4 : i+8]),
+				length:       int64(length & 0x3fffffff),
+				isRecorded:   length>>30 == 0,
+			})
+		}
+	case 1:
+		for i := 0; i+16 <= len(allocationDescriptors); i += 16 {
+			node.extentsList = append(node.extentsList, udfLongAD(allocationDescriptors[i:i+16]))
+		}
+	case 3:
+		node.embedded = allocationDescriptors
+	default:
+		return nil, fmt.Errorf("udf: unsupported allocation descriptors at block %d", icb.block)
+	}
+	recorded := int64(len(node.embedded))
+	for _, extent := range node.extentsList {
+		recorded += extent.length
+	}
+	if node.size < 0 || node.size > recorded {
+		return nil, fmt.Errorf("udf: file entry at block %d larger than its extents", icb.block)
+	}
+	return node, nil
+}
+
+func (udf *UDF) readAt(node *udfNode, p []byte, off int64) (int, error) {
+	if off >= node.size {
+		return 0, io.EOF
+	}
+	p = p[:min(int64(len(p)), node.size-off)]
+	if node.embedded != nil {
+		return copy(p, node.embedded[min(off, int64(len(node.embedded))):]), nil
+	}
+
+	n := 0
+	extentStart := int64(0)
+	for _, extent := range node.extentsList {
+		for n < len(p) && off+int64(n) < extentStart+extent.length {
+			within := off + int64(n) - extentStart
+			chunk := min(int64(len(p)-n), extent.length-within)
+			if !extent.isRecorded {
+				clear(p[n : n+int(chunk)])
+				n += int(chunk)
+				continue
+			}
+			offset, contiguous, err := udf.resolve(extent.partitionRef, int64(extent.block)*udfSectorSize+within)
+			if err != nil {
+				return n, err
+			}
+			read, err := udf.r.ReadAt(p[n:n+int(min(chunk, contiguous))], offset)
+			n += read
+			if err != nil {
+				return n, err
+			}
+		}
+		extentStart += extent.length
+		if n == len(p) {
+			break
+		}
+	}
+	if n < len(p) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+func (udf *UDF) readDir(node *udfNode) ([]*udfNode, error) {
+	if node.size > udfMaxDirectorySize {
+		return nil, fmt.Errorf("udf: directory %q too large", node.name)
+	}
+	rawData := make([]byte, node.size)
+	if _, err := udf.readAt(node, rawData, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	var childrenList []*udfNode = nil
+	for offset := 0; offset+38 <= len(rawData); {
+		if binary.LittleEndian.Uint16(rawData[offset:offset+2]) != udfFileIdentifierDescriptor {
+			return nil, errors.New("udf: invalid file identifier descriptor")
+		}
+		characteristics := rawData[offset+18]
+		lengthOfFileIdentifier := int(rawData[offset+19])
+		lengthOfImplementationUse := int(binary.LittleEndian.Uint16(rawData[offset+36 : offset+38]))
+		nameStart := offset + 38 + lengthOfImplementationUse
+		if nameStart+lengthOfFileIdentifier > len(rawData) {
+			return nil, errors.New("udf: invalid file identifier descriptor")
+		}
+		// Skip deleted entries and the parent directory.
+		if characteristics&0b1100 == 0 {
+			child, err := udf.readFileEntry(udfLongAD(rawData[offset+20 : offset+36]))
+			if err != nil {
+				return nil, err
+			}
+			child.name = udfName(rawData[nameStart : nameStart+lengthOfFileIdentifier])
+			childrenList = append(childrenList, child)
+		}
+		offset = (nameStart + lengthOfFileIdentifier + 3) &^ 3
+	}
+	return childrenList, nil
+}
+
+// NewUDF reads the volume and file set descriptors of a disc image.
+func NewUDF(r io.ReaderAt) (*UDF, error) {
+	udf := &UDF{r: r}
+	anchor, err := udf.readSector(256)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint16(anchor[0:2]) != udfAnchorVolumeDescriptorPointer {
+		return nil, errors.New("udf: no anchor volume descriptor pointer")
+	}
+	sequenceLength := int64(binary.LittleEndian.Uint32(anchor[16:20]))
+	sequenceLocation := int64(binary.LittleEndian.Uint32(anchor[20:24]))
+
+	partitionStarts := map[int]int64{}
+	var logicalVolumeDescriptor []byte = nil
+	for sector := sequenceLocation; sector < sequenceLocation+sequenceLength/udfSectorSize; sector++ {
+		rawData, err := udf.readSector(sector)
+		if err != nil {
+			return nil, err
+		}
+		tagIdentifier := binary.LittleEndian.Uint16(rawData[0:2])
+		if tagIdentifier == udfTerminatingDescriptor {
+			break
+		}
+		switch tagIdentifier {
+		case udfPartitionDescriptor:
+			partitionStarts[int(binary.LittleEndian.Uint16(rawData[22:24]))] = int64(binary.LittleEndian.Uint32(rawData[188:192]))
+		case udfLogicalVolumeDescriptor:
+			logicalVolumeDescriptor = rawData
+		}
+	}
+	if logicalVolumeDescriptor == nil {
+		return nil, errors.New("udf: no logical volume descriptor")
+	}
+	if blockSize := binary.LittleEndian.Uint32(logicalVolumeDescriptor[212:216]); blockSize != udfSectorSize {
+		return nil, fmt.Errorf("udf: unsupported block size %d", blockSize)
+	}
+
+	mapTableLength := int(binary.LittleEndian.Uint32(logicalVolumeDescriptor[264:268]))
+	numberOfPartitionMaps := int(binary.LittleEndian.Uint32(logicalVolumeDescriptor[268:272]))
+	partitionMaps := logicalVolumeDescriptor[440:min(440+mapTableLength, udfSectorSize)]
+	metadataFiles := map[*udfPartition]uint32{}
+	for i, offset := 0, 0; i < numberOfPartitionMaps && offset+2 <= len(partitionMaps); i++ {
+		mapType, mapLength := partitionMaps[offset], int(partitionMaps[offset+1])
+		if mapLength < 6 || offset+mapLength > len(partitionMaps) {
+			return nil, errors.New("udf: invalid partition map")
+		}
+		partitionMap := partitionMaps[offset : offset+mapLength]
+		switch {
+		case mapType == 1:
+			partitionNumber := int(binary.LittleEndian.Uint16(partitionMap[4:6]))
+			udf.partitionsList = append(udf.partitionsList, &udfPartition{start: partitionStarts[partitionNumber], partitionNumber: partitionNumber})
+		case mapType == 2 && mapLength >= 44 && strings.HasPrefix(string(partitionMap[5:28]), "*UDF Metadata Partition"):
+			partition := &udfPartition{isMetadata: true, partitionNumber: int(binary.LittleEndian.Uint16(partitionMap[38:40]))}
+			metadataFiles[partition] = binary.LittleEndian.Uint32(partitionMap[40:44])
+			udf.partitionsList = append(udf.partitionsList, partition)
+		default:
+			return nil, fmt.Errorf("udf: unsupported partition map %q", strings.TrimRight(string(partitionMap[min(5, mapLength):min(28, mapLength)]), "\x00"))
+		}
+		offset += mapLength
+	}
+
+	for partition, metadataFile := range metadataFiles {
+		physicalRef := slices.IndexFunc(udf.partitionsList, func(p *udfPartition) bool {
+			return !p.isMetadata && p.partitionNumber == partition.partitionNumber
+		})
+		if physicalRef < 0 {
+			return nil, fmt.Errorf("udf: no partition %d for the metadata partition", partition.partitionNumber)
+		}
+		node, err := udf.readFileEntry(&udfExtent{partitionRef: physicalRef, block: metadataFile})
+		if err != nil {
+			return nil, err
+		}
+		partition.metadataList = node.extentsList
+	}
+
+	fileSetDescriptorLocation := udfLongAD(logicalVolumeDescriptor[248:264])
+	fileSetDescriptor, err := udf.readBlock(fileSetDescriptorLocation.partitionRef, fileSetDescriptorLocation.block)
+	if err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint16(fileSetDescriptor[0:2]) != udfFileSetDescriptor {
+		return nil, errors.New("udf: no file set descriptor")
+	}
+	if udf.root, err = udf.readFileEntry(udfLongAD(fileSetDescriptor[400:416])); err != nil {
+		return nil, err
+	}
+	udf.root.name = "."
+	return udf, nil
+}
+
+func (udf *UDF) lookup(name string) (*udfNode, error) {
+	node := udf.root
+	if name == "." {
+		return node, nil
+	}
+	for _, element := range strings.Split(name, "/") {
+		if !node.isDir {
+			return nil, fs.ErrNotExist
+		}
+		childrenList, err := udf.readDir(node)
+		if err != nil {
+			return nil, err
+		}
+		index := slices.IndexFunc(childrenList, func(child *udfNode) bool { return child.name == element })
+		if index < 0 {
+			return nil, fs.ErrNotExist
+		}
+		node = childrenList[index]
+	}
+	return node, nil
+}
+
+func (udf *UDF) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	node, err := udf.lookup(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &udfFile{udf: udf, node: node}, nil
+}
+
+type udfFileInfo struct {
+	node *udfNode
+}
+
+func (info *udfFileInfo) Name() string       { return info.node.name }
+func (info *udfFileInfo) Size() int64        { return info.node.size }
+func (info *udfFileInfo) ModTime() time.Time { return info.node.modTime }
+func (info *udfFileInfo) IsDir() bool        { return info.node.isDir }
+func (info *udfFileInfo) Sys() any           { return nil }
+
+func (info *udfFileInfo) Mode() fs.FileMode {
+	if info.node.isDir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// udfFile is an open file or directory of a UDF. Files also implement
+// io.ReaderAt and io.Seeker.
+type udfFile struct {
+	udf          *UDF
+	node         *udfNode
+	offset       int64
+	childrenList []*udfNode
+	isListed     bool
+}
+
+func (file *udfFile) Stat() (fs.FileInfo, error) {
+	return &udfFileInfo{node: file.node}, nil
+}
+
+func (file *udfFile) Read(p []byte) (int, error) {
+	n, err := file.ReadAt(p, file.offset)
+	file.offset += int64(n)
+	// A short read is reported by the next call, which reads nothing.
+	if n > 0 && (errors.Is(err, io.ErrUnexpectedEOF) || err == io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+func (file *udfFile) ReadAt(p []byte, off int64) (int, error) {
+	if file.node.isDir {
+		return 0, &fs.PathError{Op: "read", Path: file.node.name, Err: errors.New("is a directory")}
+	}
+	n, err := file.udf.readAt(file.node, p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (file *udfFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += file.offset
+	case io.SeekEnd:
+		offset += file.node.size
+	}
+	if offset < 0 {
+		return 0, errors.New("udf: negative position")
+	}
+	file.offset = offset
+	return offset, nil
+}
+
+func (file *udfFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !file.node.isDir {
+		return nil, &fs.PathError{Op: "readdir", Path: file.node.name, Err: errors.New("not a directory")}
+	}
+	if !file.isListed {
+		childrenList, err := file.udf.readDir(file.node)
+		if err != nil {
+			return nil, err
+		}
+		file.childrenList, file.isListed = childrenList, true
+	}
+	count := len(file.childrenList)
+	if n > 0 {
+		if count == 0 {
+			return nil, io.EOF
+		}
+		count = min(n, count)
+	}
+	entriesList := make([]fs.DirEntry, 0, count)
+	for _, child := range file.childrenList[:count] {
+		entriesList = append(entriesList, fs.FileInfoToDirEntry(&udfFileInfo{node: child}))
+	}
+	file.childrenList = file.childrenList[count:]
+	return entriesList, nil
+}
+
+func (file *udfFile) Close() error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/syxxzzr/go-mpls/internal/fixture"
+)
+
+func TestUDF(t *testing.T) {
+	content := make([]byte, udfSectorSize+100)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	udf, err := NewUDF(bytes.NewReader(fixture.UDF(map[string][]byte{"00800.mpls": content, "00801.mpls": nil})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(udf, "BDMV/PLAYLIST/00800.mpls", "BDMV/PLAYLIST/00801.mpls"); err != nil {
+		t.Fatal(err)
+	}
+
+	rawData, err := fs.ReadFile(udf, "BDMV/PLAYLIST/00800.mpls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rawData, content) {
+		t.Fatal("unexpected content")
+	}
+	file, err := udf.Open("BDMV/PLAYLIST/00800.mpls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	part := make([]byte, 20)
+	if _, err := file.(io.ReaderAt).ReadAt(part, udfSectorSize-10); err != nil || !bytes.Equal(part, content[udfSectorSize-10:udfSectorSize+10]) {
+		t.Fatalf("unexpected read across extents %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ModTime().Year() != 2024 || info.ModTime().Day() != 17 {
+		t.Fatalf("unexpected modification time %v", info.ModTime())
+	}
+	if _, err := udf.Open("BDMV/STREAM"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestUDFCorrupt(t *testing.T) {
+	content := make([]byte, udfSectorSize+100)
+
+	// 00800.mpls larger than its extents
+	rawData := fixture.UDF(map[string][]byte{"00800.mpls": content, "00801.mpls": nil})
+	binary.LittleEndian.PutUint64(fixture.UDFEntry(rawData, 4)[56:64], uint64(len(content)+udfSectorSize))
+	udf, err := NewUDF(bytes.NewReader(rawData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile(udf, "BDMV/PLAYLIST/00800.mpls"); err == nil {
+		t.Fatal("no error for a file larger than its extents")
+	}
+	file := &udfFile{udf: udf, node: &udfNode{size: 10}}
+	if _, err := io.ReadAll(file); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// PLAYLIST with a negative and a huge size
+	for _, size := range []uint64{1 << 63, 1 << 40} {
+		rawData = fixture.UDF(map[string][]byte{"00800.mpls": content, "00801.mpls": nil})
+		binary.LittleEndian.PutUint64(fixture.UDFEntry(rawData, 3)[56:64], size)
+		if udf, err = NewUDF(bytes.NewReader(rawData)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.ReadDir(udf, "BDMV/PLAYLIST"); err == nil {
+			t.Fatalf("no error for a directory of size %d", size)
+		}
+	}
+
+	// the metadata file mapped onto the metadata partition itself
+	rawData = fixture.UDF(map[string][]byte{"00800.mpls": content, "00801.mpls": nil})
+	metadataExtent := make([]byte, 16)
+	fixture.PutUDFLongAD(metadataExtent, 8*udfSectorSize, 0, 1)
+	fixture.PutUDFFileEntry(fixture.UDFMetadataFileEntry(rawData), 250, 8*udfSectorSize, 1, metadataExtent)
+	if _, err := NewUDF(bytes.NewReader(rawData)); err == nil {
+		t.Fatal("no error for a recursive metadata partition")
+	}
+}
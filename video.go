@@ -0,0 +1,919 @@
+package go_mpls
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+type MasteringDisplayInfo struct {
+	DisplayPrimaries [3][2]int
+	WhitePoint       [2]int
+	MaxLuminance     float64
+	MinLuminance     float64
+}
+
+type DolbyVisionInfo struct {
+	Profile    int
+	ELType     string
+	BLBitDepth int
+	ELBitDepth int
+}
+
+type VideoStreamInfo struct {
+	PID                     int
+	StreamCodingType        StreamCodingType
+	Codec                   string
+	Profile                 string
+	Level                   string
+	Tier                    string
+	Width                   int
+	Height                  int
+	BitDepth                int
+	ChromaFormat            string
+	FrameRate               float64
+	ColourPrimaries         int
+	TransferCharacteristics int
+	MatrixCoefficients      int
+	MasteringDisplay        *MasteringDisplayInfo
+	MaxCLL                  int
+	MaxFALL                 int
+	HasHDR10Plus            bool
+	DolbyVision             *DolbyVisionInfo
+}
+
+type VideoStreamMismatch struct {
+	Stream   *Stream
+	PID      int
+	Field    string
+	Expected string
+	Actual   string
+}
+
+type VideoStreamAnalysis struct {
+	PlayItem        *PlayItem
+	Stream          *Stream
+	VideoStreamInfo *VideoStreamInfo
+	MismatchesList  []*VideoStreamMismatch
+}
+
+const (
+	transferPQ    = 16
+	primaries2020 = 9
+)
+
+func (info *VideoStreamInfo) DynamicRangeType() DynamicRangeType {
+	if info.DolbyVision != nil {
+		return DolbyVision
+	}
+	if info.TransferCharacteristics == transferPQ {
+		return HDR10
+	}
+	return SDR
+}
+
+func (info *VideoStreamInfo) ColorSpace() ColorSpace {
+	switch info.ColourPrimaries {
+	case 1:
+		return BT709
+	case primaries2020:
+		return BT2020
+	}
+	return Reserved
+}
+
+var chromaFormats = []string{"4:0:0", "4:2:0", "4:2:2", "4:4:4"}
+
+func splitNALUnits(payload []byte) [][]byte {
+	var nalUnits [][]byte
+	start := -1
+	for i := 0; i+3 <= len(payload); i++ {
+		if payload[i] != 0x00 || payload[i+1] != 0x00 || payload[i+2] != 0x01 {
+			continue
+		}
+		if start >= 0 {
+			nalUnits = append(nalUnits, bytes.TrimRight(payload[start:i], "\x00"))
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 && start < len(payload) {
+		nalUnits = append(nalUnits, payload[start:])
+	}
+	return nalUnits
+}
+
+func removeEmulationPrevention(nalUnit []byte) []byte {
+	if !bytes.Contains(nalUnit, []byte{0x00, 0x00, 0x03}) {
+		return nalUnit
+	}
+
+	rbsp := make([]byte, 0, len(nalUnit))
+	zeros := 0
+	for _, b := range nalUnit {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
+
+func parseHEVCProfileTierLevel(reader *bitReader, info *VideoStreamInfo, maxSubLayersMinus1 int) {
+	reader.skipBits(2)
+	tier := reader.readInt(1)
+	profile := reader.readInt(5)
+	reader.skipBits(32 + 4 + 43 + 1)
+	level := reader.readInt(8)
+
+	info.Profile = map[int]string{1: "Main", 2: "Main 10", 3: "Main Still Picture", 4: "Format Range Extensions"}[profile]
+	if info.Profile == "" {
+		info.Profile = fmt.Sprintf("Profile %d", profile)
+	}
+	info.Tier = []string{"Main", "High"}[tier]
+	if level%30 == 0 {
+		info.Level = fmt.Sprintf("%d", level/30)
+	} else {
+		info.Level = fmt.Sprintf("%d.%d", level/30, level%30/3)
+	}
+
+	subLayerProfilePresent := make([]bool, maxSubLayersMinus1)
+	subLayerLevelPresent := make([]bool, maxSubLayersMinus1)
+	for i := 0; i < maxSubLayersMinus1; i++ {
+		subLayerProfilePresent[i] = reader.readFlag()
+		subLayerLevelPresent[i] = reader.readFlag()
+	}
+	if maxSubLayersMinus1 > 0 {
+		reader.skipBits(2 * (8 - maxSubLayersMinus1))
+	}
+	for i := 0; i < maxSubLayersMinus1; i++ {
+		if subLayerProfilePresent[i] {
+			reader.skipBits(88)
+		}
+		if subLayerLevelPresent[i] {
+			reader.skipBits(8)
+		}
+	}
+}
+
+func skipHEVCScalingListData(reader *bitReader) {
+	for sizeID := 0; sizeID < 4; sizeID++ {
+		step := 1
+		if sizeID == 3 {
+			step = 3
+		}
+		for matrixID := 0; matrixID < 6; matrixID += step {
+			if !reader.readFlag() {
+				reader.readUE()
+				continue
+			}
+			coefficients := min(64, 1<<(4+(sizeID<<1)))
+			if sizeID > 1 {
+				reader.readSE()
+			}
+			for i := 0; i < coefficients; i++ {
+				reader.readSE()
+			}
+		}
+	}
+}
+
+func skipHEVCShortTermRefPicSets(reader *bitReader, count int) bool {
+	if count > 64 {
+		return false
+	}
+	numDeltaPocs := make([]int, count)
+	for idx := 0; idx < count; idx++ {
+		interRefPicSetPrediction := idx != 0 && reader.readFlag()
+		if interRefPicSetPrediction {
+			reader.skipBits(1)
+			reader.readUE()
+			for j := 0; j <= numDeltaPocs[idx-1]; j++ {
+				usedByCurrPic := reader.readFlag()
+				if usedByCurrPic || reader.readFlag() {
+					numDeltaPocs[idx]++
+				}
+			}
+		} else {
+			numNegativePics := reader.readUE()
+			numPositivePics := reader.readUE()
+			if numNegativePics > 16 || numPositivePics > 16 {
+				return false
+			}
+			for i := 0; i < numNegativePics+numPositivePics; i++ {
+				reader.readUE()
+				reader.skipBits(1)
+			}
+			numDeltaPocs[idx] = numNegativePics + numPositivePics
+		}
+		if reader.overrun() {
+			return false
+		}
+	}
+	return true
+}
+
+func parseVUIColour(reader *bitReader, info *VideoStreamInfo) {
+	if reader.readFlag() {
+		if reader.readInt(8) == 255 {
+			reader.skipBits(32)
+		}
+	}
+	if reader.readFlag() {
+		reader.skipBits(1)
+	}
+	if reader.readFlag() {
+		reader.skipBits(3 + 1)
+		if reader.readFlag() {
+			info.ColourPrimaries = reader.readInt(8)
+			info.TransferCharacteristics = reader.readInt(8)
+			info.MatrixCoefficients = reader.readInt(8)
+		}
+	}
+	if reader.readFlag() {
+		reader.readUE()
+		reader.readUE()
+	}
+}
+
+func parseHEVCSPS(rbsp []byte, info *VideoStreamInfo) bool {
+	reader := newBitReader(rbsp[2:])
+	reader.skipBits(4)
+	maxSubLayersMinus1 := reader.readInt(3)
+	reader.skipBits(1)
+	parseHEVCProfileTierLevel(reader, info, maxSubLayersMinus1)
+	reader.readUE()
+
+	chromaFormatIdc := reader.readUE()
+	if chromaFormatIdc > 3 {
+		return false
+	}
+	if chromaFormatIdc == 3 {
+		reader.skipBits(1)
+	}
+	width := reader.readUE()
+	height := reader.readUE()
+	if reader.readFlag() {
+		subWidth, subHeight := 1, 1
+		if chromaFormatIdc == 1 || chromaFormatIdc == 2 {
+			subWidth = 2
+		}
+		if chromaFormatIdc == 1 {
+			subHeight = 2
+		}
+		width -= subWidth * (reader.readUE() + reader.readUE())
+		height -= subHeight * (reader.readUE() + reader.readUE())
+	}
+	info.Width = width
+	info.Height = height
+	info.ChromaFormat = chromaFormats[chromaFormatIdc]
+	info.BitDepth = reader.readUE() + 8
+	reader.readUE()
+
+	log2MaxPicOrderCntLsb := reader.readUE() + 4
+	firstSubLayer := maxSubLayersMinus1
+	if reader.readFlag() {
+		firstSubLayer = 0
+	}
+	for i := firstSubLayer; i <= maxSubLayersMinus1; i++ {
+		reader.readUE()
+		reader.readUE()
+		reader.readUE()
+	}
+	for i := 0; i < 6; i++ {
+		reader.readUE()
+	}
+	if reader.readFlag() && reader.readFlag() {
+		skipHEVCScalingListData(reader)
+	}
+	reader.skipBits(2)
+	if reader.readFlag() {
+		reader.skipBits(8)
+		reader.readUE()
+		reader.readUE()
+		reader.skipBits(1)
+	}
+	if !skipHEVCShortTermRefPicSets(reader, reader.readUE()) {
+		return true
+	}
+	if reader.readFlag() {
+		numLongTermRefPics := reader.readUE()
+		for i := 0; i < numLongTermRefPics; i++ {
+			reader.skipBits(log2MaxPicOrderCntLsb + 1)
+		}
+	}
+	reader.skipBits(2)
+
+	if reader.readFlag() {
+		parseVUIColour(reader, info)
+		reader.skipBits(3)
+		if reader.readFlag() {
+			reader.readUE()
+			reader.readUE()
+			reader.readUE()
+			reader.readUE()
+		}
+		if reader.readFlag() {
+			numUnitsInTick := reader.readInt(32)
+			timeScale := reader.readInt(32)
+			if numUnitsInTick != 0 && !reader.overrun() {
+				info.FrameRate = float64(timeScale) / float64(numUnitsInTick)
+			}
+		}
+	}
+	return true
+}
+
+func parseSEIMessages(rbsp []byte, info *VideoStreamInfo) {
+	for offset := 0; offset < len(rbsp) && rbsp[offset] != 0x80; {
+		payloadType := 0
+		for offset < len(rbsp) && rbsp[offset] == 0xff {
+			payloadType += 255
+			offset++
+		}
+		if offset >= len(rbsp) {
+			return
+		}
+		payloadType += int(rbsp[offset])
+		offset++
+
+		payloadSize := 0
+		for offset < len(rbsp) && rbsp[offset] == 0xff {
+			payloadSize += 255
+			offset++
+		}
+		if offset >= len(rbsp) {
+			return
+		}
+		payloadSize += int(rbsp[offset])
+		offset++
+		if offset+payloadSize > len(rbsp) {
+			return
+		}
+		payload := rbsp[offset : offset+payloadSize]
+		offset += payloadSize
+
+		switch payloadType {
+		case 4:
+			// HDR10+ is carried as ITU-T T.35 user data registered by
+			// Samsung (country 0xb5, provider 0x003c, application 4)
+			if len(payload) >= 6 && payload[0] == 0xb5 && binary.BigEndian.Uint16(payload[1:3]) == 0x003c &&
+				binary.BigEndian.Uint16(payload[3:5]) == 0x0001 && payload[5] == 4 {
+				info.HasHDR10Plus = true
+			}
+		case 137:
+			if len(payload) >= 24 {
+				display := &MasteringDisplayInfo{}
+				for i := 0; i < 3; i++ {
+					display.DisplayPrimaries[i][0] = int(binary.BigEndian.Uint16(payload[4*i : 4*i+2]))
+					display.DisplayPrimaries[i][1] = int(binary.BigEndian.Uint16(payload[4*i+2 : 4*i+4]))
+				}
+				display.WhitePoint[0] = int(binary.BigEndian.Uint16(payload[12:14]))
+				display.WhitePoint[1] = int(binary.BigEndian.Uint16(payload[14:16]))
+				display.MaxLuminance = float64(binary.BigEndian.Uint32(payload[16:20])) / 10000
+				display.MinLuminance = float64(binary.BigEndian.Uint32(payload[20:24])) / 10000
+				info.MasteringDisplay = display
+			}
+		case 144:
+			if len(payload) >= 4 {
+				info.MaxCLL = int(binary.BigEndian.Uint16(payload[0:2]))
+				info.MaxFALL = int(binary.BigEndian.Uint16(payload[2:4]))
+			}
+		}
+	}
+}
+
+func parseDolbyVisionRPU(rbsp []byte) *DolbyVisionInfo {
+	if len(rbsp) < 4 || rbsp[2] != 0x19 {
+		return nil
+	}
+
+	reader := newBitReader(rbsp[3:])
+	rpuType := reader.readInt(6)
+	rpuFormat := reader.readInt(11)
+	if rpuType != 2 {
+		return nil
+	}
+	// vdr_rpu_profile 0 is profile 5, 1 is profile 7 or 8, told apart by
+	// the residual data of the sequence info
+	rpuProfile := reader.readInt(4)
+	reader.skipBits(4)
+	profile := 8
+	if rpuProfile == 0 {
+		profile = 5
+	}
+	if !reader.readFlag() {
+		return &DolbyVisionInfo{Profile: profile}
+	}
+	reader.skipBits(1)
+	coefficientDataType := reader.readInt(2)
+	coefficientLog2Denom := 0
+	if coefficientDataType == 0 {
+		coefficientLog2Denom = reader.readUE()
+	}
+	reader.skipBits(2 + 1)
+
+	info := &DolbyVisionInfo{}
+	disableResidual := true
+	if rpuFormat&0x700 == 0 {
+		info.BLBitDepth = reader.readUE() + 8
+		info.ELBitDepth = reader.readUE() + 8
+		reader.readUE()
+		reader.skipBits(1 + 3 + 1)
+		disableResidual = reader.readFlag()
+	}
+
+	// profile 7 carries an enhancement layer with residual data, profile 8
+	// and 5 are single layer
+	if disableResidual || rpuProfile == 0 {
+		info.Profile = profile
+		return info
+	}
+	info.Profile = 7
+
+	fractionBits := coefficientLog2Denom
+	if coefficientDataType != 0 {
+		fractionBits = 32
+	}
+	readCoefficient := func(signed bool) (int, int) {
+		integer := 0
+		if coefficientDataType == 0 {
+			if signed {
+				integer = reader.readSE()
+			} else {
+				integer = reader.readUE()
+			}
+		}
+		return integer, reader.readInt(fractionBits)
+	}
+
+	reader.skipBits(1)
+	if reader.readFlag() {
+		return info
+	}
+	reader.readUE()
+	reader.readUE()
+	reader.readUE()
+	numPivots := make([]int, 3)
+	for cmp := 0; cmp < 3; cmp++ {
+		numPivots[cmp] = reader.readUE() + 2
+		reader.skipBits(info.BLBitDepth * numPivots[cmp])
+	}
+	nlqMethod := reader.readInt(3)
+	numXPartitions := reader.readUE() + 1
+	numYPartitions := reader.readUE() + 1
+
+	for partition := 0; partition < numXPartitions*numYPartitions; partition++ {
+		for cmp := 0; cmp < 3; cmp++ {
+			for pivot := 0; pivot < numPivots[cmp]-1; pivot++ {
+				switch reader.readUE() {
+				case 0:
+					polyOrder := reader.readUE() + 1
+					if polyOrder == 1 && reader.readFlag() {
+						readCoefficient(false)
+						continue
+					}
+					for i := 0; i <= polyOrder; i++ {
+						readCoefficient(true)
+					}
+				case 1:
+					mmrOrder := reader.readInt(2) + 1
+					readCoefficient(true)
+					for i := 0; i < mmrOrder*7; i++ {
+						readCoefficient(true)
+					}
+				}
+			}
+		}
+		if reader.overrun() {
+			return info
+		}
+
+		// a minimal enhancement layer signals an identity NLQ mapping
+		mel := true
+		for cmp := 0; cmp < 3; cmp++ {
+			offset := reader.readInt(info.ELBitDepth)
+			inMaxInteger, inMaxFraction := readCoefficient(false)
+			slopeInteger, slopeFraction, thresholdInteger, thresholdFraction := 0, 0, 0, 0
+			if nlqMethod == 0 {
+				slopeInteger, slopeFraction = readCoefficient(false)
+				thresholdInteger, thresholdFraction = readCoefficient(false)
+			}
+			if offset != 0 || inMaxInteger != 1 || inMaxFraction != 0 ||
+				slopeInteger != 0 || slopeFraction != 0 || thresholdInteger != 0 || thresholdFraction != 0 {
+				mel = false
+			}
+		}
+		if reader.overrun() {
+			return info
+		}
+		if mel {
+			info.ELType = "MEL"
+		} else {
+			info.ELType = "FEL"
+		}
+		break
+	}
+	return info
+}
+
+func analyzeHEVC(info *VideoStreamInfo, payload []byte) bool {
+	parsed := false
+	for _, nalUnit := range splitNALUnits(payload) {
+		if len(nalUnit) < 3 {
+			continue
+		}
+		switch (nalUnit[0] >> 1) & 0x3f {
+		case 33:
+			parsed = parseHEVCSPS(removeEmulationPrevention(nalUnit), info) || parsed
+		case 39, 40:
+			parseSEIMessages(removeEmulationPrevention(nalUnit)[2:], info)
+		case 62:
+			if rpu := parseDolbyVisionRPU(removeEmulationPrevention(nalUnit)); rpu != nil {
+				info.DolbyVision = rpu
+			}
+		}
+	}
+	return parsed
+}
+
+var avcHighProfiles = map[int]bool{100: true, 110: true, 122: true, 244: true, 44: true, 83: true, 86: true,
+	118: true, 128: true, 138: true, 139: true, 134: true, 135: true}
+var avcProfiles = map[int]string{66: "Baseline", 77: "Main", 88: "Extended", 100: "High", 110: "High 10",
+	122: "High 4:2:2", 244: "High 4:4:4 Predictive", 118: "Multiview High", 128: "Stereo High"}
+
+func parseAVCSPS(rbsp []byte, info *VideoStreamInfo) bool {
+	reader := newBitReader(rbsp[1:])
+	profile := reader.readInt(8)
+	reader.skipBits(8)
+	level := reader.readInt(8)
+	reader.readUE()
+
+	info.Profile = avcProfiles[profile]
+	if info.Profile == "" {
+		info.Profile = fmt.Sprintf("Profile %d", profile)
+	}
+	info.Level = fmt.Sprintf("%d.%d", level/10, level%10)
+
+	chromaFormatIdc := 1
+	info.BitDepth = 8
+	if avcHighProfiles[profile] {
+		chromaFormatIdc = reader.readUE()
+		if chromaFormatIdc > 3 {
+			return false
+		}
+		if chromaFormatIdc == 3 {
+			reader.skipBits(1)
+		}
+		info.BitDepth = reader.readUE() + 8
+		reader.readUE()
+		reader.skipBits(1)
+		if reader.readFlag() {
+			scalingLists := 8
+			if chromaFormatIdc == 3 {
+				scalingLists = 12
+			}
+			for i := 0; i < scalingLists; i++ {
+				if !reader.readFlag() {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				lastScale, nextScale := 8, 8
+				for j := 0; j < size && nextScale != 0; j++ {
+					nextScale = (lastScale + reader.readSE() + 256) % 256
+					if nextScale != 0 {
+						lastScale = nextScale
+					}
+				}
+			}
+		}
+	}
+	info.ChromaFormat = chromaFormats[chromaFormatIdc]
+
+	reader.readUE()
+	switch reader.readUE() {
+	case 0:
+		reader.readUE()
+	case 1:
+		reader.skipBits(1)
+		reader.readSE()
+		reader.readSE()
+		cycle := reader.readUE()
+		for i := 0; i < cycle; i++ {
+			reader.readSE()
+		}
+	}
+	reader.readUE()
+	reader.skipBits(1)
+	widthInMbs := reader.readUE() + 1
+	heightInMapUnits := reader.readUE() + 1
+	frameMbsOnly := reader.readInt(1)
+	if frameMbsOnly == 0 {
+		reader.skipBits(1)
+	}
+	reader.skipBits(1)
+
+	width := widthInMbs * 16
+	height := (2 - frameMbsOnly) * heightInMapUnits * 16
+	if reader.readFlag() {
+		cropUnitX, cropUnitY := 1, 2-frameMbsOnly
+		if chromaFormatIdc == 1 || chromaFormatIdc == 2 {
+			cropUnitX = 2
+		}
+		if chromaFormatIdc == 1 {
+			cropUnitY *= 2
+		}
+		width -= cropUnitX * (reader.readUE() + reader.readUE())
+		height -= cropUnitY * (reader.readUE() + reader.readUE())
+	}
+	info.Width = width
+	info.Height = height
+
+	if reader.readFlag() {
+		parseVUIColour(reader, info)
+		if reader.readFlag() {
+			numUnitsInTick := reader.readInt(32)
+			timeScale := reader.readInt(32)
+			if numUnitsInTick != 0 && !reader.overrun() {
+				info.FrameRate = float64(timeScale) / float64(numUnitsInTick) / 2
+			}
+		}
+	}
+	return !reader.overrun()
+}
+
+func analyzeAVC(info *VideoStreamInfo, payload []byte) bool {
+	parsed := false
+	for _, nalUnit := range splitNALUnits(payload) {
+		if len(nalUnit) < 2 {
+			continue
+		}
+		switch nalUnit[0] & 0x1f {
+		case 7:
+			parsed = parseAVCSPS(removeEmulationPrevention(nalUnit), info) || parsed
+		case 6:
+			parseSEIMessages(removeEmulationPrevention(nalUnit)[1:], info)
+		}
+	}
+	return parsed
+}
+
+var mpeg2FrameRates = []float64{0, 24000.0 / 1001, 24, 25, 30000.0 / 1001, 30, 50, 60000.0 / 1001, 60}
+var mpeg2Profiles = map[int]string{1: "High", 2: "Spatially Scalable", 3: "SNR Scalable", 4: "Main", 5: "Simple"}
+var mpeg2Levels = map[int]string{4: "High", 6: "High 1440", 8: "Main", 10: "Low"}
+
+func analyzeMPEG2(info *VideoStreamInfo, payload []byte) bool {
+	parsed := false
+	for offset := 0; offset+12 <= len(payload); offset++ {
+		if payload[offset] != 0x00 || payload[offset+1] != 0x00 || payload[offset+2] != 0x01 {
+			continue
+		}
+
+		switch payload[offset+3] {
+		case 0xb3:
+			reader := newBitReader(payload[offset+4:])
+			info.Width = reader.readInt(12)
+			info.Height = reader.readInt(12)
+			reader.skipBits(4)
+			if frameRateCode := reader.readInt(4); frameRateCode < len(mpeg2FrameRates) {
+				info.FrameRate = mpeg2FrameRates[frameRateCode]
+			}
+			info.BitDepth = 8
+			info.ChromaFormat = "4:2:0"
+			parsed = true
+		case 0xb5:
+			if payload[offset+4]>>4 == 1 {
+				reader := newBitReader(payload[offset+4:])
+				reader.skipBits(4 + 1)
+				info.Profile = mpeg2Profiles[reader.readInt(3)]
+				info.Level = mpeg2Levels[reader.readInt(4)]
+				reader.skipBits(1)
+				info.ChromaFormat = chromaFormats[reader.readInt(2)]
+			} else if payload[offset+4]>>4 == 2 {
+				reader := newBitReader(payload[offset+4:])
+				reader.skipBits(4 + 3)
+				if reader.readFlag() {
+					info.ColourPrimaries = reader.readInt(8)
+					info.TransferCharacteristics = reader.readInt(8)
+					info.MatrixCoefficients = reader.readInt(8)
+				}
+			}
+		}
+	}
+	return parsed
+}
+
+func analyzeVC1(info *VideoStreamInfo, payload []byte) bool {
+	offset := bytes.Index(payload, []byte{0x00, 0x00, 0x01, 0x0f})
+	if offset < 0 || offset+10 > len(payload) {
+		return false
+	}
+
+	reader := newBitReader(payload[offset+4:])
+	profile := reader.readInt(2)
+	level := reader.readInt(3)
+	chromaFormat := reader.readInt(2)
+	reader.skipBits(3 + 5 + 1)
+	info.Width = (reader.readInt(12) + 1) * 2
+	info.Height = (reader.readInt(12) + 1) * 2
+	info.Profile = map[int]string{0: "Simple", 1: "Main", 3: "Advanced"}[profile]
+	info.Level = fmt.Sprintf("%d", level)
+	info.BitDepth = 8
+	if chromaFormat == 1 {
+		info.ChromaFormat = "4:2:0"
+	}
+	return true
+}
+
+func isVideoStreamCodingType(streamCodingType StreamCodingType) bool {
+	switch streamCodingType {
+	case MPEG1Video, MPEG2Video, MPEG4AVCVideo, MPEG4MVCVideo, SMTPEVC1Video, HEVCVideo:
+		return true
+	}
+	return false
+}
+
+// AnalyzeVideoPayload feeds one PES payload to the analyzer and reports whether
+// the sequence header of the stream has been found.
+func AnalyzeVideoPayload(info *VideoStreamInfo, payload []byte) bool {
+	switch info.StreamCodingType {
+	case HEVCVideo:
+		info.Codec = "HEVC"
+		return analyzeHEVC(info, payload)
+	case MPEG4AVCVideo, MPEG4MVCVideo:
+		info.Codec = "AVC"
+		if info.StreamCodingType == MPEG4MVCVideo {
+			info.Codec = "MVC"
+		}
+		return analyzeAVC(info, payload)
+	case MPEG1Video, MPEG2Video:
+		info.Codec = "MPEG-2"
+		if info.StreamCodingType == MPEG1Video {
+			info.Codec = "MPEG-1"
+		}
+		return analyzeMPEG2(info, payload)
+	case SMTPEVC1Video:
+		info.Codec = "VC-1"
+		return analyzeVC1(info, payload)
+	}
+	return true
+}
+
+// AnalyzeVideoReader demuxes at most maxPackets packets (all of them if
+// maxPackets is not positive) and analyzes every video stream of the clip.
+// SEI messages are collected from the access units following the first
+// sequence header, since HDR metadata is only repeated at random access points.
+func AnalyzeVideoReader(r io.Reader, maxPackets int) ([]*VideoStreamInfo, error) {
+	const seiPackets = 8
+
+	demuxer := NewDemuxer(r)
+	infos := map[int]*VideoStreamInfo{}
+	remaining := map[int]int{}
+	var videoStreamInfosList []*VideoStreamInfo = nil
+
+	for packets := 0; maxPackets <= 0 || packets < maxPackets; packets++ {
+		_, err := demuxer.ReadPacket()
+		if err == io.EOF {
+			demuxer.Flush()
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, pesPacket := range demuxer.PendingPES() {
+			streamCodingType, _ := demuxer.StreamCodingType(pesPacket.PID)
+			if !isVideoStreamCodingType(streamCodingType) {
+				continue
+			}
+			info, ok := infos[pesPacket.PID]
+			if !ok {
+				info = &VideoStreamInfo{PID: pesPacket.PID, StreamCodingType: streamCodingType}
+				infos[pesPacket.PID] = info
+				remaining[pesPacket.PID] = seiPackets
+				videoStreamInfosList = append(videoStreamInfosList, info)
+			}
+			if remaining[pesPacket.PID] == 0 {
+				continue
+			}
+			if AnalyzeVideoPayload(info, pesPacket.Payload) || remaining[pesPacket.PID] < seiPackets {
+				remaining[pesPacket.PID]--
+			}
+		}
+
+		if err == io.EOF || (len(demuxer.PMTsList) > 0 && videoAnalysisDone(demuxer, remaining)) {
+			break
+		}
+	}
+
+	return videoStreamInfosList, nil
+}
+
+func videoAnalysisDone(demuxer *Demuxer, remaining map[int]int) bool {
+	for pid, streamCodingType := range demuxer.esPIDs {
+		if isVideoStreamCodingType(streamCodingType) {
+			if count, ok := remaining[pid]; !ok || count > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func AnalyzeVideo(path string, maxPackets int) ([]*VideoStreamInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return AnalyzeVideoReader(file, maxPackets)
+}
+
+var videoFormatHeights = map[VideoFormat]int{VF480I: 480, VF576I: 576, VF480P: 480, VF1080I: 1080,
+	VF720P: 720, VF1080P: 1080, VF576P: 576, VF2160P: 2160}
+
+// CompareVideoStream flags the differences between the attributes declared
+// in the playlist and the ones found in the bitstream. The enhancement layer
+// of a Dolby Vision stream is compared against the Dolby Vision attributes only.
+func CompareVideoStream(stream *Stream, info *VideoStreamInfo, isEnhancementLayer bool) []*VideoStreamMismatch {
+	var mismatchesList []*VideoStreamMismatch = nil
+	attributes := stream.StreamAttributes
+	mismatch := func(field string, expected, actual string) {
+		mismatchesList = append(mismatchesList, &VideoStreamMismatch{
+			Stream:   stream,
+			PID:      info.PID,
+			Field:    field,
+			Expected: expected,
+			Actual:   actual,
+		})
+	}
+
+	if isEnhancementLayer {
+		if info.DolbyVision == nil {
+			mismatch("DynamicRangeType", DolbyVision.String(), SDR.String())
+		}
+		return mismatchesList
+	}
+
+	if height, ok := videoFormatHeights[attributes.VideoFormat]; ok && info.Height != 0 && height != info.Height {
+		mismatch("VideoFormat", attributes.VideoFormat.String(), fmt.Sprintf("%dx%d", info.Width, info.Height))
+	}
+	if attributes.StreamCodingType != HEVCVideo {
+		return mismatchesList
+	}
+
+	actualRange := info.DynamicRangeType()
+	if attributes.DynamicRangeType == DolbyVision && actualRange == HDR10 {
+		actualRange = DolbyVision
+	}
+	if attributes.DynamicRangeType != actualRange {
+		mismatch("DynamicRangeType", attributes.DynamicRangeType.String(), actualRange.String())
+	}
+	if info.ColourPrimaries != 0 && attributes.ColorSpace != info.ColorSpace() {
+		mismatch("ColorSpace", attributes.ColorSpace.String(), info.ColorSpace().String())
+	}
+	if attributes.HDRPlusFlag != info.HasHDR10Plus {
+		mismatch("HDRPlusFlag", fmt.Sprint(attributes.HDRPlusFlag), fmt.Sprint(info.HasHDR10Plus))
+	}
+	return mismatchesList
+}
+
+// AnalyzeVideoStreams analyzes the clip of every play item and compares the
+// primary video and Dolby Vision streams of its STN table with the bitstream.
+func (mpls *MPLS) AnalyzeVideoStreams(bdmvRoot string, maxPackets int) ([]*VideoStreamAnalysis, error) {
+	var videoStreamAnalysesList []*VideoStreamAnalysis = nil
+	for _, playItem := range mpls.PlayList.PlayItemList {
+		infos, err := AnalyzeVideo(playItem.ClipStreamPath(bdmvRoot), maxPackets)
+		if err != nil {
+			return nil, err
+		}
+
+		analyze := func(stream *Stream, isEnhancementLayer bool) {
+			analysis := &VideoStreamAnalysis{PlayItem: playItem, Stream: stream}
+			for _, info := range infos {
+				if stream.StreamEntry.StreamType != 0x02 && info.PID == stream.StreamEntry.RefToStreamPID {
+					analysis.VideoStreamInfo = info
+					analysis.MismatchesList = CompareVideoStream(stream, info, isEnhancementLayer)
+				}
+			}
+			videoStreamAnalysesList = append(videoStreamAnalysesList, analysis)
+		}
+		for _, stream := range playItem.STNTable.PrimaryVideoStreamsList {
+			analyze(stream, false)
+		}
+		for _, stream := range playItem.STNTable.DVStreamsList {
+			analyze(stream, true)
+		}
+	}
+	return videoStreamAnalysesList, nil
+}
@@ -0,0 +1,322 @@
+package go_mpls
+
+import (
+	"testing"
+)
+
+func (w *bitWriter) writeUE(value int) {
+	value++
+	bits := 0
+	for v := value; v > 0; v >>= 1 {
+		bits++
+	}
+	w.writeBits(0, bits-1)
+	w.writeBits(uint64(value), bits)
+}
+
+func buildAVCSPS() []byte {
+	w := &bitWriter{}
+	w.writeBits(0x67, 8)
+	w.writeBits(100, 8)
+	w.writeBits(0, 8)
+	w.writeBits(41, 8)
+	w.writeUE(0)
+	w.writeUE(1)
+	w.writeUE(0)
+	w.writeUE(0)
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	w.writeUE(0)
+	w.writeUE(0)
+	w.writeUE(0)
+	w.writeUE(4)
+	w.writeBits(0, 1)
+	w.writeUE(119)
+	w.writeUE(67)
+	w.writeBits(1, 1)
+	w.writeBits(1, 1)
+	w.writeBits(1, 1)
+	w.writeUE(0)
+	w.writeUE(0)
+	w.writeUE(0)
+	w.writeUE(4)
+	w.writeBits(1, 1)
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	w.writeBits(5, 3)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	w.writeBits(1, 8)
+	w.writeBits(1, 8)
+	w.writeBits(1, 8)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	w.writeBits(1001, 32)
+	w.writeBits(48000, 32)
+	w.writeBits(1, 1)
+	return w.data
+}
+
+func TestAnalyzeAVC(t *testing.T) {
+	payload := append([]byte{0x00, 0x00, 0x00, 0x01}, buildAVCSPS()...)
+	info := &VideoStreamInfo{StreamCodingType: MPEG4AVCVideo}
+	if !AnalyzeVideoPayload(info, payload) {
+		t.Fatal("sequence header not found")
+	}
+	if info.Codec != "AVC" || info.Profile != "High" || info.Level != "4.1" {
+		t.Fatalf("unexpected profile %#v", info)
+	}
+	if info.Width != 1920 || info.Height != 1080 || info.BitDepth != 8 || info.ChromaFormat != "4:2:0" {
+		t.Fatalf("unexpected picture format %#v", info)
+	}
+	if info.FrameRate < 23.97 || info.FrameRate > 23.98 || info.ColorSpace() != BT709 {
+		t.Fatalf("unexpected timing or colour %#v", info)
+	}
+}
+
+func TestAnalyzeHEVCSEI(t *testing.T) {
+	sei := []byte{0x00, 0x00, 0x01, 0x4e, 0x01}
+	sei = append(sei, 137, 24,
+		0x33, 0xc2, 0x86, 0xc4, 0x1d, 0x4c, 0x0b, 0xb8, 0x84, 0xd0, 0x3e, 0x80,
+		0x3d, 0x13, 0x40, 0x42, 0x00, 0x98, 0x96, 0x80, 0x00, 0x00, 0x00, 0x32)
+	sei = append(sei, 144, 4, 0x03, 0xe8, 0x01, 0x90)
+	sei = append(sei, 4, 7, 0xb5, 0x00, 0x3c, 0x00, 0x01, 0x04, 0x01)
+	sei = append(sei, 0x80)
+
+	info := &VideoStreamInfo{StreamCodingType: HEVCVideo, ColourPrimaries: 9, TransferCharacteristics: 16}
+	AnalyzeVideoPayload(info, sei)
+	if info.MasteringDisplay == nil || info.MasteringDisplay.MaxLuminance != 1000 || info.MasteringDisplay.MinLuminance != 0.005 {
+		t.Fatalf("unexpected mastering display %#v", info.MasteringDisplay)
+	}
+	if info.MaxCLL != 1000 || info.MaxFALL != 400 || !info.HasHDR10Plus {
+		t.Fatalf("unexpected light level %#v", info)
+	}
+
+	stream := &Stream{
+		StreamEntry:      &StreamEntry{StreamType: 0x01, RefToStreamPID: 0x1011},
+		StreamAttributes: &StreamAttributes{StreamCodingType: HEVCVideo, VideoFormat: VF2160P, DynamicRangeType: SDR, ColorSpace: BT2020},
+	}
+	info.Height = 2160
+	mismatches := CompareVideoStream(stream, info, false)
+	if len(mismatches) != 2 || mismatches[0].Field != "DynamicRangeType" || mismatches[1].Field != "HDRPlusFlag" {
+		t.Fatalf("unexpected mismatches %#v", mismatches)
+	}
+}
+
+// addEmulationPrevention escapes the start code prefixes in an RBSP.
+func addEmulationPrevention(rbsp []byte) []byte {
+	var nalUnit []byte = nil
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 0x03 {
+			nalUnit = append(nalUnit, 0x03)
+			zeros = 0
+		}
+		if b == 0x00 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		nalUnit = append(nalUnit, b)
+	}
+	return nalUnit
+}
+
+// buildHEVCSPS returns the SPS of a 3840x2160 Main 10 stream at 23.976 fps
+// with BT.2020 primaries and the given transfer characteristics.
+func buildHEVCSPS(transferCharacteristics int) []byte {
+	w := &bitWriter{}
+	w.writeBits(0x4201, 16)
+	w.writeBits(0, 4)
+	w.writeBits(0, 3)
+	w.writeBits(1, 1)
+	w.writeBits(0, 2)
+	w.writeBits(0, 1)
+	w.writeBits(2, 5)
+	w.writeBits(0, 32)
+	w.writeBits(0, 4+43+1)
+	w.writeBits(153, 8)
+	w.writeUE(0)
+	w.writeUE(1)
+	w.writeUE(3840)
+	w.writeUE(2160)
+	w.writeBits(0, 1)
+	w.writeUE(2)
+	w.writeUE(2)
+	w.writeUE(4)
+	w.writeBits(1, 1)
+	w.writeUE(0)
+	w.writeUE(0)
+	w.writeUE(0)
+	for i := 0; i < 6; i++ {
+		w.writeUE(0)
+	}
+	w.writeBits(0, 1)
+	w.writeBits(0, 2)
+	w.writeBits(0, 1)
+	w.writeUE(0)
+	w.writeBits(0, 1)
+	w.writeBits(0, 2)
+	w.writeBits(1, 1)
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	w.writeBits(5, 3)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	w.writeBits(primaries2020, 8)
+	w.writeBits(uint64(transferCharacteristics), 8)
+	w.writeBits(9, 8)
+	w.writeBits(0, 1)
+	w.writeBits(0, 3)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	w.writeBits(1001, 32)
+	w.writeBits(24000, 32)
+	w.writeBits(1, 1)
+	return addEmulationPrevention(w.data)
+}
+
+func TestAnalyzeHEVC(t *testing.T) {
+	for _, test := range []struct {
+		transferCharacteristics int
+		dynamicRangeType        DynamicRangeType
+	}{
+		{transferPQ, HDR10},
+		{1, SDR},
+	} {
+		payload := append([]byte{0x00, 0x00, 0x00, 0x01}, buildHEVCSPS(test.transferCharacteristics)...)
+		info := &VideoStreamInfo{StreamCodingType: HEVCVideo}
+		if !AnalyzeVideoPayload(info, payload) {
+			t.Fatal("sequence parameter set not found")
+		}
+		if info.Codec != "HEVC" || info.Profile != "Main 10" || info.Tier != "Main" || info.Level != "5.1" {
+			t.Fatalf("unexpected profile %#v", info)
+		}
+		if info.Width != 3840 || info.Height != 2160 || info.BitDepth != 10 || info.ChromaFormat != "4:2:0" {
+			t.Fatalf("unexpected picture format %#v", info)
+		}
+		if info.FrameRate < 23.97 || info.FrameRate > 23.98 || info.ColorSpace() != BT2020 {
+			t.Fatalf("unexpected timing or colour %#v", info)
+		}
+		if info.TransferCharacteristics != test.transferCharacteristics || info.DynamicRangeType() != test.dynamicRangeType {
+			t.Fatalf("unexpected transfer %d for %s", info.TransferCharacteristics, info.DynamicRangeType())
+		}
+	}
+}
+
+// buildDolbyVisionRPU returns an unspecified NAL unit carrying a 10 bit
+// Dolby Vision RPU of vdr_rpu_profile rpuProfile. Without residual data the
+// RPU describes a single layer stream, otherwise its NLQ offset tells a full
+// enhancement layer from a minimal one.
+func buildDolbyVisionRPU(rpuProfile int, residual bool, nlqOffset int) []byte {
+	w := &bitWriter{}
+	w.writeBits(0x7c01, 16)
+	w.writeBits(0x19, 8)
+	w.writeBits(2, 6)
+	w.writeBits(0, 11)
+	w.writeBits(uint64(rpuProfile), 4)
+	w.writeBits(0, 4)
+	w.writeBits(1, 1)
+	w.writeBits(0, 1)
+	w.writeBits(0, 2)
+	w.writeUE(23)
+	w.writeBits(0, 2+1)
+	w.writeUE(2)
+	w.writeUE(2)
+	w.writeUE(4)
+	w.writeBits(0, 1+3+1)
+	if !residual {
+		w.writeBits(1, 1)
+		w.writeBits(1, 1)
+		return addEmulationPrevention(w.data)
+	}
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	w.writeBits(0, 1)
+	w.writeUE(0)
+	w.writeUE(0)
+	w.writeUE(0)
+	for cmp := 0; cmp < 3; cmp++ {
+		w.writeUE(0)
+		w.writeBits(0, 10)
+		w.writeBits(1023, 10)
+	}
+	w.writeBits(0, 3)
+	w.writeUE(0)
+	w.writeUE(0)
+	for cmp := 0; cmp < 3; cmp++ {
+		w.writeUE(0)
+		w.writeUE(0)
+		w.writeBits(0, 1)
+		for i := 0; i < 2; i++ {
+			w.writeUE(0)
+			w.writeBits(0, 23)
+		}
+	}
+	for cmp := 0; cmp < 3; cmp++ {
+		w.writeBits(uint64(nlqOffset), 10)
+		w.writeUE(1)
+		w.writeBits(0, 23)
+		w.writeUE(0)
+		w.writeBits(0, 23)
+		w.writeUE(0)
+		w.writeBits(0, 23)
+	}
+	w.writeBits(1, 1)
+	return addEmulationPrevention(w.data)
+}
+
+func TestAnalyzeHEVCDolbyVision(t *testing.T) {
+	for _, test := range []struct {
+		rpuProfile int
+		residual   bool
+		nlqOffset  int
+		profile    int
+		elType     string
+	}{
+		{1, false, 0, 8, ""},
+		{1, true, 0, 7, "MEL"},
+		{1, true, 512, 7, "FEL"},
+		{0, false, 0, 5, ""},
+	} {
+		payload := append([]byte{0x00, 0x00, 0x00, 0x01}, buildHEVCSPS(transferPQ)...)
+		payload = append(payload, 0x00, 0x00, 0x01)
+		payload = append(payload, buildDolbyVisionRPU(test.rpuProfile, test.residual, test.nlqOffset)...)
+		info := &VideoStreamInfo{StreamCodingType: HEVCVideo}
+		if !AnalyzeVideoPayload(info, payload) {
+			t.Fatal("sequence parameter set not found")
+		}
+		if info.DolbyVision == nil || info.DynamicRangeType() != DolbyVision {
+			t.Fatalf("Dolby Vision not detected %#v", info)
+		}
+		if *info.DolbyVision != (DolbyVisionInfo{Profile: test.profile, ELType: test.elType, BLBitDepth: 10, ELBitDepth: 10}) {
+			t.Fatalf("unexpected Dolby Vision configuration %#v", info.DolbyVision)
+		}
+	}
+
+	// an RPU without sequence info still has a profile
+	w := &bitWriter{}
+	w.writeBits(0x7c01, 16)
+	w.writeBits(0x19, 8)
+	w.writeBits(2, 6)
+	w.writeBits(0, 11)
+	w.writeBits(0, 4+4)
+	w.writeBits(0, 1)
+	w.writeBits(1, 1)
+	info := &VideoStreamInfo{StreamCodingType: HEVCVideo}
+	AnalyzeVideoPayload(info, append([]byte{0x00, 0x00, 0x01}, addEmulationPrevention(w.data)...))
+	if info.DolbyVision == nil || info.DolbyVision.Profile != 5 {
+		t.Fatalf("unexpected Dolby Vision configuration %#v", info.DolbyVision)
+	}
+
+	// an RPU of another type is not Dolby Vision
+	rpu := buildDolbyVisionRPU(1, false, 0)
+	rpu[3] = 0x04
+	info = &VideoStreamInfo{StreamCodingType: HEVCVideo}
+	AnalyzeVideoPayload(info, append([]byte{0x00, 0x00, 0x01}, rpu...))
+	if info.DolbyVision != nil {
+		t.Fatalf("unexpected Dolby Vision configuration %#v", info.DolbyVision)
+	}
+}